@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"log"
 	"time"
 
@@ -27,6 +28,9 @@ func main() {
 	_ = viper.BindEnv("database.url", "DATABASE_URL")
 	_ = viper.BindEnv("uploads.path", "UPLOADS_PATH")
 	_ = viper.BindEnv("jwt.secret", "JWT_SECRET")
+	_ = viper.BindEnv("acts.font", "ACTS_FONT")
+	_ = viper.BindEnv("scan.clamd", "CLAMD_ADDRESS")
+	_ = viper.BindEnv("server.public_url", "PUBLIC_URL")
 
 	logger, _ := zap.NewProduction()
 	defer logger.Sync()
@@ -41,24 +45,134 @@ func main() {
 	projectRepo := repository.NewProjectRepository(gdb)
 	defectRepo := repository.NewDefectRepository(gdb)
 	attachRepo := repository.NewAttachmentRepository(gdb)
+	memberRepo := repository.NewProjectMemberRepository(gdb)
+	fieldRepo := repository.NewCustomFieldRepository(gdb)
+	orgRepo := repository.NewOrganizationRepository(gdb)
+	categoryRepo := repository.NewCategoryRepository(gdb)
+	normativeRepo := repository.NewNormativeRepository(gdb)
 
 	// services & handlers
 	jwtSecret := viper.GetString("jwt.secret")
 	authSvc := service.NewAuthServiceWithSecret(userRepo, jwtSecret)
 	authHandler := handler.NewAuthHandler(authSvc)
 
+	// project membership
+	memberSvc := service.NewMembershipService(memberRepo, projectRepo, userRepo)
+	memberHandler := handler.NewMemberHandler(memberSvc)
+
+	// organizations and defect classification catalog
+	orgHandler := handler.NewOrganizationHandler(service.NewOrganizationService(orgRepo))
+	categoryHandler := handler.NewCategoryHandler(service.NewCategoryService(categoryRepo, orgRepo))
+	// building codes cited by defects
+	normativeHandler := handler.NewNormativeHandler(service.NewNormativeService(normativeRepo))
+
 	// project/defect services & handlers
-	projectSvc := service.NewProjectService(projectRepo)
-	defectSvc := service.NewDefectService(defectRepo, projectRepo, userRepo)
-	projectHandler := handler.NewProjectHandler(projectSvc, defectSvc)
+	projectSvc := service.NewProjectService(projectRepo, orgRepo)
+	fieldSvc := service.NewCustomFieldService(fieldRepo, memberSvc)
+	fieldHandler := handler.NewCustomFieldHandler(fieldSvc)
+	labelRepo := repository.NewLabelRepository(gdb)
+	defectSvc := service.NewDefectService(defectRepo, projectRepo, userRepo, service.WithCustomFields(fieldRepo), service.WithCatalog(categoryRepo, orgRepo), service.WithNormatives(normativeRepo),
+		service.WithMembership(memberSvc), service.WithLabels(labelRepo))
+	projectHandler := handler.NewProjectHandler(projectSvc, defectSvc, fieldSvc)
+	metaHandler := handler.NewMetaHandler(service.NewDefectMetaService(projectRepo, defectRepo, memberSvc))
 	// attachments
 	storageSvc := service.NewLocalStorage()
-	attachHandler := handler.NewAttachmentHandler(storageSvc, attachRepo, defectSvc)
+	attachSvc := service.NewAttachmentService(attachRepo, defectRepo, memberSvc)
+	// new attachments are held until clamd has scanned them (scan.clamd; off when empty)
+	scanner, err := service.NewScannerFromConfig()
+	if err != nil {
+		log.Fatalf("scan: %v", err)
+	}
+	scanSvc := service.NewScanService(repository.NewScanRepository(gdb), scanner, storageSvc)
+	if scanner != nil {
+		go func() {
+			tick := time.NewTicker(time.Minute)
+			for {
+				// submitted uploads wake the loop; the ticker retries while clamd is down
+				if n, err := scanSvc.ScanPending(context.Background()); err != nil {
+					logger.Warn("scan attachments", zap.Error(err))
+				} else if n > 0 {
+					logger.Info("scanned attachments", zap.Int("count", n))
+				}
+				select {
+				case <-scanSvc.Wake():
+				case <-tick.C:
+				}
+			}
+		}()
+	}
+	// storage quotas of projects (uploads.project_quota unless set on the project) and organizations
+	quotaSvc := service.NewQuotaService(repository.NewQuotaRepository(gdb), memberSvc, viper.GetInt64("uploads.project_quota"))
+	quotaHandler := handler.NewQuotaHandler(quotaSvc)
+	go func() {
+		for w := range quotaSvc.Warnings() {
+			if w.Err != nil {
+				logger.Warn("storage quota usage", zap.Uint("defect_id", w.DefectID), zap.Error(w.Err))
+				continue
+			}
+			logger.Warn("storage quota", zap.Uint("project_id", w.ProjectID), zap.Uint("organization_id", w.OrganizationID),
+				zap.String("name", w.Name), zap.Int("level", w.Level), zap.Int64("used", w.Used), zap.Int64("quota", w.Quota))
+		}
+	}()
+	// attachment content is stored once per SHA-256; files from before are moved over in the background
+	blobSvc := service.NewBlobService(repository.NewBlobRepository(gdb), storageSvc, scanSvc, quotaSvc)
+	attachHandler := handler.NewAttachmentHandler(storageSvc, attachRepo, defectSvc, attachSvc, blobSvc)
+	// signed download links: served by the API, or by a static server asking /files/authorize in proxy mode
+	linkSecret := viper.GetString("uploads.link_secret")
+	if linkSecret == "" {
+		linkSecret = jwtSecret
+	}
+	linkBase := viper.GetString("server.public_url") + "/api/v1/files"
+	if viper.GetString("uploads.serve_via") == "proxy" {
+		linkBase = viper.GetString("uploads.public_url")
+	}
+	linkSvc := service.NewLinkService(linkSecret, viper.GetString("uploads.serve_via"), linkBase, viper.GetDuration("uploads.link_max_ttl"))
+	fileHandler := handler.NewFileHandler(linkSvc, attachRepo)
+	annotationHandler := handler.NewAnnotationHandler(service.NewAnnotationService(repository.NewAnnotationRepository(gdb), attachRepo, defectRepo, memberSvc,
+		storageSvc, viper.GetString("acts.font")), attachRepo)
+	go func() {
+		if n, err := blobSvc.AdoptLegacy(context.Background()); err != nil {
+			logger.Warn("adopt legacy attachments", zap.Error(err))
+		} else if n > 0 {
+			logger.Info("adopted legacy attachments", zap.Int("count", n))
+		}
+	}()
+	// resumable (tus) uploads; abandoned sessions are swept hourly
+	uploadSvc := service.NewUploadService(repository.NewUploadRepository(gdb), storageSvc, attachSvc, defectRepo, memberSvc, scanSvc, quotaSvc,
+		viper.GetInt64("uploads.max_size"), viper.GetDuration("uploads.session_ttl"))
+	uploadHandler := handler.NewUploadHandler(uploadSvc)
+	go func() {
+		for range time.Tick(time.Hour) {
+			if n, err := uploadSvc.ExpireSessions(context.Background()); err != nil {
+				logger.Warn("expire upload sessions", zap.Error(err))
+			} else if n > 0 {
+				logger.Info("expired upload sessions", zap.Int("count", n))
+			}
+		}
+	}()
 	userHandler := handler.NewUserHandler(userRepo, authSvc)
 	// comments
 	commentRepo := repository.NewCommentRepository(gdb)
+	// verification of fixes
+	verificationHandler := handler.NewVerificationHandler(service.NewVerificationService(repository.NewVerificationRepository(gdb), defectRepo, attachRepo, memberSvc))
+	// signed acceptance acts
+	actSvc := service.NewActService(repository.NewActRepository(gdb), defectRepo, projectRepo, userRepo, memberSvc,
+		service.NewPDFActRenderer(viper.GetString("acts.font")), service.UploadsPath())
+	actHandler := handler.NewActHandler(actSvc)
+	// inspections by checklist; failed items raise defects
+	inspectionHandler := handler.NewInspectionHandler(service.NewInspectionService(repository.NewInspectionRepository(gdb), defectSvc, projectRepo, userRepo, memberSvc))
 	commentSvc := service.NewCommentService(commentRepo)
 	commentHandler := handler.NewCommentHandler(commentSvc)
+	// labels & statistics
+	labelSvc := service.NewLabelService(labelRepo, defectRepo, memberSvc)
+	labelHandler := handler.NewLabelHandler(labelSvc)
+	statsHandler := handler.NewStatsHandler(service.NewStatsService(repository.NewStatsRepository(gdb)))
+	// search
+	searchSvc := service.NewSearchService(repository.NewSearchRepository(gdb), memberSvc)
+	searchHandler := handler.NewSearchHandler(searchSvc)
+	// saved views
+	viewSvc := service.NewSavedViewService(repository.NewSavedViewRepository(gdb), defectSvc, memberSvc)
+	viewHandler := handler.NewViewHandler(viewSvc, fieldSvc)
 
 	r := gin.Default()
 
@@ -66,9 +180,9 @@ func main() {
 	// in production configure allowed origins properly
 	r.Use(cors.New(cors.Config{
 		AllowOrigins:     []string{"http://localhost:5173"},
-		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization"},
-		ExposeHeaders:    []string{"Content-Length"},
+		AllowMethods:     []string{"GET", "HEAD", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization", "Tus-Resumable", "Upload-Length", "Upload-Metadata", "Upload-Offset", "Upload-Defer-Length"},
+		ExposeHeaders:    []string{"Content-Length", "ETag", "X-Content-SHA256", "Location", "Tus-Resumable", "Tus-Version", "Tus-Extension", "Tus-Max-Size", "Upload-Offset", "Upload-Length", "Upload-Expires", "X-Attachment-ID"},
 		AllowCredentials: true,
 		MaxAge:           12 * time.Hour,
 	}))
@@ -90,24 +204,125 @@ func main() {
 		projects.GET("/:id/defects", projectHandler.ListDefects)
 		projects.GET("/:id", projectHandler.GetProject)
 		projects.GET(":id/defects/:defectId", projectHandler.GetDefect)
+		api.GET("/defects/by-key/:key", projectHandler.GetDefectByKey)
+		projects.POST(":id/defects/bulk", middleware.JWTAuthMiddleware(), middleware.RequireRole("engineer", "manager", "admin"), projectHandler.BulkDefects)
 		projects.PATCH(":id/defects/:defectId", middleware.JWTAuthMiddleware(), middleware.RequireRole("engineer", "manager", "admin"), projectHandler.UpdateDefect)
 		// attachments (upload under defects)
 		projects.POST(":id/attachments", middleware.JWTAuthMiddleware(), middleware.RequireRole("engineer", "manager", "admin"), attachHandler.Upload)
 		api.GET("/attachments/:id", middleware.JWTAuthMiddleware(), attachHandler.Download)
+		api.PATCH("/attachments/:id", middleware.JWTAuthMiddleware(), attachHandler.Classify)
+		api.DELETE("/attachments/:id", middleware.JWTAuthMiddleware(), attachHandler.Delete)
+		api.GET("/attachments/:id/verify", middleware.JWTAuthMiddleware(), attachHandler.Verify)
+		api.POST("/attachments/:id/versions", middleware.JWTAuthMiddleware(), middleware.RequireRole("engineer", "manager", "admin"), attachHandler.UploadVersion)
+		api.GET("/attachments/:id/versions", middleware.JWTAuthMiddleware(), attachHandler.ListVersions)
+		api.GET("/attachments/:id/versions/:version", middleware.JWTAuthMiddleware(), attachHandler.DownloadVersion)
+		api.GET("/attachments/:id/annotations", middleware.JWTAuthMiddleware(), annotationHandler.Get)
+		api.PUT("/attachments/:id/annotations", middleware.JWTAuthMiddleware(), middleware.RequireRole("engineer", "manager", "admin"), annotationHandler.Put)
+		api.DELETE("/attachments/:id/annotations", middleware.JWTAuthMiddleware(), middleware.RequireRole("engineer", "manager", "admin"), annotationHandler.Delete)
+		api.GET("/attachments/:id/annotations.jpg", middleware.JWTAuthMiddleware(), annotationHandler.Render)
+		api.POST("/attachments/:id/links", middleware.JWTAuthMiddleware(), fileHandler.CreateLink)
+		// the link is the credential; a bearer token, if sent, must match a bound link
+		api.GET("/files/authorize", middleware.OptionalJWTAuthMiddleware(), fileHandler.Authorize)
+		api.GET("/files/:id", middleware.OptionalJWTAuthMiddleware(), fileHandler.Serve)
+		projects.GET(":id/defects/:defectId/photo-pairs", middleware.JWTAuthMiddleware(), attachHandler.PhotoPairs)
+		// resumable uploads (tus 1.0)
+		uploads := api.Group("/uploads")
+		uploads.OPTIONS("", uploadHandler.RequireTus, uploadHandler.Options)
+		uploads.OPTIONS("/:id", uploadHandler.RequireTus, uploadHandler.Options)
+		uploads.POST("", middleware.JWTAuthMiddleware(), middleware.RequireRole("engineer", "manager", "admin"), uploadHandler.RequireTus, uploadHandler.Create)
+		uploads.HEAD("/:id", middleware.JWTAuthMiddleware(), uploadHandler.RequireTus, uploadHandler.Head)
+		uploads.PATCH("/:id", middleware.JWTAuthMiddleware(), uploadHandler.RequireTus, uploadHandler.Patch)
+		uploads.DELETE("/:id", middleware.JWTAuthMiddleware(), uploadHandler.RequireTus, uploadHandler.Delete)
+		uploads.GET("/:id", middleware.JWTAuthMiddleware(), uploadHandler.Get)
 		// listing attachments by defect
 		api.GET("/attachments", middleware.JWTAuthMiddleware(), attachHandler.List)
 		projects.GET(":id/defects/:defectId/attachments", middleware.JWTAuthMiddleware(), attachHandler.List)
+		projects.GET(":id/defects/:defectId/attachments.zip", middleware.JWTAuthMiddleware(), attachHandler.DownloadZip)
+		projects.GET(":id/attachments.zip", middleware.JWTAuthMiddleware(), attachHandler.DownloadZip)
 		// users list for autocomplete
 		api.GET("/users", userHandler.ListUsers)
 		api.GET("/users/me", middleware.JWTAuthMiddleware(), userHandler.Me)
 		api.PATCH("/users/me", middleware.JWTAuthMiddleware(), userHandler.UpdateMe)
 		// admin: update arbitrary user
 		api.PATCH("/users/:id", middleware.JWTAuthMiddleware(), middleware.RequireRole("admin"), userHandler.UpdateUser)
+		// verification of fixes
+		projects.POST(":id/defects/:defectId/verification", middleware.JWTAuthMiddleware(), middleware.RequireRole("engineer", "manager", "admin"), verificationHandler.Submit)
+		projects.POST(":id/defects/:defectId/verification/approve", middleware.JWTAuthMiddleware(), verificationHandler.Approve)
+		projects.POST(":id/defects/:defectId/verification/reject", middleware.JWTAuthMiddleware(), verificationHandler.Reject)
+		projects.GET(":id/defects/:defectId/verifications", middleware.JWTAuthMiddleware(), verificationHandler.List)
+		// acceptance acts (drafting limited to project managers in the service)
+		projects.POST(":id/acts", middleware.JWTAuthMiddleware(), actHandler.Create)
+		projects.GET(":id/acts", middleware.JWTAuthMiddleware(), actHandler.List)
+		projects.GET(":id/acts/:actId", middleware.JWTAuthMiddleware(), actHandler.Get)
+		projects.GET(":id/acts/:actId/pdf", middleware.JWTAuthMiddleware(), actHandler.PDF)
+		projects.POST(":id/acts/:actId/sign", middleware.JWTAuthMiddleware(), actHandler.Sign)
+		// inspection checklists and inspections (rights checked in the service)
+		api.POST("/inspection-templates", middleware.JWTAuthMiddleware(), middleware.RequireRole("manager", "admin"), inspectionHandler.CreateTemplate)
+		api.PUT("/inspection-templates/:id", middleware.JWTAuthMiddleware(), inspectionHandler.UpdateTemplate)
+		projects.GET(":id/inspection-templates", middleware.JWTAuthMiddleware(), inspectionHandler.ListTemplates)
+		projects.POST(":id/inspection-templates", middleware.JWTAuthMiddleware(), inspectionHandler.CreateTemplate)
+		projects.POST(":id/inspections", middleware.JWTAuthMiddleware(), inspectionHandler.Schedule)
+		projects.GET(":id/inspections", middleware.JWTAuthMiddleware(), inspectionHandler.List)
+		projects.GET(":id/inspections/:inspectionId", middleware.JWTAuthMiddleware(), inspectionHandler.Get)
+		projects.PUT(":id/inspections/:inspectionId/results", middleware.JWTAuthMiddleware(), inspectionHandler.RecordResults)
+		projects.POST(":id/inspections/:inspectionId/complete", middleware.JWTAuthMiddleware(), inspectionHandler.Complete)
+		projects.POST(":id/inspections/:inspectionId/cancel", middleware.JWTAuthMiddleware(), inspectionHandler.Cancel)
 		// comments under defects
 		projects.POST(":id/defects/:defectId/comments", middleware.JWTAuthMiddleware(), commentHandler.Create)
-		projects.GET(":id/defects/:defectId/comments", commentHandler.List)
+		projects.GET(":id/defects/:defectId/comments", middleware.OptionalJWTAuthMiddleware(), commentHandler.List)
 		// also expose a global comments list endpoint that accepts ?defect_id= for flexibility
-		api.GET("/comments", commentHandler.List)
+		api.GET("/comments", middleware.OptionalJWTAuthMiddleware(), commentHandler.List)
+		// project members
+		projects.GET(":id/members", middleware.JWTAuthMiddleware(), memberHandler.List)
+		projects.POST(":id/members", middleware.JWTAuthMiddleware(), middleware.RequireRole("manager", "admin"), memberHandler.Add)
+		projects.DELETE(":id/members/:userId", middleware.JWTAuthMiddleware(), middleware.RequireRole("manager", "admin"), memberHandler.Remove)
+		// custom field definitions (changes limited to project admins in the service)
+		projects.GET(":id/fields", middleware.JWTAuthMiddleware(), fieldHandler.List)
+		projects.POST(":id/fields", middleware.JWTAuthMiddleware(), fieldHandler.Create)
+		projects.PATCH(":id/fields/:fieldId", middleware.JWTAuthMiddleware(), fieldHandler.Update)
+		projects.DELETE(":id/fields/:fieldId", middleware.JWTAuthMiddleware(), fieldHandler.Delete)
+		// allowed severity/priority values
+		api.GET("/meta/defects", metaHandler.Defaults)
+		projects.GET(":id/meta", metaHandler.Project)
+		projects.PUT(":id/levels", middleware.JWTAuthMiddleware(), metaHandler.UpdateLevels)
+		// labels and project statistics
+		projects.GET(":id/labels", labelHandler.List)
+		projects.POST(":id/labels", middleware.JWTAuthMiddleware(), labelHandler.Create)
+		projects.POST(":id/labels/bulk", middleware.JWTAuthMiddleware(), middleware.RequireRole("engineer", "manager", "admin"), labelHandler.Bulk)
+		projects.PATCH(":id/labels/:labelId", middleware.JWTAuthMiddleware(), labelHandler.Update)
+		projects.DELETE(":id/labels/:labelId", middleware.JWTAuthMiddleware(), labelHandler.Delete)
+		projects.GET(":id/stats", statsHandler.ProjectStats)
+		projects.GET(":id/storage", middleware.JWTAuthMiddleware(), quotaHandler.Usage)
+		// organizations and defect classification catalog
+		api.GET("/organizations", orgHandler.List)
+		api.POST("/organizations", middleware.JWTAuthMiddleware(), middleware.RequireRole("manager", "admin"), orgHandler.Create)
+		api.PATCH("/organizations/:id", middleware.JWTAuthMiddleware(), middleware.RequireRole("manager", "admin"), orgHandler.Update)
+		api.DELETE("/organizations/:id", middleware.JWTAuthMiddleware(), middleware.RequireRole("admin"), orgHandler.Delete)
+		api.GET("/categories", categoryHandler.List)
+		api.POST("/categories", middleware.JWTAuthMiddleware(), middleware.RequireRole("manager", "admin"), categoryHandler.Create)
+		api.POST("/categories/import", middleware.JWTAuthMiddleware(), middleware.RequireRole("manager", "admin"), categoryHandler.Import)
+		api.PATCH("/categories/:id", middleware.JWTAuthMiddleware(), middleware.RequireRole("manager", "admin"), categoryHandler.Update)
+		api.DELETE("/categories/:id", middleware.JWTAuthMiddleware(), middleware.RequireRole("manager", "admin"), categoryHandler.Delete)
+		// normative documents (SP, GOST, SNiP) and their clauses
+		api.GET("/normatives", normativeHandler.ListDocuments)
+		api.GET("/normatives/:id", normativeHandler.GetDocument)
+		api.POST("/normatives", middleware.JWTAuthMiddleware(), middleware.RequireRole("manager", "admin"), normativeHandler.CreateDocument)
+		api.PATCH("/normatives/:id", middleware.JWTAuthMiddleware(), middleware.RequireRole("manager", "admin"), normativeHandler.UpdateDocument)
+		api.DELETE("/normatives/:id", middleware.JWTAuthMiddleware(), middleware.RequireRole("admin"), normativeHandler.DeleteDocument)
+		api.POST("/normatives/:id/clauses", middleware.JWTAuthMiddleware(), middleware.RequireRole("manager", "admin"), normativeHandler.CreateClause)
+		api.GET("/clauses", normativeHandler.SearchClauses)
+		api.PATCH("/clauses/:id", middleware.JWTAuthMiddleware(), middleware.RequireRole("manager", "admin"), normativeHandler.UpdateClause)
+		api.DELETE("/clauses/:id", middleware.JWTAuthMiddleware(), middleware.RequireRole("manager", "admin"), normativeHandler.DeleteClause)
+		// full-text search across defects, comments and attachments
+		api.GET("/search", middleware.JWTAuthMiddleware(), searchHandler.Search)
+		// saved views (named defect filters)
+		views := api.Group("/views", middleware.JWTAuthMiddleware())
+		views.GET("", viewHandler.List)
+		views.POST("", viewHandler.Create)
+		views.GET("/:id", viewHandler.Get)
+		views.PATCH("/:id", viewHandler.Update)
+		views.DELETE("/:id", viewHandler.Delete)
+		views.GET("/:id/defects", viewHandler.Defects)
 	}
 
 	// Serve generated swagger files and Swagger UI
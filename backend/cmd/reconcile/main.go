@@ -0,0 +1,50 @@
+// Command reconcile compares attachment storage with the database: it lists
+// files no row points at, rows whose file is missing and blobs with a wrong
+// reference count. With -repair it removes the orphan files and corrects the
+// counts; missing files are only reported.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+
+	"github.com/spf13/viper"
+
+	"example.com/defect-control-system/internal/db"
+	"example.com/defect-control-system/internal/repository"
+	"example.com/defect-control-system/internal/service"
+)
+
+func main() {
+	repair := flag.Bool("repair", false, "remove orphan files and correct blob reference counts")
+	grace := flag.Duration("grace", service.DefaultReconcileGrace, "ignore files modified more recently than this")
+	flag.Parse()
+
+	viper.SetConfigFile("configs/config.yml")
+	viper.AutomaticEnv()
+	_ = viper.ReadInConfig()
+	_ = viper.BindEnv("database.url", "DATABASE_URL")
+	_ = viper.BindEnv("uploads.path", "UPLOADS_PATH")
+
+	gdb, err := db.Connect()
+	if err != nil {
+		log.Fatalf("db: %v", err)
+	}
+	svc := service.NewReconcileService(repository.NewReconcileRepository(gdb), service.UploadsPath(), *grace)
+	report, err := svc.Run(context.Background(), *repair)
+	if report != nil {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(report)
+	}
+	if err != nil {
+		log.Fatalf("reconcile: %v", err)
+	}
+	// missing files stay after a repair; anything left needs a look
+	if !report.Clean() && !(report.Repaired && len(report.MissingFiles) == 0) {
+		os.Exit(1)
+	}
+}
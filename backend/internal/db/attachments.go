@@ -0,0 +1,11 @@
+package db
+
+import "gorm.io/gorm"
+
+// backfillAttachmentKinds classifies attachments stored before kinds existed:
+// pictures as evidence, everything else as documents
+func backfillAttachmentKinds(db *gorm.DB) error {
+	return db.Exec(`UPDATE attachments
+		SET kind = CASE WHEN content_type LIKE 'image/%' THEN 'evidence' ELSE 'document' END
+		WHERE kind IS NULL OR kind = ''`).Error
+}
@@ -20,7 +20,19 @@ func Connect() (*gorm.DB, error) {
 		return nil, err
 	}
 	// AutoMigrate models (add more models as they are implemented)
-	if err := db.AutoMigrate(&models.User{}, &models.Project{}, &models.Defect{}, &models.Attachment{}, &models.Comment{}); err != nil {
+	if err := db.AutoMigrate(&models.User{}, &models.Project{}, &models.Organization{}, &models.DefectCategory{}, &models.NormativeDocument{}, &models.NormativeClause{}, &models.Defect{}, &models.Attachment{}, &models.Blob{}, &models.Comment{}, &models.ProjectMember{}, &models.SavedView{}, &models.CustomField{}, &models.Label{}, &models.Verification{}, &models.AcceptanceAct{}, &models.ActSignatory{}, &models.InspectionTemplate{}, &models.InspectionTemplateItem{}, &models.Inspection{}, &models.InspectionResult{}, &models.UploadSession{}, &models.Annotation{}); err != nil {
+		return nil, err
+	}
+	if err := migrateKeys(db); err != nil {
+		return nil, err
+	}
+	if err := migrateSearch(db); err != nil {
+		return nil, err
+	}
+	if err := backfillRanks(db); err != nil {
+		return nil, err
+	}
+	if err := backfillAttachmentKinds(db); err != nil {
 		return nil, err
 	}
 	return db, nil
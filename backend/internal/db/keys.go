@@ -0,0 +1,32 @@
+package db
+
+import "gorm.io/gorm"
+
+// keyMigrations give existing projects a key ("P<id>") and number their defects
+// in creation order, then add the unique indexes. New projects and defects get
+// keys and numbers when they are created, so the updates only touch old rows.
+var keyMigrations = []string{
+	`UPDATE projects SET key = 'P' || id WHERE key IS NULL OR key = ''`,
+	`UPDATE defects d SET number = n.num FROM (
+		SELECT id, row_number() OVER (PARTITION BY project_id ORDER BY id) +
+			coalesce((SELECT max(number) FROM defects x WHERE x.project_id = u.project_id), 0) AS num
+		FROM defects u WHERE number = 0
+	) n WHERE d.id = n.id`,
+	`UPDATE defects d SET key = p.key || '-' || d.number FROM projects p
+		WHERE p.id = d.project_id AND (d.key IS NULL OR d.key = '')`,
+	`UPDATE projects p SET defect_seq = m.max_number FROM (
+		SELECT project_id, max(number) AS max_number FROM defects GROUP BY project_id
+	) m WHERE m.project_id = p.id AND p.defect_seq < m.max_number`,
+	`CREATE UNIQUE INDEX IF NOT EXISTS idx_projects_key ON projects (key)`,
+	`CREATE UNIQUE INDEX IF NOT EXISTS idx_defects_project_number ON defects (project_id, number)`,
+	`CREATE UNIQUE INDEX IF NOT EXISTS idx_defects_key ON defects (key)`,
+}
+
+func migrateKeys(db *gorm.DB) error {
+	for _, stmt := range keyMigrations {
+		if err := db.Exec(stmt).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
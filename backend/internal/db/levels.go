@@ -0,0 +1,30 @@
+package db
+
+import (
+	"gorm.io/gorm"
+
+	"example.com/defect-control-system/internal/models"
+)
+
+// backfillRanks sets severity and priority ranks of defects stored before ranks
+// existed. Only unranked defects of projects using the default levels are touched,
+// so running it on every start is cheap and idempotent.
+func backfillRanks(db *gorm.DB) error {
+	for _, col := range []struct {
+		name, override string
+		levels         models.LevelList
+	}{
+		{"severity", "severity_levels", models.DefaultSeverityLevels},
+		{"priority", "priority_levels", models.DefaultPriorityLevels},
+	} {
+		for _, lv := range col.levels {
+			err := db.Exec(`UPDATE defects SET `+col.name+`_rank = ?
+				WHERE `+col.name+` = ? AND `+col.name+`_rank = 0
+				AND project_id IN (SELECT id FROM projects WHERE `+col.override+` IS NULL)`, lv.Rank, lv.Value).Error
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+package db
+
+import "gorm.io/gorm"
+
+// searchMigrations add generated tsvector columns and GIN indexes used by the
+// full-text search endpoints. Text is indexed with both the Russian and English
+// configurations so queries match word forms of either language; filenames are
+// split on punctuation first so "waterproofing_report.pdf" is findable by word.
+var searchMigrations = []string{
+	`ALTER TABLE defects ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (
+		setweight(to_tsvector('russian', coalesce(title, '')), 'A') ||
+		setweight(to_tsvector('english', coalesce(title, '')), 'A') ||
+		setweight(to_tsvector('russian', coalesce(description, '')), 'B') ||
+		setweight(to_tsvector('english', coalesce(description, '')), 'B')
+	) STORED`,
+	`CREATE INDEX IF NOT EXISTS idx_defects_search_vector ON defects USING GIN (search_vector)`,
+	`ALTER TABLE comments ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (
+		to_tsvector('russian', coalesce(body, '')) || to_tsvector('english', coalesce(body, ''))
+	) STORED`,
+	`CREATE INDEX IF NOT EXISTS idx_comments_search_vector ON comments USING GIN (search_vector)`,
+	`ALTER TABLE attachments ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (
+		to_tsvector('russian', regexp_replace(coalesce(filename, ''), '[._-]+', ' ', 'g')) ||
+		to_tsvector('english', regexp_replace(coalesce(filename, ''), '[._-]+', ' ', 'g'))
+	) STORED`,
+	`CREATE INDEX IF NOT EXISTS idx_attachments_search_vector ON attachments USING GIN (search_vector)`,
+	`ALTER TABLE normative_clauses ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (
+		to_tsvector('simple', coalesce(number, '')) ||
+		to_tsvector('russian', coalesce(text, '')) || to_tsvector('english', coalesce(text, ''))
+	) STORED`,
+	`CREATE INDEX IF NOT EXISTS idx_normative_clauses_search_vector ON normative_clauses USING GIN (search_vector)`,
+}
+
+func migrateSearch(db *gorm.DB) error {
+	for _, stmt := range searchMigrations {
+		if err := db.Exec(stmt).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
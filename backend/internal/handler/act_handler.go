@@ -0,0 +1,180 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"example.com/defect-control-system/internal/models"
+	"example.com/defect-control-system/internal/service"
+)
+
+type ActHandler struct {
+	svc service.ActService
+}
+
+func NewActHandler(s service.ActService) *ActHandler {
+	return &ActHandler{svc: s}
+}
+
+// actParams parses the :id and :actId path params
+func actParams(c *gin.Context) (uint, uint, bool) {
+	projectID, ok := projectIDParam(c)
+	if !ok {
+		return 0, 0, false
+	}
+	actID, ok := idParam(c, "actId", "act")
+	return projectID, actID, ok
+}
+
+// CreateAct godoc
+// @Summary Draft an acceptance act
+// @Description Drafts an act over closed defects of the project and lists the users who must sign it. Requires project management rights.
+// @Tags acts
+// @Accept json
+// @Produce json
+// @Param id path int true "Project ID"
+// @Param body body service.CreateActDTO true "Defects and signatories"
+// @Success 201 {object} handler.ActResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Security BearerAuth
+// @Router /api/v1/projects/{id}/acts [post]
+func (h *ActHandler) Create(c *gin.Context) {
+	projectID, ok := projectIDParam(c)
+	if !ok {
+		return
+	}
+	var dto service.CreateActDTO
+	if err := c.ShouldBindJSON(&dto); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"status": "error", "error": err.Error()})
+		return
+	}
+	uid, role := currentUser(c)
+	a, err := h.svc.Create(c.Request.Context(), uid, role, projectID, dto)
+	if err != nil {
+		writeServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"status": "ok", "data": a})
+}
+
+// ListActs godoc
+// @Summary List acceptance acts of a project
+// @Tags acts
+// @Produce json
+// @Param id path int true "Project ID"
+// @Success 200 {array} handler.ActResponse
+// @Security BearerAuth
+// @Router /api/v1/projects/{id}/acts [get]
+func (h *ActHandler) List(c *gin.Context) {
+	projectID, ok := projectIDParam(c)
+	if !ok {
+		return
+	}
+	uid, role := currentUser(c)
+	list, err := h.svc.List(c.Request.Context(), uid, role, projectID)
+	if err != nil {
+		writeServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "ok", "data": list})
+}
+
+// GetAct godoc
+// @Summary Get an acceptance act
+// @Tags acts
+// @Produce json
+// @Param id path int true "Project ID"
+// @Param actId path int true "Act ID"
+// @Success 200 {object} handler.ActResponse
+// @Failure 404 {object} map[string]interface{}
+// @Security BearerAuth
+// @Router /api/v1/projects/{id}/acts/{actId} [get]
+func (h *ActHandler) Get(c *gin.Context) {
+	projectID, actID, ok := actParams(c)
+	if !ok {
+		return
+	}
+	uid, role := currentUser(c)
+	a, err := h.svc.Get(c.Request.Context(), uid, role, projectID, actID)
+	if err != nil {
+		writeServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "ok", "data": a})
+}
+
+// SignAct godoc
+// @Summary Sign an acceptance act
+// @Description Signs as the current user with a drawn PNG signature or the account's full name typed as confirmation. The last signature seals the act: its PDF is stored with a SHA-256 hash and can no longer change.
+// @Tags acts
+// @Accept json
+// @Produce json
+// @Param id path int true "Project ID"
+// @Param actId path int true "Act ID"
+// @Param body body service.SignActDTO true "Signature"
+// @Success 200 {object} handler.ActResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 412 {object} map[string]interface{}
+// @Security BearerAuth
+// @Router /api/v1/projects/{id}/acts/{actId}/sign [post]
+func (h *ActHandler) Sign(c *gin.Context) {
+	projectID, actID, ok := actParams(c)
+	if !ok {
+		return
+	}
+	var dto service.SignActDTO
+	if err := c.ShouldBindJSON(&dto); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"status": "error", "error": err.Error()})
+		return
+	}
+	uid, role := currentUser(c)
+	a, err := h.svc.Sign(c.Request.Context(), uid, role, projectID, actID, dto)
+	if err != nil {
+		writeServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "ok", "data": a})
+}
+
+// ActPDF godoc
+// @Summary Download an acceptance act as PDF
+// @Description Serves the stored file of a signed act after re-checking its SHA-256 (sent in X-Content-SHA256), or a preview of a draft.
+// @Tags acts
+// @Produce application/pdf
+// @Param id path int true "Project ID"
+// @Param actId path int true "Act ID"
+// @Success 200 {file} file
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Security BearerAuth
+// @Router /api/v1/projects/{id}/acts/{actId}/pdf [get]
+func (h *ActHandler) PDF(c *gin.Context) {
+	projectID, actID, ok := actParams(c)
+	if !ok {
+		return
+	}
+	uid, role := currentUser(c)
+	a, data, err := h.svc.PDF(c.Request.Context(), uid, role, projectID, actID)
+	if err != nil {
+		if errors.Is(err, service.ErrNotFound) || errors.Is(err, service.ErrForbidden) {
+			writeServiceError(c, err)
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"status": "error", "error": err.Error()})
+		return
+	}
+	name := fmt.Sprintf("act-%d-%d.pdf", a.ProjectID, a.Number)
+	if a.Status == models.ActSigned {
+		c.Header("X-Content-SHA256", a.SHA256)
+		c.Header("ETag", `"`+a.SHA256+`"`)
+	} else {
+		name = fmt.Sprintf("act-%d-%d-draft.pdf", a.ProjectID, a.Number)
+	}
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, name))
+	c.Data(http.StatusOK, "application/pdf", data)
+}
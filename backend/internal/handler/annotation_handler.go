@@ -0,0 +1,162 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"example.com/defect-control-system/internal/repository"
+	"example.com/defect-control-system/internal/service"
+)
+
+type AnnotationHandler struct {
+	svc        service.AnnotationService
+	attachRepo repository.AttachmentRepository
+}
+
+func NewAnnotationHandler(s service.AnnotationService, ar repository.AttachmentRepository) *AnnotationHandler {
+	return &AnnotationHandler{svc: s, attachRepo: ar}
+}
+
+// GetAnnotation godoc
+// @Summary Get the annotation of a photo
+// @Description Vector shapes drawn over an image attachment, in pixels of the upright photo. A photo not annotated yet has no shapes and version 0.
+// @Tags attachments
+// @Produce json
+// @Param id path int true "Attachment ID"
+// @Success 200 {object} handler.AnnotationResponse
+// @Header 200 {string} ETag "Annotation version"
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Security BearerAuth
+// @Router /api/v1/attachments/{id}/annotations [get]
+func (h *AnnotationHandler) Get(c *gin.Context) {
+	id, ok := idParam(c, "id", "attachment")
+	if !ok {
+		return
+	}
+	uid, role := currentUser(c)
+	an, err := h.svc.Get(c.Request.Context(), uid, role, id)
+	if err != nil {
+		writeServiceError(c, err)
+		return
+	}
+	setETag(c, an.Version)
+	c.JSON(http.StatusOK, gin.H{"status": "ok", "data": an})
+}
+
+// PutAnnotation godoc
+// @Summary Create or replace the annotation of a photo
+// @Description Replaces all shapes: arrow, rect and ellipse take 2 points, text 1, freehand up to 5000. Colors are #RGB, #RRGGBB or #RRGGBBAA; fill colors the inside of rectangles and ellipses and the box behind text. stroke_width defaults to 4 and font_size to 24 pixels. If-Match "0" expects the photo not to be annotated yet.
+// @Tags attachments
+// @Accept json
+// @Produce json
+// @Param id path int true "Attachment ID"
+// @Param body body service.PutAnnotationDTO true "Shapes"
+// @Param If-Match header string false "ETag of the version being edited"
+// @Success 200 {object} handler.AnnotationResponse
+// @Header 200 {string} ETag "New annotation version"
+// @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 412 {object} map[string]interface{}
+// @Security BearerAuth
+// @Router /api/v1/attachments/{id}/annotations [put]
+func (h *AnnotationHandler) Put(c *gin.Context) {
+	id, ok := idParam(c, "id", "attachment")
+	if !ok {
+		return
+	}
+	var dto service.PutAnnotationDTO
+	if err := c.ShouldBindJSON(&dto); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"status": "error", "error": err.Error()})
+		return
+	}
+	version, ok := ifMatchVersion(c)
+	if !ok {
+		return
+	}
+	if version != nil {
+		dto.Version = version
+	}
+	uid, role := currentUser(c)
+	an, err := h.svc.Put(c.Request.Context(), uid, role, id, dto)
+	if err != nil {
+		writeServiceError(c, err)
+		return
+	}
+	setETag(c, an.Version)
+	c.JSON(http.StatusOK, gin.H{"status": "ok", "data": an})
+}
+
+// DeleteAnnotation godoc
+// @Summary Remove the annotation of a photo
+// @Tags attachments
+// @Produce json
+// @Param id path int true "Attachment ID"
+// @Param If-Match header string false "ETag of the version being removed"
+// @Success 200 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 412 {object} map[string]interface{}
+// @Security BearerAuth
+// @Router /api/v1/attachments/{id}/annotations [delete]
+func (h *AnnotationHandler) Delete(c *gin.Context) {
+	id, ok := idParam(c, "id", "attachment")
+	if !ok {
+		return
+	}
+	version, ok := ifMatchVersion(c)
+	if !ok {
+		return
+	}
+	uid, role := currentUser(c)
+	if err := h.svc.Delete(c.Request.Context(), uid, role, id, version); err != nil {
+		writeServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// RenderAnnotation godoc
+// @Summary Download a photo with its annotation burnt in
+// @Description Draws the annotation over the photo, turned upright by its EXIF orientation, and returns a flattened JPEG for reports. The stored file is not changed. Access and virus scan rules are those of a download.
+// @Tags attachments
+// @Produce image/jpeg
+// @Param id path int true "Attachment ID"
+// @Success 200
+// @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 423 {object} map[string]interface{}
+// @Security BearerAuth
+// @Router /api/v1/attachments/{id}/annotations.jpg [get]
+func (h *AnnotationHandler) Render(c *gin.Context) {
+	id, ok := idParam(c, "id", "attachment")
+	if !ok {
+		return
+	}
+	a, err := h.attachRepo.FindByID(c.Request.Context(), id)
+	if err != nil || a == nil {
+		c.JSON(http.StatusNotFound, gin.H{"status": "error", "error": "attachment not found"})
+		return
+	}
+	if !mayDownload(c, a) {
+		c.JSON(http.StatusForbidden, gin.H{"status": "error", "error": "forbidden"})
+		return
+	}
+	if !servable(c, a) {
+		return
+	}
+	uid, role := currentUser(c)
+	out, err := h.svc.Render(c.Request.Context(), uid, role, id)
+	if err != nil {
+		writeServiceError(c, err)
+		return
+	}
+	name := strings.TrimSuffix(a.Filename, filepath.Ext(a.Filename)) + "-annotated.jpg"
+	c.Header("Content-Disposition", fmt.Sprintf("inline; filename=\"%s\"", name))
+	c.Data(http.StatusOK, "image/jpeg", out)
+}
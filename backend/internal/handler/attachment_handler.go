@@ -1,10 +1,14 @@
 package handler
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/spf13/viper"
@@ -15,24 +19,52 @@ import (
 )
 
 type AttachmentHandler struct {
-	storage    service.StorageService
-	attachRepo repository.AttachmentRepository
-	defectSvc  service.DefectService
+	storage     service.StorageService
+	attachRepo  repository.AttachmentRepository
+	defectSvc   service.DefectService
+	attachments service.AttachmentService
+	blobs       service.BlobService
 }
 
-func NewAttachmentHandler(s service.StorageService, ar repository.AttachmentRepository, ds service.DefectService) *AttachmentHandler {
-	return &AttachmentHandler{storage: s, attachRepo: ar, defectSvc: ds}
+func NewAttachmentHandler(s service.StorageService, ar repository.AttachmentRepository, ds service.DefectService, as service.AttachmentService, bs service.BlobService) *AttachmentHandler {
+	return &AttachmentHandler{storage: s, attachRepo: ar, defectSvc: ds, attachments: as, blobs: bs}
+}
+
+// attachmentJSON is the listing form of an attachment
+func attachmentJSON(a *models.Attachment) gin.H {
+	return gin.H{"id": a.ID, "filename": a.Filename, "url": filepath.Join("/uploads", a.Path), "content_type": a.ContentType, "size": a.Size, "sha256": a.SHA256, "kind": a.Kind, "before_id": a.BeforeID, "scan_status": a.ScanStatus, "origin_id": a.OriginID, "version": a.Version, "superseded": a.Superseded}
+}
+
+// servable answers for attachments that have not passed the virus scan:
+// 423 while the scan is pending, 403 for infected or unscannable files
+func servable(c *gin.Context, a *models.Attachment) bool {
+	switch {
+	case a.Servable():
+		return true
+	case a.ScanStatus == models.AttachmentPendingScan:
+		c.Header("Retry-After", "30")
+		c.JSON(http.StatusLocked, gin.H{"status": "error", "error": "the file is being scanned for viruses, try again shortly"})
+	case a.ScanStatus == models.AttachmentInfected:
+		c.JSON(http.StatusForbidden, gin.H{"status": "error", "error": "the file is quarantined: " + a.ScanDetail})
+	default:
+		c.JSON(http.StatusForbidden, gin.H{"status": "error", "error": "the file could not be scanned for viruses"})
+	}
+	return false
 }
 
 // UploadAttachments godoc
 // @Summary Upload attachments to defect
-// @Description Upload one or multiple files as attachments to a defect
+// @Description Upload one or multiple files as attachments to a defect. The body is streamed: each file is written to storage as it arrives, so form fields must come before the files, and a file over uploads.max_size is rejected with 413 as soon as the limit is crossed. A file that does not fit the storage quota of the project or its organization is rejected with 413 too. The request succeeds or fails as a whole: when a file is rejected, the files stored before it in the same request are removed again, so the request can be retried.
 // @Tags attachments
 // @Accept multipart/form-data
 // @Produce json
 // @Param id path int true "Defect ID"
 // @Param files formData file true "files"
+// @Param kind formData string false "evidence, remediation, document or drawing; defaults to evidence for images, remediation with before_id, document otherwise"
+// @Param before_id formData int false "Evidence photo the uploaded remediation photos show fixed"
 // @Success 201 {array} handler.AttachmentResponse
+// @Failure 413 {object} map[string]interface{}
+// @Failure 415 {object} map[string]interface{}
 // @Security BearerAuth
 // @Router /api/v1/projects/{id}/attachments [post]
 func (h *AttachmentHandler) Upload(c *gin.Context) {
@@ -54,69 +86,136 @@ func (h *AttachmentHandler) Upload(c *gin.Context) {
 			c.JSON(http.StatusBadRequest, gin.H{"status": "error", "error": "invalid defect_id query param"})
 			return
 		}
-	} else {
-		// also accept form field "defect_id" when multipart
-		if v := c.PostForm("defect_id"); v != "" {
-			if _, err := fmt.Sscanf(v, "%d", &defectID); err != nil {
-				c.JSON(http.StatusBadRequest, gin.H{"status": "error", "error": "invalid defect_id form field"})
-				return
-			}
-		}
-	}
-
-	// if still no defect id, fall back to using path param (backward compatibility)
-	if defectID == 0 && pathID != 0 {
-		defectID = pathID
-	}
-
-	if defectID == 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"status": "error", "error": "defect_id is required"})
-		return
 	}
-	form, err := c.MultipartForm()
+	mr, err := c.Request.MultipartReader()
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"status": "error", "error": err.Error()})
 		return
 	}
-	files := form.File["files"]
-	var results []gin.H
-	for _, fh := range files {
-		relpath, size, err := h.storage.SaveFile(fh)
+	uploaderID, _ := currentUser(c)
+	var kind string
+	var beforeID *uint
+	results := []gin.H{}
+	// files are stored as they arrive; if a later part fails they are
+	// removed again, so a retried request does not duplicate them
+	var stored []*models.Attachment
+	done := false
+	defer func() {
+		if done {
+			return
+		}
+		for _, a := range stored {
+			_ = h.blobs.Delete(context.WithoutCancel(c.Request.Context()), a)
+		}
+	}()
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"status": "error", "error": err.Error()})
+			c.JSON(http.StatusBadRequest, gin.H{"status": "error", "error": err.Error()})
 			return
 		}
-		uploaderID := uint(0)
-		if v, ok := c.Get("user_id"); ok {
-			if uid, ok2 := v.(uint); ok2 {
-				uploaderID = uid
+		if part.FileName() == "" {
+			// a form field: defect_id, kind or before_id
+			v, err := io.ReadAll(io.LimitReader(part, maxFormFieldBytes))
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"status": "error", "error": err.Error()})
+				return
 			}
+			value := strings.TrimSpace(string(v))
+			switch part.FormName() {
+			case "defect_id":
+				// also accept form field "defect_id", unless given in the query
+				if defectIDStr == "" && value != "" {
+					if _, err := fmt.Sscanf(value, "%d", &defectID); err != nil {
+						c.JSON(http.StatusBadRequest, gin.H{"status": "error", "error": "invalid defect_id form field"})
+						return
+					}
+				}
+			case "kind":
+				kind = value
+			case "before_id":
+				if value == "" {
+					continue
+				}
+				var id uint
+				if _, err := fmt.Sscanf(value, "%d", &id); err != nil {
+					c.JSON(http.StatusBadRequest, gin.H{"status": "error", "error": "invalid before_id form field"})
+					return
+				}
+				beforeID = &id
+			}
+			continue
+		}
+		if part.FormName() != "files" {
+			continue
+		}
+		// if still no defect id, fall back to using path param (backward compatibility)
+		if defectID == 0 && pathID != 0 {
+			defectID = pathID
+		}
+		if defectID == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"status": "error", "error": "defect_id is required"})
+			return
 		}
 		a := &models.Attachment{
 			DefectID:    defectID,
-			UploaderID:  uploaderID,
-			Path:        relpath,
-			Filename:    fh.Filename,
-			ContentType: fh.Header.Get("Content-Type"),
-			Size:        size,
-		}
-		if err := h.attachRepo.Create(c.Request.Context(), a); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"status": "error", "error": err.Error()})
+			Filename:    part.FileName(),
+			ContentType: part.Header.Get("Content-Type"),
+			Kind:        kind,
+		}
+		if err := h.attachments.Prepare(c.Request.Context(), a, beforeID); err != nil {
+			writeServiceError(c, err)
 			return
 		}
-		results = append(results, gin.H{"id": a.ID, "filename": a.Filename, "url": filepath.Join("/uploads", a.Path), "content_type": a.ContentType, "size": a.Size})
+		st, err := h.storage.StageStream(part)
+		if err != nil {
+			writeStorageError(c, err)
+			return
+		}
+		a.UploaderID = uploaderID
+		// identical content already stored is shared, not written again
+		if err := h.blobs.Attach(c.Request.Context(), a, st); err != nil {
+			writeStorageError(c, err)
+			return
+		}
+		stored = append(stored, a)
+		results = append(results, attachmentJSON(a))
 	}
+	done = true
 	c.JSON(http.StatusCreated, gin.H{"status": "ok", "data": results})
 }
 
+// maxFormFieldBytes bounds the non-file fields of an upload form
+const maxFormFieldBytes = 1 << 10
+
+// writeStorageError maps storage limits and quotas to 413 and 415. The rest
+// of an oversized body is not read: the connection is closed instead
+func writeStorageError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrFileTooLarge), errors.Is(err, service.ErrQuotaExceeded):
+		c.Header("Connection", "close")
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"status": "error", "error": err.Error()})
+	case errors.Is(err, service.ErrContentType):
+		c.Header("Connection", "close")
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"status": "error", "error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"status": "error", "error": err.Error()})
+	}
+}
+
 // DownloadAttachment godoc
 // @Summary Download attachment
-// @Description Download attachment by id
+// @Description Download attachment by id. Files are served once the virus scan has passed: 423 with Retry-After while it is pending, 403 for quarantined files.
 // @Tags attachments
 // @Produce application/octet-stream
 // @Param id path int true "Attachment ID"
 // @Success 200 {object} handler.AttachmentResponse
+// @Failure 403 {object} map[string]interface{}
 // @Failure 404 {object} map[string]interface{}
+// @Failure 423 {object} map[string]interface{}
 // @Security BearerAuth
 // @Router /api/v1/attachments/{id} [get]
 func (h *AttachmentHandler) Download(c *gin.Context) {
@@ -131,27 +230,18 @@ func (h *AttachmentHandler) Download(c *gin.Context) {
 		c.JSON(http.StatusNotFound, gin.H{"status": "error", "error": "attachment not found"})
 		return
 	}
-	// check ownership/permission: allow if uploader, or role in manager/admin/stakeholder
-	allowed := false
-	if v, ok := c.Get("user_id"); ok {
-		if uid, ok2 := v.(uint); ok2 && uid == a.UploaderID {
-			allowed = true
-		}
-	}
-	if !allowed {
-		if rv, ok := c.Get("role"); ok {
-			if role, ok2 := rv.(string); ok2 {
-				// allow managers, admins, stakeholders and engineers (engineers can view attachments for defects they participate in)
-				if role == "manager" || role == "admin" || role == "stakeholder" || role == "engineer" {
-					allowed = true
-				}
-			}
-		}
-	}
-	if !allowed {
+	if !mayDownload(c, a) {
 		c.JSON(http.StatusForbidden, gin.H{"status": "error", "error": "forbidden"})
 		return
 	}
+	serveAttachment(c, a)
+}
+
+// serveAttachment sends the content of a once its virus scan has passed
+func serveAttachment(c *gin.Context, a *models.Attachment) {
+	if !servable(c, a) {
+		return
+	}
 	base := viper.GetString("uploads.path")
 	if base == "" {
 		base = "./uploads"
@@ -164,6 +254,10 @@ func (h *AttachmentHandler) Download(c *gin.Context) {
 		return
 	}
 	c.Header("Content-Type", a.ContentType)
+	if a.SHA256 != "" {
+		c.Header("X-Content-SHA256", a.SHA256)
+		c.Header("ETag", `"`+a.SHA256+`"`)
+	}
 	// If content type is an image, prefer inline display in browser
 	if a.ContentType != "" && (a.ContentType == "image/jpeg" || a.ContentType == "image/png" || a.ContentType == "image/gif" || a.ContentType == "image/webp") {
 		c.Header("Content-Disposition", fmt.Sprintf("inline; filename=\"%s\"", a.Filename))
@@ -173,12 +267,55 @@ func (h *AttachmentHandler) Download(c *gin.Context) {
 	c.File(full)
 }
 
+// mayDownload allows the uploader, managers, admins, stakeholders and engineers
+// (engineers can view attachments for defects they participate in)
+func mayDownload(c *gin.Context, a *models.Attachment) bool {
+	uid, role := currentUser(c)
+	if uid != 0 && uid == a.UploaderID {
+		return true
+	}
+	return role == "manager" || role == "admin" || role == "stakeholder" || role == "engineer"
+}
+
+// VerifyAttachment godoc
+// @Summary Check a stored attachment against its hash
+// @Description Hashes the stored content again and compares it to the SHA-256 recorded at upload. Files stored before content addressing have no hash until adopted (400).
+// @Tags attachments
+// @Produce json
+// @Param id path int true "Attachment ID"
+// @Success 200 {object} handler.AttachmentIntegrityResponse
+// @Failure 404 {object} map[string]interface{}
+// @Security BearerAuth
+// @Router /api/v1/attachments/{id}/verify [get]
+func (h *AttachmentHandler) Verify(c *gin.Context) {
+	id, ok := idParam(c, "id", "attachment")
+	if !ok {
+		return
+	}
+	a, err := h.attachRepo.FindByID(c.Request.Context(), id)
+	if err != nil || a == nil {
+		c.JSON(http.StatusNotFound, gin.H{"status": "error", "error": "attachment not found"})
+		return
+	}
+	if !mayDownload(c, a) {
+		c.JSON(http.StatusForbidden, gin.H{"status": "error", "error": "forbidden"})
+		return
+	}
+	intact, err := h.blobs.Verify(c.Request.Context(), a)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"status": "error", "error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "ok", "data": gin.H{"id": a.ID, "sha256": a.SHA256, "intact": intact}})
+}
+
 // ListAttachments godoc
 // @Summary List attachments
-// @Description List attachments by defect id
+// @Description List attachments by defect id: the latest version of each file, or every version with all_versions=true
 // @Tags attachments
 // @Produce json
 // @Param defect_id query int false "Defect ID"
+// @Param all_versions query bool false "Include superseded versions"
 // @Param id path int false "Project or defect id in path"
 // @Success 200 {array} handler.AttachmentResponse
 // @Router /api/v1/attachments [get]
@@ -210,13 +347,303 @@ func (h *AttachmentHandler) List(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"status": "error", "error": err.Error()})
 		return
 	}
+	all := c.Query("all_versions") == "true"
 	var out []gin.H
 	for _, a := range list {
-		out = append(out, gin.H{"id": a.ID, "filename": a.Filename, "url": filepath.Join("/uploads", a.Path), "content_type": a.ContentType, "size": a.Size})
+		if a.Superseded && !all {
+			continue
+		}
+		out = append(out, attachmentJSON(a))
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "ok", "data": out})
+}
+
+// UploadVersion godoc
+// @Summary Upload a new version of an attachment
+// @Description Stores the file as the next version of the attachment's file, after its latest version whichever version id names. Defect, kind and pairing carry over; the previous version is kept, listed under /versions and hidden from defect listings. 412 if another version was uploaded at the same time. Limits and quotas as for uploads.
+// @Tags attachments
+// @Accept multipart/form-data
+// @Produce json
+// @Param id path int true "Attachment ID"
+// @Param file formData file true "The new version"
+// @Success 201 {object} handler.AttachmentResponse
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 412 {object} map[string]interface{}
+// @Failure 413 {object} map[string]interface{}
+// @Failure 415 {object} map[string]interface{}
+// @Security BearerAuth
+// @Router /api/v1/attachments/{id}/versions [post]
+func (h *AttachmentHandler) UploadVersion(c *gin.Context) {
+	id, ok := idParam(c, "id", "attachment")
+	if !ok {
+		return
+	}
+	uid, role := currentUser(c)
+	// access to the project of the file
+	if _, err := h.attachments.Versions(c.Request.Context(), uid, role, id); err != nil {
+		writeServiceError(c, err)
+		return
+	}
+	mr, err := c.Request.MultipartReader()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"status": "error", "error": err.Error()})
+		return
+	}
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"status": "error", "error": err.Error()})
+			return
+		}
+		if part.FileName() == "" || part.FormName() != "file" {
+			continue
+		}
+		a := &models.Attachment{Filename: part.FileName(), ContentType: part.Header.Get("Content-Type"), UploaderID: uid}
+		if err := h.attachments.PrepareVersion(c.Request.Context(), a, id); err != nil {
+			writeServiceError(c, err)
+			return
+		}
+		st, err := h.storage.StageStream(part)
+		if err != nil {
+			writeStorageError(c, err)
+			return
+		}
+		if err := h.blobs.Attach(c.Request.Context(), a, st); err != nil {
+			if errors.Is(err, service.ErrPreconditionFailed) {
+				writeServiceError(c, err)
+				return
+			}
+			writeStorageError(c, err)
+			return
+		}
+		c.JSON(http.StatusCreated, gin.H{"status": "ok", "data": attachmentJSON(a)})
+		return
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"status": "error", "error": "file is required"})
+}
+
+// ListVersions godoc
+// @Summary List the versions of an attachment
+// @Description All versions of the attachment's file, newest first; each is an attachment of its own and downloads by its id too.
+// @Tags attachments
+// @Produce json
+// @Param id path int true "Attachment ID, any version"
+// @Success 200 {array} handler.AttachmentResponse
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Security BearerAuth
+// @Router /api/v1/attachments/{id}/versions [get]
+func (h *AttachmentHandler) ListVersions(c *gin.Context) {
+	id, ok := idParam(c, "id", "attachment")
+	if !ok {
+		return
+	}
+	uid, role := currentUser(c)
+	list, err := h.attachments.Versions(c.Request.Context(), uid, role, id)
+	if err != nil {
+		writeServiceError(c, err)
+		return
+	}
+	out := make([]gin.H, 0, len(list))
+	for _, a := range list {
+		out = append(out, attachmentJSON(a))
 	}
 	c.JSON(http.StatusOK, gin.H{"status": "ok", "data": out})
 }
 
+// DownloadVersion godoc
+// @Summary Download a version of an attachment
+// @Tags attachments
+// @Produce application/octet-stream
+// @Param id path int true "Attachment ID, any version"
+// @Param version path int true "Version number, from 1"
+// @Success 200
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 423 {object} map[string]interface{}
+// @Security BearerAuth
+// @Router /api/v1/attachments/{id}/versions/{version} [get]
+func (h *AttachmentHandler) DownloadVersion(c *gin.Context) {
+	id, ok := idParam(c, "id", "attachment")
+	if !ok {
+		return
+	}
+	var version int
+	if _, err := fmt.Sscanf(c.Param("version"), "%d", &version); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"status": "error", "error": "invalid version"})
+		return
+	}
+	uid, role := currentUser(c)
+	list, err := h.attachments.Versions(c.Request.Context(), uid, role, id)
+	if err != nil {
+		writeServiceError(c, err)
+		return
+	}
+	for _, a := range list {
+		if a.Version != version {
+			continue
+		}
+		if !mayDownload(c, a) {
+			c.JSON(http.StatusForbidden, gin.H{"status": "error", "error": "forbidden"})
+			return
+		}
+		serveAttachment(c, a)
+		return
+	}
+	c.JSON(http.StatusNotFound, gin.H{"status": "error", "error": "version not found"})
+}
+
+// DownloadAttachmentsZip godoc
+// @Summary Download the attachments of a project or defect as ZIP
+// @Description Streams a ZIP built on the fly: a folder per defect, named by its key, and manifest.csv listing every attachment with its defect, hash and uploader. Access rules are those of a single download; files not yet scanned clean or missing from storage are listed in the manifest with a note instead of included.
+// @Tags attachments
+// @Produce application/zip
+// @Param id path int true "Project ID"
+// @Param defectId path int false "Defect ID"
+// @Success 200
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Security BearerAuth
+// @Router /api/v1/projects/{id}/attachments.zip [get]
+// @Router /api/v1/projects/{id}/defects/{defectId}/attachments.zip [get]
+func (h *AttachmentHandler) DownloadZip(c *gin.Context) {
+	projectID, ok := projectIDParam(c)
+	if !ok {
+		return
+	}
+	var defectID uint
+	if c.Param("defectId") != "" {
+		if defectID, ok = idParam(c, "defectId", "defect"); !ok {
+			return
+		}
+	}
+	uid, role := currentUser(c)
+	list, err := h.attachments.Archive(c.Request.Context(), uid, role, projectID, defectID)
+	if err != nil {
+		writeServiceError(c, err)
+		return
+	}
+	entries := make([]service.ArchiveEntry, 0, len(list))
+	for _, a := range list {
+		if !mayDownload(c, a) {
+			continue
+		}
+		e := service.ArchiveEntry{Attachment: a}
+		if !a.Servable() {
+			e.Skip = "not served: " + a.ScanStatus
+		}
+		entries = append(entries, e)
+	}
+	name := fmt.Sprintf("project-%d-attachments.zip", projectID)
+	if defectID != 0 && len(list) > 0 && list[0].Defect.Key != "" {
+		name = list[0].Defect.Key + "-attachments.zip"
+	} else if defectID != 0 {
+		name = fmt.Sprintf("defect-%d-attachments.zip", defectID)
+	}
+	c.Header("Content-Type", "application/zip")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name))
+	c.Header("Cache-Control", "no-store")
+	c.Status(http.StatusOK)
+	if err := service.WriteAttachmentZip(c.Writer, h.storage, entries); err != nil {
+		// the status is sent already; the archive stays without its central
+		// directory, which unzip tools report as broken
+		c.Error(err)
+	}
+}
+
+// ClassifyAttachment godoc
+// @Summary Set the kind of an attachment
+// @Description Changes the kind and, for remediation photos, the evidence photo of the same defect they pair with (before_id 0 unpairs). Allowed for the uploader and project managers.
+// @Tags attachments
+// @Accept json
+// @Produce json
+// @Param id path int true "Attachment ID"
+// @Param body body service.ClassifyAttachmentDTO true "Kind and pairing"
+// @Success 200 {object} handler.AttachmentResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Security BearerAuth
+// @Router /api/v1/attachments/{id} [patch]
+func (h *AttachmentHandler) Classify(c *gin.Context) {
+	id, ok := idParam(c, "id", "attachment")
+	if !ok {
+		return
+	}
+	var dto service.ClassifyAttachmentDTO
+	if err := c.ShouldBindJSON(&dto); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"status": "error", "error": err.Error()})
+		return
+	}
+	uid, role := currentUser(c)
+	a, err := h.attachments.Classify(c.Request.Context(), uid, role, id, dto)
+	if err != nil {
+		writeServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "ok", "data": attachmentJSON(a)})
+}
+
+// DeleteAttachment godoc
+// @Summary Delete an attachment
+// @Description Allowed for the uploader within uploads.delete_window (24h by default) and for project managers. The stored file goes with the last attachment sharing it. Photos of a pending or approved verification cannot be deleted (409).
+// @Tags attachments
+// @Param id path int true "Attachment ID"
+// @Success 204
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Security BearerAuth
+// @Router /api/v1/attachments/{id} [delete]
+func (h *AttachmentHandler) Delete(c *gin.Context) {
+	id, ok := idParam(c, "id", "attachment")
+	if !ok {
+		return
+	}
+	uid, role := currentUser(c)
+	a, err := h.attachments.Deletable(c.Request.Context(), uid, role, id)
+	if err == nil {
+		err = h.blobs.Delete(c.Request.Context(), a)
+	}
+	if errors.Is(err, service.ErrAttachmentInUse) {
+		c.JSON(http.StatusConflict, gin.H{"status": "error", "error": err.Error()})
+		return
+	}
+	if err != nil {
+		writeServiceError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// PhotoPairs godoc
+// @Summary Before/after photos of a defect
+// @Description Evidence photos with the remediation photos paired to them, plus remediation photos without a pair. Used when verifying fixes and in reports.
+// @Tags attachments
+// @Produce json
+// @Param id path int true "Project ID"
+// @Param defectId path int true "Defect ID"
+// @Success 200 {object} handler.PhotoPairsResponse
+// @Failure 404 {object} map[string]interface{}
+// @Security BearerAuth
+// @Router /api/v1/projects/{id}/defects/{defectId}/photo-pairs [get]
+func (h *AttachmentHandler) PhotoPairs(c *gin.Context) {
+	projectID, defectID, ok := defectParams(c)
+	if !ok {
+		return
+	}
+	uid, role := currentUser(c)
+	pairs, err := h.attachments.Pairs(c.Request.Context(), uid, role, projectID, defectID)
+	if err != nil {
+		writeServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "ok", "data": pairs})
+}
+
 // DownloadAttachment godoc
 // @Summary Download attachment
 // @Description Download attachment by id
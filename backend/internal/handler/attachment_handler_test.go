@@ -4,10 +4,12 @@ import (
 	"bytes"
 	"context"
 	"io"
+	"io/fs"
 	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/gin-gonic/gin"
@@ -16,6 +18,7 @@ import (
 
 	hpkg "example.com/defect-control-system/internal/handler"
 	"example.com/defect-control-system/internal/models"
+	"example.com/defect-control-system/internal/repository"
 	"example.com/defect-control-system/internal/service"
 )
 
@@ -32,6 +35,43 @@ func (m *mockAttachRepo) FindByID(ctx context.Context, id uint) (*models.Attachm
 func (m *mockAttachRepo) ListByDefect(ctx context.Context, defectID uint) ([]*models.Attachment, error) {
 	return []*models.Attachment{}, nil
 }
+func (m *mockAttachRepo) ListVersions(ctx context.Context, rootID uint) ([]*models.Attachment, error) {
+	return nil, nil
+}
+func (m *mockAttachRepo) ListByProject(ctx context.Context, projectID uint) ([]*models.Attachment, error) {
+	return []*models.Attachment{}, nil
+}
+func (m *mockAttachRepo) Update(ctx context.Context, a *models.Attachment, columns []string) error {
+	return nil
+}
+
+// mockBlobRepo counts blob references in memory; the last detach removes
+// the content
+type mockBlobRepo struct{ refs map[string]int }
+
+func (m *mockBlobRepo) Attach(ctx context.Context, a *models.Attachment, b *models.Blob, quota *repository.QuotaGuard, place func() error) error {
+	if err := place(); err != nil {
+		return err
+	}
+	if m.refs == nil {
+		m.refs = map[string]int{}
+	}
+	m.refs[b.SHA256]++
+	a.ID, a.Path, a.SHA256, a.Size = uint(len(m.refs)), b.Path, b.SHA256, b.Size
+	return nil
+}
+func (m *mockBlobRepo) Detach(ctx context.Context, a *models.Attachment, remove func(b *models.Blob) error) error {
+	if m.refs[a.SHA256] == 0 {
+		return nil
+	}
+	if m.refs[a.SHA256]--; m.refs[a.SHA256] == 0 {
+		return remove(&models.Blob{SHA256: a.SHA256, Path: a.Path})
+	}
+	return nil
+}
+func (m *mockBlobRepo) Legacy(ctx context.Context, afterID uint, limit int) ([]*models.Attachment, error) {
+	return nil, nil
+}
 
 // mock defect service that accepts any project id
 type mockDefectSvc struct{}
@@ -42,10 +82,19 @@ func (m *mockDefectSvc) Create(ctx context.Context, dto service.CreateDefectDTO)
 func (m *mockDefectSvc) ListByProject(ctx context.Context, projectID uint) ([]*models.Defect, error) {
 	return []*models.Defect{}, nil
 }
+func (m *mockDefectSvc) ListFiltered(ctx context.Context, q repository.DefectQuery) ([]*models.Defect, error) {
+	return []*models.Defect{}, nil
+}
 func (m *mockDefectSvc) FindByID(ctx context.Context, id uint) (*models.Defect, error) {
 	return &models.Defect{ID: id, Title: "mock"}, nil
 }
-func (m *mockDefectSvc) Update(ctx context.Context, id uint, dto service.UpdateDefectDTO) (*models.Defect, error) {
+func (m *mockDefectSvc) FindByKey(ctx context.Context, key string) (*models.Defect, error) {
+	return &models.Defect{ID: 1, Key: key, Title: "mock"}, nil
+}
+func (m *mockDefectSvc) Bulk(ctx context.Context, userID uint, role string, projectID uint, dto service.BulkDefectsDTO) (*service.BulkResult, error) {
+	return &service.BulkResult{Applied: true}, nil
+}
+func (m *mockDefectSvc) Update(ctx context.Context, userID uint, role string, id uint, dto service.UpdateDefectDTO) (*models.Defect, error) {
 	// for tests, just return a defect with updated title if provided
 	d := &models.Defect{ID: id}
 	if dto.Title != nil {
@@ -67,7 +116,7 @@ func TestUploadHandler(t *testing.T) {
 	storage := service.NewLocalStorage()
 	attachRepo := &mockAttachRepo{}
 	defectSvc := &mockDefectSvc{}
-	h := hpkg.NewAttachmentHandler(storage, attachRepo, defectSvc)
+	h := hpkg.NewAttachmentHandler(storage, attachRepo, defectSvc, service.NewAttachmentService(attachRepo, nil, nil), service.NewBlobService(&mockBlobRepo{}, storage, service.NewScanService(nil, nil, storage), service.NewQuotaService(nil, nil, 0)))
 
 	r := gin.Default()
 	r.POST("/upload/:id", h.Upload)
@@ -88,3 +137,86 @@ func TestUploadHandler(t *testing.T) {
 
 	assert.Equal(t, http.StatusCreated, resp.Code)
 }
+
+func TestUploadHandler_StreamLimits(t *testing.T) {
+	tmpDir := t.TempDir()
+	viper.Set("uploads.path", tmpDir)
+	viper.Set("uploads.allowed_types", []string{"image/jpeg"})
+	viper.Set("uploads.max_size", 1000)
+	defer viper.Set("uploads.max_size", 0)
+
+	attachRepo := &mockAttachRepo{}
+	storage := service.NewLocalStorage()
+	blobs := &mockBlobRepo{}
+	h := hpkg.NewAttachmentHandler(storage, attachRepo, &mockDefectSvc{}, service.NewAttachmentService(attachRepo, nil, nil), service.NewBlobService(blobs, storage, service.NewScanService(nil, nil, storage), service.NewQuotaService(nil, nil, 0)))
+	r := gin.Default()
+	r.POST("/upload/:id", h.Upload)
+	jpeg := func(n int) []byte { return append([]byte("\xff\xd8\xff\xdb"), make([]byte, n-4)...) }
+	post := func(files ...[]byte) *httptest.ResponseRecorder {
+		var b bytes.Buffer
+		w := multipart.NewWriter(&b)
+		w.WriteField("kind", "drawing")
+		for _, f := range files {
+			fw, _ := w.CreateFormFile("files", "plan.jpg")
+			fw.Write(f)
+		}
+		w.Close()
+		req := httptest.NewRequest("POST", "/upload/1", &b)
+		req.Header.Set("Content-Type", w.FormDataContentType())
+		resp := httptest.NewRecorder()
+		r.ServeHTTP(resp, req)
+		return resp
+	}
+	stored := func() int {
+		n := 0
+		filepath.WalkDir(tmpDir, func(path string, d fs.DirEntry, err error) error {
+			if err == nil && !d.IsDir() {
+				n++
+			}
+			return nil
+		})
+		return n
+	}
+
+	resp := post(jpeg(1000))
+	assert.Equal(t, http.StatusCreated, resp.Code, "exactly the limit is fine")
+	assert.Contains(t, resp.Body.String(), `"kind":"drawing"`)
+	assert.Equal(t, 1, stored())
+
+	// the second file crosses the limit: rejected mid-stream, its bytes
+	// removed, and the first file of the request goes with it
+	resp = post(jpeg(10), jpeg(5000))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, resp.Code)
+	assert.Equal(t, 1, stored())
+	assert.NotContains(t, resp.Body.String(), `"id"`)
+
+	// a bad pairing on a later file is a service error and rolls back too
+	var b bytes.Buffer
+	w := multipart.NewWriter(&b)
+	fw, _ := w.CreateFormFile("files", "first.jpg")
+	fw.Write(jpeg(20))
+	w.WriteField("kind", "remediation")
+	w.WriteField("before_id", "5")
+	fw, _ = w.CreatePart(map[string][]string{"Content-Disposition": {`form-data; name="files"; filename="after.jpg"`}, "Content-Type": {"image/jpeg"}})
+	fw.Write(jpeg(30))
+	w.Close()
+	req := httptest.NewRequest("POST", "/upload/1", &b)
+	req.Header.Set("Content-Type", w.FormDataContentType())
+	resp = httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+	assert.Equal(t, http.StatusNotFound, resp.Code, "the before photo is not on the defect")
+	assert.Equal(t, 1, stored())
+
+	resp = post([]byte("just text"))
+	assert.Equal(t, http.StatusUnsupportedMediaType, resp.Code)
+	assert.Equal(t, 1, stored())
+
+	// the same content again is referenced, not stored twice
+	resp = post(jpeg(1000))
+	assert.Equal(t, http.StatusCreated, resp.Code)
+	assert.Equal(t, 1, stored())
+	assert.Contains(t, resp.Body.String(), `"sha256":"`)
+	for _, n := range blobs.refs {
+		assert.LessOrEqual(t, n, 2)
+	}
+}
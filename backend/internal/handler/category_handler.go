@@ -0,0 +1,177 @@
+package handler
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"example.com/defect-control-system/internal/service"
+)
+
+// maxCatalogSize limits imported catalog files
+const maxCatalogSize = 5 << 20
+
+type CategoryHandler struct {
+	svc service.CategoryService
+}
+
+func NewCategoryHandler(s service.CategoryService) *CategoryHandler {
+	return &CategoryHandler{svc: s}
+}
+
+// ListCategories godoc
+// @Summary List the defect classification catalog
+// @Description Flat list ordered by code, or a tree of categories with their types when tree=true
+// @Tags categories
+// @Produce json
+// @Param tree query bool false "Return nested categories"
+// @Success 200 {array} handler.CategoryResponse
+// @Router /api/v1/categories [get]
+func (h *CategoryHandler) List(c *gin.Context) {
+	list := h.svc.List
+	if c.Query("tree") == "true" {
+		list = h.svc.Tree
+	}
+	out, err := list(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"status": "error", "error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "ok", "data": out})
+}
+
+// CreateCategory godoc
+// @Summary Add a catalog entry
+// @Description Top level entries are trades; set parent_id to add a defect type
+// @Tags categories
+// @Accept json
+// @Produce json
+// @Param body body service.CategoryDTO true "Category"
+// @Success 201 {object} handler.CategoryResponse
+// @Failure 400 {object} map[string]interface{}
+// @Security BearerAuth
+// @Router /api/v1/categories [post]
+func (h *CategoryHandler) Create(c *gin.Context) {
+	var dto service.CategoryDTO
+	if err := c.ShouldBindJSON(&dto); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"status": "error", "error": err.Error()})
+		return
+	}
+	cat, err := h.svc.Create(c.Request.Context(), dto)
+	if err != nil {
+		writeServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"status": "ok", "data": cat})
+}
+
+// UpdateCategory godoc
+// @Summary Update a catalog entry
+// @Tags categories
+// @Accept json
+// @Produce json
+// @Param id path int true "Category ID"
+// @Param body body service.UpdateCategoryDTO true "Update"
+// @Success 200 {object} handler.CategoryResponse
+// @Security BearerAuth
+// @Router /api/v1/categories/{id} [patch]
+func (h *CategoryHandler) Update(c *gin.Context) {
+	id, ok := idParam(c, "id", "category")
+	if !ok {
+		return
+	}
+	var dto service.UpdateCategoryDTO
+	if err := c.ShouldBindJSON(&dto); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"status": "error", "error": err.Error()})
+		return
+	}
+	cat, err := h.svc.Update(c.Request.Context(), id, dto)
+	if err != nil {
+		writeServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "ok", "data": cat})
+}
+
+// DeleteCategory godoc
+// @Summary Delete a catalog entry
+// @Description Categories must have no types left. Defects of the entry become uncategorized.
+// @Tags categories
+// @Param id path int true "Category ID"
+// @Success 204
+// @Security BearerAuth
+// @Router /api/v1/categories/{id} [delete]
+func (h *CategoryHandler) Delete(c *gin.Context) {
+	id, ok := idParam(c, "id", "category")
+	if !ok {
+		return
+	}
+	if err := h.svc.Delete(c.Request.Context(), id); err != nil {
+		writeServiceError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// ImportCategories godoc
+// @Summary Import a catalog file
+// @Description Accepts a JSON array or CSV (columns code, name, parent_code, default_severity, default_organization)
+// @Description as the request body or as a multipart "file". Entries are matched by code; missing organizations are created.
+// @Tags categories
+// @Accept json
+// @Accept text/csv
+// @Accept multipart/form-data
+// @Produce json
+// @Param file formData file false "Catalog file (.json or .csv)"
+// @Success 200 {object} service.ImportResult
+// @Failure 400 {object} map[string]interface{}
+// @Security BearerAuth
+// @Router /api/v1/categories/import [post]
+func (h *CategoryHandler) Import(c *gin.Context) {
+	var (
+		body   io.Reader = http.MaxBytesReader(c.Writer, c.Request.Body, maxCatalogSize)
+		isJSON           = strings.HasPrefix(c.ContentType(), "application/json")
+	)
+	if strings.HasPrefix(c.ContentType(), "multipart/") {
+		fh, err := c.FormFile("file")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"status": "error", "error": "file is required"})
+			return
+		}
+		if fh.Size > maxCatalogSize {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"status": "error", "error": "catalog file is too large"})
+			return
+		}
+		f, err := fh.Open()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"status": "error", "error": err.Error()})
+			return
+		}
+		defer f.Close()
+		body = f
+		isJSON = strings.EqualFold(filepath.Ext(fh.Filename), ".json")
+	}
+	var items []service.CategoryImportItem
+	if isJSON {
+		if err := json.NewDecoder(body).Decode(&items); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"status": "error", "error": "invalid catalog: " + err.Error()})
+			return
+		}
+	} else {
+		var err error
+		if items, err = service.ParseCategoryCSV(body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"status": "error", "error": "invalid catalog: " + err.Error()})
+			return
+		}
+	}
+	res, err := h.svc.Import(c.Request.Context(), items)
+	if err != nil {
+		writeServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "ok", "data": res})
+}
@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 
@@ -46,9 +47,16 @@ func (h *CommentHandler) Create(c *gin.Context) {
 			authorID = uid
 		}
 	}
-	cm, err := h.svc.Create(c.Request.Context(), authorID, dto)
+	cm, err := h.svc.Create(c.Request.Context(), authorID, c.GetString("role"), dto)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"status": "error", "error": err.Error()})
+		switch {
+		case errors.Is(err, service.ErrInvalidVisibility):
+			c.JSON(http.StatusBadRequest, gin.H{"status": "error", "error": err.Error()})
+		case errors.Is(err, service.ErrForbidden):
+			c.JSON(http.StatusForbidden, gin.H{"status": "error", "error": "internal comments are not allowed for this role"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"status": "error", "error": err.Error()})
+		}
 		return
 	}
 	c.JSON(http.StatusCreated, gin.H{"status": "ok", "data": cm})
@@ -56,6 +64,7 @@ func (h *CommentHandler) Create(c *gin.Context) {
 
 // ListComments godoc
 // @Summary List comments for a defect
+// @Description Internal comments are returned only to contractor roles (engineer, manager, admin)
 // @Produce json
 // @Param id path int true "Project ID"
 // @Param defectId path int true "Defect ID"
@@ -82,7 +91,8 @@ func (h *CommentHandler) List(c *gin.Context) {
 			return
 		}
 	}
-	list, err := h.svc.ListByDefect(c.Request.Context(), defectID)
+	// role is set by OptionalJWTAuthMiddleware; anonymous callers get public comments only
+	list, err := h.svc.ListByDefect(c.Request.Context(), defectID, c.GetString("role"))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"status": "error", "error": err.Error()})
 		return
@@ -0,0 +1,81 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"example.com/defect-control-system/internal/service"
+)
+
+// currentUser returns the authenticated user id and role set by JWTAuthMiddleware
+func currentUser(c *gin.Context) (uint, string) {
+	var uid uint
+	if v, ok := c.Get("user_id"); ok {
+		uid, _ = v.(uint)
+	}
+	return uid, c.GetString("role")
+}
+
+// writeServiceError maps service sentinel errors to HTTP statuses. Errors that
+// are not ErrInvalid are not the client's fault and answer 500
+func writeServiceError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"status": "error", "error": err.Error()})
+	case errors.Is(err, service.ErrForbidden):
+		c.JSON(http.StatusForbidden, gin.H{"status": "error", "error": err.Error()})
+	case errors.Is(err, service.ErrPreconditionFailed):
+		c.JSON(http.StatusPreconditionFailed, gin.H{"status": "error", "error": err.Error()})
+	case errors.Is(err, service.ErrConflict):
+		c.JSON(http.StatusConflict, gin.H{"status": "error", "error": err.Error()})
+	case errors.Is(err, service.ErrInvalid):
+		c.JSON(http.StatusBadRequest, gin.H{"status": "error", "error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"status": "error", "error": err.Error()})
+	}
+}
+
+// projectIDParam parses the :id path param of project routes, writing a 400 on failure
+func projectIDParam(c *gin.Context) (uint, bool) {
+	var id uint
+	if _, err := fmt.Sscanf(c.Param("id"), "%d", &id); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"status": "error", "error": "invalid project id"})
+		return 0, false
+	}
+	return id, true
+}
+
+// idParam parses a numeric path param, writing a 400 naming what on failure
+func idParam(c *gin.Context, name, what string) (uint, bool) {
+	var id uint
+	if _, err := fmt.Sscanf(c.Param(name), "%d", &id); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"status": "error", "error": "invalid " + what + " id"})
+		return 0, false
+	}
+	return id, true
+}
+
+// setETag exposes the version of a defect or project as a strong entity tag
+func setETag(c *gin.Context, version int) {
+	c.Header("ETag", strconv.Quote(strconv.Itoa(version)))
+}
+
+// ifMatchVersion parses the If-Match header into an expected version. A missing
+// header or "*" yields nil (unconditional update); a malformed one writes a 400
+func ifMatchVersion(c *gin.Context) (*int, bool) {
+	h := strings.TrimSpace(c.GetHeader("If-Match"))
+	if h == "" || h == "*" {
+		return nil, true
+	}
+	v, err := strconv.Atoi(strings.Trim(strings.TrimPrefix(h, "W/"), `"`))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"status": "error", "error": "invalid If-Match header, expected an ETag like \"3\""})
+		return nil, false
+	}
+	return &v, true
+}
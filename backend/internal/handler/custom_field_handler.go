@@ -0,0 +1,134 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"example.com/defect-control-system/internal/service"
+)
+
+type CustomFieldHandler struct {
+	svc service.CustomFieldService
+}
+
+func NewCustomFieldHandler(s service.CustomFieldService) *CustomFieldHandler {
+	return &CustomFieldHandler{svc: s}
+}
+
+// ListCustomFields godoc
+// @Summary List custom fields of a project
+// @Tags custom-fields
+// @Produce json
+// @Param id path int true "Project ID"
+// @Success 200 {array} handler.CustomFieldResponse
+// @Security BearerAuth
+// @Router /api/v1/projects/{id}/fields [get]
+func (h *CustomFieldHandler) List(c *gin.Context) {
+	projectID, ok := projectIDParam(c)
+	if !ok {
+		return
+	}
+	list, err := h.svc.List(c.Request.Context(), projectID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"status": "error", "error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "ok", "data": list})
+}
+
+// CreateCustomField godoc
+// @Summary Define a custom field
+// @Description Types: text, number, date, enum (with options), user, boolean. Project admins only.
+// @Tags custom-fields
+// @Accept json
+// @Produce json
+// @Param id path int true "Project ID"
+// @Param body body service.CreateCustomFieldDTO true "Field"
+// @Success 201 {object} handler.CustomFieldResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Security BearerAuth
+// @Router /api/v1/projects/{id}/fields [post]
+func (h *CustomFieldHandler) Create(c *gin.Context) {
+	projectID, ok := projectIDParam(c)
+	if !ok {
+		return
+	}
+	var dto service.CreateCustomFieldDTO
+	if err := c.ShouldBindJSON(&dto); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"status": "error", "error": err.Error()})
+		return
+	}
+	uid, role := currentUser(c)
+	f, err := h.svc.Create(c.Request.Context(), uid, role, projectID, dto)
+	if err != nil {
+		writeServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"status": "ok", "data": f})
+}
+
+// UpdateCustomField godoc
+// @Summary Update a custom field
+// @Description Label, required flag, enum options and position can be changed; key and type are fixed
+// @Tags custom-fields
+// @Accept json
+// @Produce json
+// @Param id path int true "Project ID"
+// @Param fieldId path int true "Field ID"
+// @Param body body service.UpdateCustomFieldDTO true "Update"
+// @Success 200 {object} handler.CustomFieldResponse
+// @Security BearerAuth
+// @Router /api/v1/projects/{id}/fields/{fieldId} [patch]
+func (h *CustomFieldHandler) Update(c *gin.Context) {
+	projectID, ok := projectIDParam(c)
+	if !ok {
+		return
+	}
+	var fieldID uint
+	if _, err := fmt.Sscanf(c.Param("fieldId"), "%d", &fieldID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"status": "error", "error": "invalid field id"})
+		return
+	}
+	var dto service.UpdateCustomFieldDTO
+	if err := c.ShouldBindJSON(&dto); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"status": "error", "error": err.Error()})
+		return
+	}
+	uid, role := currentUser(c)
+	f, err := h.svc.Update(c.Request.Context(), uid, role, projectID, fieldID, dto)
+	if err != nil {
+		writeServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "ok", "data": f})
+}
+
+// DeleteCustomField godoc
+// @Summary Delete a custom field
+// @Description Removes the definition and its values from all defects of the project
+// @Tags custom-fields
+// @Param id path int true "Project ID"
+// @Param fieldId path int true "Field ID"
+// @Success 204
+// @Security BearerAuth
+// @Router /api/v1/projects/{id}/fields/{fieldId} [delete]
+func (h *CustomFieldHandler) Delete(c *gin.Context) {
+	projectID, ok := projectIDParam(c)
+	if !ok {
+		return
+	}
+	var fieldID uint
+	if _, err := fmt.Sscanf(c.Param("fieldId"), "%d", &fieldID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"status": "error", "error": "invalid field id"})
+		return
+	}
+	uid, role := currentUser(c)
+	if err := h.svc.Delete(c.Request.Context(), uid, role, projectID, fieldID); err != nil {
+		writeServiceError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,115 @@
+package handler
+
+import (
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"example.com/defect-control-system/internal/models"
+	"example.com/defect-control-system/internal/utils"
+)
+
+// writeDefectsCSV streams defects as a CSV attachment. A UTF-8 BOM is written
+// first so spreadsheet software detects the encoding of Cyrillic text. Custom
+// fields become extra columns; fields of different projects sharing a key share a column.
+// Cells that would start a formula are escaped with utils.SpreadsheetCell.
+func writeDefectsCSV(c *gin.Context, filename string, list []*models.Defect, fields []*models.CustomField) {
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", filename))
+	c.Status(http.StatusOK)
+	_, _ = c.Writer.Write([]byte("\xef\xbb\xbf"))
+	w := csv.NewWriter(c.Writer)
+	header := []string{"key", "id", "project_id", "title", "status", "severity", "priority", "assignee_id", "due_date", "created_at", "category", "responsible_org", "labels", "citations", "description"}
+	var keys []string
+	seen := map[string]bool{}
+	for _, f := range fields {
+		if !seen[f.Key] {
+			seen[f.Key] = true
+			keys = append(keys, f.Key)
+			header = append(header, f.Label)
+		}
+	}
+	_ = w.Write(utils.SpreadsheetRow(header))
+	for _, d := range list {
+		assignee := ""
+		if d.AssigneeID != nil {
+			assignee = strconv.FormatUint(uint64(*d.AssigneeID), 10)
+		}
+		due := ""
+		if d.DueDate != nil {
+			due = d.DueDate.Format("2006-01-02")
+		}
+		row := []string{
+			d.Key,
+			strconv.FormatUint(uint64(d.ID), 10),
+			strconv.FormatUint(uint64(d.ProjectID), 10),
+			d.Title,
+			d.Status,
+			d.Severity,
+			d.Priority,
+			assignee,
+			due,
+			d.CreatedAt.Format(time.RFC3339),
+			categoryName(d.Category),
+			orgName(d.ResponsibleOrg),
+			labelNames(d.Labels),
+			citations(d.Clauses),
+			d.Description,
+		}
+		for _, k := range keys {
+			row = append(row, customValueString(d.CustomFields[k]))
+		}
+		_ = w.Write(utils.SpreadsheetRow(row))
+	}
+	w.Flush()
+}
+
+func customValueString(v interface{}) string {
+	switch x := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return x
+	case float64:
+		return strconv.FormatFloat(x, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(x)
+	default:
+		return fmt.Sprint(x)
+	}
+}
+
+func labelNames(labels []models.Label) string {
+	names := make([]string, len(labels))
+	for i, l := range labels {
+		names[i] = l.Name
+	}
+	return strings.Join(names, ", ")
+}
+
+func categoryName(c *models.DefectCategory) string {
+	if c == nil {
+		return ""
+	}
+	return c.Code + " " + c.Name
+}
+
+func orgName(o *models.Organization) string {
+	if o == nil {
+		return ""
+	}
+	return o.Name
+}
+
+func citations(clauses []models.NormativeClause) string {
+	out := make([]string, len(clauses))
+	for i, c := range clauses {
+		out[i] = c.Citation()
+	}
+	return strings.Join(out, "; ")
+}
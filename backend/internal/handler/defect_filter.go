@@ -0,0 +1,116 @@
+package handler
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"example.com/defect-control-system/internal/models"
+)
+
+// dateLayouts are accepted for date query params and due dates
+var dateLayouts = []string{time.RFC3339, "2006-01-02", "2006-01-02T15:04:05", "2006-01-02 15:04:05"}
+
+func parseDate(v string) (time.Time, error) {
+	var err error
+	for _, l := range dateLayouts {
+		var t time.Time
+		if t, err = time.Parse(l, v); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, err
+}
+
+// splitList parses a comma separated query param, skipping empty items
+func splitList(v string) []string {
+	var out []string
+	for _, s := range strings.Split(v, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// parseDefectFilter builds a DefectFilter from list query params:
+// status, severity, priority (comma separated), assignee_id (0 = unassigned),
+// due_before, due_after, overdue, category_id, responsible_org_id, labels (ids) with labels_mode (any/all), q, cf.<key> (custom field value) and sort (e.g. -due_date).
+func parseDefectFilter(c *gin.Context) (models.DefectFilter, error) {
+	return parseDefectQuery(c.Request.URL.Query())
+}
+
+// parseDefectQuery parses the list query params of parseDefectFilter from q
+func parseDefectQuery(q url.Values) (models.DefectFilter, error) {
+	var f models.DefectFilter
+	f.Statuses = splitList(q.Get("status"))
+	f.Severities = splitList(q.Get("severity"))
+	f.Priorities = splitList(q.Get("priority"))
+	if v := q.Get("assignee_id"); v != "" {
+		n, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			return f, fmt.Errorf("invalid assignee_id")
+		}
+		id := uint(n)
+		f.AssigneeID = &id
+	}
+	if v := q.Get("due_before"); v != "" {
+		t, err := parseDate(v)
+		if err != nil {
+			return f, fmt.Errorf("invalid due_before: %v", err)
+		}
+		f.DueBefore = &t
+	}
+	if v := q.Get("due_after"); v != "" {
+		t, err := parseDate(v)
+		if err != nil {
+			return f, fmt.Errorf("invalid due_after: %v", err)
+		}
+		f.DueAfter = &t
+	}
+	if v := q.Get("overdue"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return f, fmt.Errorf("invalid overdue")
+		}
+		f.Overdue = b
+	}
+	for name, dst := range map[string]**uint{"category_id": &f.CategoryID, "responsible_org_id": &f.ResponsibleOrgID} {
+		if v := q.Get(name); v != "" {
+			n, err := strconv.ParseUint(v, 10, 64)
+			if err != nil {
+				return f, fmt.Errorf("invalid %s", name)
+			}
+			id := uint(n)
+			*dst = &id
+		}
+	}
+	for _, v := range splitList(q.Get("labels")) {
+		n, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			return f, fmt.Errorf("invalid labels")
+		}
+		f.LabelIDs = append(f.LabelIDs, uint(n))
+	}
+	if m := q.Get("labels_mode"); m != "" {
+		if m != "any" && m != "all" {
+			return f, fmt.Errorf("labels_mode must be any or all")
+		}
+		f.LabelMode = m
+	}
+	for key, vals := range q {
+		if name, ok := strings.CutPrefix(key, "cf."); ok && len(vals) > 0 {
+			if f.Custom == nil {
+				f.Custom = map[string]string{}
+			}
+			f.Custom[name] = vals[0]
+		}
+	}
+	f.Query = strings.TrimSpace(q.Get("q"))
+	f.Sort = q.Get("sort")
+	return f, nil
+}
@@ -0,0 +1,159 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"example.com/defect-control-system/internal/repository"
+	"example.com/defect-control-system/internal/service"
+)
+
+// FileHandler issues signed download links and serves attachments by them,
+// without a bearer header
+type FileHandler struct {
+	links      service.LinkService
+	attachRepo repository.AttachmentRepository
+}
+
+func NewFileHandler(l service.LinkService, ar repository.AttachmentRepository) *FileHandler {
+	return &FileHandler{links: l, attachRepo: ar}
+}
+
+// CreateLinkRequest asks for a signed link
+type CreateLinkRequest struct {
+	// ExpiresIn is the lifetime in seconds; one hour when omitted
+	ExpiresIn int `json:"expires_in"`
+	// BindUser binds the link to the caller, who then has to send their
+	// bearer token with it
+	BindUser bool `json:"bind_user"`
+}
+
+// verifyLink checks the signed link of the request and its user binding: a
+// bound link is served only with the bearer token of its user
+func (h *FileHandler) verifyLink(c *gin.Context, u *url.URL) error {
+	bound, err := h.links.Verify(u.Path, u.Query())
+	if err != nil {
+		return err
+	}
+	if uid, _ := currentUser(c); bound != 0 && uid != bound {
+		return service.ErrLinkInvalid
+	}
+	return nil
+}
+
+// CreateLink godoc
+// @Summary Create a signed download link
+// @Description A URL that downloads the attachment without a bearer header until it expires, for <img> tags, emails and reports. An unbound link is a bearer credential: whoever holds it downloads the file. With bind_user the link names the caller and is served only with the caller's bearer token, so it does not work in <img> tags or emails. Links cannot be revoked before they expire; keep lifetimes short.
+// @Tags attachments
+// @Accept json
+// @Produce json
+// @Param id path int true "Attachment ID"
+// @Param body body handler.CreateLinkRequest false "Lifetime and binding"
+// @Success 201 {object} handler.SignedLinkResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 423 {object} map[string]interface{}
+// @Security BearerAuth
+// @Router /api/v1/attachments/{id}/links [post]
+func (h *FileHandler) CreateLink(c *gin.Context) {
+	id, ok := idParam(c, "id", "attachment")
+	if !ok {
+		return
+	}
+	var req CreateLinkRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"status": "error", "error": err.Error()})
+			return
+		}
+	}
+	a, err := h.attachRepo.FindByID(c.Request.Context(), id)
+	if err != nil || a == nil {
+		c.JSON(http.StatusNotFound, gin.H{"status": "error", "error": "attachment not found"})
+		return
+	}
+	if !mayDownload(c, a) {
+		c.JSON(http.StatusForbidden, gin.H{"status": "error", "error": "forbidden"})
+		return
+	}
+	if !servable(c, a) {
+		return
+	}
+	var bind uint
+	if req.BindUser {
+		bind, _ = currentUser(c)
+	}
+	link, err := h.links.Sign(a, bind, time.Duration(req.ExpiresIn)*time.Second)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"status": "error", "error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"status": "ok", "data": link})
+}
+
+// ServeFile godoc
+// @Summary Download an attachment by signed link
+// @Description The expires, uid and signature query parameters of a link from POST /api/v1/attachments/{id}/links authorize the download without a bearer header; a link bound with uid also needs the bearer token of that user.
+// @Tags attachments
+// @Produce application/octet-stream
+// @Param id path int true "Attachment ID"
+// @Param expires query int true "Expiry, Unix seconds"
+// @Param uid query int false "Bound user"
+// @Param signature query string true "Link signature"
+// @Success 200
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 410 {object} map[string]interface{}
+// @Router /api/v1/files/{id} [get]
+func (h *FileHandler) Serve(c *gin.Context) {
+	err := h.verifyLink(c, c.Request.URL)
+	if errors.Is(err, service.ErrLinkExpired) {
+		c.JSON(http.StatusGone, gin.H{"status": "error", "error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"status": "error", "error": err.Error()})
+		return
+	}
+	id, ok := idParam(c, "id", "attachment")
+	if !ok {
+		return
+	}
+	a, err := h.attachRepo.FindByID(c.Request.Context(), id)
+	if err != nil || a == nil {
+		c.JSON(http.StatusNotFound, gin.H{"status": "error", "error": "attachment not found"})
+		return
+	}
+	if exp, err := strconv.ParseInt(c.Query("expires"), 10, 64); err == nil {
+		// caches keep the file no longer than the link is valid
+		c.Header("Cache-Control", fmt.Sprintf("private, max-age=%d", max(exp-time.Now().Unix(), 0)))
+	}
+	serveAttachment(c, a)
+}
+
+// AuthorizeFile godoc
+// @Summary Check a signed link for a static file server
+// @Description For uploads.serve_via proxy: nginx auth_request (or similar) passes the requested URI in X-Original-URI; 204 lets the static server send the file, 403 refuses. Needs no database.
+// @Tags attachments
+// @Param X-Original-URI header string true "Requested path and query"
+// @Success 204
+// @Failure 403
+// @Router /api/v1/files/authorize [get]
+func (h *FileHandler) Authorize(c *gin.Context) {
+	u, err := url.ParseRequestURI(c.GetHeader("X-Original-URI"))
+	if err == nil {
+		err = h.verifyLink(c, u)
+	}
+	if err != nil {
+		c.Status(http.StatusForbidden)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,101 @@
+package handler_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	hpkg "example.com/defect-control-system/internal/handler"
+	"example.com/defect-control-system/internal/middleware"
+	"example.com/defect-control-system/internal/models"
+	"example.com/defect-control-system/internal/service"
+	"example.com/defect-control-system/internal/utils"
+)
+
+func TestFileHandler_SignedLinks(t *testing.T) {
+	dir := t.TempDir()
+	viper.Set("uploads.path", dir)
+	viper.Set("jwt.secret", "test-secret")
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "2025", "10", "11"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "2025", "10", "11", "file.jpg"), []byte("jpeg"), 0o644))
+	token := func(id uint) string {
+		s, err := utils.CreateJWT("test-secret", &models.User{ID: id, Role: "engineer"})
+		require.NoError(t, err)
+		return "Bearer " + s
+	}
+	get := func(r *gin.Engine, target, auth string, header ...string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		if auth != "" {
+			req.Header.Set("Authorization", auth)
+		}
+		for i := 0; i+1 < len(header); i += 2 {
+			req.Header.Set(header[i], header[i+1])
+		}
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		return rr
+	}
+
+	links := service.NewLinkService("link-secret", "file", "/api/v1/files", 0)
+	h := hpkg.NewFileHandler(links, &mockAttachRepo{})
+	r := gin.New()
+	r.POST("/api/v1/attachments/:id/links", middleware.JWTAuthMiddleware(), h.CreateLink)
+	r.GET("/api/v1/files/authorize", middleware.OptionalJWTAuthMiddleware(), h.Authorize)
+	r.GET("/api/v1/files/:id", middleware.OptionalJWTAuthMiddleware(), h.Serve)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/attachments/5/links", strings.NewReader(`{"expires_in": 600, "bind_user": true}`))
+	req.Header.Set("Authorization", token(4))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+	assert.Contains(t, rr.Body.String(), `"url":"/api/v1/files/5?expires=`)
+	assert.Contains(t, rr.Body.String(), `"user_id":4`)
+
+	// no bearer header needed
+	link, err := links.Sign(&models.Attachment{ID: 5}, 0, 10*time.Minute)
+	require.NoError(t, err)
+	rr = get(r, link.URL, "")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "jpeg", rr.Body.String())
+	assert.Contains(t, rr.Header().Get("Cache-Control"), "max-age=")
+
+	// a bound link takes the bound user's token
+	bound, err := links.Sign(&models.Attachment{ID: 5}, 4, 10*time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, get(r, bound.URL, token(4)).Code)
+	assert.Equal(t, http.StatusForbidden, get(r, bound.URL, token(9)).Code, "bound to another user")
+	assert.Equal(t, http.StatusForbidden, get(r, bound.URL, "").Code, "anonymous")
+
+	u, _ := url.Parse(link.URL)
+	q := u.Query()
+	q.Set("expires", "9999999999")
+	assert.Equal(t, http.StatusForbidden, get(r, u.Path+"?"+q.Encode(), "").Code, "expiry is signed")
+	assert.Equal(t, http.StatusForbidden, get(r, "/api/v1/files/6?"+u.RawQuery, "").Code, "so is the attachment")
+	assert.Equal(t, http.StatusForbidden, get(r, "/api/v1/files/5", "").Code)
+
+	// proxy mode: the static server asks before sending the stored file
+	proxy := service.NewLinkService("link-secret", "proxy", "https://static.example.com/uploads", 0)
+	h = hpkg.NewFileHandler(proxy, &mockAttachRepo{})
+	r = gin.New()
+	r.GET("/api/v1/files/authorize", middleware.OptionalJWTAuthMiddleware(), h.Authorize)
+	link, err = proxy.Sign(&models.Attachment{ID: 5, Path: "blobs/ab/cd/abcd"}, 0, time.Minute)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(link.URL, "https://static.example.com/uploads/blobs/ab/cd/abcd?"))
+	u, _ = url.Parse(link.URL)
+	assert.Equal(t, http.StatusNoContent, get(r, "/api/v1/files/authorize", "", "X-Original-URI", u.RequestURI()).Code)
+	assert.Equal(t, http.StatusForbidden, get(r, "/api/v1/files/authorize", "", "X-Original-URI", "/uploads/blobs/ef/01/ef01?"+u.RawQuery).Code)
+
+	_, err = links.Sign(&models.Attachment{ID: 5}, 0, 30*24*time.Hour)
+	assert.Error(t, err, "longer than the maximum")
+}
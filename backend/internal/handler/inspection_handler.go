@@ -0,0 +1,289 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"example.com/defect-control-system/internal/service"
+)
+
+type InspectionHandler struct {
+	svc service.InspectionService
+}
+
+func NewInspectionHandler(s service.InspectionService) *InspectionHandler {
+	return &InspectionHandler{svc: s}
+}
+
+// inspectionParams parses the :id and :inspectionId path params
+func inspectionParams(c *gin.Context) (uint, uint, bool) {
+	projectID, ok := projectIDParam(c)
+	if !ok {
+		return 0, 0, false
+	}
+	id, ok := idParam(c, "inspectionId", "inspection")
+	return projectID, id, ok
+}
+
+// ListTemplates godoc
+// @Summary List checklist templates usable in a project
+// @Description Returns the shared templates and the templates of the project.
+// @Tags inspections
+// @Produce json
+// @Param id path int true "Project ID"
+// @Success 200 {array} handler.InspectionTemplateResponse
+// @Security BearerAuth
+// @Router /api/v1/projects/{id}/inspection-templates [get]
+func (h *InspectionHandler) ListTemplates(c *gin.Context) {
+	projectID, ok := projectIDParam(c)
+	if !ok {
+		return
+	}
+	uid, role := currentUser(c)
+	list, err := h.svc.ListTemplates(c.Request.Context(), uid, role, projectID)
+	if err != nil {
+		writeServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "ok", "data": list})
+}
+
+// CreateTemplate godoc
+// @Summary Create a checklist template
+// @Description Creates a template of the project (project managers) or, on /inspection-templates, a template shared by all projects (managers and admins).
+// @Tags inspections
+// @Accept json
+// @Produce json
+// @Param id path int true "Project ID"
+// @Param body body service.TemplateDTO true "Template"
+// @Success 201 {object} handler.InspectionTemplateResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Security BearerAuth
+// @Router /api/v1/projects/{id}/inspection-templates [post]
+func (h *InspectionHandler) CreateTemplate(c *gin.Context) {
+	var projectID *uint
+	if c.Param("id") != "" {
+		id, ok := projectIDParam(c)
+		if !ok {
+			return
+		}
+		projectID = &id
+	}
+	var dto service.TemplateDTO
+	if err := c.ShouldBindJSON(&dto); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"status": "error", "error": err.Error()})
+		return
+	}
+	uid, role := currentUser(c)
+	t, err := h.svc.CreateTemplate(c.Request.Context(), uid, role, projectID, dto)
+	if err != nil {
+		writeServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"status": "ok", "data": t})
+}
+
+// UpdateTemplate godoc
+// @Summary Replace a checklist template
+// @Description Replaces name, description and items. Inspections already scheduled keep their copy of the items.
+// @Tags inspections
+// @Accept json
+// @Produce json
+// @Param id path int true "Template ID"
+// @Param body body service.TemplateDTO true "Template"
+// @Success 200 {object} handler.InspectionTemplateResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Security BearerAuth
+// @Router /api/v1/inspection-templates/{id} [put]
+func (h *InspectionHandler) UpdateTemplate(c *gin.Context) {
+	id, ok := idParam(c, "id", "template")
+	if !ok {
+		return
+	}
+	var dto service.TemplateDTO
+	if err := c.ShouldBindJSON(&dto); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"status": "error", "error": err.Error()})
+		return
+	}
+	uid, role := currentUser(c)
+	t, err := h.svc.UpdateTemplate(c.Request.Context(), uid, role, id, dto)
+	if err != nil {
+		writeServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "ok", "data": t})
+}
+
+// ScheduleInspection godoc
+// @Summary Schedule an inspection
+// @Tags inspections
+// @Accept json
+// @Produce json
+// @Param id path int true "Project ID"
+// @Param body body service.ScheduleInspectionDTO true "Inspection"
+// @Success 201 {object} handler.InspectionResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Security BearerAuth
+// @Router /api/v1/projects/{id}/inspections [post]
+func (h *InspectionHandler) Schedule(c *gin.Context) {
+	projectID, ok := projectIDParam(c)
+	if !ok {
+		return
+	}
+	var dto service.ScheduleInspectionDTO
+	if err := c.ShouldBindJSON(&dto); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"status": "error", "error": err.Error()})
+		return
+	}
+	uid, role := currentUser(c)
+	in, err := h.svc.Schedule(c.Request.Context(), uid, role, projectID, dto)
+	if err != nil {
+		writeServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"status": "ok", "data": in})
+}
+
+// ListInspections godoc
+// @Summary List inspections of a project
+// @Tags inspections
+// @Produce json
+// @Param id path int true "Project ID"
+// @Param status query string false "scheduled, in_progress, completed or cancelled"
+// @Success 200 {array} handler.InspectionResponse
+// @Security BearerAuth
+// @Router /api/v1/projects/{id}/inspections [get]
+func (h *InspectionHandler) List(c *gin.Context) {
+	projectID, ok := projectIDParam(c)
+	if !ok {
+		return
+	}
+	uid, role := currentUser(c)
+	list, err := h.svc.List(c.Request.Context(), uid, role, projectID, c.Query("status"))
+	if err != nil {
+		writeServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "ok", "data": list})
+}
+
+// GetInspection godoc
+// @Summary Get an inspection with its checklist
+// @Tags inspections
+// @Produce json
+// @Param id path int true "Project ID"
+// @Param inspectionId path int true "Inspection ID"
+// @Success 200 {object} handler.InspectionResponse
+// @Failure 404 {object} map[string]interface{}
+// @Security BearerAuth
+// @Router /api/v1/projects/{id}/inspections/{inspectionId} [get]
+func (h *InspectionHandler) Get(c *gin.Context) {
+	projectID, id, ok := inspectionParams(c)
+	if !ok {
+		return
+	}
+	uid, role := currentUser(c)
+	in, err := h.svc.Get(c.Request.Context(), uid, role, projectID, id)
+	if err != nil {
+		writeServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "ok", "data": in})
+}
+
+// RecordResults godoc
+// @Summary Record checklist outcomes
+// @Description Sets pass, fail or na on checklist items, starting the inspection. Only the inspector or project managers record results.
+// @Tags inspections
+// @Accept json
+// @Produce json
+// @Param id path int true "Project ID"
+// @Param inspectionId path int true "Inspection ID"
+// @Param body body service.RecordResultsDTO true "Outcomes"
+// @Success 200 {object} handler.InspectionResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Security BearerAuth
+// @Router /api/v1/projects/{id}/inspections/{inspectionId}/results [put]
+func (h *InspectionHandler) RecordResults(c *gin.Context) {
+	projectID, id, ok := inspectionParams(c)
+	if !ok {
+		return
+	}
+	var dto service.RecordResultsDTO
+	if err := c.ShouldBindJSON(&dto); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"status": "error", "error": err.Error()})
+		return
+	}
+	uid, role := currentUser(c)
+	in, err := h.svc.RecordResults(c.Request.Context(), uid, role, projectID, id, dto)
+	if err != nil {
+		writeServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "ok", "data": in})
+}
+
+// CompleteInspection godoc
+// @Summary Complete an inspection
+// @Description Requires every item checked. A defect is created for each failed item, with the inspection, location and item in its description; the given fields apply to all of them. While another completion of the inspection is running the request gets 409.
+// @Tags inspections
+// @Accept json
+// @Produce json
+// @Param id path int true "Project ID"
+// @Param inspectionId path int true "Inspection ID"
+// @Param body body service.CompleteInspectionDTO false "Fields of the raised defects"
+// @Success 200 {object} handler.InspectionResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Security BearerAuth
+// @Router /api/v1/projects/{id}/inspections/{inspectionId}/complete [post]
+func (h *InspectionHandler) Complete(c *gin.Context) {
+	projectID, id, ok := inspectionParams(c)
+	if !ok {
+		return
+	}
+	var dto service.CompleteInspectionDTO
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&dto); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"status": "error", "error": err.Error()})
+			return
+		}
+	}
+	uid, role := currentUser(c)
+	in, err := h.svc.Complete(c.Request.Context(), uid, role, projectID, id, dto)
+	if err != nil {
+		writeServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "ok", "data": in})
+}
+
+// CancelInspection godoc
+// @Summary Cancel an inspection
+// @Tags inspections
+// @Produce json
+// @Param id path int true "Project ID"
+// @Param inspectionId path int true "Inspection ID"
+// @Success 200 {object} handler.InspectionResponse
+// @Failure 403 {object} map[string]interface{}
+// @Security BearerAuth
+// @Router /api/v1/projects/{id}/inspections/{inspectionId}/cancel [post]
+func (h *InspectionHandler) Cancel(c *gin.Context) {
+	projectID, id, ok := inspectionParams(c)
+	if !ok {
+		return
+	}
+	uid, role := currentUser(c)
+	in, err := h.svc.Cancel(c.Request.Context(), uid, role, projectID, id)
+	if err != nil {
+		writeServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "ok", "data": in})
+}
@@ -0,0 +1,164 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"example.com/defect-control-system/internal/service"
+)
+
+type LabelHandler struct {
+	svc service.LabelService
+}
+
+func NewLabelHandler(s service.LabelService) *LabelHandler { return &LabelHandler{svc: s} }
+
+func labelIDParam(c *gin.Context) (uint, bool) {
+	var id uint
+	if _, err := fmt.Sscanf(c.Param("labelId"), "%d", &id); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"status": "error", "error": "invalid label id"})
+		return 0, false
+	}
+	return id, true
+}
+
+// ListLabels godoc
+// @Summary List project labels
+// @Tags labels
+// @Produce json
+// @Param id path int true "Project ID"
+// @Success 200 {array} handler.LabelResponse
+// @Router /api/v1/projects/{id}/labels [get]
+func (h *LabelHandler) List(c *gin.Context) {
+	projectID, ok := projectIDParam(c)
+	if !ok {
+		return
+	}
+	list, err := h.svc.List(c.Request.Context(), projectID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"status": "error", "error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "ok", "data": list})
+}
+
+// CreateLabel godoc
+// @Summary Create a project label
+// @Description Project admins only. Color is #RRGGBB.
+// @Tags labels
+// @Accept json
+// @Produce json
+// @Param id path int true "Project ID"
+// @Param body body service.LabelDTO true "Label"
+// @Success 201 {object} handler.LabelResponse
+// @Security BearerAuth
+// @Router /api/v1/projects/{id}/labels [post]
+func (h *LabelHandler) Create(c *gin.Context) {
+	projectID, ok := projectIDParam(c)
+	if !ok {
+		return
+	}
+	var dto service.LabelDTO
+	if err := c.ShouldBindJSON(&dto); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"status": "error", "error": err.Error()})
+		return
+	}
+	uid, role := currentUser(c)
+	l, err := h.svc.Create(c.Request.Context(), uid, role, projectID, dto)
+	if err != nil {
+		writeServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"status": "ok", "data": l})
+}
+
+// UpdateLabel godoc
+// @Summary Rename or recolor a label
+// @Tags labels
+// @Accept json
+// @Produce json
+// @Param id path int true "Project ID"
+// @Param labelId path int true "Label ID"
+// @Param body body service.UpdateLabelDTO true "Update"
+// @Success 200 {object} handler.LabelResponse
+// @Security BearerAuth
+// @Router /api/v1/projects/{id}/labels/{labelId} [patch]
+func (h *LabelHandler) Update(c *gin.Context) {
+	projectID, ok := projectIDParam(c)
+	if !ok {
+		return
+	}
+	labelID, ok := labelIDParam(c)
+	if !ok {
+		return
+	}
+	var dto service.UpdateLabelDTO
+	if err := c.ShouldBindJSON(&dto); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"status": "error", "error": err.Error()})
+		return
+	}
+	uid, role := currentUser(c)
+	l, err := h.svc.Update(c.Request.Context(), uid, role, projectID, labelID, dto)
+	if err != nil {
+		writeServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "ok", "data": l})
+}
+
+// DeleteLabel godoc
+// @Summary Delete a label
+// @Description Also removes it from all defects
+// @Tags labels
+// @Param id path int true "Project ID"
+// @Param labelId path int true "Label ID"
+// @Success 204
+// @Security BearerAuth
+// @Router /api/v1/projects/{id}/labels/{labelId} [delete]
+func (h *LabelHandler) Delete(c *gin.Context) {
+	projectID, ok := projectIDParam(c)
+	if !ok {
+		return
+	}
+	labelID, ok := labelIDParam(c)
+	if !ok {
+		return
+	}
+	uid, role := currentUser(c)
+	if err := h.svc.Delete(c.Request.Context(), uid, role, projectID, labelID); err != nil {
+		writeServiceError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// BulkLabels godoc
+// @Summary Add/remove labels on many defects
+// @Tags labels
+// @Accept json
+// @Produce json
+// @Param id path int true "Project ID"
+// @Param body body service.BulkLabelsDTO true "Defects and labels"
+// @Success 200 {object} map[string]interface{}
+// @Security BearerAuth
+// @Router /api/v1/projects/{id}/labels/bulk [post]
+func (h *LabelHandler) Bulk(c *gin.Context) {
+	projectID, ok := projectIDParam(c)
+	if !ok {
+		return
+	}
+	var dto service.BulkLabelsDTO
+	if err := c.ShouldBindJSON(&dto); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"status": "error", "error": err.Error()})
+		return
+	}
+	uid, role := currentUser(c)
+	n, err := h.svc.Bulk(c.Request.Context(), uid, role, projectID, dto)
+	if err != nil {
+		writeServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "ok", "data": gin.H{"updated": n}})
+}
@@ -0,0 +1,51 @@
+package handler_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	hpkg "example.com/defect-control-system/internal/handler"
+	"example.com/defect-control-system/internal/models"
+	"example.com/defect-control-system/internal/repository"
+	"example.com/defect-control-system/internal/service"
+)
+
+// managingMembers lets everyone manage every project
+type managingMembers struct{ service.MembershipService }
+
+func (m *managingMembers) CanManageProject(ctx context.Context, userID uint, role string, projectID uint) (bool, error) {
+	return true, nil
+}
+
+// brokenLabelRepo fails every write like a lost database connection
+type brokenLabelRepo struct{ repository.LabelRepository }
+
+func (m *brokenLabelRepo) Create(ctx context.Context, l *models.Label) error {
+	return errors.New("driver: bad connection")
+}
+
+func TestLabelHandler_ErrorStatuses(t *testing.T) {
+	h := hpkg.NewLabelHandler(service.NewLabelService(&brokenLabelRepo{}, nil, &managingMembers{}))
+	r := gin.New()
+	r.POST("/projects/:id/labels", h.Create)
+	create := func(body string) int {
+		req := httptest.NewRequest(http.MethodPost, "/projects/1/labels", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp := httptest.NewRecorder()
+		r.ServeHTTP(resp, req)
+		return resp.Code
+	}
+
+	// a validation error is the client's
+	assert.Equal(t, http.StatusBadRequest, create(`{"name":" "}`))
+	assert.Equal(t, http.StatusBadRequest, create(`{"name":"Urgent","color":"red"}`))
+	// a storage failure is not
+	assert.Equal(t, http.StatusInternalServerError, create(`{"name":"Urgent"}`))
+}
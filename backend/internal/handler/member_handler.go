@@ -0,0 +1,95 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"example.com/defect-control-system/internal/service"
+)
+
+type MemberHandler struct {
+	svc service.MembershipService
+}
+
+func NewMemberHandler(s service.MembershipService) *MemberHandler { return &MemberHandler{svc: s} }
+
+// ListMembers godoc
+// @Summary List project members
+// @Tags projects
+// @Produce json
+// @Param id path int true "Project ID"
+// @Success 200 {array} handler.ProjectMemberResponse
+// @Failure 403 {object} map[string]interface{}
+// @Security BearerAuth
+// @Router /api/v1/projects/{id}/members [get]
+func (h *MemberHandler) List(c *gin.Context) {
+	projectID, ok := projectIDParam(c)
+	if !ok {
+		return
+	}
+	uid, role := currentUser(c)
+	list, err := h.svc.ListMembers(c.Request.Context(), uid, role, projectID)
+	if err != nil {
+		writeServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "ok", "data": list})
+}
+
+// AddMember godoc
+// @Summary Add project member
+// @Description Add a user to the project or change their project role (member/admin)
+// @Tags projects
+// @Accept json
+// @Produce json
+// @Param id path int true "Project ID"
+// @Param body body service.AddMemberDTO true "Member"
+// @Success 201 {object} handler.ProjectMemberResponse
+// @Failure 400 {object} map[string]interface{}
+// @Security BearerAuth
+// @Router /api/v1/projects/{id}/members [post]
+func (h *MemberHandler) Add(c *gin.Context) {
+	var projectID uint
+	if _, err := fmt.Sscanf(c.Param("id"), "%d", &projectID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"status": "error", "error": "invalid project id"})
+		return
+	}
+	var dto service.AddMemberDTO
+	if err := c.ShouldBindJSON(&dto); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"status": "error", "error": err.Error()})
+		return
+	}
+	m, err := h.svc.AddMember(c.Request.Context(), projectID, dto)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"status": "error", "error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"status": "ok", "data": m})
+}
+
+// RemoveMember godoc
+// @Summary Remove project member
+// @Tags projects
+// @Param id path int true "Project ID"
+// @Param userId path int true "User ID"
+// @Success 204
+// @Security BearerAuth
+// @Router /api/v1/projects/{id}/members/{userId} [delete]
+func (h *MemberHandler) Remove(c *gin.Context) {
+	var projectID, userID uint
+	if _, err := fmt.Sscanf(c.Param("id"), "%d", &projectID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"status": "error", "error": "invalid project id"})
+		return
+	}
+	if _, err := fmt.Sscanf(c.Param("userId"), "%d", &userID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"status": "error", "error": "invalid user id"})
+		return
+	}
+	if err := h.svc.RemoveMember(c.Request.Context(), projectID, userID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"status": "error", "error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,85 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"example.com/defect-control-system/internal/service"
+)
+
+type MetaHandler struct {
+	svc service.DefectMetaService
+}
+
+func NewMetaHandler(s service.DefectMetaService) *MetaHandler { return &MetaHandler{svc: s} }
+
+// DefectMeta godoc
+// @Summary Default severity and priority values
+// @Description Values are ordered by rank, highest first. Use the project variant for a project's own levels.
+// @Tags meta
+// @Produce json
+// @Success 200 {object} handler.DefectMetaResponse
+// @Router /api/v1/meta/defects [get]
+func (h *MetaHandler) Defaults(c *gin.Context) {
+	m, err := h.svc.Meta(c.Request.Context(), 0)
+	if err != nil {
+		writeServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "ok", "data": m})
+}
+
+// ProjectDefectMeta godoc
+// @Summary Severity and priority values of a project
+// @Tags meta
+// @Produce json
+// @Param id path int true "Project ID"
+// @Success 200 {object} handler.DefectMetaResponse
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/projects/{id}/meta [get]
+func (h *MetaHandler) Project(c *gin.Context) {
+	projectID, ok := projectIDParam(c)
+	if !ok {
+		return
+	}
+	m, err := h.svc.Meta(c.Request.Context(), projectID)
+	if err != nil {
+		writeServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "ok", "data": m})
+}
+
+// UpdateProjectLevels godoc
+// @Summary Override severity and priority values of a project
+// @Description Project admins only. An empty list restores the defaults. Ranks of existing defects are recomputed.
+// @Tags meta
+// @Accept json
+// @Produce json
+// @Param id path int true "Project ID"
+// @Param body body service.UpdateLevelsDTO true "Levels"
+// @Success 200 {object} handler.DefectMetaResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Security BearerAuth
+// @Router /api/v1/projects/{id}/levels [put]
+func (h *MetaHandler) UpdateLevels(c *gin.Context) {
+	projectID, ok := projectIDParam(c)
+	if !ok {
+		return
+	}
+	var dto service.UpdateLevelsDTO
+	if err := c.ShouldBindJSON(&dto); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"status": "error", "error": err.Error()})
+		return
+	}
+	uid, role := currentUser(c)
+	m, err := h.svc.UpdateLevels(c.Request.Context(), uid, role, projectID, dto)
+	if err != nil {
+		writeServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "ok", "data": m})
+}
@@ -0,0 +1,237 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"example.com/defect-control-system/internal/service"
+)
+
+type NormativeHandler struct {
+	svc service.NormativeService
+}
+
+func NewNormativeHandler(s service.NormativeService) *NormativeHandler {
+	return &NormativeHandler{svc: s}
+}
+
+// ListNormativeDocuments godoc
+// @Summary List normative documents
+// @Tags normatives
+// @Produce json
+// @Param obsolete query bool false "Include obsolete documents"
+// @Success 200 {array} handler.NormativeDocumentResponse
+// @Router /api/v1/normatives [get]
+func (h *NormativeHandler) ListDocuments(c *gin.Context) {
+	list, err := h.svc.ListDocuments(c.Request.Context(), c.Query("obsolete") == "true")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"status": "error", "error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "ok", "data": list})
+}
+
+// GetNormativeDocument godoc
+// @Summary Get a normative document with its clauses
+// @Tags normatives
+// @Produce json
+// @Param id path int true "Document ID"
+// @Success 200 {object} handler.NormativeDocumentResponse
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/normatives/{id} [get]
+func (h *NormativeHandler) GetDocument(c *gin.Context) {
+	id, ok := idParam(c, "id", "document")
+	if !ok {
+		return
+	}
+	d, err := h.svc.GetDocument(c.Request.Context(), id)
+	if err != nil {
+		writeServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "ok", "data": d})
+}
+
+// CreateNormativeDocument godoc
+// @Summary Add a normative document
+// @Tags normatives
+// @Accept json
+// @Produce json
+// @Param body body service.NormativeDocumentDTO true "Document"
+// @Success 201 {object} handler.NormativeDocumentResponse
+// @Failure 400 {object} map[string]interface{}
+// @Security BearerAuth
+// @Router /api/v1/normatives [post]
+func (h *NormativeHandler) CreateDocument(c *gin.Context) {
+	var dto service.NormativeDocumentDTO
+	if err := c.ShouldBindJSON(&dto); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"status": "error", "error": err.Error()})
+		return
+	}
+	d, err := h.svc.CreateDocument(c.Request.Context(), dto)
+	if err != nil {
+		writeServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"status": "ok", "data": d})
+}
+
+// UpdateNormativeDocument godoc
+// @Summary Update a normative document
+// @Description Set obsolete=true when a code is superseded; existing citations are kept
+// @Tags normatives
+// @Accept json
+// @Produce json
+// @Param id path int true "Document ID"
+// @Param body body service.UpdateNormativeDocumentDTO true "Update"
+// @Success 200 {object} handler.NormativeDocumentResponse
+// @Security BearerAuth
+// @Router /api/v1/normatives/{id} [patch]
+func (h *NormativeHandler) UpdateDocument(c *gin.Context) {
+	id, ok := idParam(c, "id", "document")
+	if !ok {
+		return
+	}
+	var dto service.UpdateNormativeDocumentDTO
+	if err := c.ShouldBindJSON(&dto); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"status": "error", "error": err.Error()})
+		return
+	}
+	d, err := h.svc.UpdateDocument(c.Request.Context(), id, dto)
+	if err != nil {
+		writeServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "ok", "data": d})
+}
+
+// DeleteNormativeDocument godoc
+// @Summary Delete a normative document
+// @Description Also deletes its clauses and removes them from defects
+// @Tags normatives
+// @Param id path int true "Document ID"
+// @Success 204
+// @Security BearerAuth
+// @Router /api/v1/normatives/{id} [delete]
+func (h *NormativeHandler) DeleteDocument(c *gin.Context) {
+	id, ok := idParam(c, "id", "document")
+	if !ok {
+		return
+	}
+	if err := h.svc.DeleteDocument(c.Request.Context(), id); err != nil {
+		writeServiceError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// CreateNormativeClause godoc
+// @Summary Add a clause to a normative document
+// @Tags normatives
+// @Accept json
+// @Produce json
+// @Param id path int true "Document ID"
+// @Param body body service.NormativeClauseDTO true "Clause"
+// @Success 201 {object} handler.NormativeClauseResponse
+// @Failure 400 {object} map[string]interface{}
+// @Security BearerAuth
+// @Router /api/v1/normatives/{id}/clauses [post]
+func (h *NormativeHandler) CreateClause(c *gin.Context) {
+	docID, ok := idParam(c, "id", "document")
+	if !ok {
+		return
+	}
+	var dto service.NormativeClauseDTO
+	if err := c.ShouldBindJSON(&dto); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"status": "error", "error": err.Error()})
+		return
+	}
+	cl, err := h.svc.CreateClause(c.Request.Context(), docID, dto)
+	if err != nil {
+		writeServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"status": "ok", "data": cl})
+}
+
+// SearchNormativeClauses godoc
+// @Summary Search clauses of normative documents
+// @Description Matches clause text and numbers, document codes and titles
+// @Tags normatives
+// @Produce json
+// @Param q query string false "Query, e.g. 'гидроизоляция' or 'СП 70'"
+// @Param document_id query int false "Limit to one document"
+// @Param obsolete query bool false "Include obsolete documents"
+// @Param limit query int false "Page size (default 20, max 100)"
+// @Param offset query int false "Offset"
+// @Success 200 {array} handler.NormativeClauseResponse
+// @Router /api/v1/clauses [get]
+func (h *NormativeHandler) SearchClauses(c *gin.Context) {
+	dto := service.ClauseSearchDTO{Query: c.Query("q"), IncludeObsolete: c.Query("obsolete") == "true"}
+	if v := c.Query("document_id"); v != "" {
+		n, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"status": "error", "error": "invalid document_id"})
+			return
+		}
+		id := uint(n)
+		dto.DocumentID = &id
+	}
+	dto.Limit, _ = strconv.Atoi(c.Query("limit"))
+	dto.Offset, _ = strconv.Atoi(c.Query("offset"))
+	list, err := h.svc.SearchClauses(c.Request.Context(), dto)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"status": "error", "error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "ok", "data": list})
+}
+
+// UpdateNormativeClause godoc
+// @Summary Update a clause
+// @Tags normatives
+// @Accept json
+// @Produce json
+// @Param id path int true "Clause ID"
+// @Param body body service.UpdateNormativeClauseDTO true "Update"
+// @Success 200 {object} handler.NormativeClauseResponse
+// @Security BearerAuth
+// @Router /api/v1/clauses/{id} [patch]
+func (h *NormativeHandler) UpdateClause(c *gin.Context) {
+	id, ok := idParam(c, "id", "clause")
+	if !ok {
+		return
+	}
+	var dto service.UpdateNormativeClauseDTO
+	if err := c.ShouldBindJSON(&dto); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"status": "error", "error": err.Error()})
+		return
+	}
+	cl, err := h.svc.UpdateClause(c.Request.Context(), id, dto)
+	if err != nil {
+		writeServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "ok", "data": cl})
+}
+
+// DeleteNormativeClause godoc
+// @Summary Delete a clause
+// @Tags normatives
+// @Param id path int true "Clause ID"
+// @Success 204
+// @Security BearerAuth
+// @Router /api/v1/clauses/{id} [delete]
+func (h *NormativeHandler) DeleteClause(c *gin.Context) {
+	id, ok := idParam(c, "id", "clause")
+	if !ok {
+		return
+	}
+	if err := h.svc.DeleteClause(c.Request.Context(), id); err != nil {
+		writeServiceError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
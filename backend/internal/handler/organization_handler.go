@@ -0,0 +1,103 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"example.com/defect-control-system/internal/service"
+)
+
+type OrganizationHandler struct {
+	svc service.OrganizationService
+}
+
+func NewOrganizationHandler(s service.OrganizationService) *OrganizationHandler {
+	return &OrganizationHandler{svc: s}
+}
+
+// ListOrganizations godoc
+// @Summary List organizations
+// @Tags organizations
+// @Produce json
+// @Success 200 {array} handler.OrganizationResponse
+// @Router /api/v1/organizations [get]
+func (h *OrganizationHandler) List(c *gin.Context) {
+	list, err := h.svc.List(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"status": "error", "error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "ok", "data": list})
+}
+
+// CreateOrganization godoc
+// @Summary Add an organization
+// @Tags organizations
+// @Accept json
+// @Produce json
+// @Param body body service.OrganizationDTO true "Organization"
+// @Success 201 {object} handler.OrganizationResponse
+// @Failure 400 {object} map[string]interface{}
+// @Security BearerAuth
+// @Router /api/v1/organizations [post]
+func (h *OrganizationHandler) Create(c *gin.Context) {
+	var dto service.OrganizationDTO
+	if err := c.ShouldBindJSON(&dto); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"status": "error", "error": err.Error()})
+		return
+	}
+	o, err := h.svc.Create(c.Request.Context(), dto)
+	if err != nil {
+		writeServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"status": "ok", "data": o})
+}
+
+// UpdateOrganization godoc
+// @Summary Update an organization
+// @Tags organizations
+// @Accept json
+// @Produce json
+// @Param id path int true "Organization ID"
+// @Param body body service.UpdateOrganizationDTO true "Update"
+// @Success 200 {object} handler.OrganizationResponse
+// @Security BearerAuth
+// @Router /api/v1/organizations/{id} [patch]
+func (h *OrganizationHandler) Update(c *gin.Context) {
+	id, ok := idParam(c, "id", "organization")
+	if !ok {
+		return
+	}
+	var dto service.UpdateOrganizationDTO
+	if err := c.ShouldBindJSON(&dto); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"status": "error", "error": err.Error()})
+		return
+	}
+	o, err := h.svc.Update(c.Request.Context(), id, dto)
+	if err != nil {
+		writeServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "ok", "data": o})
+}
+
+// DeleteOrganization godoc
+// @Summary Delete an organization
+// @Tags organizations
+// @Param id path int true "Organization ID"
+// @Success 204
+// @Security BearerAuth
+// @Router /api/v1/organizations/{id} [delete]
+func (h *OrganizationHandler) Delete(c *gin.Context) {
+	id, ok := idParam(c, "id", "organization")
+	if !ok {
+		return
+	}
+	if err := h.svc.Delete(c.Request.Context(), id); err != nil {
+		writeServiceError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
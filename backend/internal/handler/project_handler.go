@@ -1,22 +1,50 @@
 package handler
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 
+	"example.com/defect-control-system/internal/repository"
 	"example.com/defect-control-system/internal/service"
 )
 
 type ProjectHandler struct {
 	svc       service.ProjectService
 	defectSvc service.DefectService
+	fieldSvc  service.CustomFieldService
 }
 
-func NewProjectHandler(s service.ProjectService, d service.DefectService) *ProjectHandler {
-	return &ProjectHandler{svc: s, defectSvc: d}
+func NewProjectHandler(s service.ProjectService, d service.DefectService, f service.CustomFieldService) *ProjectHandler {
+	return &ProjectHandler{svc: s, defectSvc: d, fieldSvc: f}
+}
+
+// writeDefectError reports validation errors, custom field, level and workflow
+// ones included, as 400, denied status changes as 403, version mismatches as 412, writes that
+// kept racing as 409 and everything else as 500
+func writeDefectError(c *gin.Context, err error) {
+	if errors.Is(err, service.ErrPreconditionFailed) {
+		c.JSON(http.StatusPreconditionFailed, gin.H{"status": "error", "error": err.Error()})
+		return
+	}
+	if errors.Is(err, service.ErrConflict) {
+		c.JSON(http.StatusConflict, gin.H{"status": "error", "error": err.Error()})
+		return
+	}
+	if errors.Is(err, service.ErrForbidden) {
+		c.JSON(http.StatusForbidden, gin.H{"status": "error", "error": err.Error()})
+		return
+	}
+	if errors.Is(err, service.ErrInvalid) {
+		c.JSON(http.StatusBadRequest, gin.H{"status": "error", "error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"status": "error", "error": err.Error()})
 }
 
 // CreateProject godoc
@@ -37,6 +65,10 @@ func (h *ProjectHandler) Create(c *gin.Context) {
 		return
 	}
 	p, err := h.svc.Create(c.Request.Context(), dto)
+	if errors.Is(err, service.ErrInvalidProjectKey) {
+		c.JSON(http.StatusBadRequest, gin.H{"status": "error", "error": err.Error()})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"status": "error", "error": err.Error()})
 		return
@@ -62,7 +94,7 @@ func (h *ProjectHandler) List(c *gin.Context) {
 
 // CreateDefect godoc
 // @Summary Create a defect in a project
-// @Description Create a defect under specified project
+// @Description Create a defect under specified project. Only project managers set verifier_id
 // @Tags defects
 // @Accept json
 // @Produce json
@@ -70,6 +102,7 @@ func (h *ProjectHandler) List(c *gin.Context) {
 // @Param body body service.CreateDefectDTO true "Create Defect"
 // @Success 201 {object} handler.DefectResponse
 // @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
 // @Security BearerAuth
 // @Router /api/v1/projects/{id}/defects [post]
 func (h *ProjectHandler) CreateDefect(c *gin.Context) {
@@ -81,6 +114,12 @@ func (h *ProjectHandler) CreateDefect(c *gin.Context) {
 		AssigneeID  uint   `json:"assignee_id"`
 		DueDate     string `json:"due_date"`
 		Priority    string `json:"priority"`
+		// custom field values keyed by field key
+		CustomFields     map[string]interface{} `json:"custom_fields"`
+		CategoryID       *uint                  `json:"category_id"`
+		ResponsibleOrgID *uint                  `json:"responsible_org_id"`
+		ClauseIDs        []uint                 `json:"clause_ids"`
+		VerifierID       *uint                  `json:"verifier_id"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"status": "error", "error": err.Error()})
@@ -101,6 +140,12 @@ func (h *ProjectHandler) CreateDefect(c *gin.Context) {
 	dto.Severity = req.Severity
 	dto.AssigneeID = req.AssigneeID
 	dto.Priority = req.Priority
+	dto.CustomFields = req.CustomFields
+	dto.CategoryID = req.CategoryID
+	dto.ResponsibleOrgID = req.ResponsibleOrgID
+	dto.ClauseIDs = req.ClauseIDs
+	dto.VerifierID = req.VerifierID
+	dto.UserID, dto.Role = currentUser(c)
 	if req.DueDate != "" {
 		// try several common date formats: RFC3339 and date-only YYYY-MM-DD
 		var parsed time.Time
@@ -121,7 +166,7 @@ func (h *ProjectHandler) CreateDefect(c *gin.Context) {
 
 	d, err := h.defectSvc.Create(c.Request.Context(), dto)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"status": "error", "error": err.Error()})
+		writeDefectError(c, err)
 		return
 	}
 	c.JSON(http.StatusCreated, gin.H{"status": "ok", "data": d})
@@ -149,11 +194,25 @@ func (h *ProjectHandler) ListDefects(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"status": "error", "error": "invalid project id"})
 		return
 	}
-	list, err := h.defectSvc.ListByProject(c.Request.Context(), id)
+	filter, err := parseDefectFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"status": "error", "error": err.Error()})
+		return
+	}
+	list, err := h.defectSvc.ListFiltered(c.Request.Context(), repository.DefectQuery{ProjectIDs: []uint{id}, Filter: filter})
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"status": "error", "error": err.Error()})
 		return
 	}
+	if c.Query("format") == "csv" {
+		fields, err := h.fieldSvc.List(c.Request.Context(), id)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"status": "error", "error": err.Error()})
+			return
+		}
+		writeDefectsCSV(c, fmt.Sprintf("project-%d-defects.csv", id), list, fields)
+		return
+	}
 	c.JSON(http.StatusOK, gin.H{"status": "ok", "data": list})
 }
 
@@ -164,6 +223,7 @@ func (h *ProjectHandler) ListDefects(c *gin.Context) {
 // @Produce json
 // @Param id path int true "Project ID"
 // @Success 200 {object} handler.ProjectResponse
+// @Header 200 {string} ETag "Entity version, send it back in If-Match"
 // @Router /api/v1/projects/{id} [get]
 func (h *ProjectHandler) GetProject(c *gin.Context) {
 	pid := c.Param("id")
@@ -177,6 +237,7 @@ func (h *ProjectHandler) GetProject(c *gin.Context) {
 		c.JSON(http.StatusNotFound, gin.H{"status": "error", "error": "project not found"})
 		return
 	}
+	setETag(c, p.Version)
 	c.JSON(http.StatusOK, gin.H{"status": "ok", "data": p})
 }
 
@@ -188,6 +249,7 @@ func (h *ProjectHandler) GetProject(c *gin.Context) {
 // @Param id path int true "Project ID"
 // @Param defectId path int true "Defect ID"
 // @Success 200 {object} handler.DefectResponse
+// @Header 200 {string} ETag "Entity version, send it back in If-Match"
 // @Router /api/v1/projects/{id}/defects/{defectId} [get]
 func (h *ProjectHandler) GetDefect(c *gin.Context) {
 	did := c.Param("defectId")
@@ -201,19 +263,44 @@ func (h *ProjectHandler) GetDefect(c *gin.Context) {
 		c.JSON(http.StatusNotFound, gin.H{"status": "error", "error": "defect not found"})
 		return
 	}
+	setETag(c, d.Version)
+	c.JSON(http.StatusOK, gin.H{"status": "ok", "data": d})
+}
+
+// GetDefectByKey godoc
+// @Summary Get defect by key
+// @Description Resolve a project-scoped defect key such as TWR-142
+// @Tags defects
+// @Produce json
+// @Param key path string true "Defect key"
+// @Success 200 {object} handler.DefectResponse
+// @Failure 404 {object} map[string]interface{}
+// @Header 200 {string} ETag "Entity version, send it back in If-Match"
+// @Router /api/v1/defects/by-key/{key} [get]
+func (h *ProjectHandler) GetDefectByKey(c *gin.Context) {
+	d, err := h.defectSvc.FindByKey(c.Request.Context(), c.Param("key"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"status": "error", "error": "defect not found"})
+		return
+	}
+	setETag(c, d.Version)
 	c.JSON(http.StatusOK, gin.H{"status": "ok", "data": d})
 }
 
 // UpdateProject godoc
 // @Summary Update a project
-// @Description Update project fields
+// @Description Update project fields. Without If-Match a write that races another one is retried once, then answered 409
 // @Tags projects
 // @Accept json
 // @Produce json
 // @Param id path int true "Project ID"
 // @Param body body service.UpdateProjectDTO true "Update Project"
+// @Param If-Match header string false "ETag of the version being edited"
 // @Success 200 {object} handler.ProjectResponse
+// @Header 200 {string} ETag "New entity version"
 // @Failure 400 {object} map[string]interface{}}
+// @Failure 409 {object} map[string]interface{}
+// @Failure 412 {object} map[string]interface{}
 // @Security BearerAuth
 // @Router /api/v1/projects/{id} [patch]
 func (h *ProjectHandler) UpdateProject(c *gin.Context) {
@@ -228,25 +315,50 @@ func (h *ProjectHandler) UpdateProject(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"status": "error", "error": err.Error()})
 		return
 	}
+	version, ok := ifMatchVersion(c)
+	if !ok {
+		return
+	}
+	if version != nil {
+		dto.Version = version
+	}
 	p, err := h.svc.Update(c.Request.Context(), id, dto)
-	if err != nil {
+	switch {
+	case errors.Is(err, service.ErrInvalidProjectKey):
+		c.JSON(http.StatusBadRequest, gin.H{"status": "error", "error": err.Error()})
+		return
+	case errors.Is(err, service.ErrPreconditionFailed):
+		c.JSON(http.StatusPreconditionFailed, gin.H{"status": "error", "error": err.Error()})
+		return
+	case errors.Is(err, service.ErrConflict):
+		c.JSON(http.StatusConflict, gin.H{"status": "error", "error": err.Error()})
+		return
+	case errors.Is(err, service.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"status": "error", "error": "project not found"})
+		return
+	case err != nil:
 		c.JSON(http.StatusInternalServerError, gin.H{"status": "error", "error": err.Error()})
 		return
 	}
+	setETag(c, p.Version)
 	c.JSON(http.StatusOK, gin.H{"status": "ok", "data": p})
 }
 
 // UpdateDefect godoc
 // @Summary Update a defect
-// @Description Update defect fields
+// @Description Update defect fields. Without If-Match a write that races another one is retried once, then answered 409
 // @Tags defects
 // @Accept json
 // @Produce json
 // @Param id path int true "Project ID"
 // @Param defectId path int true "Defect ID"
 // @Param body body service.UpdateDefectDTO true "Update Defect"
+// @Param If-Match header string false "ETag of the version being edited"
 // @Success 200 {object} handler.DefectResponse
+// @Header 200 {string} ETag "New entity version"
 // @Failure 400 {object} map[string]interface{}}
+// @Failure 409 {object} map[string]interface{}
+// @Failure 412 {object} map[string]interface{}
 // @Security BearerAuth
 // @Router /api/v1/projects/{id}/defects/{defectId} [patch]
 func (h *ProjectHandler) UpdateDefect(c *gin.Context) {
@@ -261,20 +373,96 @@ func (h *ProjectHandler) UpdateDefect(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"status": "error", "error": err.Error()})
 		return
 	}
-	d, err := h.defectSvc.Update(c.Request.Context(), id, dto)
+	version, ok := ifMatchVersion(c)
+	if !ok {
+		return
+	}
+	if version != nil {
+		dto.Version = version
+	}
+	uid, role := currentUser(c)
+	d, err := h.defectSvc.Update(c.Request.Context(), uid, role, id, dto)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"status": "error", "error": err.Error()})
+		writeDefectError(c, err)
 		return
 	}
+	setETag(c, d.Version)
 	c.JSON(http.StatusOK, gin.H{"status": "ok", "data": d})
 }
 
+// BulkDefects godoc
+// @Summary Change many defects at once
+// @Description Apply a status, assignee, due date, priority or label change to defects selected by ids or by a list filter query.
+// @Description Every defect is checked against the workflow and the caller's rights; by default nothing is written when one fails (422 with the report), with skip_invalid the rest is applied.
+// @Tags defects
+// @Accept json
+// @Produce json
+// @Param id path int true "Project ID"
+// @Param body body service.BulkDefectsDTO true "Selection and change"
+// @Success 200 {object} service.BulkResult
+// @Failure 400 {object} map[string]interface{}
+// @Failure 412 {object} map[string]interface{}
+// @Failure 422 {object} map[string]interface{}
+// @Security BearerAuth
+// @Router /api/v1/projects/{id}/defects/bulk [post]
+func (h *ProjectHandler) BulkDefects(c *gin.Context) {
+	projectID, ok := projectIDParam(c)
+	if !ok {
+		return
+	}
+	var dto service.BulkDefectsDTO
+	if err := c.ShouldBindJSON(&dto); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"status": "error", "error": err.Error()})
+		return
+	}
+	if dto.Filter != "" {
+		q, err := url.ParseQuery(strings.TrimPrefix(dto.Filter, "?"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"status": "error", "error": "invalid filter: " + err.Error()})
+			return
+		}
+		f, err := parseDefectQuery(q)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"status": "error", "error": err.Error()})
+			return
+		}
+		dto.Selection = &f
+	}
+	uid, role := currentUser(c)
+	res, err := h.defectSvc.Bulk(c.Request.Context(), uid, role, projectID, dto)
+	if err != nil {
+		writeServiceError(c, err)
+		return
+	}
+	if !res.Applied {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"status": "error", "error": fmt.Sprintf("%d defects failed the checks, nothing was changed", res.Failed), "data": res})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "ok", "data": res})
+}
+
 // ListDefects godoc
 // @Summary List defects for a project
-// @Description Get defects for given project id
+// @Description Get defects for given project id, optionally filtered and sorted
 // @Tags defects
 // @Produce json
+// @Produce text/csv
 // @Param id path int true "Project ID"
+// @Param status query string false "Comma separated statuses"
+// @Param severity query string false "Comma separated severities"
+// @Param priority query string false "Comma separated priorities"
+// @Param assignee_id query int false "Assignee user id (0 = unassigned)"
+// @Param due_before query string false "Due date before (YYYY-MM-DD or RFC3339)"
+// @Param due_after query string false "Due date on or after"
+// @Param overdue query bool false "Only open defects past their due date"
+// @Param category_id query int false "Catalog category, including its child types"
+// @Param responsible_org_id query int false "Responsible organization id"
+// @Param labels query string false "Comma separated label ids"
+// @Param labels_mode query string false "any (default) or all"
+// @Param q query string false "Full-text query over title and description"
+// @Param cf.{key} query string false "Exact match on a custom field value"
+// @Param sort query string false "Sort field or cf.<key>, prefix with - for descending (e.g. -due_date)"
+// @Param format query string false "csv to export"
 // @Success 200 {array} handler.DefectResponse
 // @Failure 400 {object} map[string]interface{}
 // @Router /api/v1/projects/{id}/defects [get]
@@ -0,0 +1,138 @@
+package handler_test
+
+import (
+	"context"
+	"encoding/csv"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	hpkg "example.com/defect-control-system/internal/handler"
+	"example.com/defect-control-system/internal/models"
+	"example.com/defect-control-system/internal/repository"
+	"example.com/defect-control-system/internal/service"
+)
+
+// racingProjectRepo loses every write to a concurrent one
+type racingProjectRepo struct{ repository.ProjectRepository }
+
+func (m *racingProjectRepo) FindByID(ctx context.Context, id uint) (*models.Project, error) {
+	return &models.Project{ID: id, Name: "Tower", Version: 3}, nil
+}
+func (m *racingProjectRepo) Update(ctx context.Context, p *models.Project, columns []string) error {
+	return repository.ErrVersionConflict
+}
+
+// racingDefectRepo loses every write to a concurrent one
+type racingDefectRepo struct{ repository.DefectRepository }
+
+func (m *racingDefectRepo) FindByID(ctx context.Context, id uint) (*models.Defect, error) {
+	return &models.Defect{ID: id, ProjectID: 1, Title: "x", Status: "open", Version: 3}, nil
+}
+func (m *racingDefectRepo) Update(ctx context.Context, d *models.Defect, columns []string) error {
+	return repository.ErrVersionConflict
+}
+
+func TestProjectHandler_UpdateRaces(t *testing.T) {
+	projects := &racingProjectRepo{}
+	h := hpkg.NewProjectHandler(service.NewProjectService(projects, nil),
+		service.NewDefectService(&racingDefectRepo{}, projects, nil), nil)
+	r := gin.New()
+	r.PATCH("/projects/:id", h.UpdateProject)
+	r.PATCH("/projects/:id/defects/:defectId", h.UpdateDefect)
+	patch := func(path, ifMatch string) int {
+		req := httptest.NewRequest(http.MethodPatch, path, strings.NewReader(`{"name":"y","title":"y"}`))
+		req.Header.Set("Content-Type", "application/json")
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		resp := httptest.NewRecorder()
+		r.ServeHTTP(resp, req)
+		return resp.Code
+	}
+
+	for _, path := range []string{"/projects/1", "/projects/1/defects/5"} {
+		// without If-Match the write is retried, then the client is told to try again
+		assert.Equal(t, http.StatusConflict, patch(path, ""), path)
+		// a precondition the client set fails as such
+		assert.Equal(t, http.StatusPreconditionFailed, patch(path, `"3"`), path)
+	}
+}
+
+// csvDefectSvc lists defects with formula-like values
+type csvDefectSvc struct{ mockDefectSvc }
+
+func (m *csvDefectSvc) ListFiltered(ctx context.Context, q repository.DefectQuery) ([]*models.Defect, error) {
+	return []*models.Defect{{
+		ID: 1, Key: "TWR-1", Title: `=HYPERLINK("http://evil.example","open")`, Description: "+cmd|' /C calc'!A0",
+		Status: "open", Labels: []models.Label{{Name: "@risk"}}, CustomFields: models.JSONMap{"note": "-1+1"},
+	}, {
+		ID: 2, Key: "TWR-2", Title: "Crack at -1 level", Status: "open",
+	}}, nil
+}
+
+// csvFieldSvc defines one custom field with a formula-like label
+type csvFieldSvc struct{ service.CustomFieldService }
+
+func (m *csvFieldSvc) List(ctx context.Context, projectID uint) ([]*models.CustomField, error) {
+	return []*models.CustomField{{Key: "note", Label: "=Note"}}, nil
+}
+
+func TestProjectHandler_DefectsCSVEscapesFormulas(t *testing.T) {
+	h := hpkg.NewProjectHandler(nil, &csvDefectSvc{}, &csvFieldSvc{})
+	r := gin.New()
+	r.GET("/projects/:id/defects", h.ListDefects)
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/projects/1/defects?format=csv", nil))
+	assert.Equal(t, http.StatusOK, resp.Code)
+
+	rows, err := csv.NewReader(strings.NewReader(strings.TrimPrefix(resp.Body.String(), "\ufeff"))).ReadAll()
+	assert.NoError(t, err)
+	assert.Len(t, rows, 3)
+	header, row := rows[0], rows[1]
+	assert.Equal(t, "'=Note", header[len(header)-1])
+	assert.Equal(t, `'=HYPERLINK("http://evil.example","open")`, row[3])
+	assert.Equal(t, "'@risk", row[12])
+	assert.Equal(t, "'+cmd|' /C calc'!A0", row[14])
+	assert.Equal(t, "'-1+1", row[15])
+	// only the first character counts
+	assert.Equal(t, "Crack at -1 level", rows[2][3])
+}
+
+// recordingDefectSvc keeps the last defect it was asked to create
+type recordingDefectSvc struct {
+	mockDefectSvc
+	created service.CreateDefectDTO
+}
+
+func (m *recordingDefectSvc) Create(ctx context.Context, dto service.CreateDefectDTO) (*models.Defect, error) {
+	m.created = dto
+	return &models.Defect{ID: 1, ProjectID: dto.ProjectID, Title: dto.Title, VerifierID: dto.VerifierID}, nil
+}
+
+func TestProjectHandler_CreateDefectWithVerifier(t *testing.T) {
+	defects := &recordingDefectSvc{}
+	h := hpkg.NewProjectHandler(nil, defects, nil)
+	r := gin.New()
+	r.POST("/projects/:id/defects", func(c *gin.Context) {
+		c.Set("user_id", uint(9))
+		c.Set("role", "manager")
+	}, h.CreateDefect)
+	req := httptest.NewRequest(http.MethodPost, "/projects/7/defects", strings.NewReader(`{"title":"Crack","verifier_id":5}`))
+	req.Header.Set("Content-Type", "application/json")
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusCreated, resp.Code)
+	if assert.NotNil(t, defects.created.VerifierID) {
+		assert.Equal(t, uint(5), *defects.created.VerifierID)
+	}
+	assert.Equal(t, uint(7), defects.created.ProjectID)
+	// the service checks the creator may designate a verifier
+	assert.Equal(t, uint(9), defects.created.UserID)
+	assert.Equal(t, "manager", defects.created.Role)
+}
@@ -0,0 +1,40 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"example.com/defect-control-system/internal/service"
+)
+
+type QuotaHandler struct {
+	svc service.QuotaService
+}
+
+func NewQuotaHandler(s service.QuotaService) *QuotaHandler { return &QuotaHandler{svc: s} }
+
+// StorageUsage godoc
+// @Summary Project storage usage
+// @Description Bytes of attachments of the project against its storage quota, by content type and by uploader, and the usage of the organization running the project. Sizes are per attachment: content shared by several attachments counts for each. Reserved is the declared size of resumable uploads in progress.
+// @Tags projects
+// @Produce json
+// @Param id path int true "Project ID"
+// @Success 200 {object} handler.StorageUsageResponse
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Security BearerAuth
+// @Router /api/v1/projects/{id}/storage [get]
+func (h *QuotaHandler) Usage(c *gin.Context) {
+	projectID, ok := projectIDParam(c)
+	if !ok {
+		return
+	}
+	uid, role := currentUser(c)
+	u, err := h.svc.Usage(c.Request.Context(), uid, role, projectID)
+	if err != nil {
+		writeServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "ok", "data": u})
+}
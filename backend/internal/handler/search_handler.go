@@ -0,0 +1,62 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"example.com/defect-control-system/internal/service"
+)
+
+type SearchHandler struct {
+	svc service.SearchService
+}
+
+func NewSearchHandler(s service.SearchService) *SearchHandler { return &SearchHandler{svc: s} }
+
+// Search godoc
+// @Summary Full-text search
+// @Description Ranked search over defect titles/descriptions, comment bodies and attachment filenames
+// @Description in projects the caller is a member of. Matches in snippets are wrapped in <mark>.
+// @Tags search
+// @Produce json
+// @Param q query string true "Search query (websearch syntax: words, \"phrases\", -exclude, or)"
+// @Param kind query string false "Comma separated kinds: defect,comment,attachment"
+// @Param limit query int false "Max results (default 20, max 100)"
+// @Param offset query int false "Offset"
+// @Success 200 {array} handler.SearchHitResponse
+// @Failure 400 {object} map[string]interface{}
+// @Security BearerAuth
+// @Router /api/v1/search [get]
+func (h *SearchHandler) Search(c *gin.Context) {
+	dto := service.SearchDTO{Query: c.Query("q")}
+	if k := c.Query("kind"); k != "" {
+		dto.Kinds = strings.Split(k, ",")
+	}
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"status": "error", "error": "invalid limit"})
+			return
+		}
+		dto.Limit = n
+	}
+	if v := c.Query("offset"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"status": "error", "error": "invalid offset"})
+			return
+		}
+		dto.Offset = n
+	}
+	userID, _ := c.Get("user_id")
+	uid, _ := userID.(uint)
+	hits, err := h.svc.Search(c.Request.Context(), uid, c.GetString("role"), dto)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"status": "error", "error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "ok", "data": hits})
+}
@@ -0,0 +1,36 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"example.com/defect-control-system/internal/service"
+)
+
+type StatsHandler struct {
+	svc service.StatsService
+}
+
+func NewStatsHandler(s service.StatsService) *StatsHandler { return &StatsHandler{svc: s} }
+
+// ProjectStats godoc
+// @Summary Project statistics
+// @Description Defect counts by status, severity, priority and category, label usage and the most cited clauses
+// @Tags projects
+// @Produce json
+// @Param id path int true "Project ID"
+// @Success 200 {object} handler.ProjectStatsResponse
+// @Router /api/v1/projects/{id}/stats [get]
+func (h *StatsHandler) ProjectStats(c *gin.Context) {
+	projectID, ok := projectIDParam(c)
+	if !ok {
+		return
+	}
+	st, err := h.svc.ProjectStats(c.Request.Context(), projectID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"status": "error", "error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "ok", "data": st})
+}
@@ -32,37 +32,393 @@ type AuthResponse struct {
 
 // ProjectResponse represents a project
 type ProjectResponse struct {
-	ID        uint      `json:"id" example:"1"`
-	Name      string    `json:"name" example:"New Building"`
-	Address   string    `json:"address" example:"123 Main St, City"`
-	CreatedAt time.Time `json:"created_at" example:"2025-10-12T12:00:00Z"`
+	ID      uint   `json:"id" example:"1"`
+	Key     string `json:"key" example:"TWR"`
+	Version int    `json:"version" example:"3"`
+	Name    string `json:"name" example:"New Building"`
+	Address string `json:"address" example:"123 Main St, City"`
+	// OrganizationID is the organization running the project
+	OrganizationID *uint `json:"organization_id,omitempty" example:"1"`
+	// StorageQuota caps attachment bytes; 0 applies uploads.project_quota
+	StorageQuota int64     `json:"storage_quota" example:"10737418240"`
+	CreatedAt    time.Time `json:"created_at" example:"2025-10-12T12:00:00Z"`
 }
 
 // DefectResponse represents a defect
 type DefectResponse struct {
-	ID          uint      `json:"id" example:"1"`
-	ProjectID   uint      `json:"project_id" example:"1"`
-	Title       string    `json:"title" example:"Cracked wall"`
-	Description string    `json:"description" example:"Long vertical crack on east wall"`
-	Severity    string    `json:"severity" example:"major"`
-	Status      string    `json:"status" example:"open"`
-	CreatedAt   time.Time `json:"created_at" example:"2025-10-12T12:00:00Z"`
+	ID          uint   `json:"id" example:"1"`
+	Number      int    `json:"number" example:"142"`
+	Key         string `json:"key" example:"TWR-142"`
+	Version     int    `json:"version" example:"3"`
+	ProjectID   uint   `json:"project_id" example:"1"`
+	Title       string `json:"title" example:"Cracked wall"`
+	Description string `json:"description" example:"Long vertical crack on east wall"`
+	Severity    string `json:"severity" example:"major"`
+	Status      string `json:"status" example:"open"`
+	Priority    string `json:"priority" example:"high"`
+	// ranks of severity and priority, higher is more severe or urgent
+	SeverityRank int `json:"severity_rank" example:"20"`
+	PriorityRank int `json:"priority_rank" example:"30"`
+	// verification of the fix
+	VerifierID     *uint      `json:"verifier_id,omitempty" example:"5"`
+	RejectionCount int        `json:"rejection_count" example:"1"`
+	VerifiedByID   *uint      `json:"verified_by_id,omitempty" example:"5"`
+	VerifiedAt     *time.Time `json:"verified_at,omitempty" example:"2025-10-14T09:30:00Z"`
+	CreatedAt      time.Time  `json:"created_at" example:"2025-10-12T12:00:00Z"`
 }
 
 // AttachmentResponse represents an attachment
 type AttachmentResponse struct {
-	ID          uint      `json:"id" example:"1"`
-	DefectID    uint      `json:"defect_id" example:"1"`
-	UploaderID  uint      `json:"uploader_id" example:"2"`
-	Filename    string    `json:"filename" example:"photo.jpg"`
-	ContentType string    `json:"content_type" example:"image/jpeg"`
-	Size        int64     `json:"size" example:"23456"`
-	URL         string    `json:"url" example:"/uploads/2025/10/12/uuid-photo.jpg"`
-	CreatedAt   time.Time `json:"created_at" example:"2025-10-12T12:00:00Z"`
+	ID          uint   `json:"id" example:"1"`
+	DefectID    uint   `json:"defect_id" example:"1"`
+	UploaderID  uint   `json:"uploader_id" example:"2"`
+	Filename    string `json:"filename" example:"photo.jpg"`
+	ContentType string `json:"content_type" example:"image/jpeg"`
+	Size        int64  `json:"size" example:"23456"`
+	URL         string `json:"url" example:"/uploads/2025/10/12/uuid-photo.jpg"`
+	SHA256      string `json:"sha256,omitempty" example:"9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"`
+	Kind        string `json:"kind" example:"remediation"`
+	BeforeID    *uint  `json:"before_id,omitempty" example:"12"`
+	ScanStatus  string `json:"scan_status" example:"clean"`
+	ScanDetail  string `json:"scan_detail,omitempty" example:"Win.Test.EICAR_HDB-1"`
+	// OriginID is the first version of the file; absent on the first version
+	OriginID   *uint     `json:"origin_id,omitempty" example:"7"`
+	Version    int       `json:"version" example:"2"`
+	Superseded bool      `json:"superseded" example:"false"`
+	CreatedAt  time.Time `json:"created_at" example:"2025-10-12T12:00:00Z"`
+}
+
+// AnnotationResponse is the vector drawing over a photo
+type AnnotationResponse struct {
+	ID           uint                      `json:"id" example:"1"`
+	AttachmentID uint                      `json:"attachment_id" example:"12"`
+	Shapes       []AnnotationShapeResponse `json:"shapes"`
+	UpdatedByID  uint                      `json:"updated_by_id" example:"2"`
+	Version      int                       `json:"version" example:"3"`
+	UpdatedAt    time.Time                 `json:"updated_at" example:"2025-10-12T12:00:00Z"`
+}
+
+// AnnotationShapeResponse is one shape of an annotation
+type AnnotationShapeResponse struct {
+	Type        string                    `json:"type" example:"arrow" enums:"arrow,rect,ellipse,freehand,text"`
+	Points      []AnnotationPointResponse `json:"points"`
+	Color       string                    `json:"color" example:"#ff0000"`
+	StrokeWidth float64                   `json:"stroke_width,omitempty" example:"4"`
+	Fill        string                    `json:"fill,omitempty" example:"#ffff0080"`
+	Text        string                    `json:"text,omitempty" example:"Трещина 2 мм"`
+	FontSize    float64                   `json:"font_size,omitempty" example:"24"`
+}
+
+// AnnotationPointResponse is a position in pixels of the upright photo
+type AnnotationPointResponse struct {
+	X float64 `json:"x" example:"120"`
+	Y float64 `json:"y" example:"80.5"`
+}
+
+// AttachmentIntegrityResponse is the result of rehashing stored content
+type AttachmentIntegrityResponse struct {
+	ID     uint   `json:"id" example:"1"`
+	SHA256 string `json:"sha256" example:"9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"`
+	Intact bool   `json:"intact" example:"true"`
+}
+
+// SignedLinkResponse is a download link that needs no bearer header
+type SignedLinkResponse struct {
+	URL       string    `json:"url" example:"/api/v1/files/12?expires=1760972400&signature=3q2-7w"`
+	ExpiresAt time.Time `json:"expires_at" example:"2025-10-20T15:00:00Z"`
+	UserID    uint      `json:"user_id,omitempty" example:"4"`
+}
+
+// PhotoPairResponse is an evidence photo and the remediation photos showing it fixed
+type PhotoPairResponse struct {
+	Before AttachmentResponse   `json:"before"`
+	After  []AttachmentResponse `json:"after"`
+}
+
+// PhotoPairsResponse lists the before/after photos of a defect
+type PhotoPairsResponse struct {
+	DefectID      uint                 `json:"defect_id" example:"1"`
+	Pairs         []PhotoPairResponse  `json:"pairs"`
+	UnpairedAfter []AttachmentResponse `json:"unpaired_after"`
+}
+
+// UploadSessionResponse represents a resumable upload
+type UploadSessionResponse struct {
+	ID           string    `json:"id" example:"9f86d081884c7d659a2feaa0c55ad015"`
+	DefectID     uint      `json:"defect_id" example:"1"`
+	UploaderID   uint      `json:"uploader_id" example:"2"`
+	Filename     string    `json:"filename" example:"drone-roof.mp4"`
+	ContentType  string    `json:"content_type" example:"video/mp4"`
+	Kind         string    `json:"kind" example:"evidence"`
+	BeforeID     *uint     `json:"before_id,omitempty"`
+	Length       int64     `json:"length" example:"209715200"`
+	Offset       int64     `json:"offset" example:"52428800"`
+	ExpiresAt    time.Time `json:"expires_at" example:"2025-10-13T12:00:00Z"`
+	AttachmentID *uint     `json:"attachment_id,omitempty" example:"17"`
+	CreatedAt    time.Time `json:"created_at" example:"2025-10-12T12:00:00Z"`
+}
+
+// CommentResponse represents a comment on a defect
+type CommentResponse struct {
+	ID         uint      `json:"id" example:"1"`
+	DefectID   uint      `json:"defect_id" example:"1"`
+	AuthorID   *uint     `json:"author_id" example:"2"`
+	Body       string    `json:"body" example:"Crack widened since last inspection"`
+	Visibility string    `json:"visibility" example:"internal"`
+	CreatedAt  time.Time `json:"created_at" example:"2025-10-12T12:00:00Z"`
+}
+
+// VerificationResponse represents a submitted fix and its review
+type VerificationResponse struct {
+	ID            uint                 `json:"id" example:"4"`
+	DefectID      uint                 `json:"defect_id" example:"1"`
+	SubmittedByID uint                 `json:"submitted_by_id" example:"2"`
+	Comment       *CommentResponse     `json:"comment,omitempty"`
+	Photos        []AttachmentResponse `json:"photos"`
+	Decision      string               `json:"decision" example:"rejected"`
+	Reason        string               `json:"reason,omitempty" example:"Sealant not applied along the joint"`
+	ReviewerID    *uint                `json:"reviewer_id,omitempty" example:"5"`
+	ReviewedAt    *time.Time           `json:"reviewed_at,omitempty" example:"2025-10-14T09:30:00Z"`
+	CreatedAt     time.Time            `json:"created_at" example:"2025-10-13T16:00:00Z"`
+}
+
+// ActSignatoryResponse represents a signatory of an acceptance act and their signature
+type ActSignatoryResponse struct {
+	ID        uint          `json:"id" example:"1"`
+	UserID    uint          `json:"user_id" example:"5"`
+	User      *UserResponse `json:"user,omitempty"`
+	Party     string        `json:"party" example:"Заказчик"`
+	Method    string        `json:"method,omitempty" example:"typed"`
+	TypedName string        `json:"typed_name,omitempty" example:"Иван Петров"`
+	SignedAt  *time.Time    `json:"signed_at,omitempty" example:"2025-10-20T10:00:00Z"`
+}
+
+// ActResponse represents an acceptance act
+type ActResponse struct {
+	ID          uint                   `json:"id" example:"3"`
+	ProjectID   uint                   `json:"project_id" example:"1"`
+	Number      int                    `json:"number" example:"2"`
+	Title       string                 `json:"title" example:"Приёмка отделочных работ, секция 2"`
+	Status      string                 `json:"status" example:"signed"`
+	Defects     []DefectResponse       `json:"defects"`
+	Signatories []ActSignatoryResponse `json:"signatories"`
+	CreatedByID uint                   `json:"created_by_id" example:"9"`
+	SHA256      string                 `json:"sha256,omitempty" example:"9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"`
+	Size        int64                  `json:"size,omitempty" example:"48213"`
+	SignedAt    *time.Time             `json:"signed_at,omitempty" example:"2025-10-20T10:00:00Z"`
+	CreatedAt   time.Time              `json:"created_at" example:"2025-10-19T15:00:00Z"`
+}
+
+// InspectionTemplateItemResponse is one check of a checklist template
+type InspectionTemplateItemResponse struct {
+	ID         uint   `json:"id" example:"1"`
+	Position   int    `json:"position" example:"0"`
+	Text       string `json:"text" example:"Гидроизоляция заведена на стены не менее 200 мм"`
+	Severity   string `json:"severity,omitempty" example:"major"`
+	CategoryID *uint  `json:"category_id,omitempty" example:"3"`
+	ClauseID   *uint  `json:"clause_id,omitempty" example:"12"`
+}
+
+// InspectionTemplateResponse represents a checklist template
+type InspectionTemplateResponse struct {
+	ID          uint                             `json:"id" example:"1"`
+	ProjectID   *uint                            `json:"project_id,omitempty" example:"1"`
+	Name        string                           `json:"name" example:"Приёмка гидроизоляции"`
+	Description string                           `json:"description" example:""`
+	Items       []InspectionTemplateItemResponse `json:"items"`
+}
+
+// InspectionResultResponse is the outcome of a checklist item of an inspection
+type InspectionResultResponse struct {
+	ID       uint   `json:"id" example:"7"`
+	Position int    `json:"position" example:"0"`
+	Text     string `json:"text" example:"Гидроизоляция заведена на стены не менее 200 мм"`
+	Outcome  string `json:"outcome" example:"fail"`
+	Note     string `json:"note,omitempty" example:"Примыкание не проклеено у стояка"`
+	DefectID *uint  `json:"defect_id,omitempty" example:"42"`
+}
+
+// InspectionResponse represents an inspection
+type InspectionResponse struct {
+	ID          uint                       `json:"id" example:"3"`
+	ProjectID   uint                       `json:"project_id" example:"1"`
+	TemplateID  *uint                      `json:"template_id,omitempty" example:"1"`
+	Title       string                     `json:"title" example:"Приёмка гидроизоляции"`
+	Location    string                     `json:"location" example:"Секция 2, этаж 5, кв. 51"`
+	ScheduledAt time.Time                  `json:"scheduled_at" example:"2025-10-21T09:00:00Z"`
+	InspectorID *uint                      `json:"inspector_id,omitempty" example:"5"`
+	Status      string                     `json:"status" example:"completed"`
+	Results     []InspectionResultResponse `json:"results"`
+	StartedAt   *time.Time                 `json:"started_at,omitempty" example:"2025-10-21T09:05:00Z"`
+	CompletedAt *time.Time                 `json:"completed_at,omitempty" example:"2025-10-21T10:40:00Z"`
+}
+
+// ProjectMemberResponse represents a project membership
+type ProjectMemberResponse struct {
+	ID        uint          `json:"id" example:"1"`
+	ProjectID uint          `json:"project_id" example:"1"`
+	UserID    uint          `json:"user_id" example:"2"`
+	Role      string        `json:"role" example:"member"`
+	User      *UserResponse `json:"user,omitempty"`
+}
+
+// SearchHitResponse represents a full-text search hit
+type SearchHitResponse struct {
+	Kind      string  `json:"kind" example:"comment"`
+	ID        uint    `json:"id" example:"12"`
+	DefectID  uint    `json:"defect_id" example:"3"`
+	DefectKey string  `json:"defect_key" example:"TWR-3"`
+	ProjectID uint    `json:"project_id" example:"1"`
+	Title     string  `json:"title" example:"Leaking basement wall"`
+	Snippet   string  `json:"snippet" example:"re-apply <mark>waterproofing</mark> membrane"`
+	Rank      float64 `json:"rank" example:"0.42"`
+}
+
+// SavedViewResponse represents a saved defect filter
+type SavedViewResponse struct {
+	ID        uint                   `json:"id" example:"1"`
+	OwnerID   uint                   `json:"owner_id" example:"2"`
+	ProjectID *uint                  `json:"project_id" example:"1"`
+	Name      string                 `json:"name" example:"My overdue"`
+	Filter    map[string]interface{} `json:"filter"`
+	Shared    bool                   `json:"shared" example:"false"`
+	IsDefault bool                   `json:"is_default" example:"true"`
+}
+
+// CustomFieldResponse represents a custom field definition
+type CustomFieldResponse struct {
+	ID        uint     `json:"id" example:"1"`
+	ProjectID uint     `json:"project_id" example:"1"`
+	Key       string   `json:"key" example:"warranty_clause"`
+	Label     string   `json:"label" example:"Warranty clause"`
+	Type      string   `json:"type" example:"enum"`
+	Required  bool     `json:"required" example:"true"`
+	Options   []string `json:"options,omitempty" example:"5.1,5.2"`
+	Position  int      `json:"position" example:"0"`
+}
+
+// OrganizationResponse represents an organization
+type OrganizationResponse struct {
+	ID          uint   `json:"id" example:"1"`
+	Name        string `json:"name" example:"ElectroMontazh LLC"`
+	Description string `json:"description" example:"Electrical subcontractor"`
+	// StorageQuota caps attachment bytes of the organization's projects; 0 for none
+	StorageQuota int64 `json:"storage_quota" example:"107374182400"`
+}
+
+// CategoryResponse represents a defect classification catalog entry
+type CategoryResponse struct {
+	ID                    uint               `json:"id" example:"3"`
+	ParentID              *uint              `json:"parent_id,omitempty" example:"1"`
+	Code                  string             `json:"code" example:"EL.03"`
+	Name                  string             `json:"name" example:"Missing grounding"`
+	DefaultSeverity       string             `json:"default_severity,omitempty" example:"major"`
+	DefaultOrganizationID *uint              `json:"default_organization_id,omitempty" example:"1"`
+	Children              []CategoryResponse `json:"children,omitempty"`
+}
+
+// NormativeClauseResponse represents a clause of a normative document
+type NormativeClauseResponse struct {
+	ID         uint   `json:"id" example:"12"`
+	DocumentID uint   `json:"document_id" example:"1"`
+	Number     string `json:"number" example:"5.3.1"`
+	Text       string `json:"text" example:"Отклонение от вертикали не более 8 мм"`
+}
+
+// NormativeDocumentResponse represents a building code or standard
+type NormativeDocumentResponse struct {
+	ID       uint                      `json:"id" example:"1"`
+	Code     string                    `json:"code" example:"СП 70.13330.2012"`
+	Title    string                    `json:"title" example:"Несущие и ограждающие конструкции"`
+	Obsolete bool                      `json:"obsolete" example:"false"`
+	Clauses  []NormativeClauseResponse `json:"clauses,omitempty"`
+}
+
+// LevelResponse is an allowed severity or priority value
+type LevelResponse struct {
+	Value   string `json:"value" example:"major"`
+	Label   string `json:"label" example:"Значительный"`
+	Rank    int    `json:"rank" example:"20"`
+	Default bool   `json:"default,omitempty" example:"false"`
+}
+
+// DefectMetaResponse lists allowed severity and priority values, highest rank first
+type DefectMetaResponse struct {
+	Severities []LevelResponse `json:"severities"`
+	Priorities []LevelResponse `json:"priorities"`
+}
+
+// LabelResponse represents a project label
+type LabelResponse struct {
+	ID        uint   `json:"id" example:"1"`
+	ProjectID uint   `json:"project_id" example:"1"`
+	Name      string `json:"name" example:"waterproofing"`
+	Color     string `json:"color" example:"#2563eb"`
+}
+
+// LabelUsageResponse is the number of defects carrying a label
+type LabelUsageResponse struct {
+	LabelID uint   `json:"label_id" example:"1"`
+	Name    string `json:"name" example:"waterproofing"`
+	Color   string `json:"color" example:"#2563eb"`
+	Count   int64  `json:"count" example:"12"`
+}
+
+// ProjectStatsResponse represents aggregated defect counts of a project
+type ProjectStatsResponse struct {
+	ProjectID  uint                  `json:"project_id" example:"1"`
+	Total      int64                 `json:"total" example:"42"`
+	ByStatus   map[string]int64      `json:"by_status"`
+	BySeverity map[string]int64      `json:"by_severity"`
+	ByPriority map[string]int64      `json:"by_priority"`
+	ByCategory map[string]int64      `json:"by_category"`
+	ByType     map[string]int64      `json:"by_type"`
+	Labels     []LabelUsageResponse  `json:"labels"`
+	Citations  []ClauseUsageResponse `json:"citations"`
+}
+
+// StorageUsageResponse represents the attachment storage of a project
+type StorageUsageResponse struct {
+	ProjectID     uint                  `json:"project_id" example:"1"`
+	Files         int64                 `json:"files" example:"312"`
+	Bytes         int64                 `json:"bytes" example:"8589934592"`
+	Reserved      int64                 `json:"reserved" example:"104857600"`
+	Quota         int64                 `json:"quota" example:"10737418240"`
+	Percent       float64               `json:"percent" example:"80"`
+	ByContentType []UsageBucketResponse `json:"by_content_type"`
+	ByUploader    []UsageBucketResponse `json:"by_uploader"`
+	Organization  *QuotaUsageResponse   `json:"organization,omitempty"`
+}
+
+// UsageBucketResponse is the storage of one content type or one uploader
+type UsageBucketResponse struct {
+	ContentType  string `json:"content_type,omitempty" example:"image/jpeg"`
+	UploaderID   uint   `json:"uploader_id,omitempty" example:"4"`
+	UploaderName string `json:"uploader_name,omitempty" example:"Ivan Petrov"`
+	Files        int64  `json:"files" example:"120"`
+	Bytes        int64  `json:"bytes" example:"524288000"`
+}
+
+// QuotaUsageResponse is the storage of an organization against its quota
+type QuotaUsageResponse struct {
+	OrganizationID uint    `json:"organization_id" example:"1"`
+	Name           string  `json:"name" example:"StroyInvest LLC"`
+	Bytes          int64   `json:"bytes" example:"53687091200"`
+	Reserved       int64   `json:"reserved" example:"0"`
+	Quota          int64   `json:"quota" example:"107374182400"`
+	Percent        float64 `json:"percent" example:"50"`
+}
+
+// ClauseUsageResponse is the number of defects citing a clause
+type ClauseUsageResponse struct {
+	ClauseID uint   `json:"clause_id" example:"12"`
+	Citation string `json:"citation" example:"СП 70.13330.2012, п. 5.3.1"`
+	Count    int64  `json:"count" example:"4"`
 }
 
 // CreateProjectRequest used in swagger for creating projects
 type CreateProjectRequest struct {
+	Key     string `json:"key" example:"TWR"`
 	Name    string `json:"name" example:"New Building"`
 	Address string `json:"address" example:"123 Main St"`
 }
@@ -0,0 +1,286 @@
+package handler
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"example.com/defect-control-system/internal/models"
+	"example.com/defect-control-system/internal/service"
+)
+
+const (
+	tusVersion    = "1.0.0"
+	tusExtensions = "creation,creation-with-upload,termination,expiration"
+	// offsetOctetStream is the content type of tus chunks
+	offsetOctetStream = "application/offset+octet-stream"
+)
+
+// UploadHandler serves resumable uploads over the tus 1.0 protocol
+// (https://tus.io/protocols/resumable-upload). Metadata of the creation
+// request carries filename, filetype, defect_id, and optionally kind and
+// before_id as for multipart uploads.
+type UploadHandler struct {
+	svc service.UploadService
+}
+
+func NewUploadHandler(s service.UploadService) *UploadHandler {
+	return &UploadHandler{svc: s}
+}
+
+// RequireTus answers tus requests of another protocol version with 412
+func (h *UploadHandler) RequireTus(c *gin.Context) {
+	c.Header("Tus-Resumable", tusVersion)
+	if c.Request.Method != http.MethodOptions && c.GetHeader("Tus-Resumable") != tusVersion {
+		c.Header("Tus-Version", tusVersion)
+		c.AbortWithStatusJSON(http.StatusPreconditionFailed, gin.H{"status": "error", "error": "unsupported Tus-Resumable version, expected " + tusVersion})
+		return
+	}
+	c.Next()
+}
+
+// writeUploadError maps upload errors to the statuses tus clients expect
+func writeUploadError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrUploadOffset):
+		c.JSON(http.StatusConflict, gin.H{"status": "error", "error": err.Error()})
+	case errors.Is(err, service.ErrUploadExpired):
+		c.JSON(http.StatusGone, gin.H{"status": "error", "error": err.Error()})
+	case errors.Is(err, service.ErrUploadTooLarge), errors.Is(err, service.ErrQuotaExceeded):
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"status": "error", "error": err.Error()})
+	case errors.Is(err, service.ErrContentType):
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"status": "error", "error": err.Error()})
+	default:
+		writeServiceError(c, err)
+	}
+}
+
+// uploadHeaders sets the offset and expiry of a session on the response
+func uploadHeaders(c *gin.Context, u *models.UploadSession) {
+	c.Header("Upload-Offset", strconv.FormatInt(u.Offset, 10))
+	c.Header("Upload-Expires", u.ExpiresAt.UTC().Format(http.TimeFormat))
+	if u.AttachmentID != nil {
+		c.Header("X-Attachment-ID", strconv.FormatUint(uint64(*u.AttachmentID), 10))
+	}
+}
+
+// parseUploadMetadata decodes the Upload-Metadata header: comma separated
+// pairs of a key and a base64 value
+func parseUploadMetadata(h string) (map[string]string, error) {
+	meta := map[string]string{}
+	for _, pair := range strings.Split(h, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		key, value, _ := strings.Cut(pair, " ")
+		v, err := base64.StdEncoding.DecodeString(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("invalid Upload-Metadata value of %q", key)
+		}
+		meta[key] = string(v)
+	}
+	return meta, nil
+}
+
+// Options godoc
+// @Summary Discover tus upload capabilities
+// @Tags uploads
+// @Success 204
+// @Header 204 {string} Tus-Version "1.0.0"
+// @Header 204 {string} Tus-Extension "creation,creation-with-upload,termination,expiration"
+// @Header 204 {integer} Tus-Max-Size "Largest accepted upload, bytes"
+// @Router /api/v1/uploads [options]
+func (h *UploadHandler) Options(c *gin.Context) {
+	c.Header("Tus-Version", tusVersion)
+	c.Header("Tus-Extension", tusExtensions)
+	c.Header("Tus-Max-Size", strconv.FormatInt(h.svc.MaxSize(), 10))
+	c.Status(http.StatusNoContent)
+}
+
+// CreateUpload godoc
+// @Summary Start a resumable upload
+// @Description tus creation. Upload-Metadata holds base64 values of filename, filetype, defect_id (required), kind and before_id; replaces instead of defect_id uploads a new version of that attachment. A body with Content-Type application/offset+octet-stream is stored as the first chunk. Upload-Length counts against the storage quota from the start; 413 if it does not fit. The Location header addresses the upload; sessions idle longer than the expiry are removed.
+// @Tags uploads
+// @Param Tus-Resumable header string true "1.0.0"
+// @Param Upload-Length header int true "File size, bytes"
+// @Param Upload-Metadata header string true "e.g. filename ZHJvbmUubXA0,defect_id NDI="
+// @Success 201
+// @Header 201 {string} Location "URL of the upload"
+// @Header 201 {string} Upload-Expires "When the upload is dropped unless continued"
+// @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 413 {object} map[string]interface{}
+// @Security BearerAuth
+// @Router /api/v1/uploads [post]
+func (h *UploadHandler) Create(c *gin.Context) {
+	if c.GetHeader("Upload-Defer-Length") != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"status": "error", "error": "deferred upload length is not supported"})
+		return
+	}
+	length, err := strconv.ParseInt(c.GetHeader("Upload-Length"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"status": "error", "error": "invalid Upload-Length header"})
+		return
+	}
+	meta, err := parseUploadMetadata(c.GetHeader("Upload-Metadata"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"status": "error", "error": err.Error()})
+		return
+	}
+	dto := service.CreateUploadDTO{Filename: meta["filename"], ContentType: meta["filetype"], Kind: meta["kind"], Length: length}
+	if v := meta["replaces"]; v != "" {
+		if _, err := fmt.Sscanf(v, "%d", &dto.Replaces); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"status": "error", "error": "invalid replaces metadata"})
+			return
+		}
+	} else if _, err := fmt.Sscanf(meta["defect_id"], "%d", &dto.DefectID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"status": "error", "error": "defect_id metadata is required"})
+		return
+	}
+	if v := meta["before_id"]; v != "" {
+		var id uint
+		if _, err := fmt.Sscanf(v, "%d", &id); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"status": "error", "error": "invalid before_id metadata"})
+			return
+		}
+		dto.BeforeID = &id
+	}
+	uid, role := currentUser(c)
+	u, err := h.svc.Create(c.Request.Context(), uid, role, dto)
+	if err != nil {
+		writeUploadError(c, err)
+		return
+	}
+	c.Header("Location", "/api/v1/uploads/"+u.ID)
+	if c.ContentType() == offsetOctetStream && c.Request.ContentLength != 0 {
+		if u, err = h.svc.Append(c.Request.Context(), uid, u.ID, 0, c.Request.Body); err != nil {
+			writeUploadError(c, err)
+			return
+		}
+	}
+	uploadHeaders(c, u)
+	c.Status(http.StatusCreated)
+}
+
+// HeadUpload godoc
+// @Summary Get the offset of a resumable upload
+// @Description tus HEAD: Upload-Offset tells where to resume.
+// @Tags uploads
+// @Param id path string true "Upload ID"
+// @Param Tus-Resumable header string true "1.0.0"
+// @Success 200
+// @Header 200 {integer} Upload-Offset "Bytes stored"
+// @Header 200 {integer} Upload-Length "File size"
+// @Failure 404
+// @Failure 410
+// @Security BearerAuth
+// @Router /api/v1/uploads/{id} [head]
+func (h *UploadHandler) Head(c *gin.Context) {
+	uid, _ := currentUser(c)
+	u, err := h.svc.Get(c.Request.Context(), uid, c.Param("id"))
+	if err != nil {
+		c.Status(http.StatusNotFound)
+		return
+	}
+	c.Header("Cache-Control", "no-store")
+	if u.AttachmentID == nil && time.Now().After(u.ExpiresAt) {
+		c.Status(http.StatusGone)
+		return
+	}
+	c.Header("Upload-Length", strconv.FormatInt(u.Length, 10))
+	uploadHeaders(c, u)
+	c.Status(http.StatusOK)
+}
+
+// PatchUpload godoc
+// @Summary Append a chunk to a resumable upload
+// @Description tus PATCH. Upload-Offset must equal the stored offset (409 otherwise). Bytes received before a connection drops are kept. The chunk completing the file creates the attachment, whose id is returned in X-Attachment-ID.
+// @Tags uploads
+// @Accept application/offset+octet-stream
+// @Param id path string true "Upload ID"
+// @Param Tus-Resumable header string true "1.0.0"
+// @Param Upload-Offset header int true "Offset the chunk starts at"
+// @Success 204
+// @Header 204 {integer} Upload-Offset "Bytes stored"
+// @Header 204 {integer} X-Attachment-ID "Created attachment, once complete"
+// @Failure 409 {object} map[string]interface{}
+// @Failure 410 {object} map[string]interface{}
+// @Failure 413 {object} map[string]interface{}
+// @Failure 415 {object} map[string]interface{}
+// @Security BearerAuth
+// @Router /api/v1/uploads/{id} [patch]
+func (h *UploadHandler) Patch(c *gin.Context) {
+	if c.ContentType() != offsetOctetStream {
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"status": "error", "error": "Content-Type must be " + offsetOctetStream})
+		return
+	}
+	offset, err := strconv.ParseInt(c.GetHeader("Upload-Offset"), 10, 64)
+	if err != nil || offset < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"status": "error", "error": "invalid Upload-Offset header"})
+		return
+	}
+	uid, _ := currentUser(c)
+	u, err := h.svc.Get(c.Request.Context(), uid, c.Param("id"))
+	if err != nil {
+		writeUploadError(c, err)
+		return
+	}
+	if n := c.Request.ContentLength; n > 0 && offset+n > u.Length {
+		// refuse before reading a body that cannot fit
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"status": "error", "error": "chunk exceeds Upload-Length"})
+		return
+	}
+	u, err = h.svc.Append(c.Request.Context(), uid, u.ID, offset, c.Request.Body)
+	if err != nil {
+		writeUploadError(c, err)
+		return
+	}
+	uploadHeaders(c, u)
+	c.Status(http.StatusNoContent)
+}
+
+// DeleteUpload godoc
+// @Summary Abort a resumable upload
+// @Description tus termination: drops the session and the bytes received. An attachment already created stays.
+// @Tags uploads
+// @Param id path string true "Upload ID"
+// @Param Tus-Resumable header string true "1.0.0"
+// @Success 204
+// @Failure 404 {object} map[string]interface{}
+// @Security BearerAuth
+// @Router /api/v1/uploads/{id} [delete]
+func (h *UploadHandler) Delete(c *gin.Context) {
+	uid, _ := currentUser(c)
+	if err := h.svc.Terminate(c.Request.Context(), uid, c.Param("id")); err != nil {
+		writeUploadError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// GetUpload godoc
+// @Summary Get a resumable upload
+// @Description The session as JSON, with attachment_id once the file is complete.
+// @Tags uploads
+// @Produce json
+// @Param id path string true "Upload ID"
+// @Success 200 {object} handler.UploadSessionResponse
+// @Failure 404 {object} map[string]interface{}
+// @Security BearerAuth
+// @Router /api/v1/uploads/{id} [get]
+func (h *UploadHandler) Get(c *gin.Context) {
+	uid, _ := currentUser(c)
+	u, err := h.svc.Get(c.Request.Context(), uid, c.Param("id"))
+	if err != nil {
+		writeUploadError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "ok", "data": u})
+}
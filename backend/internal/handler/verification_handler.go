@@ -0,0 +1,139 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"example.com/defect-control-system/internal/service"
+)
+
+type VerificationHandler struct {
+	svc service.VerificationService
+}
+
+func NewVerificationHandler(s service.VerificationService) *VerificationHandler {
+	return &VerificationHandler{svc: s}
+}
+
+// defectParams parses the :id and :defectId path params
+func defectParams(c *gin.Context) (uint, uint, bool) {
+	projectID, ok := projectIDParam(c)
+	if !ok {
+		return 0, 0, false
+	}
+	defectID, ok := idParam(c, "defectId", "defect")
+	return projectID, defectID, ok
+}
+
+// SubmitVerification godoc
+// @Summary Submit a fix for verification
+// @Description Posts the remediation comment with photos already attached to the defect and moves it from in_progress to verification.
+// @Tags verification
+// @Accept json
+// @Produce json
+// @Param id path int true "Project ID"
+// @Param defectId path int true "Defect ID"
+// @Param body body service.SubmitVerificationDTO true "Evidence"
+// @Success 201 {object} handler.VerificationResponse
+// @Failure 400 {object} map[string]interface{}
+// @Security BearerAuth
+// @Router /api/v1/projects/{id}/defects/{defectId}/verification [post]
+func (h *VerificationHandler) Submit(c *gin.Context) {
+	projectID, defectID, ok := defectParams(c)
+	if !ok {
+		return
+	}
+	var dto service.SubmitVerificationDTO
+	if err := c.ShouldBindJSON(&dto); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"status": "error", "error": err.Error()})
+		return
+	}
+	uid, role := currentUser(c)
+	v, err := h.svc.Submit(c.Request.Context(), uid, role, projectID, defectID, dto)
+	if err != nil {
+		writeServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"status": "ok", "data": v})
+}
+
+// ApproveVerification godoc
+// @Summary Approve a fix
+// @Description The defect's verifier (or a project manager when none is designated) approves the fix; the defect is closed and the approval recorded.
+// @Tags verification
+// @Produce json
+// @Param id path int true "Project ID"
+// @Param defectId path int true "Defect ID"
+// @Success 200 {object} handler.VerificationResponse
+// @Failure 403 {object} map[string]interface{}
+// @Security BearerAuth
+// @Router /api/v1/projects/{id}/defects/{defectId}/verification/approve [post]
+func (h *VerificationHandler) Approve(c *gin.Context) {
+	projectID, defectID, ok := defectParams(c)
+	if !ok {
+		return
+	}
+	uid, role := currentUser(c)
+	v, err := h.svc.Approve(c.Request.Context(), uid, role, projectID, defectID)
+	if err != nil {
+		writeServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "ok", "data": v})
+}
+
+// RejectVerification godoc
+// @Summary Reject a fix
+// @Description Returns the defect to in_progress and increments its rejection counter.
+// @Tags verification
+// @Accept json
+// @Produce json
+// @Param id path int true "Project ID"
+// @Param defectId path int true "Defect ID"
+// @Param body body service.RejectVerificationDTO true "Reason"
+// @Success 200 {object} handler.VerificationResponse
+// @Failure 403 {object} map[string]interface{}
+// @Security BearerAuth
+// @Router /api/v1/projects/{id}/defects/{defectId}/verification/reject [post]
+func (h *VerificationHandler) Reject(c *gin.Context) {
+	projectID, defectID, ok := defectParams(c)
+	if !ok {
+		return
+	}
+	var dto service.RejectVerificationDTO
+	if err := c.ShouldBindJSON(&dto); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"status": "error", "error": err.Error()})
+		return
+	}
+	uid, role := currentUser(c)
+	v, err := h.svc.Reject(c.Request.Context(), uid, role, projectID, defectID, dto)
+	if err != nil {
+		writeServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "ok", "data": v})
+}
+
+// ListVerifications godoc
+// @Summary Verification history of a defect
+// @Tags verification
+// @Produce json
+// @Param id path int true "Project ID"
+// @Param defectId path int true "Defect ID"
+// @Success 200 {array} handler.VerificationResponse
+// @Security BearerAuth
+// @Router /api/v1/projects/{id}/defects/{defectId}/verifications [get]
+func (h *VerificationHandler) List(c *gin.Context) {
+	projectID, defectID, ok := defectParams(c)
+	if !ok {
+		return
+	}
+	uid, role := currentUser(c)
+	list, err := h.svc.List(c.Request.Context(), uid, role, projectID, defectID)
+	if err != nil {
+		writeServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "ok", "data": list})
+}
@@ -0,0 +1,195 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"example.com/defect-control-system/internal/service"
+)
+
+type ViewHandler struct {
+	svc      service.SavedViewService
+	fieldSvc service.CustomFieldService
+}
+
+func NewViewHandler(s service.SavedViewService, f service.CustomFieldService) *ViewHandler {
+	return &ViewHandler{svc: s, fieldSvc: f}
+}
+
+// ListViews godoc
+// @Summary List saved views
+// @Description Own views plus views shared by project teams the caller belongs to
+// @Tags views
+// @Produce json
+// @Param project_id query int false "Only views of this project"
+// @Success 200 {array} handler.SavedViewResponse
+// @Security BearerAuth
+// @Router /api/v1/views [get]
+func (h *ViewHandler) List(c *gin.Context) {
+	uid, role := currentUser(c)
+	var projectID *uint
+	if v := c.Query("project_id"); v != "" {
+		var id uint
+		if _, err := fmt.Sscanf(v, "%d", &id); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"status": "error", "error": "invalid project_id"})
+			return
+		}
+		projectID = &id
+	}
+	list, err := h.svc.List(c.Request.Context(), uid, role, projectID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"status": "error", "error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "ok", "data": list})
+}
+
+// CreateView godoc
+// @Summary Save a defect filter as a view
+// @Tags views
+// @Accept json
+// @Produce json
+// @Param body body service.CreateViewDTO true "View"
+// @Success 201 {object} handler.SavedViewResponse
+// @Failure 400 {object} map[string]interface{}
+// @Security BearerAuth
+// @Router /api/v1/views [post]
+func (h *ViewHandler) Create(c *gin.Context) {
+	var dto service.CreateViewDTO
+	if err := c.ShouldBindJSON(&dto); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"status": "error", "error": err.Error()})
+		return
+	}
+	uid, role := currentUser(c)
+	v, err := h.svc.Create(c.Request.Context(), uid, role, dto)
+	if err != nil {
+		writeServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"status": "ok", "data": v})
+}
+
+// GetView godoc
+// @Summary Get saved view
+// @Tags views
+// @Produce json
+// @Param id path int true "View ID"
+// @Success 200 {object} handler.SavedViewResponse
+// @Security BearerAuth
+// @Router /api/v1/views/{id} [get]
+func (h *ViewHandler) Get(c *gin.Context) {
+	id, ok := viewID(c)
+	if !ok {
+		return
+	}
+	uid, role := currentUser(c)
+	v, err := h.svc.Get(c.Request.Context(), uid, role, id)
+	if err != nil {
+		writeServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "ok", "data": v})
+}
+
+// UpdateView godoc
+// @Summary Update saved view
+// @Description Rename, change filter, share or mark as default (owner only)
+// @Tags views
+// @Accept json
+// @Produce json
+// @Param id path int true "View ID"
+// @Param body body service.UpdateViewDTO true "Update"
+// @Success 200 {object} handler.SavedViewResponse
+// @Security BearerAuth
+// @Router /api/v1/views/{id} [patch]
+func (h *ViewHandler) Update(c *gin.Context) {
+	id, ok := viewID(c)
+	if !ok {
+		return
+	}
+	var dto service.UpdateViewDTO
+	if err := c.ShouldBindJSON(&dto); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"status": "error", "error": err.Error()})
+		return
+	}
+	uid, _ := currentUser(c)
+	v, err := h.svc.Update(c.Request.Context(), uid, id, dto)
+	if err != nil {
+		writeServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "ok", "data": v})
+}
+
+// DeleteView godoc
+// @Summary Delete saved view
+// @Tags views
+// @Param id path int true "View ID"
+// @Success 204
+// @Security BearerAuth
+// @Router /api/v1/views/{id} [delete]
+func (h *ViewHandler) Delete(c *gin.Context) {
+	id, ok := viewID(c)
+	if !ok {
+		return
+	}
+	uid, role := currentUser(c)
+	if err := h.svc.Delete(c.Request.Context(), uid, role, id); err != nil {
+		writeServiceError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// ViewDefects godoc
+// @Summary Defects matched by a saved view
+// @Description Runs the view's filter. Use format=csv to export the result.
+// @Tags views
+// @Produce json
+// @Produce text/csv
+// @Param id path int true "View ID"
+// @Param format query string false "csv to export"
+// @Success 200 {array} handler.DefectResponse
+// @Security BearerAuth
+// @Router /api/v1/views/{id}/defects [get]
+func (h *ViewHandler) Defects(c *gin.Context) {
+	id, ok := viewID(c)
+	if !ok {
+		return
+	}
+	uid, role := currentUser(c)
+	v, list, err := h.svc.Defects(c.Request.Context(), uid, role, id)
+	if err != nil {
+		writeServiceError(c, err)
+		return
+	}
+	if c.Query("format") == "csv" {
+		projectIDs := map[uint]bool{}
+		var ids []uint
+		for _, d := range list {
+			if !projectIDs[d.ProjectID] {
+				projectIDs[d.ProjectID] = true
+				ids = append(ids, d.ProjectID)
+			}
+		}
+		fields, err := h.fieldSvc.ListByProjects(c.Request.Context(), ids)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"status": "error", "error": err.Error()})
+			return
+		}
+		writeDefectsCSV(c, fmt.Sprintf("view-%d-defects.csv", v.ID), list, fields)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "ok", "data": list})
+}
+
+func viewID(c *gin.Context) (uint, bool) {
+	var id uint
+	if _, err := fmt.Sscanf(c.Param("id"), "%d", &id); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"status": "error", "error": "invalid view id"})
+		return 0, false
+	}
+	return id, true
+}
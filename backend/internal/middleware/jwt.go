@@ -38,3 +38,25 @@ func JWTAuthMiddleware() gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// OptionalJWTAuthMiddleware sets user ID and role in context when a valid Bearer token
+// is present, but lets anonymous requests through. Handlers can then tailor the
+// response to the caller (e.g. hide internal comments) on otherwise public routes.
+func OptionalJWTAuthMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		auth := c.GetHeader("Authorization")
+		var tokenStr string
+		if _, err := fmt.Sscanf(auth, "Bearer %s", &tokenStr); err != nil || tokenStr == "" {
+			c.Next()
+			return
+		}
+		claims, err := utils.ParseJWT(viper.GetString("jwt.secret"), tokenStr)
+		if err != nil {
+			c.Next()
+			return
+		}
+		c.Set("user_id", claims.UserID)
+		c.Set("role", claims.Role)
+		c.Next()
+	}
+}
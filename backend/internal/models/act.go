@@ -0,0 +1,53 @@
+package models
+
+import "time"
+
+// Acceptance act statuses
+const (
+	ActDraft  = "draft"
+	ActSigned = "signed"
+)
+
+// Signature methods
+const (
+	SignatureDrawn = "drawn"
+	SignatureTyped = "typed"
+)
+
+// AcceptanceAct lists eliminated defects handed over to the customer. It is a
+// draft until every signatory has signed; then the PDF is rendered once, stored
+// and never changed, its SHA-256 recorded for later verification.
+type AcceptanceAct struct {
+	ID        uint    `gorm:"primaryKey" json:"id"`
+	ProjectID uint    `gorm:"uniqueIndex:idx_acts_project_number" json:"project_id"`
+	Project   Project `gorm:"foreignKey:ProjectID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"-"`
+	// Number counts acts within the project
+	Number      int            `gorm:"uniqueIndex:idx_acts_project_number" json:"number"`
+	Title       string         `gorm:"size:255" json:"title"`
+	Status      string         `gorm:"size:20;default:draft;index" json:"status"`
+	Defects     []Defect       `gorm:"many2many:act_defects;constraint:OnUpdate:CASCADE,OnDelete:RESTRICT;" json:"defects"`
+	Signatories []ActSignatory `gorm:"foreignKey:ActID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"signatories"`
+	CreatedByID uint           `json:"created_by_id"`
+	CreatedBy   *User          `gorm:"foreignKey:CreatedByID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;" json:"created_by,omitempty"`
+	// Path of the signed PDF relative to the uploads directory, its hash and size
+	Path      string     `gorm:"size:1024" json:"-"`
+	SHA256    string     `gorm:"column:sha256;size:64" json:"sha256,omitempty"`
+	Size      int64      `json:"size,omitempty"`
+	SignedAt  *time.Time `json:"signed_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// ActSignatory is a party expected to sign an act and, once signed, the
+// signature: a drawn image or the signer's typed name confirmed by their login
+type ActSignatory struct {
+	ID     uint   `gorm:"primaryKey" json:"id"`
+	ActID  uint   `gorm:"uniqueIndex:idx_act_signatories_user" json:"act_id"`
+	UserID uint   `gorm:"uniqueIndex:idx_act_signatories_user" json:"user_id"`
+	User   *User  `gorm:"foreignKey:UserID;constraint:OnUpdate:CASCADE,OnDelete:RESTRICT;" json:"user,omitempty"`
+	Party  string `gorm:"size:255" json:"party"`
+	Method string `gorm:"size:20" json:"method,omitempty"`
+	// Image is the drawn signature as PNG
+	Image     []byte     `json:"-"`
+	TypedName string     `gorm:"size:255" json:"typed_name,omitempty"`
+	SignedAt  *time.Time `json:"signed_at,omitempty"`
+}
@@ -0,0 +1,79 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"time"
+)
+
+// Annotation shape types
+const (
+	// ShapeArrow points from its first point to its second
+	ShapeArrow = "arrow"
+	// ShapeRect and ShapeEllipse span the box between their two points
+	ShapeRect    = "rect"
+	ShapeEllipse = "ellipse"
+	// ShapeFreehand is a polyline of any number of points
+	ShapeFreehand = "freehand"
+	// ShapeText places its text with the top-left corner at its point
+	ShapeText = "text"
+)
+
+// AnnotationShapeTypes lists the valid shape types
+var AnnotationShapeTypes = []string{ShapeArrow, ShapeRect, ShapeEllipse, ShapeFreehand, ShapeText}
+
+// AnnotationPoint is a position on the upright image in pixels from the
+// top-left corner
+type AnnotationPoint struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+}
+
+// AnnotationShape is one vector mark drawn over a photo
+type AnnotationShape struct {
+	Type   string            `json:"type"`
+	Points []AnnotationPoint `json:"points"`
+	// Color is the stroke and text color as #RGB, #RRGGBB or #RRGGBBAA
+	Color       string  `json:"color"`
+	StrokeWidth float64 `json:"stroke_width,omitempty"`
+	// Fill is the interior of rectangles and ellipses and the box behind
+	// text; empty leaves it transparent
+	Fill     string  `json:"fill,omitempty"`
+	Text     string  `json:"text,omitempty"`
+	FontSize float64 `json:"font_size,omitempty"`
+}
+
+// AnnotationShapes is the drawing order of the shapes, stored in a jsonb column
+type AnnotationShapes []AnnotationShape
+
+// Value implements driver.Valuer
+func (s AnnotationShapes) Value() (driver.Value, error) {
+	if s == nil {
+		return "[]", nil
+	}
+	b, err := json.Marshal(s)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+// Scan implements sql.Scanner
+func (s *AnnotationShapes) Scan(src interface{}) error {
+	*s = nil
+	return scanJSON(src, s)
+}
+
+// Annotation is the vector drawing layered over an image attachment. The file
+// itself is never changed; renders burn the shapes into a copy
+type Annotation struct {
+	ID           uint             `gorm:"primaryKey" json:"id"`
+	AttachmentID uint             `gorm:"uniqueIndex" json:"attachment_id"`
+	Attachment   *Attachment      `gorm:"foreignKey:AttachmentID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"-"`
+	Shapes       AnnotationShapes `gorm:"type:jsonb" json:"shapes"`
+	UpdatedByID  uint             `json:"updated_by_id"`
+	// Version is the optimistic-locking counter exposed as ETag
+	Version   int       `gorm:"not null;default:1" json:"version"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
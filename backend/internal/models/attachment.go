@@ -1,16 +1,80 @@
 package models
 
-import "time"
+import (
+	"strings"
+	"time"
+)
+
+// Attachment kinds
+const (
+	// AttachmentEvidence documents the defect as found: the "before" photos
+	AttachmentEvidence = "evidence"
+	// AttachmentRemediation shows the fix: the "after" photos
+	AttachmentRemediation = "remediation"
+	AttachmentDocument    = "document"
+	AttachmentDrawing     = "drawing"
+)
+
+// Attachment scan states
+const (
+	// AttachmentPendingScan holds the file back until the scanner has seen it
+	AttachmentPendingScan = "pending_scan"
+	AttachmentClean       = "clean"
+	// AttachmentInfected files are moved to quarantine and never served
+	AttachmentInfected = "infected"
+	// AttachmentScanFailed files could not be scanned, e.g. for their size,
+	// and stay blocked
+	AttachmentScanFailed = "scan_failed"
+)
+
+// AttachmentKinds lists the valid kinds
+var AttachmentKinds = []string{AttachmentEvidence, AttachmentRemediation, AttachmentDocument, AttachmentDrawing}
 
 type Attachment struct {
-	ID          uint      `gorm:"primaryKey" json:"id"`
-	DefectID    uint      `json:"defect_id"`
-	Defect      Defect    `gorm:"foreignKey:DefectID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;" json:"defect,omitempty"`
-	UploaderID  uint      `json:"uploader_id"`
-	Uploader    User      `gorm:"foreignKey:UploaderID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;" json:"uploader,omitempty"`
-	Path        string    `gorm:"size:1024" json:"path"`
-	Filename    string    `gorm:"size:512" json:"filename"`
-	ContentType string    `gorm:"size:255" json:"content_type"`
-	Size        int64     `json:"size"`
-	CreatedAt   time.Time `json:"created_at"`
+	ID          uint   `gorm:"primaryKey" json:"id"`
+	DefectID    uint   `json:"defect_id"`
+	Defect      Defect `gorm:"foreignKey:DefectID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;" json:"defect,omitempty"`
+	UploaderID  uint   `json:"uploader_id"`
+	Uploader    User   `gorm:"foreignKey:UploaderID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;" json:"uploader,omitempty"`
+	Path        string `gorm:"size:1024" json:"path"`
+	Filename    string `gorm:"size:512" json:"filename"`
+	ContentType string `gorm:"size:255" json:"content_type"`
+	Size        int64  `json:"size"`
+	// SHA256 names the blob holding the content; empty for files stored
+	// before content addressing until they are adopted
+	SHA256 string `gorm:"column:sha256;size:64;index" json:"sha256,omitempty"`
+	Kind   string `gorm:"size:20;index" json:"kind"`
+	// BeforeID pairs a remediation photo with the evidence photo it shows fixed
+	BeforeID *uint       `gorm:"index" json:"before_id,omitempty"`
+	Before   *Attachment `gorm:"foreignKey:BeforeID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;" json:"-"`
+	// OriginID is the first version of a revised file; nil on the first
+	// version itself
+	OriginID *uint `gorm:"index" json:"origin_id,omitempty"`
+	// Version numbers the revisions of a file from 1
+	Version int `gorm:"not null;default:1" json:"version"`
+	// Superseded is set once a newer version is uploaded
+	Superseded bool `gorm:"not null;default:false;index" json:"superseded"`
+	// ScanStatus is the antivirus verdict; only clean files are served
+	ScanStatus string `gorm:"size:16;default:clean;index" json:"scan_status"`
+	// ScanDetail names the signature found or why the scan failed
+	ScanDetail string    `gorm:"size:255" json:"scan_detail,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// Servable reports whether the content passed the scan
+func (a *Attachment) Servable() bool {
+	return a.ScanStatus == "" || a.ScanStatus == AttachmentClean
+}
+
+// Root is the ID of the first version of the file
+func (a *Attachment) Root() uint {
+	if a.OriginID != nil {
+		return *a.OriginID
+	}
+	return a.ID
+}
+
+// IsImage reports whether the attachment is a picture
+func (a *Attachment) IsImage() bool {
+	return strings.HasPrefix(a.ContentType, "image/")
 }
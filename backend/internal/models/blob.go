@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// Blob is stored file content, addressed by its SHA-256. Attachments with the
+// same content share one blob; RefCount is the number of them, and the blob
+// is removed with the last one.
+type Blob struct {
+	SHA256    string    `gorm:"primaryKey;column:sha256;size:64" json:"sha256"`
+	Size      int64     `json:"size"`
+	Path      string    `gorm:"size:1024" json:"-"`
+	RefCount  int       `gorm:"not null;default:0" json:"ref_count"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
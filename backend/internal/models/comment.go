@@ -2,13 +2,21 @@ package models
 
 import "time"
 
+// Comment visibility values. Internal comments are only shown to the contractor
+// team (engineers, managers, admins); public ones are visible to stakeholders too.
+const (
+	CommentVisibilityPublic   = "public"
+	CommentVisibilityInternal = "internal"
+)
+
 type Comment struct {
-	ID        uint      `gorm:"primaryKey" json:"id"`
-	DefectID  uint      `json:"defect_id"`
-	Defect    Defect    `gorm:"foreignKey:DefectID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"defect,omitempty"`
-	AuthorID  *uint     `json:"author_id"`
-	Author    *User     `gorm:"foreignKey:AuthorID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;" json:"author,omitempty"`
-	Body      string    `gorm:"type:text" json:"body"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	DefectID   uint      `json:"defect_id"`
+	Defect     Defect    `gorm:"foreignKey:DefectID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"defect,omitempty"`
+	AuthorID   *uint     `json:"author_id"`
+	Author     *User     `gorm:"foreignKey:AuthorID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;" json:"author,omitempty"`
+	Body       string    `gorm:"type:text" json:"body"`
+	Visibility string    `gorm:"size:20;default:public;index" json:"visibility"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
 }
@@ -0,0 +1,36 @@
+package models
+
+import (
+	"regexp"
+	"time"
+)
+
+// Custom field types
+const (
+	CustomFieldText    = "text"
+	CustomFieldNumber  = "number"
+	CustomFieldDate    = "date"
+	CustomFieldEnum    = "enum"
+	CustomFieldUser    = "user"
+	CustomFieldBoolean = "boolean"
+)
+
+// CustomFieldKeyPattern restricts field keys to identifiers safe to use in JSON paths
+var CustomFieldKeyPattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,63}$`)
+
+// CustomField is a project-defined typed attribute of defects. Values are stored
+// in Defect.CustomFields under Key: strings for text, enum and date (YYYY-MM-DD),
+// numbers for number, user ids for user and booleans for boolean fields.
+type CustomField struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	ProjectID uint       `gorm:"uniqueIndex:idx_custom_fields_project_key" json:"project_id"`
+	Project   Project    `gorm:"foreignKey:ProjectID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"-"`
+	Key       string     `gorm:"size:64;uniqueIndex:idx_custom_fields_project_key" json:"key"`
+	Label     string     `gorm:"size:255" json:"label"`
+	Type      string     `gorm:"size:20" json:"type"`
+	Required  bool       `json:"required"`
+	Options   StringList `gorm:"type:jsonb" json:"options,omitempty"`
+	Position  int        `json:"position"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
@@ -5,6 +5,9 @@ import "time"
 type Defect struct {
 	ID        uint `gorm:"primaryKey" json:"id"`
 	ProjectID uint `json:"project_id"`
+	// Number is sequential within the project; Key is "<project key>-<number>"
+	Number int    `gorm:"default:0" json:"number"`
+	Key    string `gorm:"size:32" json:"key"`
 	// Project is the relation to project; add constraint to create FK
 	Project     Project    `gorm:"foreignKey:ProjectID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"project,omitempty"`
 	Title       string     `gorm:"size:255" json:"title"`
@@ -15,6 +18,29 @@ type Defect struct {
 	Assignee    *User      `gorm:"foreignKey:AssigneeID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;" json:"assignee,omitempty"`
 	DueDate     *time.Time `json:"due_date,omitempty"`
 	Priority    string     `gorm:"size:50" json:"priority"`
-	CreatedAt   time.Time  `json:"created_at"`
-	UpdatedAt   time.Time  `json:"updated_at"`
+	// SeverityRank and PriorityRank copy the rank of the level for sorting
+	SeverityRank int `gorm:"default:0;index" json:"severity_rank"`
+	PriorityRank int `gorm:"default:0;index" json:"priority_rank"`
+	// CategoryID points to a trade or defect type of the classification catalog
+	CategoryID       *uint           `gorm:"index" json:"category_id,omitempty"`
+	Category         *DefectCategory `gorm:"foreignKey:CategoryID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;" json:"category,omitempty"`
+	ResponsibleOrgID *uint           `gorm:"index" json:"responsible_org_id,omitempty"`
+	ResponsibleOrg   *Organization   `gorm:"foreignKey:ResponsibleOrgID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;" json:"responsible_org,omitempty"`
+	// CustomFields holds values of the project's custom fields keyed by field key
+	CustomFields JSONMap `gorm:"type:jsonb;default:'{}'" json:"custom_fields"`
+	Labels       []Label `gorm:"many2many:defect_labels;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"labels"`
+	// Clauses are the violated requirements of building codes
+	Clauses []NormativeClause `gorm:"many2many:defect_clauses;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"clauses"`
+	// VerifierID designates who verifies the fix before the defect is closed;
+	// RejectionCount counts rejected fixes, VerifiedBy and VerifiedAt record the approval
+	VerifierID     *uint      `json:"verifier_id,omitempty"`
+	Verifier       *User      `gorm:"foreignKey:VerifierID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;" json:"verifier,omitempty"`
+	RejectionCount int        `gorm:"not null;default:0" json:"rejection_count"`
+	VerifiedByID   *uint      `json:"verified_by_id,omitempty"`
+	VerifiedBy     *User      `gorm:"foreignKey:VerifiedByID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;" json:"verified_by,omitempty"`
+	VerifiedAt     *time.Time `json:"verified_at,omitempty"`
+	// Version is incremented by every update and served as the ETag
+	Version   int       `gorm:"not null;default:1" json:"version"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
 }
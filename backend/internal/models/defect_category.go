@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// DefectCategory is an entry of the company-wide defect classification. Top level
+// entries are trades (concrete, electrical, roofing); their children are defect
+// types. Defaults are applied to new defects of the category.
+type DefectCategory struct {
+	ID       uint            `gorm:"primaryKey" json:"id"`
+	ParentID *uint           `gorm:"index" json:"parent_id,omitempty"`
+	Parent   *DefectCategory `gorm:"foreignKey:ParentID;constraint:OnUpdate:CASCADE,OnDelete:RESTRICT;" json:"-"`
+	// Code identifies the entry in imported catalogs, e.g. "EL" or "EL.03"
+	Code                  string           `gorm:"size:50;uniqueIndex" json:"code"`
+	Name                  string           `gorm:"size:255" json:"name"`
+	DefaultSeverity       string           `gorm:"size:50" json:"default_severity,omitempty"`
+	DefaultOrganizationID *uint            `json:"default_organization_id,omitempty"`
+	DefaultOrganization   *Organization    `gorm:"foreignKey:DefaultOrganizationID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;" json:"default_organization,omitempty"`
+	Children              []DefectCategory `gorm:"-" json:"children,omitempty"`
+	CreatedAt             time.Time        `json:"created_at"`
+	UpdatedAt             time.Time        `json:"updated_at"`
+}
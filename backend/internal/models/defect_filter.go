@@ -0,0 +1,50 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"time"
+)
+
+// DefectFilter is a set of defect list filters. It is built from the defect list
+// query string and stored as JSON in saved views.
+type DefectFilter struct {
+	IDs        []uint     `json:"ids,omitempty"`
+	Statuses   []string   `json:"statuses,omitempty"`
+	Severities []string   `json:"severities,omitempty"`
+	Priorities []string   `json:"priorities,omitempty"`
+	AssigneeID *uint      `json:"assignee_id,omitempty"`
+	DueBefore  *time.Time `json:"due_before,omitempty"`
+	DueAfter   *time.Time `json:"due_after,omitempty"`
+	// Overdue selects open defects whose due date has passed
+	Overdue bool `json:"overdue,omitempty"`
+	// CategoryID selects defects of a catalog entry and of its child types
+	CategoryID *uint `json:"category_id,omitempty"`
+	// ResponsibleOrgID selects defects assigned to an organization
+	ResponsibleOrgID *uint `json:"responsible_org_id,omitempty"`
+	// Query is a full-text query over title and description
+	Query string `json:"q,omitempty"`
+	// LabelIDs selects defects by labels; LabelMode "all" requires every label, otherwise any of them
+	LabelIDs  []uint `json:"label_ids,omitempty"`
+	LabelMode string `json:"label_mode,omitempty"`
+	// Custom matches custom field values exactly, keyed by field key
+	Custom map[string]string `json:"custom,omitempty"`
+	// Sort is a field name, prefixed with "-" for descending order (e.g. "-created_at").
+	// Custom fields are sorted by "cf.<key>".
+	Sort string `json:"sort,omitempty"`
+}
+
+// Value implements driver.Valuer so the filter can be stored in a jsonb column
+func (f DefectFilter) Value() (driver.Value, error) {
+	b, err := json.Marshal(f)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+// Scan implements sql.Scanner for jsonb columns
+func (f *DefectFilter) Scan(src interface{}) error {
+	*f = DefectFilter{}
+	return scanJSON(src, f)
+}
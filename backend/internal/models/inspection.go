@@ -0,0 +1,86 @@
+package models
+
+import "time"
+
+// Inspection statuses
+const (
+	InspectionScheduled  = "scheduled"
+	InspectionInProgress = "in_progress"
+	InspectionCompleted  = "completed"
+	InspectionCancelled  = "cancelled"
+)
+
+// Checklist item outcomes
+const (
+	OutcomePass = "pass"
+	OutcomeFail = "fail"
+	OutcomeNA   = "na"
+)
+
+// InspectionTemplate is a reusable checklist. Templates without a project are
+// shared by all projects.
+type InspectionTemplate struct {
+	ID          uint                     `gorm:"primaryKey" json:"id"`
+	ProjectID   *uint                    `gorm:"index" json:"project_id,omitempty"`
+	Project     *Project                 `gorm:"foreignKey:ProjectID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"-"`
+	Name        string                   `gorm:"size:255" json:"name"`
+	Description string                   `gorm:"type:text" json:"description"`
+	Items       []InspectionTemplateItem `gorm:"foreignKey:TemplateID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"items"`
+	CreatedAt   time.Time                `json:"created_at"`
+	UpdatedAt   time.Time                `json:"updated_at"`
+}
+
+// InspectionTemplateItem is one check of a checklist. Severity, CategoryID and
+// ClauseID are copied into the defect raised when the check fails.
+type InspectionTemplateItem struct {
+	ID         uint   `gorm:"primaryKey" json:"id"`
+	TemplateID uint   `gorm:"index" json:"template_id"`
+	Position   int    `json:"position"`
+	Text       string `gorm:"type:text" json:"text"`
+	Severity   string `gorm:"size:50" json:"severity,omitempty"`
+	CategoryID *uint  `json:"category_id,omitempty"`
+	ClauseID   *uint  `json:"clause_id,omitempty"`
+}
+
+// Inspection is a checklist scheduled at a location of a project. Scheduling
+// copies the template items into Results, so later template edits do not
+// change inspections already planned or done.
+type Inspection struct {
+	ID          uint                `gorm:"primaryKey" json:"id"`
+	ProjectID   uint                `gorm:"index" json:"project_id"`
+	Project     Project             `gorm:"foreignKey:ProjectID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"-"`
+	TemplateID  *uint               `json:"template_id,omitempty"`
+	Template    *InspectionTemplate `gorm:"foreignKey:TemplateID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;" json:"-"`
+	Title       string              `gorm:"size:255" json:"title"`
+	Location    string              `gorm:"size:512" json:"location"`
+	ScheduledAt time.Time           `gorm:"index" json:"scheduled_at"`
+	InspectorID *uint               `json:"inspector_id,omitempty"`
+	Inspector   *User               `gorm:"foreignKey:InspectorID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;" json:"inspector,omitempty"`
+	Status      string              `gorm:"size:20;default:scheduled;index" json:"status"`
+	Results     []InspectionResult  `gorm:"foreignKey:InspectionID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"results"`
+	CreatedByID uint                `json:"created_by_id"`
+	StartedAt   *time.Time          `json:"started_at,omitempty"`
+	CompletedAt *time.Time          `json:"completed_at,omitempty"`
+	CreatedAt   time.Time           `json:"created_at"`
+	UpdatedAt   time.Time           `json:"updated_at"`
+	// CompletingSince is set while a completion raises the defects, so that
+	// concurrent completions do not raise them twice
+	CompletingSince *time.Time `json:"-"`
+}
+
+// InspectionResult is the outcome of one checklist item. A failed item gets a
+// defect when the inspection is completed.
+type InspectionResult struct {
+	ID           uint   `gorm:"primaryKey" json:"id"`
+	InspectionID uint   `gorm:"index" json:"inspection_id"`
+	Position     int    `json:"position"`
+	Text         string `gorm:"type:text" json:"text"`
+	Severity     string `gorm:"size:50" json:"severity,omitempty"`
+	CategoryID   *uint  `json:"category_id,omitempty"`
+	ClauseID     *uint  `json:"clause_id,omitempty"`
+	// Outcome is empty until the item is checked
+	Outcome  string  `gorm:"size:10" json:"outcome"`
+	Note     string  `gorm:"type:text" json:"note,omitempty"`
+	DefectID *uint   `json:"defect_id,omitempty"`
+	Defect   *Defect `gorm:"foreignKey:DefectID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;" json:"-"`
+}
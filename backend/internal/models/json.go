@@ -0,0 +1,62 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+// JSONMap is a free-form JSON object stored in a jsonb column
+type JSONMap map[string]interface{}
+
+// Value implements driver.Valuer
+func (m JSONMap) Value() (driver.Value, error) {
+	if m == nil {
+		return "{}", nil
+	}
+	b, err := json.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+// Scan implements sql.Scanner
+func (m *JSONMap) Scan(src interface{}) error {
+	*m = nil
+	return scanJSON(src, m)
+}
+
+// StringList is a list of strings stored in a jsonb column
+type StringList []string
+
+// Value implements driver.Valuer
+func (l StringList) Value() (driver.Value, error) {
+	if l == nil {
+		return "[]", nil
+	}
+	b, err := json.Marshal(l)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+// Scan implements sql.Scanner
+func (l *StringList) Scan(src interface{}) error {
+	*l = nil
+	return scanJSON(src, l)
+}
+
+func scanJSON(src interface{}, dst interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		return nil
+	case []byte:
+		return json.Unmarshal(v, dst)
+	case string:
+		return json.Unmarshal([]byte(v), dst)
+	default:
+		return fmt.Errorf("unsupported type for jsonb column: %T", src)
+	}
+}
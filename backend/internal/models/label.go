@@ -0,0 +1,20 @@
+package models
+
+import (
+	"regexp"
+	"time"
+)
+
+// LabelColorPattern accepts #RRGGBB colors
+var LabelColorPattern = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+// Label is a project-scoped tag that can be put on any number of defects
+type Label struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	ProjectID uint      `gorm:"uniqueIndex:idx_labels_project_name" json:"project_id"`
+	Project   Project   `gorm:"foreignKey:ProjectID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"-"`
+	Name      string    `gorm:"size:100;uniqueIndex:idx_labels_project_name" json:"name"`
+	Color     string    `gorm:"size:7" json:"color"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
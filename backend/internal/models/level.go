@@ -0,0 +1,71 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+)
+
+// Level is an allowed value of an ordered defect attribute (severity, priority).
+// Rank orders values; higher is more severe or more urgent.
+type Level struct {
+	Value string `json:"value"`
+	Label string `json:"label"`
+	Rank  int    `json:"rank"`
+	// Default is used when a defect is created without a value
+	Default bool `json:"default,omitempty"`
+}
+
+// LevelList is a list of levels stored in a jsonb column. A nil list means the
+// project uses the defaults.
+type LevelList []Level
+
+// Default severities follow the defect classes of GOST 15467
+var DefaultSeverityLevels = LevelList{
+	{Value: "critical", Label: "Критический", Rank: 30},
+	{Value: "major", Label: "Значительный", Rank: 20},
+	{Value: "minor", Label: "Малозначительный", Rank: 10, Default: true},
+}
+
+var DefaultPriorityLevels = LevelList{
+	{Value: "high", Label: "Высокий", Rank: 30},
+	{Value: "medium", Label: "Средний", Rank: 20, Default: true},
+	{Value: "low", Label: "Низкий", Rank: 10},
+}
+
+// Find returns the level with the given value
+func (l LevelList) Find(value string) (Level, bool) {
+	for _, lv := range l {
+		if lv.Value == value {
+			return lv, true
+		}
+	}
+	return Level{}, false
+}
+
+// DefaultLevel returns the level flagged as default, if any
+func (l LevelList) DefaultLevel() (Level, bool) {
+	for _, lv := range l {
+		if lv.Default {
+			return lv, true
+		}
+	}
+	return Level{}, false
+}
+
+// Value implements driver.Valuer
+func (l LevelList) Value() (driver.Value, error) {
+	if l == nil {
+		return nil, nil
+	}
+	b, err := json.Marshal(l)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+// Scan implements sql.Scanner
+func (l *LevelList) Scan(src interface{}) error {
+	*l = nil
+	return scanJSON(src, l)
+}
@@ -0,0 +1,40 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// NormativeDocument is a building code or standard (SP, GOST, SNiP) that
+// defects can be cited against
+type NormativeDocument struct {
+	ID uint `gorm:"primaryKey" json:"id"`
+	// Code is the official designation, e.g. "СП 70.13330.2012"
+	Code  string `gorm:"size:100;uniqueIndex" json:"code"`
+	Title string `gorm:"size:512" json:"title"`
+	// Obsolete documents stay citable by old defects but are hidden from new citations
+	Obsolete  bool              `gorm:"default:false" json:"obsolete"`
+	Clauses   []NormativeClause `gorm:"foreignKey:DocumentID" json:"clauses,omitempty"`
+	CreatedAt time.Time         `json:"created_at"`
+	UpdatedAt time.Time         `json:"updated_at"`
+}
+
+// NormativeClause is a numbered requirement of a normative document
+type NormativeClause struct {
+	ID         uint               `gorm:"primaryKey" json:"id"`
+	DocumentID uint               `gorm:"uniqueIndex:idx_normative_clauses_doc_number" json:"document_id"`
+	Document   *NormativeDocument `gorm:"foreignKey:DocumentID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"document,omitempty"`
+	Number     string             `gorm:"size:50;uniqueIndex:idx_normative_clauses_doc_number" json:"number"`
+	Text       string             `gorm:"type:text" json:"text"`
+	CreatedAt  time.Time          `json:"created_at"`
+	UpdatedAt  time.Time          `json:"updated_at"`
+}
+
+// Citation formats the clause the way it is cited in acts, e.g.
+// "СП 70.13330.2012, п. 5.3.1". The document must be loaded.
+func (c NormativeClause) Citation() string {
+	if c.Document == nil {
+		return "п. " + c.Number
+	}
+	return strings.TrimSpace(c.Document.Code) + ", п. " + c.Number
+}
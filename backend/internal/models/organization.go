@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// Organization is a company taking part in construction: general contractor,
+// subcontractor or supplier. Defects can name the organization responsible for the fix.
+type Organization struct {
+	ID          uint   `gorm:"primaryKey" json:"id"`
+	Name        string `gorm:"size:255;uniqueIndex" json:"name"`
+	Description string `gorm:"type:text" json:"description"`
+	// StorageQuota caps the bytes of attachments of all projects of the
+	// organization; 0 for none
+	StorageQuota int64 `gorm:"not null;default:0" json:"storage_quota"`
+	// QuotaLevel is the last quota threshold warned about, in percent
+	QuotaLevel int       `gorm:"not null;default:0" json:"-"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
@@ -1,11 +1,51 @@
 package models
 
-import "time"
+import (
+	"regexp"
+	"time"
+)
+
+// ProjectKeyPattern accepts 2-10 upper-case letters and digits starting with a letter
+var ProjectKeyPattern = regexp.MustCompile(`^[A-Z][A-Z0-9]{1,9}$`)
 
 type Project struct {
-	ID        uint      `gorm:"primaryKey" json:"id"`
-	Name      string    `gorm:"size:255" json:"name"`
-	Address   string    `gorm:"size:512" json:"address"`
+	ID      uint   `gorm:"primaryKey" json:"id"`
+	Name    string `gorm:"size:255" json:"name"`
+	Address string `gorm:"size:512" json:"address"`
+	// Key prefixes defect keys of the project, e.g. "TWR" in "TWR-142"
+	Key string `gorm:"size:10" json:"key"`
+	// DefectSeq is the last defect number allocated in the project
+	DefectSeq int `gorm:"default:0" json:"-"`
+	// SeverityLevels and PriorityLevels override the defaults for the project
+	SeverityLevels LevelList `gorm:"type:jsonb" json:"severity_levels,omitempty"`
+	PriorityLevels LevelList `gorm:"type:jsonb" json:"priority_levels,omitempty"`
+	// OrganizationID is the organization running the project; its storage
+	// quota covers all its projects
+	OrganizationID *uint         `gorm:"index" json:"organization_id,omitempty"`
+	Organization   *Organization `gorm:"foreignKey:OrganizationID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;" json:"organization,omitempty"`
+	// StorageQuota caps the bytes of attachments of the project; 0 applies
+	// uploads.project_quota
+	StorageQuota int64 `gorm:"not null;default:0" json:"storage_quota"`
+	// QuotaLevel is the last quota threshold warned about, in percent
+	QuotaLevel int `gorm:"not null;default:0" json:"-"`
+	// Version is incremented by every update and served as the ETag
+	Version   int       `gorm:"not null;default:1" json:"version"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 }
+
+// Severities returns the severity levels allowed in the project
+func (p *Project) Severities() LevelList {
+	if len(p.SeverityLevels) > 0 {
+		return p.SeverityLevels
+	}
+	return DefaultSeverityLevels
+}
+
+// Priorities returns the priority levels allowed in the project
+func (p *Project) Priorities() LevelList {
+	if len(p.PriorityLevels) > 0 {
+		return p.PriorityLevels
+	}
+	return DefaultPriorityLevels
+}
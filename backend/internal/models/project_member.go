@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// Project-scoped roles of a member
+const (
+	ProjectRoleMember = "member"
+	ProjectRoleAdmin  = "admin"
+)
+
+// ProjectMember links a user to a project they take part in. Access to
+// project data for engineers and stakeholders is limited to their projects.
+type ProjectMember struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	ProjectID uint      `gorm:"uniqueIndex:idx_project_members_project_user" json:"project_id"`
+	Project   Project   `gorm:"foreignKey:ProjectID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"-"`
+	UserID    uint      `gorm:"uniqueIndex:idx_project_members_project_user;index" json:"user_id"`
+	User      *User     `gorm:"foreignKey:UserID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"user,omitempty"`
+	Role      string    `gorm:"size:50" json:"role"`
+	CreatedAt time.Time `json:"created_at"`
+}
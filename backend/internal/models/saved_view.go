@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// SavedView is a named defect filter of a user. A view either belongs to a
+// project (ProjectID set) or is global and spans all projects of the user.
+// Shared project views are visible to the whole project team.
+type SavedView struct {
+	ID        uint         `gorm:"primaryKey" json:"id"`
+	OwnerID   uint         `gorm:"index" json:"owner_id"`
+	Owner     *User        `gorm:"foreignKey:OwnerID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"owner,omitempty"`
+	ProjectID *uint        `gorm:"index" json:"project_id"`
+	Project   *Project     `gorm:"foreignKey:ProjectID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"-"`
+	Name      string       `gorm:"size:255" json:"name"`
+	Filter    DefectFilter `gorm:"type:jsonb" json:"filter"`
+	Shared    bool         `json:"shared"`
+	IsDefault bool         `json:"is_default"`
+	CreatedAt time.Time    `json:"created_at"`
+	UpdatedAt time.Time    `json:"updated_at"`
+}
@@ -0,0 +1,14 @@
+package models
+
+// SearchHit is a single full-text search result. It is not persisted; Kind tells
+// which table the hit comes from ("defect", "comment" or "attachment").
+type SearchHit struct {
+	Kind      string  `json:"kind"`
+	ID        uint    `json:"id"`
+	DefectID  uint    `json:"defect_id"`
+	DefectKey string  `json:"defect_key"`
+	ProjectID uint    `json:"project_id"`
+	Title     string  `json:"title"`
+	Snippet   string  `json:"snippet"`
+	Rank      float64 `json:"rank"`
+}
@@ -0,0 +1,32 @@
+package models
+
+// ProjectStats aggregates defect counts of a project. It is computed, not stored.
+type ProjectStats struct {
+	ProjectID  uint             `json:"project_id"`
+	Total      int64            `json:"total"`
+	ByStatus   map[string]int64 `json:"by_status"`
+	BySeverity map[string]int64 `json:"by_severity"`
+	ByPriority map[string]int64 `json:"by_priority"`
+	// ByCategory counts defects per trade (top level category code), ByType per
+	// exact category code. Uncategorized defects are counted under "".
+	ByCategory map[string]int64 `json:"by_category"`
+	ByType     map[string]int64 `json:"by_type"`
+	Labels     []LabelUsage     `json:"labels"`
+	// Citations lists the most cited clauses of normative documents
+	Citations []ClauseUsage `json:"citations"`
+}
+
+// ClauseUsage is the number of defects citing a clause
+type ClauseUsage struct {
+	ClauseID uint   `json:"clause_id"`
+	Citation string `json:"citation"`
+	Count    int64  `json:"count"`
+}
+
+// LabelUsage is the number of defects carrying a label
+type LabelUsage struct {
+	LabelID uint   `json:"label_id"`
+	Name    string `json:"name"`
+	Color   string `json:"color"`
+	Count   int64  `json:"count"`
+}
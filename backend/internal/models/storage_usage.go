@@ -0,0 +1,38 @@
+package models
+
+// StorageUsage is the attachment storage of a project against its quota. It
+// is computed, not stored. Sizes are those of the attachments: content
+// shared by several attachments counts once for each.
+type StorageUsage struct {
+	ProjectID uint  `json:"project_id"`
+	Files     int64 `json:"files"`
+	Bytes     int64 `json:"bytes"`
+	// Reserved is the declared size of resumable uploads not finished yet
+	Reserved int64 `json:"reserved"`
+	// Quota is the limit in bytes, 0 for none
+	Quota int64 `json:"quota"`
+	// Percent is the share of the quota used, 0 without a quota
+	Percent       float64       `json:"percent"`
+	ByContentType []UsageBucket `json:"by_content_type"`
+	ByUploader    []UsageBucket `json:"by_uploader"`
+	Organization  *QuotaUsage   `json:"organization,omitempty"`
+}
+
+// UsageBucket is the storage taken by one content type or one uploader
+type UsageBucket struct {
+	ContentType  string `json:"content_type,omitempty"`
+	UploaderID   uint   `json:"uploader_id,omitempty"`
+	UploaderName string `json:"uploader_name,omitempty"`
+	Files        int64  `json:"files"`
+	Bytes        int64  `json:"bytes"`
+}
+
+// QuotaUsage is the storage of an organization's projects against its quota
+type QuotaUsage struct {
+	OrganizationID uint    `json:"organization_id"`
+	Name           string  `json:"name"`
+	Bytes          int64   `json:"bytes"`
+	Reserved       int64   `json:"reserved"`
+	Quota          int64   `json:"quota"`
+	Percent        float64 `json:"percent"`
+}
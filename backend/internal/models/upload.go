@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+// UploadSession is a resumable upload in progress. The bytes received so far
+// live in a partial file; once Offset reaches Length the file becomes an
+// Attachment and AttachmentID is set.
+type UploadSession struct {
+	ID          string `gorm:"primaryKey;size:32" json:"id"`
+	DefectID    uint   `gorm:"index" json:"defect_id"`
+	UploaderID  uint   `gorm:"index" json:"uploader_id"`
+	Filename    string `gorm:"size:512" json:"filename"`
+	ContentType string `gorm:"size:255" json:"content_type"`
+	Kind        string `gorm:"size:20" json:"kind"`
+	BeforeID    *uint  `json:"before_id,omitempty"`
+	// ReplacesID makes the file a new version of that attachment
+	ReplacesID *uint  `json:"replaces_id,omitempty"`
+	Length     int64  `json:"length"`
+	Offset     int64  `json:"offset"`
+	PartPath   string `gorm:"size:1024" json:"-"`
+	// ExpiresAt is pushed forward by every chunk; abandoned sessions are removed after it
+	ExpiresAt    time.Time `gorm:"index" json:"expires_at"`
+	AttachmentID *uint     `json:"attachment_id,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// Complete reports whether all bytes have been received
+func (s *UploadSession) Complete() bool {
+	return s.Offset == s.Length
+}
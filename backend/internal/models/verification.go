@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// Verification decisions
+const (
+	VerificationPending  = "pending"
+	VerificationApproved = "approved"
+	VerificationRejected = "rejected"
+)
+
+// Verification is one submission of remediation evidence for a defect and the
+// verifier's decision on it. Approved verifications back acceptance documents.
+type Verification struct {
+	ID            uint   `gorm:"primaryKey" json:"id"`
+	DefectID      uint   `gorm:"index" json:"defect_id"`
+	Defect        Defect `gorm:"foreignKey:DefectID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"-"`
+	SubmittedByID uint   `json:"submitted_by_id"`
+	SubmittedBy   *User  `gorm:"foreignKey:SubmittedByID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;" json:"submitted_by,omitempty"`
+	// Comment holds the remediation description posted on the defect, Photos the evidence
+	CommentID  *uint        `json:"comment_id,omitempty"`
+	Comment    *Comment     `gorm:"foreignKey:CommentID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;" json:"comment,omitempty"`
+	Photos     []Attachment `gorm:"many2many:verification_attachments;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"photos"`
+	Decision   string       `gorm:"size:20;default:pending;index" json:"decision"`
+	Reason     string       `gorm:"type:text" json:"reason,omitempty"`
+	ReviewerID *uint        `json:"reviewer_id,omitempty"`
+	Reviewer   *User        `gorm:"foreignKey:ReviewerID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;" json:"reviewer,omitempty"`
+	ReviewedAt *time.Time   `json:"reviewed_at,omitempty"`
+	CreatedAt  time.Time    `json:"created_at"`
+}
@@ -0,0 +1,300 @@
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"image"
+	"io"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf16"
+)
+
+// A4 page size in points
+const (
+	PageWidth  = 595.28
+	PageHeight = 841.89
+)
+
+// Document is a PDF being built page by page; coordinates are in points with
+// the origin at the bottom-left corner of the page
+type Document struct {
+	font    *Font
+	pages   []*bytes.Buffer
+	images  []image.Image
+	used    map[uint16]rune
+	Title   string
+	Created time.Time
+}
+
+// New starts an empty document drawing text with font
+func New(font *Font) *Document {
+	return &Document{font: font, used: map[uint16]rune{}, Created: time.Now()}
+}
+
+// AddPage appends a blank page; drawing goes to the last page
+func (d *Document) AddPage() {
+	d.pages = append(d.pages, &bytes.Buffer{})
+}
+
+// Pages returns the number of pages
+func (d *Document) Pages() int {
+	return len(d.pages)
+}
+
+func (d *Document) page() *bytes.Buffer {
+	if len(d.pages) == 0 {
+		d.AddPage()
+	}
+	return d.pages[len(d.pages)-1]
+}
+
+// TextWidth returns the width of s set at size
+func (d *Document) TextWidth(s string, size float64) float64 {
+	w := 0
+	for _, r := range s {
+		w += d.font.width(d.font.glyph(r))
+	}
+	return float64(w) * size / 1000
+}
+
+// Text draws s with its baseline starting at x, y
+func (d *Document) Text(x, y, size float64, s string) {
+	var hex strings.Builder
+	for _, r := range s {
+		g := d.font.glyph(r)
+		if _, ok := d.used[g]; !ok || g == 0 {
+			d.used[g] = r
+		}
+		fmt.Fprintf(&hex, "%04X", g)
+	}
+	fmt.Fprintf(d.page(), "BT /F1 %s Tf %s %s Td <%s> Tj ET\n", num(size), num(x), num(y), hex.String())
+}
+
+// Wrap splits s into lines no wider than width at size, breaking at spaces
+// and inside words longer than a line
+func (d *Document) Wrap(s string, size, width float64) []string {
+	var lines []string
+	for _, para := range strings.Split(s, "\n") {
+		line := ""
+		for _, word := range strings.Fields(para) {
+			next := word
+			if line != "" {
+				next = line + " " + word
+			}
+			if d.TextWidth(next, size) <= width {
+				line = next
+				continue
+			}
+			if line != "" {
+				lines = append(lines, line)
+			}
+			line = word
+			for d.TextWidth(line, size) > width {
+				rs := []rune(line)
+				cut := len(rs) - 1
+				for cut > 1 && d.TextWidth(string(rs[:cut]), size) > width {
+					cut--
+				}
+				lines = append(lines, string(rs[:cut]))
+				line = string(rs[cut:])
+			}
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// Line draws a thin line from x1, y1 to x2, y2
+func (d *Document) Line(x1, y1, x2, y2 float64) {
+	fmt.Fprintf(d.page(), "0.5 w %s %s m %s %s l S\n", num(x1), num(y1), num(x2), num(y2))
+}
+
+// Image draws img scaled into the w by h box whose bottom-left corner is x, y
+func (d *Document) Image(img image.Image, x, y, w, h float64) {
+	d.images = append(d.images, img)
+	fmt.Fprintf(d.page(), "q %s 0 0 %s %s %s cm /Im%d Do Q\n", num(w), num(h), num(x), num(y), len(d.images))
+}
+
+// WriteTo writes the finished document
+func (d *Document) WriteTo(w io.Writer) (int64, error) {
+	if len(d.pages) == 0 {
+		d.AddPage()
+	}
+	out := &writer{}
+	out.buf.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+
+	// fixed objects: 1 catalog, 2 page tree, 3 info, 4-8 font
+	const catalog, pageTree, info, type0, cidFont, descriptor, fontFile, toUnicode = 1, 2, 3, 4, 5, 6, 7, 8
+	next := 9
+	imageObjs := make([]int, len(d.images))
+	for i := range d.images {
+		imageObjs[i] = next
+		next += 2 // image and its alpha mask
+	}
+	pageObjs := make([]int, len(d.pages))
+	for i := range d.pages {
+		pageObjs[i] = next
+		next += 2 // page and its content stream
+	}
+
+	out.object(catalog, fmt.Sprintf("<< /Type /Catalog /Pages %d 0 R >>", pageTree))
+	kids := make([]string, len(pageObjs))
+	for i, p := range pageObjs {
+		kids[i] = fmt.Sprintf("%d 0 R", p)
+	}
+	out.object(pageTree, fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pageObjs)))
+	out.object(info, fmt.Sprintf("<< /Title %s /Producer (defect-control-system) /CreationDate (D:%s) >>",
+		textString(d.Title), d.Created.UTC().Format("20060102150405Z")))
+	d.writeFont(out, type0, cidFont, descriptor, fontFile, toUnicode)
+
+	var xobjects strings.Builder
+	for i, img := range d.images {
+		rgb, alpha, bounds := pixels(img)
+		obj := imageObjs[i]
+		out.stream(obj, fmt.Sprintf("/Type /XObject /Subtype /Image /Width %d /Height %d /ColorSpace /DeviceRGB /BitsPerComponent 8 /SMask %d 0 R",
+			bounds.Dx(), bounds.Dy(), obj+1), rgb)
+		out.stream(obj+1, fmt.Sprintf("/Type /XObject /Subtype /Image /Width %d /Height %d /ColorSpace /DeviceGray /BitsPerComponent 8",
+			bounds.Dx(), bounds.Dy()), alpha)
+		fmt.Fprintf(&xobjects, "/Im%d %d 0 R ", i+1, obj)
+	}
+	resources := fmt.Sprintf("<< /Font << /F1 %d 0 R >> /XObject << %s>> >>", type0, xobjects.String())
+	for i, content := range d.pages {
+		out.object(pageObjs[i], fmt.Sprintf("<< /Type /Page /Parent %d 0 R /MediaBox [0 0 %s %s] /Resources %s /Contents %d 0 R >>",
+			pageTree, num(PageWidth), num(PageHeight), resources, pageObjs[i]+1))
+		out.stream(pageObjs[i]+1, "", content.Bytes())
+	}
+
+	xref := out.buf.Len()
+	fmt.Fprintf(&out.buf, "xref\n0 %d\n0000000000 65535 f \n", next)
+	for i := 1; i < next; i++ {
+		fmt.Fprintf(&out.buf, "%010d 00000 n \n", out.offsets[i])
+	}
+	fmt.Fprintf(&out.buf, "trailer\n<< /Size %d /Root %d 0 R /Info %d 0 R >>\nstartxref\n%d\n%%%%EOF\n", next, catalog, info, xref)
+	return out.buf.WriteTo(w)
+}
+
+// Bytes renders the document into memory
+func (d *Document) Bytes() ([]byte, error) {
+	var buf bytes.Buffer
+	if _, err := d.WriteTo(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// writeFont embeds the font as a composite font addressed by glyph id
+func (d *Document) writeFont(out *writer, type0, cidFont, descriptor, fontFile, toUnicode int) {
+	f := d.font
+	glyphs := make([]int, 0, len(d.used))
+	for g := range d.used {
+		glyphs = append(glyphs, int(g))
+	}
+	sort.Ints(glyphs)
+
+	var widths, cmap strings.Builder
+	for _, g := range glyphs {
+		fmt.Fprintf(&widths, "%d [%d] ", g, f.width(uint16(g)))
+	}
+	var chars []string
+	for _, g := range glyphs {
+		if r := d.used[uint16(g)]; g != 0 {
+			var u strings.Builder
+			for _, c := range utf16.Encode([]rune{r}) {
+				fmt.Fprintf(&u, "%04X", c)
+			}
+			chars = append(chars, fmt.Sprintf("<%04X> <%s>", g, u.String()))
+		}
+	}
+	cmap.WriteString("/CIDInit /ProcSet findresource begin\n12 dict begin\nbegincmap\n" +
+		"/CIDSystemInfo << /Registry (Adobe) /Ordering (UCS) /Supplement 0 >> def\n" +
+		"/CMapName /Adobe-Identity-UCS def\n/CMapType 2 def\n" +
+		"1 begincodespacerange\n<0000> <FFFF>\nendcodespacerange\n")
+	for start := 0; start < len(chars); start += 100 {
+		end := start + 100
+		if end > len(chars) {
+			end = len(chars)
+		}
+		fmt.Fprintf(&cmap, "%d beginbfchar\n%s\nendbfchar\n", end-start, strings.Join(chars[start:end], "\n"))
+	}
+	cmap.WriteString("endcmap\nCMapName currentdict /CMapResource defineresource pop\nend\nend\n")
+
+	out.object(type0, fmt.Sprintf("<< /Type /Font /Subtype /Type0 /BaseFont /%s /Encoding /Identity-H /DescendantFonts [%d 0 R] /ToUnicode %d 0 R >>",
+		f.Name, cidFont, toUnicode))
+	out.object(cidFont, fmt.Sprintf("<< /Type /Font /Subtype /CIDFontType2 /BaseFont /%s "+
+		"/CIDSystemInfo << /Registry (Adobe) /Ordering (Identity) /Supplement 0 >> "+
+		"/FontDescriptor %d 0 R /CIDToGIDMap /Identity /DW 1000 /W [%s] >>", f.Name, descriptor, widths.String()))
+	out.object(descriptor, fmt.Sprintf("<< /Type /FontDescriptor /FontName /%s /Flags 32 /FontBBox [%d %d %d %d] "+
+		"/ItalicAngle 0 /Ascent %d /Descent %d /CapHeight %d /StemV 80 /FontFile2 %d 0 R >>",
+		f.Name, f.scale(f.bbox[0]), f.scale(f.bbox[1]), f.scale(f.bbox[2]), f.scale(f.bbox[3]),
+		f.scale(f.ascent), f.scale(f.descent), f.scale(f.capHeight), fontFile))
+	out.stream(fontFile, fmt.Sprintf("/Length1 %d", len(f.data)), f.data)
+	out.stream(toUnicode, "", []byte(cmap.String()))
+}
+
+// pixels flattens img into RGB samples and an alpha mask
+func pixels(img image.Image) ([]byte, []byte, image.Rectangle) {
+	b := img.Bounds()
+	rgb := make([]byte, 0, b.Dx()*b.Dy()*3)
+	alpha := make([]byte, 0, b.Dx()*b.Dy())
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			r, g, bl, a := img.At(x, y).RGBA()
+			if a > 0 {
+				// un-premultiply so transparent edges keep their colour
+				r, g, bl = r*0xffff/a, g*0xffff/a, bl*0xffff/a
+			}
+			rgb = append(rgb, byte(r>>8), byte(g>>8), byte(bl>>8))
+			alpha = append(alpha, byte(a>>8))
+		}
+	}
+	return rgb, alpha, b
+}
+
+// writer collects numbered objects and their offsets
+type writer struct {
+	buf     bytes.Buffer
+	offsets map[int]int
+}
+
+func (w *writer) object(n int, body string) {
+	if w.offsets == nil {
+		w.offsets = map[int]int{}
+	}
+	w.offsets[n] = w.buf.Len()
+	fmt.Fprintf(&w.buf, "%d 0 obj\n%s\nendobj\n", n, body)
+}
+
+// stream writes a flate-compressed stream object with extra dictionary entries
+func (w *writer) stream(n int, dict string, data []byte) {
+	var z bytes.Buffer
+	zw := zlib.NewWriter(&z)
+	zw.Write(data)
+	zw.Close()
+	if w.offsets == nil {
+		w.offsets = map[int]int{}
+	}
+	w.offsets[n] = w.buf.Len()
+	fmt.Fprintf(&w.buf, "%d 0 obj\n<< %s /Filter /FlateDecode /Length %d >>\nstream\n", n, dict, z.Len())
+	w.buf.Write(z.Bytes())
+	w.buf.WriteString("\nendstream\nendobj\n")
+}
+
+// num formats a coordinate without exponent or trailing zeros
+func num(v float64) string {
+	s := strings.TrimRight(fmt.Sprintf("%.2f", v), "0")
+	return strings.TrimSuffix(s, ".")
+}
+
+// textString encodes s as a UTF-16 PDF text string
+func textString(s string) string {
+	var b strings.Builder
+	b.WriteString("<FEFF")
+	for _, c := range utf16.Encode([]rune(s)) {
+		fmt.Fprintf(&b, "%04X", c)
+	}
+	b.WriteString(">")
+	return b.String()
+}
@@ -0,0 +1,62 @@
+package pdf_test
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"regexp"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"example.com/defect-control-system/internal/pdf"
+)
+
+const testFont = "/usr/share/fonts/truetype/dejavu/DejaVuSans.ttf"
+
+func TestDocument_CyrillicTextAndImage(t *testing.T) {
+	font, err := pdf.LoadFont(testFont)
+	if err != nil {
+		t.Skipf("font not available: %v", err)
+	}
+	assert.Equal(t, "DejaVuSans", font.Name)
+
+	doc := pdf.New(font)
+	doc.Title = "Акт приёмки"
+	doc.Text(50, 800, 14, "Акт № 1 приёмки")
+	assert.Greater(t, doc.TextWidth("Ж", 12), doc.TextWidth("i", 12))
+	lines := doc.Wrap("Трещина в несущей стене у оконного проёма", 12, 120)
+	assert.Greater(t, len(lines), 1)
+	for _, l := range lines {
+		assert.LessOrEqual(t, doc.TextWidth(l, 12), 120.0)
+	}
+	sig := image.NewNRGBA(image.Rect(0, 0, 4, 2))
+	sig.Set(1, 1, color.NRGBA{A: 255})
+	doc.Image(sig, 50, 700, 80, 40)
+	doc.AddPage()
+	doc.Line(50, 800, 500, 800)
+
+	out, err := doc.Bytes()
+	require.NoError(t, err)
+	assert.True(t, bytes.HasPrefix(out, []byte("%PDF-1.4")))
+	assert.Equal(t, 2, doc.Pages())
+
+	// every xref entry points at the start of its object
+	m := regexp.MustCompile(`startxref\n(\d+)\n`).FindSubmatch(out)
+	require.NotNil(t, m)
+	xref, _ := strconv.Atoi(string(m[1]))
+	entries := regexp.MustCompile(`(\d{10}) 00000 n `).FindAllSubmatch(out[xref:], -1)
+	require.NotEmpty(t, entries)
+	for i, e := range entries {
+		off, _ := strconv.Atoi(string(e[1]))
+		assert.True(t, bytes.HasPrefix(out[off:], []byte(fmt.Sprintf("%d 0 obj", i+1))), "object %d", i+1)
+	}
+}
+
+func TestParseFont_Rejects(t *testing.T) {
+	_, err := pdf.ParseFont("x", []byte("not a font at all"))
+	assert.Error(t, err)
+}
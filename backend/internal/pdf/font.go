@@ -0,0 +1,207 @@
+// Package pdf writes simple PDF documents: text in one embedded TrueType font,
+// lines and raster images, enough for printable acts and reports.
+package pdf
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+)
+
+// Font is a parsed TrueType font embedded whole into documents using it
+type Font struct {
+	Name       string
+	data       []byte
+	unitsPerEm int
+	bbox       [4]int
+	ascent     int
+	descent    int
+	capHeight  int
+	advances   []uint16
+	cmap       map[rune]uint16
+	// loca and glyf hold the outlines, for Outline
+	loca     []byte
+	glyf     []byte
+	longLoca bool
+}
+
+var fontNameUnsafe = regexp.MustCompile(`[^A-Za-z0-9-]`)
+
+// LoadFont reads a TrueType (.ttf) font file
+func LoadFont(path string) (*Font, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	name := fontNameUnsafe.ReplaceAllString(filepath.Base(path[:len(path)-len(filepath.Ext(path))]), "")
+	f, err := ParseFont(name, data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return f, nil
+}
+
+var errBadFont = errors.New("invalid or unsupported TrueType font")
+
+// ParseFont parses TrueType font data; name becomes the PDF font name
+func ParseFont(name string, data []byte) (*Font, error) {
+	if len(data) < 12 {
+		return nil, errBadFont
+	}
+	if v := binary.BigEndian.Uint32(data); v != 0x00010000 && v != 0x74727565 {
+		return nil, errBadFont
+	}
+	tables := map[string][]byte{}
+	n := int(binary.BigEndian.Uint16(data[4:]))
+	for i := 0; i < n; i++ {
+		rec := 12 + 16*i
+		if rec+16 > len(data) {
+			return nil, errBadFont
+		}
+		off := int(binary.BigEndian.Uint32(data[rec+8:]))
+		size := int(binary.BigEndian.Uint32(data[rec+12:]))
+		if off < 0 || size < 0 || off+size > len(data) {
+			return nil, errBadFont
+		}
+		tables[string(data[rec:rec+4])] = data[off : off+size]
+	}
+	head, hhea, maxp, hmtx, cmap := tables["head"], tables["hhea"], tables["maxp"], tables["hmtx"], tables["cmap"]
+	if len(head) < 54 || len(hhea) < 36 || len(maxp) < 6 || cmap == nil {
+		return nil, errBadFont
+	}
+	if name == "" {
+		name = "Font"
+	}
+	f := &Font{Name: name, data: data, cmap: map[rune]uint16{}}
+	f.unitsPerEm = int(binary.BigEndian.Uint16(head[18:]))
+	if f.unitsPerEm == 0 {
+		return nil, errBadFont
+	}
+	for i := range f.bbox {
+		f.bbox[i] = int(int16(binary.BigEndian.Uint16(head[36+2*i:])))
+	}
+	f.ascent = int(int16(binary.BigEndian.Uint16(hhea[4:])))
+	f.descent = int(int16(binary.BigEndian.Uint16(hhea[6:])))
+	f.capHeight = f.ascent
+	if os2 := tables["OS/2"]; len(os2) >= 90 && binary.BigEndian.Uint16(os2) >= 2 {
+		f.capHeight = int(int16(binary.BigEndian.Uint16(os2[88:])))
+	}
+	numGlyphs := int(binary.BigEndian.Uint16(maxp[4:]))
+	numMetrics := int(binary.BigEndian.Uint16(hhea[34:]))
+	if numMetrics == 0 || numMetrics > numGlyphs || len(hmtx) < 4*numMetrics {
+		return nil, errBadFont
+	}
+	f.advances = make([]uint16, numGlyphs)
+	for g := 0; g < numGlyphs; g++ {
+		m := g
+		if m >= numMetrics {
+			m = numMetrics - 1
+		}
+		f.advances[g] = binary.BigEndian.Uint16(hmtx[4*m:])
+	}
+	if err := f.parseCmap(cmap); err != nil {
+		return nil, err
+	}
+	f.loca, f.glyf = tables["loca"], tables["glyf"]
+	f.longLoca = binary.BigEndian.Uint16(head[50:]) == 1
+	return f, nil
+}
+
+// parseCmap reads the Unicode mapping, preferring the full-repertoire subtable
+func (f *Font) parseCmap(t []byte) error {
+	if len(t) < 4 {
+		return errBadFont
+	}
+	var fmt4, fmt12 []byte
+	for i := 0; i < int(binary.BigEndian.Uint16(t[2:])); i++ {
+		rec := 4 + 8*i
+		if rec+8 > len(t) {
+			return errBadFont
+		}
+		platform, encoding := binary.BigEndian.Uint16(t[rec:]), binary.BigEndian.Uint16(t[rec+2:])
+		off := int(binary.BigEndian.Uint32(t[rec+4:]))
+		if off+2 > len(t) {
+			return errBadFont
+		}
+		sub := t[off:]
+		switch format := binary.BigEndian.Uint16(sub); {
+		case format == 12 && (platform == 3 && encoding == 10 || platform == 0):
+			fmt12 = sub
+		case format == 4 && (platform == 3 && encoding == 1 || platform == 0):
+			fmt4 = sub
+		}
+	}
+	switch {
+	case fmt12 != nil:
+		if len(fmt12) < 16 {
+			return errBadFont
+		}
+		groups := int(binary.BigEndian.Uint32(fmt12[12:]))
+		if len(fmt12) < 16+12*groups {
+			return errBadFont
+		}
+		for i := 0; i < groups; i++ {
+			g := fmt12[16+12*i:]
+			start, end, gid := binary.BigEndian.Uint32(g), binary.BigEndian.Uint32(g[4:]), binary.BigEndian.Uint32(g[8:])
+			for c := start; c <= end && c <= 0x10FFFF; c++ {
+				f.cmap[rune(c)] = uint16(gid + c - start)
+			}
+		}
+	case fmt4 != nil:
+		if len(fmt4) < 14 {
+			return errBadFont
+		}
+		segX2 := int(binary.BigEndian.Uint16(fmt4[6:]))
+		ends, starts, deltas, ranges := 14, 16+segX2, 16+2*segX2, 16+3*segX2
+		if len(fmt4) < ranges+segX2 {
+			return errBadFont
+		}
+		for s := 0; s < segX2; s += 2 {
+			end := int(binary.BigEndian.Uint16(fmt4[ends+s:]))
+			start := int(binary.BigEndian.Uint16(fmt4[starts+s:]))
+			delta := int(binary.BigEndian.Uint16(fmt4[deltas+s:]))
+			rangeOff := int(binary.BigEndian.Uint16(fmt4[ranges+s:]))
+			for c := start; c <= end && c != 0xFFFF; c++ {
+				gid := 0
+				if rangeOff == 0 {
+					gid = (c + delta) & 0xFFFF
+				} else {
+					at := ranges + s + rangeOff + 2*(c-start)
+					if at+2 > len(fmt4) {
+						continue
+					}
+					if gid = int(binary.BigEndian.Uint16(fmt4[at:])); gid != 0 {
+						gid = (gid + delta) & 0xFFFF
+					}
+				}
+				if gid != 0 {
+					f.cmap[rune(c)] = uint16(gid)
+				}
+			}
+		}
+	default:
+		return errors.New("font has no Unicode character map")
+	}
+	return nil
+}
+
+// glyph returns the glyph of r, 0 (.notdef) when the font lacks it
+func (f *Font) glyph(r rune) uint16 {
+	return f.cmap[r]
+}
+
+// width returns the advance of glyph g in thousandths of the font size
+func (f *Font) width(g uint16) int {
+	if int(g) >= len(f.advances) {
+		return 0
+	}
+	return int(f.advances[g]) * 1000 / f.unitsPerEm
+}
+
+// scale converts font units to thousandths of the font size
+func (f *Font) scale(v int) int {
+	return v * 1000 / f.unitsPerEm
+}
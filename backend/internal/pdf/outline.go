@@ -0,0 +1,207 @@
+package pdf
+
+import "encoding/binary"
+
+// GlyphPoint is a point of a glyph contour in font units, y pointing up.
+// Off-curve points are control points of quadratic Bézier curves; between
+// two of them an on-curve point is implied halfway
+type GlyphPoint struct {
+	X, Y    float64
+	OnCurve bool
+}
+
+// UnitsPerEm is the size of the em square outlines are given in
+func (f *Font) UnitsPerEm() int { return f.unitsPerEm }
+
+// Ascent and Descent are the line extents above and below the baseline in
+// font units; Descent is negative
+func (f *Font) Ascent() int  { return f.ascent }
+func (f *Font) Descent() int { return f.descent }
+
+// Outline returns the contours of the glyph of r, the .notdef glyph when the
+// font lacks it, and its advance, all in font units. Fonts without TrueType
+// outlines give no contours
+func (f *Font) Outline(r rune) ([][]GlyphPoint, int) {
+	g := f.glyph(r)
+	adv := 0
+	if int(g) < len(f.advances) {
+		adv = int(f.advances[g])
+	}
+	return f.outline(g, 0), adv
+}
+
+// maxComponentDepth bounds nesting of composite glyphs
+const maxComponentDepth = 8
+
+// glyphData returns the glyf entry of glyph g
+func (f *Font) glyphData(g uint16) []byte {
+	i := int(g)
+	var from, to int
+	if f.longLoca {
+		if 4*i+8 > len(f.loca) {
+			return nil
+		}
+		from, to = int(binary.BigEndian.Uint32(f.loca[4*i:])), int(binary.BigEndian.Uint32(f.loca[4*i+4:]))
+	} else {
+		if 2*i+4 > len(f.loca) {
+			return nil
+		}
+		from, to = 2*int(binary.BigEndian.Uint16(f.loca[2*i:])), 2*int(binary.BigEndian.Uint16(f.loca[2*i+2:]))
+	}
+	if from >= to || to > len(f.glyf) {
+		return nil
+	}
+	return f.glyf[from:to]
+}
+
+func (f *Font) outline(g uint16, depth int) [][]GlyphPoint {
+	d := f.glyphData(g)
+	if len(d) < 10 {
+		return nil
+	}
+	n := int(int16(binary.BigEndian.Uint16(d)))
+	if n >= 0 {
+		return simpleOutline(d, n)
+	}
+	if depth >= maxComponentDepth {
+		return nil
+	}
+	return f.compositeOutline(d[10:], depth)
+}
+
+// simpleOutline decodes a glyph of n contours
+func simpleOutline(d []byte, n int) [][]GlyphPoint {
+	p := 10
+	if p+2*n+2 > len(d) {
+		return nil
+	}
+	ends := make([]int, n)
+	for i := range ends {
+		ends[i] = int(binary.BigEndian.Uint16(d[p+2*i:]))
+	}
+	p += 2 * n
+	if n == 0 {
+		return nil
+	}
+	count := ends[n-1] + 1
+	p += 2 + int(binary.BigEndian.Uint16(d[p:]))
+	flags := make([]byte, 0, count)
+	for len(flags) < count {
+		if p >= len(d) {
+			return nil
+		}
+		fl := d[p]
+		p++
+		flags = append(flags, fl)
+		if fl&0x08 != 0 {
+			if p >= len(d) {
+				return nil
+			}
+			for k := 0; k < int(d[p]) && len(flags) < count; k++ {
+				flags = append(flags, fl)
+			}
+			p++
+		}
+	}
+	pts := make([]GlyphPoint, count)
+	// coordinates are deltas: x of all points, then y
+	for axis := 0; axis < 2; axis++ {
+		short, same := byte(0x02), byte(0x10)
+		if axis == 1 {
+			short, same = 0x04, 0x20
+		}
+		v := 0
+		for i, fl := range flags {
+			switch {
+			case fl&short != 0:
+				if p >= len(d) {
+					return nil
+				}
+				if fl&same != 0 {
+					v += int(d[p])
+				} else {
+					v -= int(d[p])
+				}
+				p++
+			case fl&same == 0:
+				if p+2 > len(d) {
+					return nil
+				}
+				v += int(int16(binary.BigEndian.Uint16(d[p:])))
+				p += 2
+			}
+			if axis == 0 {
+				pts[i].X = float64(v)
+			} else {
+				pts[i].Y = float64(v)
+			}
+		}
+	}
+	out := make([][]GlyphPoint, 0, n)
+	start := 0
+	for _, end := range ends {
+		if end < start || end >= count {
+			return nil
+		}
+		c := pts[start : end+1]
+		for i := range c {
+			c[i].OnCurve = flags[start+i]&0x01 != 0
+		}
+		out = append(out, c)
+		start = end + 1
+	}
+	return out
+}
+
+// compositeOutline assembles a glyph from transformed component glyphs
+func (f *Font) compositeOutline(d []byte, depth int) [][]GlyphPoint {
+	var out [][]GlyphPoint
+	for p := 0; p+4 <= len(d); {
+		flags := binary.BigEndian.Uint16(d[p:])
+		g := binary.BigEndian.Uint16(d[p+2:])
+		p += 4
+		var dx, dy float64
+		if flags&0x0001 != 0 {
+			if p+4 > len(d) {
+				return out
+			}
+			dx, dy = float64(int16(binary.BigEndian.Uint16(d[p:]))), float64(int16(binary.BigEndian.Uint16(d[p+2:])))
+			p += 4
+		} else {
+			if p+2 > len(d) {
+				return out
+			}
+			dx, dy = float64(int8(d[p])), float64(int8(d[p+1]))
+			p += 2
+		}
+		if flags&0x0002 == 0 {
+			// components aligned by point numbers are placed unshifted
+			dx, dy = 0, 0
+		}
+		f2dot14 := func(i int) float64 { return float64(int16(binary.BigEndian.Uint16(d[p+2*i:]))) / 16384 }
+		a, b, c, e := 1.0, 0.0, 0.0, 1.0
+		switch {
+		case flags&0x0008 != 0 && p+2 <= len(d):
+			a = f2dot14(0)
+			e = a
+			p += 2
+		case flags&0x0040 != 0 && p+4 <= len(d):
+			a, e = f2dot14(0), f2dot14(1)
+			p += 4
+		case flags&0x0080 != 0 && p+8 <= len(d):
+			a, b, c, e = f2dot14(0), f2dot14(1), f2dot14(2), f2dot14(3)
+			p += 8
+		}
+		for _, contour := range f.outline(g, depth+1) {
+			t := make([]GlyphPoint, len(contour))
+			for i, pt := range contour {
+				t[i] = GlyphPoint{X: a*pt.X + c*pt.Y + dx, Y: b*pt.X + e*pt.Y + dy, OnCurve: pt.OnCurve}
+			}
+			out = append(out, t)
+		}
+		if flags&0x0020 == 0 {
+			break
+		}
+	}
+	return out
+}
@@ -0,0 +1,154 @@
+package raster
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+)
+
+// MaxPixels bounds the size of decoded images. An upright RGBA copy takes 4
+// bytes a pixel, 100 MB at the limit, next to the decoded source
+const MaxPixels = 25_000_000
+
+// ErrTooLarge is returned for images of more than MaxPixels pixels
+var ErrTooLarge = errors.New("image too large")
+
+// DecodeOriented decodes a JPEG, PNG or GIF image and turns it upright as its
+// EXIF orientation asks, the way viewers display it. An RGBA source is used as
+// is and mirrored images are flipped in place
+func DecodeOriented(r io.Reader) (*image.RGBA, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if int64(cfg.Width)*int64(cfg.Height) > MaxPixels {
+		return nil, fmt.Errorf("%w: %dx%d", ErrTooLarge, cfg.Width, cfg.Height)
+	}
+	o := exifOrientation(data)
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	img, ok := src.(*image.RGBA)
+	if !ok || img.Rect.Min != (image.Point{}) {
+		b := src.Bounds()
+		img = image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+		draw.Draw(img, img.Bounds(), src, b.Min, draw.Src)
+	}
+	return orient(img, o), nil
+}
+
+// orient applies EXIF orientation o, 1 to 8, to img. Orientations 2 to 4
+// keep the size and are applied in place; the others return a new image
+func orient(img *image.RGBA, o int) *image.RGBA {
+	if o < 2 || o > 8 {
+		return img
+	}
+	w, h := img.Rect.Dx(), img.Rect.Dy()
+	// from maps a pixel of the upright image to the stored one
+	var from func(x, y int) (int, int)
+	switch o {
+	case 2:
+		from = func(x, y int) (int, int) { return w - 1 - x, y }
+	case 3:
+		from = func(x, y int) (int, int) { return w - 1 - x, h - 1 - y }
+	case 4:
+		from = func(x, y int) (int, int) { return x, h - 1 - y }
+	case 5:
+		from = func(x, y int) (int, int) { return y, x }
+	case 6:
+		from = func(x, y int) (int, int) { return y, h - 1 - x }
+	case 7:
+		from = func(x, y int) (int, int) { return w - 1 - y, h - 1 - x }
+	case 8:
+		from = func(x, y int) (int, int) { return w - 1 - y, x }
+	}
+	if o < 5 {
+		// mirrors: swap each pair of pixels once
+		for y := 0; y < h; y++ {
+			for x := 0; x < w; x++ {
+				sx, sy := from(x, y)
+				i, j := img.PixOffset(x, y), img.PixOffset(sx, sy)
+				if i < j {
+					a, b := img.Pix[i:i+4], img.Pix[j:j+4]
+					for k := range a {
+						a[k], b[k] = b[k], a[k]
+					}
+				}
+			}
+		}
+		return img
+	}
+	dw, dh := h, w
+	out := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		for x := 0; x < dw; x++ {
+			sx, sy := from(x, y)
+			copy(out.Pix[out.PixOffset(x, y):][:4], img.Pix[img.PixOffset(sx, sy):][:4])
+		}
+	}
+	return out
+}
+
+// exifOrientation reads the orientation tag of a JPEG's EXIF data, 1 when
+// there is none
+func exifOrientation(data []byte) int {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return 1
+	}
+	for p := 2; p+4 <= len(data) && data[p] == 0xFF; {
+		marker := data[p+1]
+		n := int(binary.BigEndian.Uint16(data[p+2:]))
+		if marker == 0xDA || n < 2 || p+2+n > len(data) {
+			break
+		}
+		seg := data[p+4 : p+2+n]
+		if marker == 0xE1 && bytes.HasPrefix(seg, []byte("Exif\x00\x00")) {
+			return tiffOrientation(seg[6:])
+		}
+		p += 2 + n
+	}
+	return 1
+}
+
+// tiffOrientation finds tag 0x0112 in IFD0 of a TIFF structure
+func tiffOrientation(t []byte) int {
+	if len(t) < 8 {
+		return 1
+	}
+	var order binary.ByteOrder
+	switch string(t[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+	ifd := int(order.Uint32(t[4:]))
+	if ifd < 8 || ifd+2 > len(t) {
+		return 1
+	}
+	n := int(order.Uint16(t[ifd:]))
+	for i := 0; i < n; i++ {
+		e := ifd + 2 + 12*i
+		if e+12 > len(t) {
+			break
+		}
+		if order.Uint16(t[e:]) == 0x0112 && order.Uint16(t[e+2:]) == 3 {
+			return int(order.Uint16(t[e+8:]))
+		}
+	}
+	return 1
+}
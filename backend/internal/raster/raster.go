@@ -0,0 +1,183 @@
+// Package raster fills anti-aliased vector paths onto images, enough to burn
+// annotations into photos: polygons, strokes, ellipses and text.
+package raster
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"sort"
+)
+
+// Point is a position in pixels, y pointing down
+type Point struct {
+	X, Y float64
+}
+
+// Path is a set of closed polygons filled together with the nonzero winding
+// rule, so overlapping polygons of the same orientation form their union
+type Path [][]Point
+
+// subsamples is the number of scanlines sampled per pixel row; coverage
+// along a scanline is exact
+const subsamples = 4
+
+type edge struct {
+	x0, y0, x1, y1 float64
+	dir            int
+}
+
+type crossing struct {
+	x   float64
+	dir int
+}
+
+// Fill paints p onto dst in color c
+func Fill(dst draw.Image, p Path, c color.Color) {
+	var edges []edge
+	minX, minY, maxX, maxY := math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)
+	for _, poly := range p {
+		for i, a := range poly {
+			b := poly[(i+1)%len(poly)]
+			if a.Y == b.Y || math.IsNaN(a.X+a.Y+b.X+b.Y) {
+				continue
+			}
+			e := edge{a.X, a.Y, b.X, b.Y, 1}
+			if a.Y > b.Y {
+				e = edge{b.X, b.Y, a.X, a.Y, -1}
+			}
+			edges = append(edges, e)
+			minX, maxX = math.Min(minX, math.Min(a.X, b.X)), math.Max(maxX, math.Max(a.X, b.X))
+			minY, maxY = math.Min(minY, e.y0), math.Max(maxY, e.y1)
+		}
+	}
+	if len(edges) == 0 {
+		return
+	}
+	r := image.Rect(int(math.Floor(minX)), int(math.Floor(minY)), int(math.Ceil(maxX))+1, int(math.Ceil(maxY))+1).Intersect(dst.Bounds())
+	if r.Empty() {
+		return
+	}
+	sort.Slice(edges, func(i, j int) bool { return edges[i].y0 < edges[j].y0 })
+	mask := image.NewAlpha(r)
+	acc := make([]float64, r.Dx())
+	var active []edge
+	var xs []crossing
+	next := 0
+	for py := r.Min.Y; py < r.Max.Y; py++ {
+		clear(acc)
+		for s := 0; s < subsamples; s++ {
+			sy := float64(py) + (float64(s)+0.5)/subsamples
+			for next < len(edges) && edges[next].y0 <= sy {
+				active = append(active, edges[next])
+				next++
+			}
+			xs = xs[:0]
+			kept := active[:0]
+			for _, e := range active {
+				if e.y1 <= sy {
+					continue
+				}
+				kept = append(kept, e)
+				if e.y0 <= sy {
+					xs = append(xs, crossing{e.x0 + (sy-e.y0)*(e.x1-e.x0)/(e.y1-e.y0), e.dir})
+				}
+			}
+			active = kept
+			sort.Slice(xs, func(i, j int) bool { return xs[i].x < xs[j].x })
+			wind, start := 0, 0.0
+			for _, x := range xs {
+				prev := wind
+				wind += x.dir
+				switch {
+				case prev == 0 && wind != 0:
+					start = x.x
+				case prev != 0 && wind == 0:
+					addSpan(acc, start-float64(r.Min.X), x.x-float64(r.Min.X), 1.0/subsamples)
+				}
+			}
+		}
+		row := mask.Pix[(py-r.Min.Y)*mask.Stride:]
+		for i, a := range acc {
+			row[i] = uint8(math.Min(a, 1)*255 + 0.5)
+		}
+	}
+	draw.DrawMask(dst, r, image.NewUniform(c), image.Point{}, mask, r.Min, draw.Over)
+}
+
+// addSpan adds weight w times the covered share of every pixel between x0
+// and x1
+func addSpan(acc []float64, x0, x1, w float64) {
+	x0, x1 = math.Max(x0, 0), math.Min(x1, float64(len(acc)))
+	if x1 <= x0 {
+		return
+	}
+	i0, i1 := int(x0), int(x1)
+	if i0 == i1 {
+		acc[i0] += (x1 - x0) * w
+		return
+	}
+	acc[i0] += (float64(i0+1) - x0) * w
+	for i := i0 + 1; i < i1; i++ {
+		acc[i] += w
+	}
+	if i1 < len(acc) {
+		acc[i1] += (x1 - float64(i1)) * w
+	}
+}
+
+// oriented returns poly wound clockwise on screen, reversing it if needed
+func oriented(poly []Point) []Point {
+	area := 0.0
+	for i, a := range poly {
+		b := poly[(i+1)%len(poly)]
+		area += a.X*b.Y - b.X*a.Y
+	}
+	if area < 0 {
+		for i, j := 0, len(poly)-1; i < j; i, j = i+1, j-1 {
+			poly[i], poly[j] = poly[j], poly[i]
+		}
+	}
+	return poly
+}
+
+// Stroke outlines the polyline pts with a line of the given width with round
+// joins and caps; closed connects the last point back to the first
+func Stroke(pts []Point, width float64, closed bool) Path {
+	h := width / 2
+	var p Path
+	n := len(pts)
+	segments := n - 1
+	if closed && n > 2 {
+		segments = n
+	}
+	for i := 0; i < segments; i++ {
+		a, b := pts[i], pts[(i+1)%n]
+		dx, dy := b.X-a.X, b.Y-a.Y
+		l := math.Hypot(dx, dy)
+		if l == 0 {
+			continue
+		}
+		nx, ny := -dy/l*h, dx/l*h
+		p = append(p, oriented([]Point{{a.X + nx, a.Y + ny}, {b.X + nx, b.Y + ny}, {b.X - nx, b.Y - ny}, {a.X - nx, a.Y - ny}}))
+	}
+	for _, a := range pts {
+		p = append(p, Ellipse(a.X, a.Y, h, h))
+	}
+	return p
+}
+
+// Ellipse approximates an axis-aligned ellipse by a polygon fine enough to
+// look smooth at its size
+func Ellipse(cx, cy, rx, ry float64) []Point {
+	rx, ry = math.Abs(rx), math.Abs(ry)
+	n := int(math.Ceil(math.Pi * (rx + ry) / 3))
+	n = min(max(n, 8), 256)
+	pts := make([]Point, n)
+	for i := range pts {
+		a := 2 * math.Pi * float64(i) / float64(n)
+		pts[i] = Point{cx + rx*math.Cos(a), cy + ry*math.Sin(a)}
+	}
+	return oriented(pts)
+}
@@ -0,0 +1,96 @@
+package raster_test
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"example.com/defect-control-system/internal/pdf"
+	"example.com/defect-control-system/internal/raster"
+)
+
+func TestFill_CoverageAndWinding(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 20, 20))
+	red := color.RGBA{R: 255, A: 255}
+	raster.Fill(img, raster.Path{{{2, 2}, {10.5, 2}, {10.5, 8}, {2, 8}}}, red)
+	assert.Equal(t, red, img.RGBAAt(5, 5))
+	assert.Equal(t, color.RGBA{}, img.RGBAAt(12, 5))
+	// the right edge halves the last column
+	assert.InDelta(t, 128, int(img.RGBAAt(10, 5).A), 2)
+
+	// overlapping strokes form their union instead of cancelling out
+	img = image.NewRGBA(image.Rect(0, 0, 20, 20))
+	raster.Fill(img, raster.Stroke([]raster.Point{{2, 10}, {18, 10}, {10, 2}}, 4, false), red)
+	assert.Equal(t, red, img.RGBAAt(10, 10))
+	assert.Equal(t, red, img.RGBAAt(17, 9))
+	assert.Equal(t, color.RGBA{}, img.RGBAAt(10, 17))
+}
+
+func TestText(t *testing.T) {
+	font, err := pdf.LoadFont("/usr/share/fonts/truetype/dejavu/DejaVuSans.ttf")
+	if err != nil {
+		t.Skipf("font not available: %v", err)
+	}
+	p, w, h := raster.Text(font, 0, 0, 20, "Трещина\nI")
+	assert.Greater(t, w, 60.0)
+	assert.InDelta(t, 2*20*float64(font.Ascent()-font.Descent())/float64(font.UnitsPerEm()), h, 0.01)
+	img := image.NewRGBA(image.Rect(0, 0, int(w)+1, int(h)+1))
+	raster.Fill(img, p, color.Black)
+	inked := 0
+	for i := 3; i < len(img.Pix); i += 4 {
+		if img.Pix[i] > 0 {
+			inked++
+		}
+	}
+	assert.Greater(t, inked, 200)
+	// "О" is a ring: the counter stays clear
+	p, w, h = raster.Text(font, 0, 0, 40, "О")
+	img = image.NewRGBA(image.Rect(0, 0, int(w)+1, int(h)+1))
+	raster.Fill(img, p, color.Black)
+	assert.Zero(t, img.RGBAAt(int(w/2), int(h/2)).A)
+}
+
+func TestDecodeOriented(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 40, 20))
+	for y := 0; y < 20; y++ {
+		for x := 0; x < 40; x++ {
+			src.Set(x, y, color.White)
+		}
+	}
+	// a black block at the top-left of the stored image
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			src.Set(x, y, color.Black)
+		}
+	}
+	var buf bytes.Buffer
+	require.NoError(t, jpeg.Encode(&buf, src, &jpeg.Options{Quality: 95}))
+
+	img, err := raster.DecodeOriented(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	assert.Equal(t, image.Rect(0, 0, 40, 20), img.Bounds())
+
+	// orientation 6: rotate 90° clockwise to display
+	tiff := []byte("MM\x00\x2a\x00\x00\x00\x08\x00\x01\x01\x12\x00\x03\x00\x00\x00\x01\x00\x06\x00\x00\x00\x00\x00\x00")
+	app1 := append([]byte{0xFF, 0xE1, 0, byte(2 + 6 + len(tiff))}, append([]byte("Exif\x00\x00"), tiff...)...)
+	rotated := append(append([]byte{0xFF, 0xD8}, app1...), buf.Bytes()[2:]...)
+	img, err = raster.DecodeOriented(bytes.NewReader(rotated))
+	require.NoError(t, err)
+	assert.Equal(t, image.Rect(0, 0, 20, 40), img.Bounds())
+	assert.Less(t, img.RGBAAt(16, 3).R, uint8(64), "the block moves to the top-right")
+	assert.Greater(t, img.RGBAAt(3, 3).R, uint8(192))
+
+	// orientation 3: turned half round, flipped in place
+	tiff[len(tiff)-7] = 3
+	app1 = append([]byte{0xFF, 0xE1, 0, byte(2 + 6 + len(tiff))}, append([]byte("Exif\x00\x00"), tiff...)...)
+	img, err = raster.DecodeOriented(bytes.NewReader(append(append([]byte{0xFF, 0xD8}, app1...), buf.Bytes()[2:]...)))
+	require.NoError(t, err)
+	assert.Equal(t, image.Rect(0, 0, 40, 20), img.Bounds())
+	assert.Less(t, img.RGBAAt(36, 16).R, uint8(64), "the block moves to the bottom-right")
+	assert.Greater(t, img.RGBAAt(3, 3).R, uint8(192))
+}
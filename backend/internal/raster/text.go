@@ -0,0 +1,82 @@
+package raster
+
+import (
+	"math"
+	"strings"
+
+	"example.com/defect-control-system/internal/pdf"
+)
+
+// Text lays out s, lines split at \n, in font f at size pixels per em with
+// the top-left corner of the first line at (x, y). It returns the glyph
+// outlines and the width and height of the text block
+func Text(f *pdf.Font, x, y, size float64, s string) (Path, float64, float64) {
+	scale := size / float64(f.UnitsPerEm())
+	lineHeight := float64(f.Ascent()-f.Descent()) * scale
+	var p Path
+	width := 0.0
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		baseline := y + float64(i)*lineHeight + float64(f.Ascent())*scale
+		pen := x
+		for _, r := range line {
+			contours, adv := f.Outline(r)
+			for _, c := range contours {
+				tf := func(g pdf.GlyphPoint) Point { return Point{pen + g.X*scale, baseline - g.Y*scale} }
+				if poly := flatten(c, tf); len(poly) > 2 {
+					p = append(p, poly)
+				}
+			}
+			pen += float64(adv) * scale
+		}
+		width = math.Max(width, pen-x)
+	}
+	return p, width, float64(len(lines)) * lineHeight
+}
+
+// flatten converts a glyph contour of quadratic curves to a polygon
+func flatten(c []pdf.GlyphPoint, tf func(pdf.GlyphPoint) Point) []Point {
+	if len(c) == 0 {
+		return nil
+	}
+	// make the implied on-curve points between off-curve ones explicit
+	pts := make([]pdf.GlyphPoint, 0, 2*len(c))
+	start := -1
+	for i, cur := range c {
+		next := c[(i+1)%len(c)]
+		pts = append(pts, cur)
+		if cur.OnCurve && start < 0 {
+			start = len(pts) - 1
+		}
+		if !cur.OnCurve && !next.OnCurve {
+			pts = append(pts, pdf.GlyphPoint{X: (cur.X + next.X) / 2, Y: (cur.Y + next.Y) / 2, OnCurve: true})
+			if start < 0 {
+				start = len(pts) - 1
+			}
+		}
+	}
+	n := len(pts)
+	at := func(i int) pdf.GlyphPoint { return pts[(start+i)%n] }
+	prev := tf(at(0))
+	out := []Point{prev}
+	for i := 1; i <= n; {
+		g := at(i)
+		if g.OnCurve {
+			prev = tf(g)
+			out = append(out, prev)
+			i++
+			continue
+		}
+		ctrl, end := tf(g), tf(at(i+1))
+		steps := int(math.Ceil((math.Hypot(ctrl.X-prev.X, ctrl.Y-prev.Y) + math.Hypot(end.X-ctrl.X, end.Y-ctrl.Y)) / 3))
+		steps = min(max(steps, 1), 16)
+		for k := 1; k <= steps; k++ {
+			t := float64(k) / float64(steps)
+			u := 1 - t
+			out = append(out, Point{u*u*prev.X + 2*u*t*ctrl.X + t*t*end.X, u*u*prev.Y + 2*u*t*ctrl.Y + t*t*end.Y})
+		}
+		prev = end
+		i += 2
+	}
+	return out
+}
@@ -0,0 +1,23 @@
+package repository
+
+import (
+	"context"
+
+	"example.com/defect-control-system/internal/models"
+)
+
+type ActRepository interface {
+	// Create numbers the act within its project and inserts it with its
+	// signatories, linking the existing defects
+	Create(ctx context.Context, a *models.AcceptanceAct) error
+	// FindByID loads the act with its defects and signatories
+	FindByID(ctx context.Context, id uint) (*models.AcceptanceAct, error)
+	ListByProject(ctx context.Context, projectID uint) ([]*models.AcceptanceAct, error)
+	// Sign stores the signature of a signatory who has not signed yet; when
+	// sealed is given the act is marked signed with its file in the same
+	// transaction. ErrVersionConflict is returned when the signatory already
+	// signed, the act is no longer a draft, or sealed does not match the
+	// signatories left: given while others still have to sign, or missing
+	// for the last signature
+	Sign(ctx context.Context, s *models.ActSignatory, sealed *models.AcceptanceAct) error
+}
@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"context"
+
+	"example.com/defect-control-system/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type actRepoPG struct{ db *gorm.DB }
+
+func NewActRepository(db *gorm.DB) ActRepository {
+	return &actRepoPG{db: db}
+}
+
+// Create locks the project row so concurrent acts of a project get distinct numbers
+func (r *actRepoPG) Create(ctx context.Context, a *models.AcceptanceAct) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var p models.Project
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&p, a.ProjectID).Error; err != nil {
+			return err
+		}
+		var last int
+		if err := tx.Model(&models.AcceptanceAct{}).Where("project_id = ?", a.ProjectID).
+			Select("COALESCE(MAX(number), 0)").Scan(&last).Error; err != nil {
+			return err
+		}
+		a.Number = last + 1
+		// defects exist already: link them without upserting
+		return tx.Omit("Defects.*").Create(a).Error
+	})
+}
+
+func (r *actRepoPG) FindByID(ctx context.Context, id uint) (*models.AcceptanceAct, error) {
+	var a models.AcceptanceAct
+	err := r.db.WithContext(ctx).
+		Preload("Defects", func(db *gorm.DB) *gorm.DB { return db.Order("number") }).
+		Preload("Defects.VerifiedBy").Preload("Defects.ResponsibleOrg").
+		Preload("Signatories", func(db *gorm.DB) *gorm.DB { return db.Order("id") }).
+		Preload("Signatories.User").Preload("CreatedBy").
+		First(&a, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+func (r *actRepoPG) ListByProject(ctx context.Context, projectID uint) ([]*models.AcceptanceAct, error) {
+	var list []*models.AcceptanceAct
+	err := r.db.WithContext(ctx).
+		Preload("Defects", func(db *gorm.DB) *gorm.DB {
+			return db.Select("defects.id", "defects.key", "defects.title").Order("number")
+		}).
+		Preload("Signatories", func(db *gorm.DB) *gorm.DB { return db.Order("id") }).
+		Preload("Signatories.User").
+		Where("project_id = ?", projectID).Order("number desc").Find(&list).Error
+	if err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func (r *actRepoPG) Sign(ctx context.Context, s *models.ActSignatory, sealed *models.AcceptanceAct) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// only drafts take signatures; the lock orders concurrent signers
+		var a models.AcceptanceAct
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").
+			Where("status = ?", models.ActDraft).First(&a, s.ActID).Error
+		if err == gorm.ErrRecordNotFound {
+			return ErrVersionConflict
+		}
+		if err != nil {
+			return err
+		}
+		res := tx.Model(s).Where("signed_at IS NULL").
+			Select("method", "image", "typed_name", "signed_at").Updates(s)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrVersionConflict
+		}
+		// counted under the lock: the caller's count may predate a signer
+		// that committed meanwhile
+		var unsigned int64
+		if err := tx.Model(&models.ActSignatory{}).Where("act_id = ? AND signed_at IS NULL", s.ActID).
+			Count(&unsigned).Error; err != nil {
+			return err
+		}
+		if sealed == nil {
+			if unsigned == 0 {
+				// the last signature must seal the act
+				return ErrVersionConflict
+			}
+			return nil
+		}
+		// every other signatory must have signed before this one sealed the act
+		if unsigned > 0 {
+			return ErrVersionConflict
+		}
+		return tx.Model(sealed).Select("status", "path", "sha256", "size", "signed_at").Updates(sealed).Error
+	})
+}
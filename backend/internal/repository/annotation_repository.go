@@ -0,0 +1,20 @@
+package repository
+
+import (
+	"context"
+
+	"example.com/defect-control-system/internal/models"
+)
+
+type AnnotationRepository interface {
+	// FindByAttachment returns the annotation of an attachment, nil if it has none
+	FindByAttachment(ctx context.Context, attachmentID uint) (*models.Annotation, error)
+	// Create inserts the first annotation of an attachment; ErrVersionConflict
+	// is returned when one was created concurrently
+	Create(ctx context.Context, a *models.Annotation) error
+	// Update replaces the shapes guarded by the version read, which is
+	// incremented on success
+	Update(ctx context.Context, a *models.Annotation) error
+	// Delete removes the annotation if it still has the version read
+	Delete(ctx context.Context, a *models.Annotation) error
+}
@@ -0,0 +1,47 @@
+package repository
+
+import (
+	"context"
+
+	"example.com/defect-control-system/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type annotationRepoPG struct{ db *gorm.DB }
+
+func NewAnnotationRepository(db *gorm.DB) AnnotationRepository {
+	return &annotationRepoPG{db: db}
+}
+
+func (r *annotationRepoPG) FindByAttachment(ctx context.Context, attachmentID uint) (*models.Annotation, error) {
+	var a models.Annotation
+	err := r.db.WithContext(ctx).Where("attachment_id = ?", attachmentID).First(&a).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+func (r *annotationRepoPG) Create(ctx context.Context, a *models.Annotation) error {
+	res := r.db.WithContext(ctx).Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "attachment_id"}}, DoNothing: true}).Create(a)
+	if res.Error == nil && res.RowsAffected == 0 {
+		return ErrVersionConflict
+	}
+	return res.Error
+}
+
+func (r *annotationRepoPG) Update(ctx context.Context, a *models.Annotation) error {
+	return versionedUpdate(r.db.WithContext(ctx).Omit(clause.Associations), a, &a.Version, []string{"shapes", "updated_by_id"})
+}
+
+func (r *annotationRepoPG) Delete(ctx context.Context, a *models.Annotation) error {
+	res := r.db.WithContext(ctx).Where("version = ?", a.Version).Delete(a)
+	if res.Error == nil && res.RowsAffected == 0 {
+		return ErrVersionConflict
+	}
+	return res.Error
+}
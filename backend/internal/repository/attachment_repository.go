@@ -10,4 +10,12 @@ type AttachmentRepository interface {
 	Create(ctx context.Context, a *models.Attachment) error
 	FindByID(ctx context.Context, id uint) (*models.Attachment, error)
 	ListByDefect(ctx context.Context, defectID uint) ([]*models.Attachment, error)
+	// ListByProject returns the attachments of all defects of a project with
+	// their defect, ordered by defect number
+	ListByProject(ctx context.Context, projectID uint) ([]*models.Attachment, error)
+	// ListVersions returns the versions of the file whose first version is
+	// rootID, newest first
+	ListVersions(ctx context.Context, rootID uint) ([]*models.Attachment, error)
+	// Update writes the given columns
+	Update(ctx context.Context, a *models.Attachment, columns []string) error
 }
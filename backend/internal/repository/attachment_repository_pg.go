@@ -25,8 +25,28 @@ func (r *attachmentRepoPG) FindByID(ctx context.Context, id uint) (*models.Attac
 
 func (r *attachmentRepoPG) ListByDefect(ctx context.Context, defectID uint) ([]*models.Attachment, error) {
 	var list []*models.Attachment
-	if err := r.db.WithContext(ctx).Where("defect_id = ?", defectID).Find(&list).Error; err != nil {
+	if err := r.db.WithContext(ctx).Where("defect_id = ?", defectID).Order("id").Find(&list).Error; err != nil {
 		return nil, err
 	}
 	return list, nil
 }
+
+func (r *attachmentRepoPG) ListVersions(ctx context.Context, rootID uint) ([]*models.Attachment, error) {
+	var list []*models.Attachment
+	err := r.db.WithContext(ctx).Where("id = ? OR origin_id = ?", rootID, rootID).Order("version DESC").Find(&list).Error
+	return list, err
+}
+
+func (r *attachmentRepoPG) ListByProject(ctx context.Context, projectID uint) ([]*models.Attachment, error) {
+	var list []*models.Attachment
+	err := r.db.WithContext(ctx).Joins("Defect").Where(`"Defect".project_id = ?`, projectID).
+		Order(`"Defect".number, attachments.id`).Find(&list).Error
+	if err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func (r *attachmentRepoPG) Update(ctx context.Context, a *models.Attachment, columns []string) error {
+	return r.db.WithContext(ctx).Model(a).Select(columns).Updates(a).Error
+}
@@ -0,0 +1,27 @@
+package repository
+
+import (
+	"context"
+
+	"example.com/defect-control-system/internal/models"
+)
+
+type BlobRepository interface {
+	// Attach makes attachment a reference blob b in one transaction: quota
+	// runs first unless nil, the blob row is created or its count
+	// incremented, place puts the file in position while the row is locked,
+	// then a is created, or updated when it already has an ID. A new version
+	// supersedes the version before it in the same transaction;
+	// ErrVersionConflict if that one is gone or superseded
+	Attach(ctx context.Context, a *models.Attachment, b *models.Blob, quota *QuotaGuard, place func() error) error
+	// Detach deletes attachment a and drops its reference to the blob in one
+	// transaction. When it was the last, remove deletes the file while the
+	// blob row is still locked, then the row goes; a file stored before content
+	// addressing is removed as a blob of its own. Deleting the latest version
+	// of a file makes the newest remaining one current. Photos of a pending or
+	// approved verification are ErrInUse; an attachment already gone is
+	// ErrVersionConflict
+	Detach(ctx context.Context, a *models.Attachment, remove func(b *models.Blob) error) error
+	// Legacy lists attachments stored before content addressing, by id after afterID
+	Legacy(ctx context.Context, afterID uint, limit int) ([]*models.Attachment, error)
+}
@@ -0,0 +1,117 @@
+package repository
+
+import (
+	"context"
+
+	"example.com/defect-control-system/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type blobRepoPG struct{ db *gorm.DB }
+
+func NewBlobRepository(db *gorm.DB) BlobRepository { return &blobRepoPG{db: db} }
+
+// attachBlob counts a reference to b and stores a within tx. Holding the blob
+// row lock while placing the file keeps a concurrent release of the last
+// reference from deleting it in between
+func attachBlob(tx *gorm.DB, a *models.Attachment, b *models.Blob, place func() error) error {
+	if a.ID == 0 && a.OriginID != nil {
+		if err := supersede(tx, a); err != nil {
+			return err
+		}
+	}
+	b.RefCount = 1
+	err := tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "sha256"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{"ref_count": gorm.Expr("blobs.ref_count + 1"), "updated_at": gorm.Expr("now()")}),
+	}).Create(b).Error
+	if err != nil {
+		return err
+	}
+	if err := place(); err != nil {
+		return err
+	}
+	a.Path, a.SHA256, a.Size = b.Path, b.SHA256, b.Size
+	if a.ID != 0 {
+		return tx.Model(a).Select("path", "sha256", "size").Updates(a).Error
+	}
+	return tx.Create(a).Error
+}
+
+// supersede marks the version before new version a superseded
+func supersede(tx *gorm.DB, a *models.Attachment) error {
+	res := tx.Model(&models.Attachment{}).
+		Where("(id = ? OR origin_id = ?) AND version = ? AND NOT superseded", *a.OriginID, *a.OriginID, a.Version-1).
+		Update("superseded", true)
+	if res.Error == nil && res.RowsAffected == 0 {
+		return ErrVersionConflict
+	}
+	return res.Error
+}
+
+func (r *blobRepoPG) Attach(ctx context.Context, a *models.Attachment, b *models.Blob, quota *QuotaGuard, place func() error) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := guardQuota(tx, quota); err != nil {
+			return err
+		}
+		return attachBlob(tx, a, b, place)
+	})
+}
+
+func (r *blobRepoPG) Detach(ctx context.Context, a *models.Attachment, remove func(b *models.Blob) error) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var used int64
+		err := tx.Table("verification_attachments AS va").Joins("JOIN verifications v ON v.id = va.verification_id").
+			Where("va.attachment_id = ? AND v.decision <> ?", a.ID, models.VerificationRejected).Count(&used).Error
+		if err != nil {
+			return err
+		}
+		if used > 0 {
+			return ErrInUse
+		}
+		res := tx.Delete(&models.Attachment{}, a.ID)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrVersionConflict
+		}
+		if !a.Superseded {
+			// the newest remaining version becomes current
+			root := a.Root()
+			err := tx.Model(&models.Attachment{}).
+				Where("id = (?)", tx.Model(&models.Attachment{}).Select("id").
+					Where("id = ? OR origin_id = ?", root, root).Order("version DESC").Limit(1)).
+				Update("superseded", false).Error
+			if err != nil {
+				return err
+			}
+		}
+		if a.SHA256 == "" {
+			return remove(&models.Blob{Path: a.Path})
+		}
+		var b models.Blob
+		err = tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("sha256 = ?", a.SHA256).First(&b).Error
+		if err == gorm.ErrRecordNotFound {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if b.RefCount > 1 {
+			return tx.Model(&b).Update("ref_count", gorm.Expr("ref_count - 1")).Error
+		}
+		if err := remove(&b); err != nil {
+			return err
+		}
+		return tx.Delete(&b).Error
+	})
+}
+
+func (r *blobRepoPG) Legacy(ctx context.Context, afterID uint, limit int) ([]*models.Attachment, error) {
+	var list []*models.Attachment
+	err := r.db.WithContext(ctx).Where("(sha256 IS NULL OR sha256 = '') AND id > ?", afterID).
+		Order("id").Limit(limit).Find(&list).Error
+	return list, err
+}
@@ -0,0 +1,21 @@
+package repository
+
+import (
+	"context"
+
+	"example.com/defect-control-system/internal/models"
+)
+
+type CategoryRepository interface {
+	Create(ctx context.Context, c *models.DefectCategory) error
+	FindByID(ctx context.Context, id uint) (*models.DefectCategory, error)
+	FindByCode(ctx context.Context, code string) (*models.DefectCategory, error)
+	// List returns the whole catalog ordered by code
+	List(ctx context.Context) ([]*models.DefectCategory, error)
+	Update(ctx context.Context, c *models.DefectCategory) error
+	Delete(ctx context.Context, id uint) error
+	// Import saves the categories in order in one transaction, creating those
+	// without an id. A new DefaultOrganization is created first, and ParentID
+	// is taken from Parent, which may be saved earlier in the list
+	Import(ctx context.Context, list []*models.DefectCategory) error
+}
@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"context"
+
+	"example.com/defect-control-system/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type categoryRepoPG struct{ db *gorm.DB }
+
+func NewCategoryRepository(db *gorm.DB) CategoryRepository { return &categoryRepoPG{db: db} }
+
+func (r *categoryRepoPG) Create(ctx context.Context, c *models.DefectCategory) error {
+	return r.db.WithContext(ctx).Omit(clause.Associations).Create(c).Error
+}
+
+func (r *categoryRepoPG) FindByID(ctx context.Context, id uint) (*models.DefectCategory, error) {
+	var c models.DefectCategory
+	if err := r.db.WithContext(ctx).Preload("DefaultOrganization").First(&c, id).Error; err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+func (r *categoryRepoPG) FindByCode(ctx context.Context, code string) (*models.DefectCategory, error) {
+	var c models.DefectCategory
+	if err := r.db.WithContext(ctx).Where("code = ?", code).First(&c).Error; err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+func (r *categoryRepoPG) List(ctx context.Context) ([]*models.DefectCategory, error) {
+	var list []*models.DefectCategory
+	if err := r.db.WithContext(ctx).Preload("DefaultOrganization").Order("code").Find(&list).Error; err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func (r *categoryRepoPG) Update(ctx context.Context, c *models.DefectCategory) error {
+	return r.db.WithContext(ctx).Omit(clause.Associations).Save(c).Error
+}
+
+func (r *categoryRepoPG) Delete(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Delete(&models.DefectCategory{}, id).Error
+}
+
+func (r *categoryRepoPG) Import(ctx context.Context, list []*models.DefectCategory) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, c := range list {
+			if o := c.DefaultOrganization; o != nil {
+				if o.ID == 0 {
+					if err := tx.Create(o).Error; err != nil {
+						return err
+					}
+				}
+				c.DefaultOrganizationID = &o.ID
+			}
+			if c.Parent != nil {
+				c.ParentID = &c.Parent.ID
+			}
+			if err := tx.Omit(clause.Associations).Save(c).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
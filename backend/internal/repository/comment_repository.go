@@ -8,6 +8,7 @@ import (
 
 type CommentRepository interface {
 	Create(ctx context.Context, c *models.Comment) error
-	ListByDefect(ctx context.Context, defectID uint) ([]*models.Comment, error)
+	// ListByDefect returns comments of a defect; internal comments are skipped unless includeInternal is set
+	ListByDefect(ctx context.Context, defectID uint, includeInternal bool) ([]*models.Comment, error)
 	FindByID(ctx context.Context, id uint) (*models.Comment, error)
 }
@@ -15,9 +15,13 @@ func (r *commentRepoPG) Create(ctx context.Context, c *models.Comment) error {
 	return r.db.WithContext(ctx).Create(c).Error
 }
 
-func (r *commentRepoPG) ListByDefect(ctx context.Context, defectID uint) ([]*models.Comment, error) {
+func (r *commentRepoPG) ListByDefect(ctx context.Context, defectID uint, includeInternal bool) ([]*models.Comment, error) {
 	var list []*models.Comment
-	if err := r.db.WithContext(ctx).Where("defect_id = ?", defectID).Preload("Author").Order("created_at asc").Find(&list).Error; err != nil {
+	q := r.db.WithContext(ctx).Where("defect_id = ?", defectID)
+	if !includeInternal {
+		q = q.Where("visibility = ?", models.CommentVisibilityPublic)
+	}
+	if err := q.Preload("Author").Order("created_at asc").Find(&list).Error; err != nil {
 		return nil, err
 	}
 	return list, nil
@@ -0,0 +1,17 @@
+package repository
+
+import (
+	"context"
+
+	"example.com/defect-control-system/internal/models"
+)
+
+type CustomFieldRepository interface {
+	Create(ctx context.Context, f *models.CustomField) error
+	FindByID(ctx context.Context, id uint) (*models.CustomField, error)
+	ListByProject(ctx context.Context, projectID uint) ([]*models.CustomField, error)
+	ListByProjects(ctx context.Context, projectIDs []uint) ([]*models.CustomField, error)
+	Update(ctx context.Context, f *models.CustomField) error
+	// Delete removes the definition and its stored values from the project's defects
+	Delete(ctx context.Context, f *models.CustomField) error
+}
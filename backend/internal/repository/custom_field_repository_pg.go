@@ -0,0 +1,53 @@
+package repository
+
+import (
+	"context"
+
+	"example.com/defect-control-system/internal/models"
+	"gorm.io/gorm"
+)
+
+type customFieldRepoPG struct{ db *gorm.DB }
+
+func NewCustomFieldRepository(db *gorm.DB) CustomFieldRepository { return &customFieldRepoPG{db: db} }
+
+func (r *customFieldRepoPG) Create(ctx context.Context, f *models.CustomField) error {
+	return r.db.WithContext(ctx).Create(f).Error
+}
+
+func (r *customFieldRepoPG) FindByID(ctx context.Context, id uint) (*models.CustomField, error) {
+	var f models.CustomField
+	if err := r.db.WithContext(ctx).First(&f, id).Error; err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
+
+func (r *customFieldRepoPG) ListByProject(ctx context.Context, projectID uint) ([]*models.CustomField, error) {
+	return r.ListByProjects(ctx, []uint{projectID})
+}
+
+func (r *customFieldRepoPG) ListByProjects(ctx context.Context, projectIDs []uint) ([]*models.CustomField, error) {
+	var list []*models.CustomField
+	if len(projectIDs) == 0 {
+		return list, nil
+	}
+	if err := r.db.WithContext(ctx).Where("project_id IN ?", projectIDs).Order("project_id, position, id").Find(&list).Error; err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func (r *customFieldRepoPG) Update(ctx context.Context, f *models.CustomField) error {
+	return r.db.WithContext(ctx).Save(f).Error
+}
+
+func (r *customFieldRepoPG) Delete(ctx context.Context, f *models.CustomField) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Defect{}).Where("project_id = ?", f.ProjectID).
+			Update("custom_fields", gorm.Expr("custom_fields - ?", f.Key)).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.CustomField{}, f.ID).Error
+	})
+}
@@ -6,9 +6,29 @@ import (
 	"example.com/defect-control-system/internal/models"
 )
 
+// DefectQuery selects defects of one or more projects matching a filter
+type DefectQuery struct {
+	// ProjectIDs restricts the result to these projects unless AllProjects is set
+	ProjectIDs  []uint
+	AllProjects bool
+	Filter      models.DefectFilter
+}
+
 type DefectRepository interface {
 	Create(ctx context.Context, d *models.Defect) error
 	FindByID(ctx context.Context, id uint) (*models.Defect, error)
+	// FindByKey finds a defect by its project-scoped key, e.g. "TWR-142"
+	FindByKey(ctx context.Context, key string) (*models.Defect, error)
 	ListByProject(ctx context.Context, projectID uint) ([]*models.Defect, error)
-	Update(ctx context.Context, d *models.Defect) error
+	ListFiltered(ctx context.Context, q DefectQuery) ([]*models.Defect, error)
+	// Update writes the given columns if the stored version still equals d.Version
+	// and increments it; ErrVersionConflict is returned otherwise
+	Update(ctx context.Context, d *models.Defect, columns []string) error
+	// BulkUpdate writes the columns of all defects like Update and adds and removes
+	// labels, in one transaction: either every defect is changed or none. After
+	// a failure the versions of the passed defects are not meaningful
+	BulkUpdate(ctx context.Context, defects []*models.Defect, columns []string, addLabels, removeLabels []uint) error
+	// UpdateRanks recomputes severity and priority ranks of a project's defects
+	// after its levels changed; values no longer defined get rank 0
+	UpdateRanks(ctx context.Context, projectID uint, severities, priorities models.LevelList) error
 }
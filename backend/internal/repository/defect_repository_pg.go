@@ -2,22 +2,66 @@ package repository
 
 import (
 	"context"
+	"fmt"
+	"strings"
+	"time"
 
 	"example.com/defect-control-system/internal/models"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// defectSortColumns whitelists fields usable in DefectFilter.Sort
+var defectSortColumns = map[string]string{
+	"id":         "id",
+	"number":     "number",
+	"title":      "title",
+	"status":     "status",
+	"severity":   "severity_rank",
+	"priority":   "priority_rank",
+	"due_date":   "due_date",
+	"created_at": "created_at",
+	"updated_at": "updated_at",
+}
+
 type defectRepoPG struct{ db *gorm.DB }
 
 func NewDefectRepository(db *gorm.DB) DefectRepository { return &defectRepoPG{db: db} }
 
+// Create allocates the next number of the project and inserts the defect in one
+// transaction. The row lock taken by the sequence update serializes concurrent
+// creators within a project, so numbers are never duplicated or skipped.
 func (r *defectRepoPG) Create(ctx context.Context, d *models.Defect) error {
-	return r.db.WithContext(ctx).Create(d).Error
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var seq struct {
+			DefectSeq int
+			Key       string
+		}
+		err := tx.Raw("UPDATE projects SET defect_seq = defect_seq + 1 WHERE id = ? RETURNING defect_seq, key", d.ProjectID).
+			Scan(&seq).Error
+		if err != nil {
+			return err
+		}
+		if seq.DefectSeq == 0 {
+			return fmt.Errorf("project %d not found", d.ProjectID)
+		}
+		d.Number = seq.DefectSeq
+		d.Key = fmt.Sprintf("%s-%d", seq.Key, seq.DefectSeq)
+		return tx.Create(d).Error
+	})
+}
+
+func (r *defectRepoPG) FindByKey(ctx context.Context, key string) (*models.Defect, error) {
+	var d models.Defect
+	if err := r.db.WithContext(ctx).Preload("Labels").Preload("Category").Preload("ResponsibleOrg").Preload("Clauses.Document").Where("key = ?", key).First(&d).Error; err != nil {
+		return nil, err
+	}
+	return &d, nil
 }
 
 func (r *defectRepoPG) FindByID(ctx context.Context, id uint) (*models.Defect, error) {
 	var d models.Defect
-	if err := r.db.WithContext(ctx).First(&d, id).Error; err != nil {
+	if err := r.db.WithContext(ctx).Preload("Labels").Preload("Category").Preload("ResponsibleOrg").Preload("Clauses.Document").First(&d, id).Error; err != nil {
 		return nil, err
 	}
 	return &d, nil
@@ -25,12 +69,163 @@ func (r *defectRepoPG) FindByID(ctx context.Context, id uint) (*models.Defect, e
 
 func (r *defectRepoPG) ListByProject(ctx context.Context, projectID uint) ([]*models.Defect, error) {
 	var list []*models.Defect
-	if err := r.db.WithContext(ctx).Where("project_id = ?", projectID).Find(&list).Error; err != nil {
+	if err := r.db.WithContext(ctx).Where("project_id = ?", projectID).Preload("Labels").Preload("Category").Preload("ResponsibleOrg").Preload("Clauses.Document").Find(&list).Error; err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func (r *defectRepoPG) ListFiltered(ctx context.Context, q DefectQuery) ([]*models.Defect, error) {
+	var list []*models.Defect
+	tx := r.db.WithContext(ctx).Model(&models.Defect{})
+	if !q.AllProjects {
+		if len(q.ProjectIDs) == 0 {
+			return list, nil
+		}
+		tx = tx.Where("project_id IN ?", q.ProjectIDs)
+	}
+	f := q.Filter
+	if len(f.IDs) > 0 {
+		tx = tx.Where("id IN ?", f.IDs)
+	}
+	if len(f.Statuses) > 0 {
+		tx = tx.Where("status IN ?", f.Statuses)
+	}
+	if len(f.Severities) > 0 {
+		tx = tx.Where("severity IN ?", f.Severities)
+	}
+	if len(f.Priorities) > 0 {
+		tx = tx.Where("priority IN ?", f.Priorities)
+	}
+	if f.AssigneeID != nil {
+		if *f.AssigneeID == 0 {
+			tx = tx.Where("assignee_id IS NULL")
+		} else {
+			tx = tx.Where("assignee_id = ?", *f.AssigneeID)
+		}
+	}
+	if f.DueBefore != nil {
+		tx = tx.Where("due_date < ?", *f.DueBefore)
+	}
+	if f.DueAfter != nil {
+		tx = tx.Where("due_date >= ?", *f.DueAfter)
+	}
+	if f.Overdue {
+		tx = tx.Where("due_date < ? AND status <> ?", time.Now(), "closed")
+	}
+	if f.CategoryID != nil {
+		tx = tx.Where("category_id IN (SELECT id FROM defect_categories WHERE id = ? OR parent_id = ?)", *f.CategoryID, *f.CategoryID)
+	}
+	if f.ResponsibleOrgID != nil {
+		tx = tx.Where("responsible_org_id = ?", *f.ResponsibleOrgID)
+	}
+	if len(f.LabelIDs) > 0 {
+		if f.LabelMode == "all" {
+			tx = tx.Where("id IN (SELECT defect_id FROM defect_labels WHERE label_id IN ? GROUP BY defect_id HAVING COUNT(DISTINCT label_id) = ?)",
+				f.LabelIDs, len(uniqueIDs(f.LabelIDs)))
+		} else {
+			tx = tx.Where("id IN (SELECT defect_id FROM defect_labels WHERE label_id IN ?)", f.LabelIDs)
+		}
+	}
+	for key, val := range f.Custom {
+		if !models.CustomFieldKeyPattern.MatchString(key) {
+			return nil, fmt.Errorf("invalid custom field key: %s", key)
+		}
+		tx = tx.Where("custom_fields ->> ? = ?", key, val)
+	}
+	if f.Query != "" {
+		tx = tx.Where("search_vector @@ (websearch_to_tsquery('russian', ?) || websearch_to_tsquery('english', ?))", f.Query, f.Query)
+	}
+	order, err := defectOrder(f.Sort)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Order(order).Preload("Labels").Preload("Category").Preload("ResponsibleOrg").Preload("Clauses.Document").Find(&list).Error; err != nil {
 		return nil, err
 	}
 	return list, nil
 }
 
-func (r *defectRepoPG) Update(ctx context.Context, d *models.Defect) error {
-	return r.db.WithContext(ctx).Save(d).Error
+// defectOrder translates a DefectFilter sort expression into an ORDER BY clause
+func defectOrder(sort string) (string, error) {
+	if sort == "" {
+		return "created_at desc, id desc", nil
+	}
+	dir := "asc"
+	if strings.HasPrefix(sort, "-") {
+		dir = "desc"
+		sort = sort[1:]
+	}
+	col, ok := defectSortColumns[sort]
+	if key, isCustom := strings.CutPrefix(sort, "cf."); isCustom {
+		// jsonb ordering compares numbers numerically and strings (incl. ISO dates) lexically
+		if !models.CustomFieldKeyPattern.MatchString(key) {
+			return "", fmt.Errorf("invalid custom field key: %s", key)
+		}
+		col, ok = fmt.Sprintf("custom_fields -> '%s'", key), true
+	}
+	if !ok {
+		return "", fmt.Errorf("unsupported sort field: %s", sort)
+	}
+	return fmt.Sprintf("%s %s nulls last, id %s", col, dir, dir), nil
+}
+
+func (r *defectRepoPG) Update(ctx context.Context, d *models.Defect, columns []string) error {
+	// labels and clauses are managed through their repositories, never through a defect save
+	return versionedUpdate(r.db.WithContext(ctx).Omit(clause.Associations), d, &d.Version, columns)
+}
+
+func (r *defectRepoPG) BulkUpdate(ctx context.Context, defects []*models.Defect, columns []string, addLabels, removeLabels []uint) error {
+	ids := make([]uint, len(defects))
+	for i, d := range defects {
+		ids[i] = d.ID
+	}
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if len(columns) > 0 {
+			for _, d := range defects {
+				if err := versionedUpdate(tx.Omit(clause.Associations), d, &d.Version, columns); err != nil {
+					return err
+				}
+			}
+		}
+		if err := addDefectLabels(tx, ids, addLabels); err != nil {
+			return err
+		}
+		return removeDefectLabels(tx, ids, removeLabels)
+	})
+}
+
+func (r *defectRepoPG) UpdateRanks(ctx context.Context, projectID uint, severities, priorities models.LevelList) error {
+	sevExpr, sevArgs := rankCase("severity", severities)
+	prioExpr, prioArgs := rankCase("priority", priorities)
+	args := append(append(sevArgs, prioArgs...), projectID)
+	return r.db.WithContext(ctx).Exec("UPDATE defects SET severity_rank = "+sevExpr+", priority_rank = "+prioExpr+" WHERE project_id = ?", args...).Error
+}
+
+// rankCase builds a CASE expression mapping level values of column to ranks
+func rankCase(column string, levels models.LevelList) (string, []interface{}) {
+	if len(levels) == 0 {
+		return "0", nil
+	}
+	var b strings.Builder
+	args := make([]interface{}, 0, 2*len(levels))
+	b.WriteString("CASE " + column)
+	for _, lv := range levels {
+		b.WriteString(" WHEN ? THEN ?")
+		args = append(args, lv.Value, lv.Rank)
+	}
+	b.WriteString(" ELSE 0 END")
+	return b.String(), args
+}
+
+func uniqueIDs(ids []uint) []uint {
+	seen := make(map[uint]struct{}, len(ids))
+	out := make([]uint, 0, len(ids))
+	for _, id := range ids {
+		if _, ok := seen[id]; !ok {
+			seen[id] = struct{}{}
+			out = append(out, id)
+		}
+	}
+	return out
 }
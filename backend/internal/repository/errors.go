@@ -0,0 +1,11 @@
+package repository
+
+import "errors"
+
+// ErrVersionConflict is returned by versioned updates when the stored row no
+// longer has the version the caller read, i.e. someone else updated it first
+var ErrVersionConflict = errors.New("version conflict")
+
+// ErrInUse is returned when a row cannot be removed because other records
+// depend on it
+var ErrInUse = errors.New("in use")
@@ -0,0 +1,36 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"example.com/defect-control-system/internal/models"
+)
+
+type InspectionRepository interface {
+	CreateTemplate(ctx context.Context, t *models.InspectionTemplate) error
+	// UpdateTemplate writes name and description and replaces the items
+	UpdateTemplate(ctx context.Context, t *models.InspectionTemplate) error
+	FindTemplate(ctx context.Context, id uint) (*models.InspectionTemplate, error)
+	// ListTemplates returns the shared templates and those of the project
+	ListTemplates(ctx context.Context, projectID uint) ([]*models.InspectionTemplate, error)
+
+	// Create inserts the inspection with its results
+	Create(ctx context.Context, i *models.Inspection) error
+	FindByID(ctx context.Context, id uint) (*models.Inspection, error)
+	// ListByProject lists inspections by schedule, optionally of one status
+	ListByProject(ctx context.Context, projectID uint, status string) ([]*models.Inspection, error)
+	// SaveResults writes outcome and note of the results and the inspection's
+	// status and started_at, in one transaction
+	SaveResults(ctx context.Context, i *models.Inspection, results []*models.InspectionResult) error
+	// SetResultDefect links the defect raised for a failed item
+	SetResultDefect(ctx context.Context, resultID, defectID uint) error
+	// ClaimCompletion marks an open inspection as being completed unless
+	// another completion claimed it after staleBefore; it reports whether the
+	// claim succeeded
+	ClaimCompletion(ctx context.Context, id uint, staleBefore time.Time) (bool, error)
+	// ReleaseCompletion drops the claim of ClaimCompletion
+	ReleaseCompletion(ctx context.Context, id uint) error
+	// UpdateStatus writes status, started_at and completed_at
+	UpdateStatus(ctx context.Context, i *models.Inspection) error
+}
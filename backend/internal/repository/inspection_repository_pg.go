@@ -0,0 +1,114 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"example.com/defect-control-system/internal/models"
+	"gorm.io/gorm"
+)
+
+type inspectionRepoPG struct{ db *gorm.DB }
+
+func NewInspectionRepository(db *gorm.DB) InspectionRepository {
+	return &inspectionRepoPG{db: db}
+}
+
+func orderByPosition(db *gorm.DB) *gorm.DB { return db.Order("position, id") }
+
+func (r *inspectionRepoPG) CreateTemplate(ctx context.Context, t *models.InspectionTemplate) error {
+	return r.db.WithContext(ctx).Create(t).Error
+}
+
+func (r *inspectionRepoPG) UpdateTemplate(ctx context.Context, t *models.InspectionTemplate) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(t).Select("name", "description", "updated_at").Updates(t).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("template_id = ?", t.ID).Delete(&models.InspectionTemplateItem{}).Error; err != nil {
+			return err
+		}
+		if len(t.Items) == 0 {
+			return nil
+		}
+		for i := range t.Items {
+			t.Items[i].ID, t.Items[i].TemplateID = 0, t.ID
+		}
+		return tx.Create(&t.Items).Error
+	})
+}
+
+func (r *inspectionRepoPG) FindTemplate(ctx context.Context, id uint) (*models.InspectionTemplate, error) {
+	var t models.InspectionTemplate
+	if err := r.db.WithContext(ctx).Preload("Items", orderByPosition).First(&t, id).Error; err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func (r *inspectionRepoPG) ListTemplates(ctx context.Context, projectID uint) ([]*models.InspectionTemplate, error) {
+	var list []*models.InspectionTemplate
+	err := r.db.WithContext(ctx).Preload("Items", orderByPosition).
+		Where("project_id IS NULL OR project_id = ?", projectID).Order("name, id").Find(&list).Error
+	if err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func (r *inspectionRepoPG) Create(ctx context.Context, i *models.Inspection) error {
+	return r.db.WithContext(ctx).Omit("Template", "Inspector").Create(i).Error
+}
+
+func (r *inspectionRepoPG) FindByID(ctx context.Context, id uint) (*models.Inspection, error) {
+	var i models.Inspection
+	if err := r.db.WithContext(ctx).Preload("Results", orderByPosition).Preload("Inspector").First(&i, id).Error; err != nil {
+		return nil, err
+	}
+	return &i, nil
+}
+
+func (r *inspectionRepoPG) ListByProject(ctx context.Context, projectID uint, status string) ([]*models.Inspection, error) {
+	var list []*models.Inspection
+	q := r.db.WithContext(ctx).Preload("Results", orderByPosition).Preload("Inspector").Where("project_id = ?", projectID)
+	if status != "" {
+		q = q.Where("status = ?", status)
+	}
+	if err := q.Order("scheduled_at, id").Find(&list).Error; err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func (r *inspectionRepoPG) SaveResults(ctx context.Context, i *models.Inspection, results []*models.InspectionResult) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, res := range results {
+			if err := tx.Model(res).Select("outcome", "note").Updates(res).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Model(i).Select("status", "started_at", "updated_at").Updates(i).Error
+	})
+}
+
+func (r *inspectionRepoPG) SetResultDefect(ctx context.Context, resultID, defectID uint) error {
+	return r.db.WithContext(ctx).Model(&models.InspectionResult{}).Where("id = ?", resultID).
+		Update("defect_id", defectID).Error
+}
+
+func (r *inspectionRepoPG) ClaimCompletion(ctx context.Context, id uint, staleBefore time.Time) (bool, error) {
+	res := r.db.WithContext(ctx).Model(&models.Inspection{}).
+		Where("id = ? AND status IN ?", id, []string{models.InspectionScheduled, models.InspectionInProgress}).
+		Where("completing_since IS NULL OR completing_since < ?", staleBefore).
+		UpdateColumn("completing_since", time.Now())
+	return res.RowsAffected > 0, res.Error
+}
+
+func (r *inspectionRepoPG) ReleaseCompletion(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Model(&models.Inspection{}).Where("id = ?", id).
+		UpdateColumn("completing_since", nil).Error
+}
+
+func (r *inspectionRepoPG) UpdateStatus(ctx context.Context, i *models.Inspection) error {
+	return r.db.WithContext(ctx).Model(i).Select("status", "started_at", "completed_at", "updated_at").Updates(i).Error
+}
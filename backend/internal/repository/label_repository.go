@@ -0,0 +1,18 @@
+package repository
+
+import (
+	"context"
+
+	"example.com/defect-control-system/internal/models"
+)
+
+type LabelRepository interface {
+	Create(ctx context.Context, l *models.Label) error
+	FindByID(ctx context.Context, id uint) (*models.Label, error)
+	ListByProject(ctx context.Context, projectID uint) ([]*models.Label, error)
+	Update(ctx context.Context, l *models.Label) error
+	Delete(ctx context.Context, id uint) error
+	// AddToDefects puts every label on every defect, skipping existing assignments
+	AddToDefects(ctx context.Context, defectIDs, labelIDs []uint) error
+	RemoveFromDefects(ctx context.Context, defectIDs, labelIDs []uint) error
+}
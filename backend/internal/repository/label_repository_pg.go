@@ -0,0 +1,65 @@
+package repository
+
+import (
+	"context"
+
+	"example.com/defect-control-system/internal/models"
+	"gorm.io/gorm"
+)
+
+type labelRepoPG struct{ db *gorm.DB }
+
+func NewLabelRepository(db *gorm.DB) LabelRepository { return &labelRepoPG{db: db} }
+
+func (r *labelRepoPG) Create(ctx context.Context, l *models.Label) error {
+	return r.db.WithContext(ctx).Create(l).Error
+}
+
+func (r *labelRepoPG) FindByID(ctx context.Context, id uint) (*models.Label, error) {
+	var l models.Label
+	if err := r.db.WithContext(ctx).First(&l, id).Error; err != nil {
+		return nil, err
+	}
+	return &l, nil
+}
+
+func (r *labelRepoPG) ListByProject(ctx context.Context, projectID uint) ([]*models.Label, error) {
+	var list []*models.Label
+	if err := r.db.WithContext(ctx).Where("project_id = ?", projectID).Order("name").Find(&list).Error; err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func (r *labelRepoPG) Update(ctx context.Context, l *models.Label) error {
+	return r.db.WithContext(ctx).Save(l).Error
+}
+
+func (r *labelRepoPG) Delete(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Delete(&models.Label{}, id).Error
+}
+
+func (r *labelRepoPG) AddToDefects(ctx context.Context, defectIDs, labelIDs []uint) error {
+	return addDefectLabels(r.db.WithContext(ctx), defectIDs, labelIDs)
+}
+
+func (r *labelRepoPG) RemoveFromDefects(ctx context.Context, defectIDs, labelIDs []uint) error {
+	return removeDefectLabels(r.db.WithContext(ctx), defectIDs, labelIDs)
+}
+
+// addDefectLabels and removeDefectLabels are shared with defect bulk updates
+func addDefectLabels(tx *gorm.DB, defectIDs, labelIDs []uint) error {
+	if len(defectIDs) == 0 || len(labelIDs) == 0 {
+		return nil
+	}
+	return tx.Exec(`INSERT INTO defect_labels (defect_id, label_id)
+		SELECT d.id, l.id FROM defects d CROSS JOIN labels l WHERE d.id IN ? AND l.id IN ?
+		ON CONFLICT DO NOTHING`, defectIDs, labelIDs).Error
+}
+
+func removeDefectLabels(tx *gorm.DB, defectIDs, labelIDs []uint) error {
+	if len(defectIDs) == 0 || len(labelIDs) == 0 {
+		return nil
+	}
+	return tx.Exec("DELETE FROM defect_labels WHERE defect_id IN ? AND label_id IN ?", defectIDs, labelIDs).Error
+}
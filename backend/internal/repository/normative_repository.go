@@ -0,0 +1,38 @@
+package repository
+
+import (
+	"context"
+
+	"example.com/defect-control-system/internal/models"
+)
+
+// ClauseSearchParams selects normative clauses. Query matches clause numbers
+// and text as well as document codes and titles.
+type ClauseSearchParams struct {
+	Query           string
+	DocumentID      *uint
+	IncludeObsolete bool
+	Limit           int
+	Offset          int
+}
+
+type NormativeRepository interface {
+	CreateDocument(ctx context.Context, d *models.NormativeDocument) error
+	// FindDocument loads the document with its clauses
+	FindDocument(ctx context.Context, id uint) (*models.NormativeDocument, error)
+	FindDocumentByCode(ctx context.Context, code string) (*models.NormativeDocument, error)
+	ListDocuments(ctx context.Context, includeObsolete bool) ([]*models.NormativeDocument, error)
+	UpdateDocument(ctx context.Context, d *models.NormativeDocument) error
+	DeleteDocument(ctx context.Context, id uint) error
+
+	CreateClause(ctx context.Context, c *models.NormativeClause) error
+	FindClause(ctx context.Context, id uint) (*models.NormativeClause, error)
+	// FindClauses loads clauses with their documents; unknown ids are skipped
+	FindClauses(ctx context.Context, ids []uint) ([]*models.NormativeClause, error)
+	UpdateClause(ctx context.Context, c *models.NormativeClause) error
+	DeleteClause(ctx context.Context, id uint) error
+	SearchClauses(ctx context.Context, p ClauseSearchParams) ([]*models.NormativeClause, error)
+
+	// SetDefectClauses replaces the clauses cited by a defect
+	SetDefectClauses(ctx context.Context, defectID uint, clauseIDs []uint) error
+}
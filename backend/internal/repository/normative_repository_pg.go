@@ -0,0 +1,137 @@
+package repository
+
+import (
+	"context"
+	"strings"
+
+	"example.com/defect-control-system/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type normativeRepoPG struct{ db *gorm.DB }
+
+func NewNormativeRepository(db *gorm.DB) NormativeRepository { return &normativeRepoPG{db: db} }
+
+func (r *normativeRepoPG) CreateDocument(ctx context.Context, d *models.NormativeDocument) error {
+	return r.db.WithContext(ctx).Omit(clause.Associations).Create(d).Error
+}
+
+func (r *normativeRepoPG) FindDocument(ctx context.Context, id uint) (*models.NormativeDocument, error) {
+	var d models.NormativeDocument
+	err := r.db.WithContext(ctx).
+		Preload("Clauses", func(tx *gorm.DB) *gorm.DB {
+			// natural order of clause numbers: 5.2 before 5.10
+			return tx.Order("string_to_array(regexp_replace(number, '[^0-9.]', '', 'g'), '.')::int[] NULLS LAST, number")
+		}).
+		First(&d, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+func (r *normativeRepoPG) FindDocumentByCode(ctx context.Context, code string) (*models.NormativeDocument, error) {
+	var d models.NormativeDocument
+	if err := r.db.WithContext(ctx).Where("lower(code) = lower(?)", code).First(&d).Error; err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+func (r *normativeRepoPG) ListDocuments(ctx context.Context, includeObsolete bool) ([]*models.NormativeDocument, error) {
+	var list []*models.NormativeDocument
+	tx := r.db.WithContext(ctx)
+	if !includeObsolete {
+		tx = tx.Where("obsolete = ?", false)
+	}
+	if err := tx.Order("code").Find(&list).Error; err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func (r *normativeRepoPG) UpdateDocument(ctx context.Context, d *models.NormativeDocument) error {
+	return r.db.WithContext(ctx).Omit(clause.Associations).Save(d).Error
+}
+
+func (r *normativeRepoPG) DeleteDocument(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Delete(&models.NormativeDocument{}, id).Error
+}
+
+func (r *normativeRepoPG) CreateClause(ctx context.Context, c *models.NormativeClause) error {
+	return r.db.WithContext(ctx).Omit(clause.Associations).Create(c).Error
+}
+
+func (r *normativeRepoPG) FindClause(ctx context.Context, id uint) (*models.NormativeClause, error) {
+	var c models.NormativeClause
+	if err := r.db.WithContext(ctx).Preload("Document").First(&c, id).Error; err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+func (r *normativeRepoPG) FindClauses(ctx context.Context, ids []uint) ([]*models.NormativeClause, error) {
+	var list []*models.NormativeClause
+	if len(ids) == 0 {
+		return list, nil
+	}
+	if err := r.db.WithContext(ctx).Preload("Document").Where("id IN ?", ids).Find(&list).Error; err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func (r *normativeRepoPG) UpdateClause(ctx context.Context, c *models.NormativeClause) error {
+	return r.db.WithContext(ctx).Omit(clause.Associations).Save(c).Error
+}
+
+func (r *normativeRepoPG) DeleteClause(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Delete(&models.NormativeClause{}, id).Error
+}
+
+func (r *normativeRepoPG) SearchClauses(ctx context.Context, p ClauseSearchParams) ([]*models.NormativeClause, error) {
+	var list []*models.NormativeClause
+	tx := r.db.WithContext(ctx).Model(&models.NormativeClause{}).
+		Joins("JOIN normative_documents nd ON nd.id = normative_clauses.document_id").
+		Preload("Document")
+	if !p.IncludeObsolete {
+		tx = tx.Where("nd.obsolete = ?", false)
+	}
+	if p.DocumentID != nil {
+		tx = tx.Where("normative_clauses.document_id = ?", *p.DocumentID)
+	}
+	order := "nd.code, normative_clauses.number"
+	if p.Query != "" {
+		like := "%" + escapeLike(p.Query) + "%"
+		tx = tx.Where(`normative_clauses.search_vector @@ (websearch_to_tsquery('russian', ?) || websearch_to_tsquery('english', ?))
+			OR normative_clauses.number LIKE ? OR nd.code ILIKE ? OR nd.title ILIKE ?`,
+			p.Query, p.Query, escapeLike(p.Query)+"%", like, like)
+		order = "ts_rank(normative_clauses.search_vector, websearch_to_tsquery('russian', ?) || websearch_to_tsquery('english', ?)) DESC, " + order
+		tx = tx.Order(clause.OrderBy{Expression: clause.Expr{SQL: order, Vars: []interface{}{p.Query, p.Query}}})
+	} else {
+		tx = tx.Order(order)
+	}
+	if err := tx.Limit(p.Limit).Offset(p.Offset).Find(&list).Error; err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func (r *normativeRepoPG) SetDefectClauses(ctx context.Context, defectID uint, clauseIDs []uint) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("DELETE FROM defect_clauses WHERE defect_id = ?", defectID).Error; err != nil {
+			return err
+		}
+		if len(clauseIDs) == 0 {
+			return nil
+		}
+		return tx.Exec(`INSERT INTO defect_clauses (defect_id, normative_clause_id)
+			SELECT ?, id FROM normative_clauses WHERE id IN ? ON CONFLICT DO NOTHING`, defectID, clauseIDs).Error
+	})
+}
+
+// escapeLike escapes LIKE wildcards in user input
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
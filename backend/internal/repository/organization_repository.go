@@ -0,0 +1,16 @@
+package repository
+
+import (
+	"context"
+
+	"example.com/defect-control-system/internal/models"
+)
+
+type OrganizationRepository interface {
+	Create(ctx context.Context, o *models.Organization) error
+	FindByID(ctx context.Context, id uint) (*models.Organization, error)
+	FindByName(ctx context.Context, name string) (*models.Organization, error)
+	List(ctx context.Context) ([]*models.Organization, error)
+	Update(ctx context.Context, o *models.Organization) error
+	Delete(ctx context.Context, id uint) error
+}
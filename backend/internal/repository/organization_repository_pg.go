@@ -0,0 +1,50 @@
+package repository
+
+import (
+	"context"
+
+	"example.com/defect-control-system/internal/models"
+	"gorm.io/gorm"
+)
+
+type organizationRepoPG struct{ db *gorm.DB }
+
+func NewOrganizationRepository(db *gorm.DB) OrganizationRepository {
+	return &organizationRepoPG{db: db}
+}
+
+func (r *organizationRepoPG) Create(ctx context.Context, o *models.Organization) error {
+	return r.db.WithContext(ctx).Create(o).Error
+}
+
+func (r *organizationRepoPG) FindByID(ctx context.Context, id uint) (*models.Organization, error) {
+	var o models.Organization
+	if err := r.db.WithContext(ctx).First(&o, id).Error; err != nil {
+		return nil, err
+	}
+	return &o, nil
+}
+
+func (r *organizationRepoPG) FindByName(ctx context.Context, name string) (*models.Organization, error) {
+	var o models.Organization
+	if err := r.db.WithContext(ctx).Where("lower(name) = lower(?)", name).First(&o).Error; err != nil {
+		return nil, err
+	}
+	return &o, nil
+}
+
+func (r *organizationRepoPG) List(ctx context.Context) ([]*models.Organization, error) {
+	var list []*models.Organization
+	if err := r.db.WithContext(ctx).Order("name").Find(&list).Error; err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func (r *organizationRepoPG) Update(ctx context.Context, o *models.Organization) error {
+	return r.db.WithContext(ctx).Save(o).Error
+}
+
+func (r *organizationRepoPG) Delete(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Delete(&models.Organization{}, id).Error
+}
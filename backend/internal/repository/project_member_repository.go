@@ -0,0 +1,17 @@
+package repository
+
+import (
+	"context"
+
+	"example.com/defect-control-system/internal/models"
+)
+
+type ProjectMemberRepository interface {
+	// Add creates the membership or updates the role of an existing one
+	Add(ctx context.Context, m *models.ProjectMember) error
+	Remove(ctx context.Context, projectID, userID uint) error
+	Find(ctx context.Context, projectID, userID uint) (*models.ProjectMember, error)
+	ListByProject(ctx context.Context, projectID uint) ([]*models.ProjectMember, error)
+	// ProjectIDsByUser returns ids of projects the user is a member of
+	ProjectIDsByUser(ctx context.Context, userID uint) ([]uint, error)
+}
@@ -0,0 +1,53 @@
+package repository
+
+import (
+	"context"
+
+	"example.com/defect-control-system/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type projectMemberRepoPG struct{ db *gorm.DB }
+
+func NewProjectMemberRepository(db *gorm.DB) ProjectMemberRepository {
+	return &projectMemberRepoPG{db: db}
+}
+
+func (r *projectMemberRepoPG) Add(ctx context.Context, m *models.ProjectMember) error {
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "project_id"}, {Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"role"}),
+	}).Create(m).Error
+}
+
+func (r *projectMemberRepoPG) Remove(ctx context.Context, projectID, userID uint) error {
+	return r.db.WithContext(ctx).Where("project_id = ? AND user_id = ?", projectID, userID).Delete(&models.ProjectMember{}).Error
+}
+
+func (r *projectMemberRepoPG) Find(ctx context.Context, projectID, userID uint) (*models.ProjectMember, error) {
+	var m models.ProjectMember
+	if err := r.db.WithContext(ctx).Where("project_id = ? AND user_id = ?", projectID, userID).First(&m).Error; err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+func (r *projectMemberRepoPG) ListByProject(ctx context.Context, projectID uint) ([]*models.ProjectMember, error) {
+	var list []*models.ProjectMember
+	err := r.db.WithContext(ctx).Where("project_id = ?", projectID).
+		Preload("User", func(db *gorm.DB) *gorm.DB { return db.Select("id", "name", "email", "role") }).
+		Order("created_at asc").Find(&list).Error
+	if err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func (r *projectMemberRepoPG) ProjectIDsByUser(ctx context.Context, userID uint) ([]uint, error) {
+	var ids []uint
+	if err := r.db.WithContext(ctx).Model(&models.ProjectMember{}).Where("user_id = ?", userID).Pluck("project_id", &ids).Error; err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
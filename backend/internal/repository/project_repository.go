@@ -9,6 +9,10 @@ import (
 type ProjectRepository interface {
 	Create(ctx context.Context, p *models.Project) error
 	FindByID(ctx context.Context, id uint) (*models.Project, error)
+	FindByKey(ctx context.Context, key string) (*models.Project, error)
 	List(ctx context.Context) ([]*models.Project, error)
-	Update(ctx context.Context, p *models.Project) error
+	// Update writes the given columns if the stored version still equals p.Version
+	// and increments it; ErrVersionConflict is returned otherwise. Changing the
+	// "key" column rewrites the keys of the project's defects as well
+	Update(ctx context.Context, p *models.Project, columns []string) error
 }
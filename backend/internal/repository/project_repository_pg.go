@@ -31,6 +31,26 @@ func (r *projectRepoPG) List(ctx context.Context) ([]*models.Project, error) {
 	return list, nil
 }
 
-func (r *projectRepoPG) Update(ctx context.Context, p *models.Project) error {
-	return r.db.WithContext(ctx).Save(p).Error
+func (r *projectRepoPG) FindByKey(ctx context.Context, key string) (*models.Project, error) {
+	var p models.Project
+	if err := r.db.WithContext(ctx).Where("key = ?", key).First(&p).Error; err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func (r *projectRepoPG) Update(ctx context.Context, p *models.Project, columns []string) error {
+	// only named columns are written: defect_seq is owned by defect creation and
+	// saving a stale copy would reuse numbers
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := versionedUpdate(tx, p, &p.Version, columns); err != nil {
+			return err
+		}
+		for _, col := range columns {
+			if col == "key" {
+				return tx.Exec("UPDATE defects SET key = ? || '-' || number WHERE project_id = ?", p.Key, p.ID).Error
+			}
+		}
+		return nil
+	})
 }
@@ -0,0 +1,39 @@
+package repository
+
+import (
+	"context"
+
+	"example.com/defect-control-system/internal/models"
+)
+
+// QuotaUsage is the storage of a project and of its organization
+type QuotaUsage struct {
+	Used, Reserved                         int64
+	OrganizationUsed, OrganizationReserved int64
+}
+
+// QuotaGuard checks a write of attachment content against the storage quotas
+// of the defect's project. The repository locks the project and organization
+// rows in the transaction of the write, sums their usage and calls Fits with
+// the project and its organization; an error of Fits aborts the write
+type QuotaGuard struct {
+	DefectID uint
+	Fits     func(p *models.Project, u QuotaUsage) error
+}
+
+// QuotaRepository sums attachment sizes for storage quotas. Reserved bytes
+// are the declared lengths of resumable uploads not finished yet
+type QuotaRepository interface {
+	// ProjectOfDefect returns the project of a defect with its organization
+	ProjectOfDefect(ctx context.Context, defectID uint) (*models.Project, error)
+	// FindProject returns a project with its organization
+	FindProject(ctx context.Context, projectID uint) (*models.Project, error)
+	ProjectUsage(ctx context.Context, projectID uint) (used, reserved int64, err error)
+	OrganizationUsage(ctx context.Context, orgID uint) (used, reserved int64, err error)
+	// ProjectBreakdown fills files and bytes of the project by content type and by uploader
+	ProjectBreakdown(ctx context.Context, u *models.StorageUsage) error
+	// SetProjectLevel stores the warned quota level if it still is from and
+	// reports whether it did
+	SetProjectLevel(ctx context.Context, projectID uint, from, to int) (bool, error)
+	SetOrganizationLevel(ctx context.Context, orgID uint, from, to int) (bool, error)
+}
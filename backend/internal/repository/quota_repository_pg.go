@@ -0,0 +1,123 @@
+package repository
+
+import (
+	"context"
+
+	"example.com/defect-control-system/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type quotaRepoPG struct{ db *gorm.DB }
+
+func NewQuotaRepository(db *gorm.DB) QuotaRepository { return &quotaRepoPG{db: db} }
+
+func (r *quotaRepoPG) ProjectOfDefect(ctx context.Context, defectID uint) (*models.Project, error) {
+	var p models.Project
+	err := r.db.WithContext(ctx).Preload("Organization").
+		Where("id = (SELECT project_id FROM defects WHERE id = ?)", defectID).First(&p).Error
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func (r *quotaRepoPG) FindProject(ctx context.Context, projectID uint) (*models.Project, error) {
+	var p models.Project
+	if err := r.db.WithContext(ctx).Preload("Organization").First(&p, projectID).Error; err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// organizationDefects matches the defects of the projects of an organization
+const organizationDefects = "d.project_id IN (SELECT id FROM projects WHERE organization_id = ?)"
+
+// usage sums attachments and unfinished uploads of the defects matching where
+func usage(db *gorm.DB, where string, arg uint) (used, reserved int64, err error) {
+	err = db.Table("attachments a").Select("coalesce(sum(a.size), 0)").
+		Joins("JOIN defects d ON d.id = a.defect_id").Where(where, arg).Scan(&used).Error
+	if err != nil {
+		return 0, 0, err
+	}
+	err = db.Table("upload_sessions a").Select("coalesce(sum(a.length), 0)").
+		Joins("JOIN defects d ON d.id = a.defect_id").
+		Where("a.attachment_id IS NULL AND a.expires_at > now()").Where(where, arg).Scan(&reserved).Error
+	return used, reserved, err
+}
+
+func (r *quotaRepoPG) ProjectUsage(ctx context.Context, projectID uint) (int64, int64, error) {
+	return usage(r.db.WithContext(ctx), "d.project_id = ?", projectID)
+}
+
+func (r *quotaRepoPG) OrganizationUsage(ctx context.Context, orgID uint) (int64, int64, error) {
+	return usage(r.db.WithContext(ctx), organizationDefects, orgID)
+}
+
+// guardQuota runs g within tx; a nil g passes. Writes to the same project or
+// organization queue up on the row locks, so each one sums the usage committed
+// before it. NO KEY UPDATE leaves rows referencing the project, such as new
+// defects, free to be inserted meanwhile. The project is always locked first
+func guardQuota(tx *gorm.DB, g *QuotaGuard) error {
+	if g == nil {
+		return nil
+	}
+	lock := clause.Locking{Strength: "NO KEY UPDATE"}
+	var p models.Project
+	err := tx.Clauses(lock).Where("id = (SELECT project_id FROM defects WHERE id = ?)", g.DefectID).First(&p).Error
+	if err != nil {
+		return err
+	}
+	var u QuotaUsage
+	if u.Used, u.Reserved, err = usage(tx, "d.project_id = ?", p.ID); err != nil {
+		return err
+	}
+	if p.OrganizationID != nil {
+		var o models.Organization
+		if err := tx.Clauses(lock).First(&o, *p.OrganizationID).Error; err != nil {
+			return err
+		}
+		p.Organization = &o
+		if u.OrganizationUsed, u.OrganizationReserved, err = usage(tx, organizationDefects, o.ID); err != nil {
+			return err
+		}
+	}
+	return g.Fits(&p, u)
+}
+
+func (r *quotaRepoPG) ProjectBreakdown(ctx context.Context, u *models.StorageUsage) error {
+	db := r.db.WithContext(ctx)
+	u.ByContentType = []models.UsageBucket{}
+	err := db.Table("attachments a").
+		Select("a.content_type, count(*) AS files, sum(a.size) AS bytes").
+		Joins("JOIN defects d ON d.id = a.defect_id").Where("d.project_id = ?", u.ProjectID).
+		Group("a.content_type").Order("bytes DESC").Scan(&u.ByContentType).Error
+	if err != nil {
+		return err
+	}
+	u.ByUploader = []models.UsageBucket{}
+	err = db.Table("attachments a").
+		Select("a.uploader_id, coalesce(u.name, '') AS uploader_name, count(*) AS files, sum(a.size) AS bytes").
+		Joins("JOIN defects d ON d.id = a.defect_id").
+		Joins("LEFT JOIN users u ON u.id = a.uploader_id").Where("d.project_id = ?", u.ProjectID).
+		Group("a.uploader_id, u.name").Order("bytes DESC").Scan(&u.ByUploader).Error
+	if err != nil {
+		return err
+	}
+	for _, b := range u.ByContentType {
+		u.Files += b.Files
+	}
+	return nil
+}
+
+func (r *quotaRepoPG) SetProjectLevel(ctx context.Context, projectID uint, from, to int) (bool, error) {
+	res := r.db.WithContext(ctx).Model(&models.Project{}).
+		Where("id = ? AND quota_level = ?", projectID, from).UpdateColumn("quota_level", to)
+	return res.RowsAffected > 0, res.Error
+}
+
+func (r *quotaRepoPG) SetOrganizationLevel(ctx context.Context, orgID uint, from, to int) (bool, error) {
+	res := r.db.WithContext(ctx).Model(&models.Organization{}).
+		Where("id = ? AND quota_level = ?", orgID, from).UpdateColumn("quota_level", to)
+	return res.RowsAffected > 0, res.Error
+}
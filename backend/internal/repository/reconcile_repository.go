@@ -0,0 +1,36 @@
+package repository
+
+import (
+	"context"
+
+	"example.com/defect-control-system/internal/models"
+)
+
+// FileRef is a row pointing at a file in storage
+type FileRef struct {
+	Table string `json:"table"`
+	ID    string `json:"id"`
+	Path  string `json:"path"`
+}
+
+// BlobUsage is a blob whose stored reference count disagrees with the
+// attachments using it
+type BlobUsage struct {
+	SHA256   string `gorm:"column:sha256" json:"sha256"`
+	Path     string `json:"path"`
+	RefCount int    `json:"ref_count"`
+	Used     int    `json:"used"`
+}
+
+// ReconcileRepository compares the database with the files in storage
+type ReconcileRepository interface {
+	// FileRefs lists every row that points at a file: attachments, blobs,
+	// unfinished upload sessions and signed acts
+	FileRefs(ctx context.Context) ([]FileRef, error)
+	// MiscountedBlobs lists blobs whose ref_count is wrong
+	MiscountedBlobs(ctx context.Context) ([]BlobUsage, error)
+	// RepairBlob recounts the attachments of a blob while its row is locked
+	// and stores the count; a blob no attachment uses is deleted, remove
+	// deleting its file first
+	RepairBlob(ctx context.Context, sha string, remove func(b *models.Blob) error) error
+}
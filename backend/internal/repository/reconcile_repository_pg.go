@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"context"
+
+	"example.com/defect-control-system/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type reconcileRepoPG struct{ db *gorm.DB }
+
+func NewReconcileRepository(db *gorm.DB) ReconcileRepository { return &reconcileRepoPG{db: db} }
+
+func (r *reconcileRepoPG) FileRefs(ctx context.Context) ([]FileRef, error) {
+	var refs []FileRef
+	err := r.db.WithContext(ctx).Raw(`
+		SELECT 'attachments' AS "table", id::text AS id, path FROM attachments WHERE path <> ''
+		UNION ALL SELECT 'blobs', sha256, path FROM blobs
+		UNION ALL SELECT 'upload_sessions', id, part_path FROM upload_sessions WHERE attachment_id IS NULL
+		UNION ALL SELECT 'acceptance_acts', id::text, path FROM acceptance_acts WHERE path <> ''`).Scan(&refs).Error
+	return refs, err
+}
+
+func (r *reconcileRepoPG) MiscountedBlobs(ctx context.Context) ([]BlobUsage, error) {
+	var list []BlobUsage
+	err := r.db.WithContext(ctx).Raw(`
+		SELECT b.sha256, b.path, b.ref_count, COUNT(a.id) AS used
+		FROM blobs b LEFT JOIN attachments a ON a.sha256 = b.sha256
+		GROUP BY b.sha256, b.path, b.ref_count
+		HAVING b.ref_count <> COUNT(a.id)
+		ORDER BY b.sha256`).Scan(&list).Error
+	return list, err
+}
+
+func (r *reconcileRepoPG) RepairBlob(ctx context.Context, sha string, remove func(b *models.Blob) error) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var b models.Blob
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("sha256 = ?", sha).First(&b).Error
+		if err == gorm.ErrRecordNotFound {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		var used int64
+		if err := tx.Model(&models.Attachment{}).Where("sha256 = ?", sha).Count(&used).Error; err != nil {
+			return err
+		}
+		if used > 0 {
+			return tx.Model(&b).Update("ref_count", used).Error
+		}
+		if err := remove(&b); err != nil {
+			return err
+		}
+		return tx.Delete(&b).Error
+	})
+}
@@ -0,0 +1,19 @@
+package repository
+
+import (
+	"context"
+
+	"example.com/defect-control-system/internal/models"
+)
+
+type SavedViewRepository interface {
+	Create(ctx context.Context, v *models.SavedView) error
+	FindByID(ctx context.Context, id uint) (*models.SavedView, error)
+	Update(ctx context.Context, v *models.SavedView) error
+	Delete(ctx context.Context, id uint) error
+	// ListVisible returns views owned by the user plus views shared in the given projects
+	// (or in any project when allShared is set). projectID narrows the result to one project.
+	ListVisible(ctx context.Context, userID uint, sharedProjectIDs []uint, allShared bool, projectID *uint) ([]*models.SavedView, error)
+	// ClearDefault unsets the default flag on the owner's other views in the same scope
+	ClearDefault(ctx context.Context, ownerID uint, projectID *uint, exceptID uint) error
+}
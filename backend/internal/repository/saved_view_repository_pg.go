@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"context"
+
+	"example.com/defect-control-system/internal/models"
+	"gorm.io/gorm"
+)
+
+type savedViewRepoPG struct{ db *gorm.DB }
+
+func NewSavedViewRepository(db *gorm.DB) SavedViewRepository { return &savedViewRepoPG{db: db} }
+
+func (r *savedViewRepoPG) Create(ctx context.Context, v *models.SavedView) error {
+	return r.db.WithContext(ctx).Create(v).Error
+}
+
+func (r *savedViewRepoPG) FindByID(ctx context.Context, id uint) (*models.SavedView, error) {
+	var v models.SavedView
+	if err := r.db.WithContext(ctx).First(&v, id).Error; err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+func (r *savedViewRepoPG) Update(ctx context.Context, v *models.SavedView) error {
+	return r.db.WithContext(ctx).Save(v).Error
+}
+
+func (r *savedViewRepoPG) Delete(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Delete(&models.SavedView{}, id).Error
+}
+
+func (r *savedViewRepoPG) ListVisible(ctx context.Context, userID uint, sharedProjectIDs []uint, allShared bool, projectID *uint) ([]*models.SavedView, error) {
+	var list []*models.SavedView
+	tx := r.db.WithContext(ctx)
+	switch {
+	case allShared:
+		tx = tx.Where("owner_id = ? OR (shared AND project_id IS NOT NULL)", userID)
+	case len(sharedProjectIDs) > 0:
+		tx = tx.Where("owner_id = ? OR (shared AND project_id IN ?)", userID, sharedProjectIDs)
+	default:
+		tx = tx.Where("owner_id = ?", userID)
+	}
+	if projectID != nil {
+		tx = tx.Where("project_id = ?", *projectID)
+	}
+	if err := tx.Order("is_default desc, name asc").Find(&list).Error; err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func (r *savedViewRepoPG) ClearDefault(ctx context.Context, ownerID uint, projectID *uint, exceptID uint) error {
+	tx := r.db.WithContext(ctx).Model(&models.SavedView{}).Where("owner_id = ? AND id <> ?", ownerID, exceptID)
+	if projectID != nil {
+		tx = tx.Where("project_id = ?", *projectID)
+	} else {
+		tx = tx.Where("project_id IS NULL")
+	}
+	return tx.Update("is_default", false).Error
+}
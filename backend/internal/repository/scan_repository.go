@@ -0,0 +1,20 @@
+package repository
+
+import (
+	"context"
+
+	"example.com/defect-control-system/internal/models"
+)
+
+// ScanRepository records antivirus verdicts. Verdicts apply to content, so
+// they are stored for every attachment of the same SHA-256
+type ScanRepository interface {
+	// Pending lists attachments waiting for a scan, at most one per content
+	Pending(ctx context.Context, limit int) ([]*models.Attachment, error)
+	// Settle sets the status of the pending attachments of content sha
+	Settle(ctx context.Context, sha, status, detail string) error
+	// Quarantine marks every attachment of content sha infected while the
+	// blob row is locked; move relocates the file and returns its new path,
+	// which the blob and the attachments then point at
+	Quarantine(ctx context.Context, sha, signature string, move func(b *models.Blob) (string, error)) error
+}
@@ -0,0 +1,45 @@
+package repository
+
+import (
+	"context"
+
+	"example.com/defect-control-system/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type scanRepoPG struct{ db *gorm.DB }
+
+func NewScanRepository(db *gorm.DB) ScanRepository { return &scanRepoPG{db: db} }
+
+func (r *scanRepoPG) Pending(ctx context.Context, limit int) ([]*models.Attachment, error) {
+	var list []*models.Attachment
+	err := r.db.WithContext(ctx).Raw(`SELECT DISTINCT ON (sha256) * FROM attachments
+		WHERE scan_status = ? AND sha256 <> '' ORDER BY sha256, id LIMIT ?`, models.AttachmentPendingScan, limit).
+		Scan(&list).Error
+	return list, err
+}
+
+func (r *scanRepoPG) Settle(ctx context.Context, sha, status, detail string) error {
+	return r.db.WithContext(ctx).Model(&models.Attachment{}).
+		Where("sha256 = ? AND scan_status = ?", sha, models.AttachmentPendingScan).
+		Updates(map[string]interface{}{"scan_status": status, "scan_detail": detail}).Error
+}
+
+func (r *scanRepoPG) Quarantine(ctx context.Context, sha, signature string, move func(b *models.Blob) (string, error)) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var b models.Blob
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("sha256 = ?", sha).First(&b).Error; err != nil {
+			return err
+		}
+		path, err := move(&b)
+		if err != nil {
+			return err
+		}
+		if err := tx.Model(&b).Update("path", path).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.Attachment{}).Where("sha256 = ?", sha).
+			Updates(map[string]interface{}{"scan_status": models.AttachmentInfected, "scan_detail": signature, "path": path}).Error
+	})
+}
@@ -0,0 +1,32 @@
+package repository
+
+import (
+	"context"
+
+	"example.com/defect-control-system/internal/models"
+)
+
+// Search hit kinds
+const (
+	SearchKindDefect     = "defect"
+	SearchKindComment    = "comment"
+	SearchKindAttachment = "attachment"
+)
+
+// SearchParams describes a full-text query over defects, comments and attachments.
+type SearchParams struct {
+	Query string
+	// ProjectIDs restricts hits to these projects unless AllProjects is set
+	ProjectIDs  []uint
+	AllProjects bool
+	// IncludeInternal adds internal comments to the searched set
+	IncludeInternal bool
+	// Kinds limits the searched entities; empty means all of them
+	Kinds  []string
+	Limit  int
+	Offset int
+}
+
+type SearchRepository interface {
+	Search(ctx context.Context, p SearchParams) ([]*models.SearchHit, error)
+}
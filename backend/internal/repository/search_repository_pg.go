@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"context"
+	"strings"
+
+	"example.com/defect-control-system/internal/models"
+	"gorm.io/gorm"
+)
+
+type searchRepoPG struct{ db *gorm.DB }
+
+func NewSearchRepository(db *gorm.DB) SearchRepository { return &searchRepoPG{db: db} }
+
+// headlineOpts wraps matched words in <mark> so the frontend can highlight them
+const headlineOpts = "StartSel=<mark>, StopSel=</mark>, MaxFragments=2, MaxWords=25, MinWords=8"
+
+// the query is parsed with both configurations so Russian and English word forms match
+const searchQueryCTE = `WITH q AS (SELECT websearch_to_tsquery('russian', @q) || websearch_to_tsquery('english', @q) AS query)`
+
+const searchDefectsSQL = `SELECT 'defect' AS kind, d.id, d.id AS defect_id, d.key AS defect_key, d.project_id, d.title,
+	ts_headline('russian', coalesce(d.title, '') || ' — ' || coalesce(d.description, ''), q.query, '` + headlineOpts + `') AS snippet,
+	ts_rank(d.search_vector, q.query) AS rank
+	FROM defects d CROSS JOIN q
+	WHERE d.search_vector @@ q.query`
+
+const searchCommentsSQL = `SELECT 'comment' AS kind, c.id, c.defect_id, d.key AS defect_key, d.project_id, d.title,
+	ts_headline('russian', coalesce(c.body, ''), q.query, '` + headlineOpts + `') AS snippet,
+	ts_rank(c.search_vector, q.query) AS rank
+	FROM comments c JOIN defects d ON d.id = c.defect_id CROSS JOIN q
+	WHERE c.search_vector @@ q.query`
+
+const searchAttachmentsSQL = `SELECT 'attachment' AS kind, a.id, a.defect_id, d.key AS defect_key, d.project_id, a.filename AS title,
+	ts_headline('english', regexp_replace(coalesce(a.filename, ''), '[._-]+', ' ', 'g'), q.query, '` + headlineOpts + `') AS snippet,
+	ts_rank(a.search_vector, q.query) AS rank
+	FROM attachments a JOIN defects d ON d.id = a.defect_id CROSS JOIN q
+	WHERE a.search_vector @@ q.query`
+
+func (r *searchRepoPG) Search(ctx context.Context, p SearchParams) ([]*models.SearchHit, error) {
+	projectFilter := ""
+	if !p.AllProjects {
+		projectFilter = " AND d.project_id IN @projects"
+	}
+	var parts []string
+	if wantKind(p.Kinds, SearchKindDefect) {
+		parts = append(parts, searchDefectsSQL+projectFilter)
+	}
+	if wantKind(p.Kinds, SearchKindComment) {
+		sql := searchCommentsSQL + projectFilter
+		if !p.IncludeInternal {
+			sql += " AND c.visibility = '" + models.CommentVisibilityPublic + "'"
+		}
+		parts = append(parts, sql)
+	}
+	if wantKind(p.Kinds, SearchKindAttachment) {
+		parts = append(parts, searchAttachmentsSQL+projectFilter)
+	}
+	var hits []*models.SearchHit
+	if len(parts) == 0 {
+		return hits, nil
+	}
+	sql := searchQueryCTE + " SELECT * FROM (" + strings.Join(parts, " UNION ALL ") + ") hits ORDER BY rank DESC, kind, id DESC LIMIT @limit OFFSET @offset"
+	args := map[string]interface{}{"q": p.Query, "projects": p.ProjectIDs, "limit": p.Limit, "offset": p.Offset}
+	if err := r.db.WithContext(ctx).Raw(sql, args).Scan(&hits).Error; err != nil {
+		return nil, err
+	}
+	return hits, nil
+}
+
+func wantKind(kinds []string, kind string) bool {
+	if len(kinds) == 0 {
+		return true
+	}
+	for _, k := range kinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,11 @@
+package repository
+
+import (
+	"context"
+
+	"example.com/defect-control-system/internal/models"
+)
+
+type StatsRepository interface {
+	ProjectStats(ctx context.Context, projectID uint) (*models.ProjectStats, error)
+}
@@ -0,0 +1,102 @@
+package repository
+
+import (
+	"context"
+
+	"example.com/defect-control-system/internal/models"
+	"gorm.io/gorm"
+)
+
+type statsRepoPG struct{ db *gorm.DB }
+
+func NewStatsRepository(db *gorm.DB) StatsRepository { return &statsRepoPG{db: db} }
+
+// maxStatsCitations limits the most cited clauses reported in project stats
+const maxStatsCitations = 20
+
+type groupCount struct {
+	Key   string
+	Count int64
+}
+
+// countBy counts defects of a project grouped by a whitelisted column
+func (r *statsRepoPG) countBy(ctx context.Context, projectID uint, column string) (map[string]int64, error) {
+	var rows []groupCount
+	err := r.db.WithContext(ctx).Model(&models.Defect{}).
+		Select("coalesce("+column+", '') AS key, count(*) AS count").
+		Where("project_id = ?", projectID).Group(column).Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[string]int64, len(rows))
+	for _, row := range rows {
+		out[row.Key] += row.Count
+	}
+	return out, nil
+}
+
+// countByCategory counts defects of a project grouped by an expression over the
+// defect's category (c) and its parent (p)
+func (r *statsRepoPG) countByCategory(ctx context.Context, projectID uint, expr string) (map[string]int64, error) {
+	var rows []groupCount
+	err := r.db.WithContext(ctx).Table("defects d").
+		Select(expr+" AS key, count(*) AS count").
+		Joins("LEFT JOIN defect_categories c ON c.id = d.category_id").
+		Joins("LEFT JOIN defect_categories p ON p.id = c.parent_id").
+		Where("d.project_id = ?", projectID).Group("1").Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[string]int64, len(rows))
+	for _, row := range rows {
+		out[row.Key] = row.Count
+	}
+	return out, nil
+}
+
+func (r *statsRepoPG) ProjectStats(ctx context.Context, projectID uint) (*models.ProjectStats, error) {
+	st := &models.ProjectStats{ProjectID: projectID}
+	if err := r.db.WithContext(ctx).Model(&models.Defect{}).Where("project_id = ?", projectID).Count(&st.Total).Error; err != nil {
+		return nil, err
+	}
+	var err error
+	if st.ByStatus, err = r.countBy(ctx, projectID, "status"); err != nil {
+		return nil, err
+	}
+	if st.BySeverity, err = r.countBy(ctx, projectID, "severity"); err != nil {
+		return nil, err
+	}
+	if st.ByPriority, err = r.countBy(ctx, projectID, "priority"); err != nil {
+		return nil, err
+	}
+	if st.ByCategory, err = r.countByCategory(ctx, projectID, "coalesce(p.code, c.code, '')"); err != nil {
+		return nil, err
+	}
+	if st.ByType, err = r.countByCategory(ctx, projectID, "coalesce(c.code, '')"); err != nil {
+		return nil, err
+	}
+	// every project label is listed, including unused ones
+	st.Labels = []models.LabelUsage{}
+	err = r.db.WithContext(ctx).Table("labels l").
+		Select("l.id AS label_id, l.name, l.color, count(dl.defect_id) AS count").
+		Joins("LEFT JOIN defect_labels dl ON dl.label_id = l.id").
+		Where("l.project_id = ?", projectID).
+		Group("l.id, l.name, l.color").Order("count DESC, l.name").
+		Scan(&st.Labels).Error
+	if err != nil {
+		return nil, err
+	}
+	st.Citations = []models.ClauseUsage{}
+	err = r.db.WithContext(ctx).Table("defect_clauses dc").
+		Select("nc.id AS clause_id, nd.code || ', п. ' || nc.number AS citation, count(*) AS count").
+		Joins("JOIN defects d ON d.id = dc.defect_id").
+		Joins("JOIN normative_clauses nc ON nc.id = dc.normative_clause_id").
+		Joins("JOIN normative_documents nd ON nd.id = nc.document_id").
+		Where("d.project_id = ?", projectID).
+		Group("nc.id, nd.code, nc.number").Order("count DESC, citation").Limit(maxStatsCitations).
+		Scan(&st.Citations).Error
+	if err != nil {
+		return nil, err
+	}
+	return st, nil
+}
@@ -0,0 +1,23 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"example.com/defect-control-system/internal/models"
+)
+
+type UploadRepository interface {
+	// Create stores a new session in the transaction quota runs in unless nil
+	Create(ctx context.Context, s *models.UploadSession, quota *QuotaGuard) error
+	FindByID(ctx context.Context, id string) (*models.UploadSession, error)
+	// UpdateOffset stores the new offset and expiry if the stored offset is
+	// still from; ErrVersionConflict is returned otherwise
+	UpdateOffset(ctx context.Context, s *models.UploadSession, from int64) error
+	// Complete creates the attachment on blob b, as BlobRepository.Attach
+	// does, and links the session to it in one transaction
+	Complete(ctx context.Context, s *models.UploadSession, a *models.Attachment, b *models.Blob, place func() error) error
+	// Expired lists sessions that expired before t, finished or not
+	Expired(ctx context.Context, t time.Time) ([]*models.UploadSession, error)
+	Delete(ctx context.Context, id string) error
+}
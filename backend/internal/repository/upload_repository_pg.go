@@ -0,0 +1,63 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"example.com/defect-control-system/internal/models"
+	"gorm.io/gorm"
+)
+
+type uploadRepoPG struct{ db *gorm.DB }
+
+func NewUploadRepository(db *gorm.DB) UploadRepository { return &uploadRepoPG{db: db} }
+
+func (r *uploadRepoPG) Create(ctx context.Context, s *models.UploadSession, quota *QuotaGuard) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := guardQuota(tx, quota); err != nil {
+			return err
+		}
+		return tx.Create(s).Error
+	})
+}
+
+func (r *uploadRepoPG) FindByID(ctx context.Context, id string) (*models.UploadSession, error) {
+	var s models.UploadSession
+	if err := r.db.WithContext(ctx).Where("id = ?", id).First(&s).Error; err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+func (r *uploadRepoPG) UpdateOffset(ctx context.Context, s *models.UploadSession, from int64) error {
+	res := r.db.WithContext(ctx).Model(s).Where("\"offset\" = ? AND attachment_id IS NULL", from).
+		Select("offset", "expires_at", "updated_at").Updates(s)
+	if res.Error == nil && res.RowsAffected == 0 {
+		return ErrVersionConflict
+	}
+	return res.Error
+}
+
+func (r *uploadRepoPG) Complete(ctx context.Context, s *models.UploadSession, a *models.Attachment, b *models.Blob, place func() error) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := attachBlob(tx, a, b, place); err != nil {
+			return err
+		}
+		s.AttachmentID = &a.ID
+		res := tx.Model(s).Where("attachment_id IS NULL").Select("attachment_id", "updated_at").Updates(s)
+		if res.Error == nil && res.RowsAffected == 0 {
+			return ErrVersionConflict
+		}
+		return res.Error
+	})
+}
+
+func (r *uploadRepoPG) Expired(ctx context.Context, t time.Time) ([]*models.UploadSession, error) {
+	var list []*models.UploadSession
+	err := r.db.WithContext(ctx).Where("expires_at < ?", t).Find(&list).Error
+	return list, err
+}
+
+func (r *uploadRepoPG) Delete(ctx context.Context, id string) error {
+	return r.db.WithContext(ctx).Where("id = ?", id).Delete(&models.UploadSession{}).Error
+}
@@ -0,0 +1,19 @@
+package repository
+
+import (
+	"context"
+
+	"example.com/defect-control-system/internal/models"
+)
+
+type VerificationRepository interface {
+	// Submit posts the evidence comment, records the pending verification with
+	// its photos and moves the defect to verification, in one transaction
+	Submit(ctx context.Context, v *models.Verification, c *models.Comment, d *models.Defect) error
+	// Decide stores the decision of v and writes the given defect columns like
+	// DefectRepository.Update, in one transaction
+	Decide(ctx context.Context, v *models.Verification, d *models.Defect, columns []string) error
+	// Pending returns the verification of the defect awaiting a decision
+	Pending(ctx context.Context, defectID uint) (*models.Verification, error)
+	ListByDefect(ctx context.Context, defectID uint) ([]*models.Verification, error)
+}
@@ -0,0 +1,65 @@
+package repository
+
+import (
+	"context"
+
+	"example.com/defect-control-system/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type verificationRepoPG struct{ db *gorm.DB }
+
+func NewVerificationRepository(db *gorm.DB) VerificationRepository {
+	return &verificationRepoPG{db: db}
+}
+
+func (r *verificationRepoPG) Submit(ctx context.Context, v *models.Verification, c *models.Comment, d *models.Defect) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(c).Error; err != nil {
+			return err
+		}
+		v.CommentID = &c.ID
+		// photos are existing attachments: link them without upserting
+		if err := tx.Omit("Photos.*").Create(v).Error; err != nil {
+			return err
+		}
+		return versionedUpdate(tx.Omit(clause.Associations), d, &d.Version, []string{"status"})
+	})
+}
+
+func (r *verificationRepoPG) Decide(ctx context.Context, v *models.Verification, d *models.Defect, columns []string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(v).Where("decision = ?", models.VerificationPending).
+			Select("decision", "reason", "reviewer_id", "reviewed_at").Updates(v)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			// decided concurrently by someone else
+			return ErrVersionConflict
+		}
+		return versionedUpdate(tx.Omit(clause.Associations), d, &d.Version, columns)
+	})
+}
+
+func (r *verificationRepoPG) Pending(ctx context.Context, defectID uint) (*models.Verification, error) {
+	var v models.Verification
+	err := r.db.WithContext(ctx).Preload("Comment").Preload("Photos").
+		Where("defect_id = ? AND decision = ?", defectID, models.VerificationPending).
+		Order("id desc").First(&v).Error
+	if err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+func (r *verificationRepoPG) ListByDefect(ctx context.Context, defectID uint) ([]*models.Verification, error) {
+	var list []*models.Verification
+	err := r.db.WithContext(ctx).Preload("SubmittedBy").Preload("Reviewer").Preload("Comment").Preload("Photos").
+		Where("defect_id = ?", defectID).Order("id").Find(&list).Error
+	if err != nil {
+		return nil, err
+	}
+	return list, nil
+}
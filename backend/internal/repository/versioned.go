@@ -0,0 +1,19 @@
+package repository
+
+import "gorm.io/gorm"
+
+// versionedUpdate writes columns of model plus its version and updated_at,
+// guarded by the version the caller read. On success *version is incremented
+func versionedUpdate(tx *gorm.DB, model interface{}, version *int, columns []string) error {
+	read := *version
+	*version = read + 1
+	cols := append(append([]string{}, columns...), "version", "updated_at")
+	res := tx.Model(model).Where("version = ?", read).Select(cols).Updates(model)
+	if res.Error == nil && res.RowsAffected == 0 {
+		res.Error = ErrVersionConflict
+	}
+	if res.Error != nil {
+		*version = read
+	}
+	return res.Error
+}
@@ -0,0 +1,162 @@
+package service
+
+import (
+	"bytes"
+	"fmt"
+	"image/png"
+	"sync"
+
+	"example.com/defect-control-system/internal/models"
+	"example.com/defect-control-system/internal/pdf"
+)
+
+// DefaultActFont is used for act PDFs when acts.font is not configured
+const DefaultActFont = "/usr/share/fonts/truetype/dejavu/DejaVuSans.ttf"
+
+type pdfActRenderer struct {
+	fontPath string
+	once     sync.Once
+	font     *pdf.Font
+	err      error
+}
+
+// NewPDFActRenderer renders acts as A4 PDFs set in the TrueType font at
+// fontPath, which must cover Cyrillic. The font is loaded on first use.
+func NewPDFActRenderer(fontPath string) ActRenderer {
+	if fontPath == "" {
+		fontPath = DefaultActFont
+	}
+	return &pdfActRenderer{fontPath: fontPath}
+}
+
+const (
+	actMargin = 50.0
+	actBottom = 60.0
+)
+
+// actColumns are the defect table columns: header and width in points
+var actColumns = []struct {
+	title string
+	width float64
+}{
+	{"№", 25}, {"Дефект", 65}, {"Наименование", 215}, {"Подтвердил", 110}, {"Дата", 80},
+}
+
+func (r *pdfActRenderer) Render(a *models.AcceptanceAct, p *models.Project) ([]byte, error) {
+	r.once.Do(func() { r.font, r.err = pdf.LoadFont(r.fontPath) })
+	if r.err != nil {
+		return nil, fmt.Errorf("act font: %w", r.err)
+	}
+	doc := pdf.New(r.font)
+	doc.Title = fmt.Sprintf("Акт № %d — %s", a.Number, a.Title)
+	if a.SignedAt != nil {
+		doc.Created = *a.SignedAt
+	} else {
+		doc.Created = a.CreatedAt
+	}
+	width := pdf.PageWidth - 2*actMargin
+	y := pdf.PageHeight - actMargin
+	// need starts a new page when fewer than h points are left
+	need := func(h float64) {
+		if y-h < actBottom {
+			doc.AddPage()
+			y = pdf.PageHeight - actMargin
+		}
+	}
+	para := func(size float64, text string) {
+		for _, line := range doc.Wrap(text, size, width) {
+			need(size * 1.4)
+			y -= size * 1.4
+			doc.Text(actMargin, y, size, line)
+		}
+	}
+
+	doc.AddPage()
+	para(16, fmt.Sprintf("АКТ № %d", a.Number))
+	para(12, a.Title)
+	y -= 6
+	para(10, "Объект: "+p.Name)
+	if p.Address != "" {
+		para(10, "Адрес: "+p.Address)
+	}
+	date := doc.Created.UTC().Format("02.01.2006")
+	para(10, "Дата: "+date)
+	if a.Status != models.ActSigned {
+		para(10, "ПРОЕКТ — акт не подписан всеми сторонами и не имеет силы")
+	}
+	y -= 8
+	para(10, fmt.Sprintf("Комиссия подтверждает устранение следующих дефектов (%d):", len(a.Defects)))
+	y -= 4
+
+	row := func(cells []string) {
+		lines := make([][]string, len(cells))
+		height := 1
+		for i, cell := range cells {
+			lines[i] = doc.Wrap(cell, 9, actColumns[i].width-6)
+			if len(lines[i]) > height {
+				height = len(lines[i])
+			}
+		}
+		h := float64(height)*11 + 6
+		need(h)
+		doc.Line(actMargin, y, actMargin+width, y)
+		x := actMargin
+		for i, ls := range lines {
+			for j, l := range ls {
+				doc.Text(x+3, y-11-float64(j)*11, 9, l)
+			}
+			x += actColumns[i].width
+		}
+		y -= h
+	}
+	header := make([]string, len(actColumns))
+	for i, c := range actColumns {
+		header[i] = c.title
+	}
+	row(header)
+	for i, d := range a.Defects {
+		by, at := "", ""
+		if d.VerifiedBy != nil {
+			by = d.VerifiedBy.Name
+		}
+		if d.VerifiedAt != nil {
+			at = d.VerifiedAt.UTC().Format("02.01.2006")
+		}
+		row([]string{fmt.Sprint(i + 1), d.Key, d.Title, by, at})
+	}
+	doc.Line(actMargin, y, actMargin+width, y)
+	y -= 24
+
+	para(11, "Подписи сторон:")
+	for _, s := range a.Signatories {
+		need(80)
+		y -= 10
+		name := fmt.Sprintf("user #%d", s.UserID)
+		if s.User != nil {
+			name = fmt.Sprintf("%s <%s>", s.User.Name, s.User.Email)
+		}
+		para(10, s.Party+": "+name)
+		switch {
+		case s.SignedAt == nil:
+			para(10, "Подпись: ____________________ (ожидается)")
+			continue
+		case s.Method == models.SignatureDrawn:
+			img, err := png.Decode(bytes.NewReader(s.Image))
+			if err != nil {
+				return nil, fmt.Errorf("signature of user %d: %w", s.UserID, err)
+			}
+			// fit into 160x50 keeping the aspect ratio
+			b := img.Bounds()
+			w, h := 160.0, 160.0*float64(b.Dy())/float64(b.Dx())
+			if h > 50 {
+				w, h = 50*float64(b.Dx())/float64(b.Dy()), 50
+			}
+			y -= h + 4
+			doc.Image(img, actMargin, y, w, h)
+		default:
+			para(10, fmt.Sprintf("Подписано вводом имени «%s» под учётной записью пользователя", s.TypedName))
+		}
+		para(8, "Подписано: "+s.SignedAt.UTC().Format("02.01.2006 15:04 UTC"))
+	}
+	return doc.Bytes()
+}
@@ -0,0 +1,364 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"example.com/defect-control-system/internal/models"
+	"example.com/defect-control-system/internal/repository"
+)
+
+const (
+	maxActDefects = 500
+	// maxSignatureBytes limits drawn signature images
+	maxSignatureBytes = 512 << 10
+	maxSignatureSide  = 2000
+)
+
+// ErrActIntegrity is returned when a stored signed act no longer matches its hash
+var ErrActIntegrity = errors.New("stored act does not match its SHA-256 hash")
+
+// ActSignatoryDTO names a user expected to sign an act and the party they sign for
+type ActSignatoryDTO struct {
+	UserID uint   `json:"user_id" validate:"required"`
+	Party  string `json:"party" validate:"required" example:"Заказчик"`
+}
+
+// CreateActDTO drafts an acceptance act over closed defects of a project
+type CreateActDTO struct {
+	Title       string            `json:"title" example:"Приёмка отделочных работ, секция 2"`
+	DefectIDs   []uint            `json:"defect_ids" validate:"required"`
+	Signatories []ActSignatoryDTO `json:"signatories" validate:"required"`
+}
+
+// SignActDTO is a signature: a drawn PNG image (base64, a data: URL is accepted)
+// or the signer's full name typed as confirmation
+type SignActDTO struct {
+	Method    string `json:"method" validate:"required" example:"typed"`
+	Image     string `json:"image,omitempty"`
+	TypedName string `json:"typed_name,omitempty" example:"Иван Петров"`
+}
+
+// ActRenderer renders an act, with the signatures collected so far, as a document
+type ActRenderer interface {
+	Render(a *models.AcceptanceAct, p *models.Project) ([]byte, error)
+}
+
+// ActService drafts acceptance acts and collects signatures. When the last
+// signatory signs, the act is rendered once, written under the uploads
+// directory and sealed with the SHA-256 of the file; signed acts never change.
+type ActService interface {
+	Create(ctx context.Context, userID uint, role string, projectID uint, dto CreateActDTO) (*models.AcceptanceAct, error)
+	List(ctx context.Context, userID uint, role string, projectID uint) ([]*models.AcceptanceAct, error)
+	Get(ctx context.Context, userID uint, role string, projectID, actID uint) (*models.AcceptanceAct, error)
+	Sign(ctx context.Context, userID uint, role string, projectID, actID uint, dto SignActDTO) (*models.AcceptanceAct, error)
+	// PDF returns the stored document of a signed act after checking its hash,
+	// or a preview of a draft rendered on the fly
+	PDF(ctx context.Context, userID uint, role string, projectID, actID uint) (*models.AcceptanceAct, []byte, error)
+}
+
+type actService struct {
+	repo        repository.ActRepository
+	defectRepo  repository.DefectRepository
+	projectRepo repository.ProjectRepository
+	userRepo    repository.UserRepository
+	members     MembershipService
+	renderer    ActRenderer
+	basePath    string
+}
+
+// NewActService stores signed acts under basePath/acts
+func NewActService(r repository.ActRepository, dr repository.DefectRepository, pr repository.ProjectRepository, ur repository.UserRepository, m MembershipService, renderer ActRenderer, basePath string) ActService {
+	return &actService{repo: r, defectRepo: dr, projectRepo: pr, userRepo: ur, members: m, renderer: renderer, basePath: basePath}
+}
+
+func (s *actService) Create(ctx context.Context, userID uint, role string, projectID uint, dto CreateActDTO) (*models.AcceptanceAct, error) {
+	ok, err := s.members.CanManageProject(ctx, userID, role, projectID)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrForbidden
+	}
+	if _, err := s.projectRepo.FindByID(ctx, projectID); err != nil {
+		return nil, ErrNotFound
+	}
+	ids := uniqueUints(dto.DefectIDs)
+	if len(ids) == 0 {
+		return nil, invalid("select at least one defect")
+	}
+	if len(ids) > maxActDefects {
+		return nil, invalid("an act lists at most %d defects", maxActDefects)
+	}
+	defects := make([]models.Defect, 0, len(ids))
+	for _, id := range ids {
+		d, err := s.defectRepo.FindByID(ctx, id)
+		if err != nil || d == nil || d.ProjectID != projectID {
+			return nil, invalid("defect %d not found in the project", id)
+		}
+		if d.Status != StatusClosed {
+			return nil, invalid("defect %s is not closed", d.Key)
+		}
+		defects = append(defects, *d)
+	}
+	if len(dto.Signatories) == 0 {
+		return nil, invalid("at least one signatory is required")
+	}
+	signatories := make([]models.ActSignatory, 0, len(dto.Signatories))
+	seen := map[uint]bool{}
+	for _, sd := range dto.Signatories {
+		party := strings.TrimSpace(sd.Party)
+		if party == "" {
+			return nil, invalid("signatory party is required")
+		}
+		if seen[sd.UserID] {
+			return nil, invalid("user %d is listed twice", sd.UserID)
+		}
+		seen[sd.UserID] = true
+		u, err := s.userRepo.FindByID(ctx, sd.UserID)
+		if err != nil || u == nil {
+			return nil, invalid("user %d not found", sd.UserID)
+		}
+		ok, err := s.members.CanAccessProject(ctx, u.ID, u.Role, projectID)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, invalid("user %d has no access to the project", sd.UserID)
+		}
+		signatories = append(signatories, models.ActSignatory{UserID: u.ID, User: u, Party: party})
+	}
+	title := strings.TrimSpace(dto.Title)
+	if title == "" {
+		title = "Приёмка устранённых дефектов"
+	}
+	a := &models.AcceptanceAct{
+		ProjectID:   projectID,
+		Title:       title,
+		Status:      models.ActDraft,
+		Defects:     defects,
+		Signatories: signatories,
+		CreatedByID: userID,
+	}
+	if err := s.repo.Create(ctx, a); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+func (s *actService) List(ctx context.Context, userID uint, role string, projectID uint) ([]*models.AcceptanceAct, error) {
+	ok, err := s.members.CanAccessProject(ctx, userID, role, projectID)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrForbidden
+	}
+	return s.repo.ListByProject(ctx, projectID)
+}
+
+func (s *actService) Get(ctx context.Context, userID uint, role string, projectID, actID uint) (*models.AcceptanceAct, error) {
+	ok, err := s.members.CanAccessProject(ctx, userID, role, projectID)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrForbidden
+	}
+	a, err := s.repo.FindByID(ctx, actID)
+	if err != nil || a == nil || a.ProjectID != projectID {
+		return nil, ErrNotFound
+	}
+	return a, nil
+}
+
+func (s *actService) Sign(ctx context.Context, userID uint, role string, projectID, actID uint, dto SignActDTO) (*models.AcceptanceAct, error) {
+	a, err := s.sign(ctx, userID, role, projectID, actID, dto)
+	if errors.Is(err, repository.ErrVersionConflict) {
+		// another signer committed between our read and our write, possibly
+		// leaving this signature the last one: read again and seal if so
+		a, err = s.sign(ctx, userID, role, projectID, actID, dto)
+	}
+	if errors.Is(err, repository.ErrVersionConflict) {
+		return nil, fmt.Errorf("%w: the act was signed concurrently, reload it", ErrPreconditionFailed)
+	}
+	return a, err
+}
+
+// sign stores the signature on the act as read now, sealing it when this is
+// the last one; a repository conflict is returned as is
+func (s *actService) sign(ctx context.Context, userID uint, role string, projectID, actID uint, dto SignActDTO) (*models.AcceptanceAct, error) {
+	a, err := s.Get(ctx, userID, role, projectID, actID)
+	if err != nil {
+		return nil, err
+	}
+	if a.Status != models.ActDraft {
+		return nil, invalid("the act is already signed")
+	}
+	var sig *models.ActSignatory
+	pending := 0
+	for i := range a.Signatories {
+		if a.Signatories[i].UserID == userID {
+			sig = &a.Signatories[i]
+		}
+		if a.Signatories[i].SignedAt == nil {
+			pending++
+		}
+	}
+	if sig == nil {
+		return nil, fmt.Errorf("%w: you are not a signatory of this act", ErrForbidden)
+	}
+	if sig.SignedAt != nil {
+		return nil, invalid("you have already signed this act")
+	}
+	switch dto.Method {
+	case models.SignatureDrawn:
+		img, err := decodeSignature(dto.Image)
+		if err != nil {
+			return nil, err
+		}
+		sig.Image = img
+	case models.SignatureTyped:
+		// the typed name must be the name of the signed-in account
+		u, err := s.userRepo.FindByID(ctx, userID)
+		if err != nil || u == nil {
+			return nil, ErrNotFound
+		}
+		typed := strings.Join(strings.Fields(dto.TypedName), " ")
+		if typed == "" || !strings.EqualFold(typed, strings.Join(strings.Fields(u.Name), " ")) {
+			return nil, invalid("typed name must match the name of your account")
+		}
+		sig.TypedName = typed
+	default:
+		return nil, invalid("unknown signature method %q", dto.Method)
+	}
+	now := time.Now().UTC()
+	sig.Method, sig.SignedAt = dto.Method, &now
+
+	var sealed *models.AcceptanceAct
+	var full string
+	if pending == 1 {
+		if sealed, full, err = s.seal(ctx, a, now); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.repo.Sign(ctx, sig, sealed); err != nil {
+		if full != "" {
+			os.Remove(full)
+		}
+		return nil, err
+	}
+	return s.repo.FindByID(ctx, a.ID)
+}
+
+// seal renders the fully signed act and writes it read-only under the uploads
+// directory, returning the act with its file and the file's full path
+func (s *actService) seal(ctx context.Context, a *models.AcceptanceAct, now time.Time) (*models.AcceptanceAct, string, error) {
+	// the act certifies closed defects: refuse if any was reopened meanwhile
+	for _, d := range a.Defects {
+		cur, err := s.defectRepo.FindByID(ctx, d.ID)
+		if err != nil || cur.Status != StatusClosed {
+			return nil, "", invalid("defect %s is no longer closed", d.Key)
+		}
+	}
+	p, err := s.projectRepo.FindByID(ctx, a.ProjectID)
+	if err != nil {
+		return nil, "", ErrNotFound
+	}
+	a.Status, a.SignedAt = models.ActSigned, &now
+	data, err := s.renderer.Render(a, p)
+	if err != nil {
+		return nil, "", err
+	}
+	sum := sha256.Sum256(data)
+	a.SHA256, a.Size = hex.EncodeToString(sum[:]), int64(len(data))
+	a.Path = filepath.ToSlash(filepath.Join("acts", fmt.Sprint(a.ProjectID), fmt.Sprintf("%d-%s.pdf", a.Number, a.SHA256[:16])))
+	full := filepath.Join(s.basePath, filepath.FromSlash(a.Path))
+	if err := writeReadOnly(full, data); err != nil {
+		return nil, "", err
+	}
+	return a, full, nil
+}
+
+func (s *actService) PDF(ctx context.Context, userID uint, role string, projectID, actID uint) (*models.AcceptanceAct, []byte, error) {
+	a, err := s.Get(ctx, userID, role, projectID, actID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if a.Status != models.ActSigned {
+		p, err := s.projectRepo.FindByID(ctx, a.ProjectID)
+		if err != nil {
+			return nil, nil, ErrNotFound
+		}
+		data, err := s.renderer.Render(a, p)
+		return a, data, err
+	}
+	data, err := os.ReadFile(filepath.Join(s.basePath, filepath.FromSlash(a.Path)))
+	if err != nil {
+		return nil, nil, err
+	}
+	sum := sha256.Sum256(data)
+	if hex.EncodeToString(sum[:]) != a.SHA256 {
+		return nil, nil, ErrActIntegrity
+	}
+	return a, data, nil
+}
+
+// decodeSignature decodes a base64 PNG and checks it is a reasonable image
+func decodeSignature(s string) ([]byte, error) {
+	if i := strings.Index(s, ","); strings.HasPrefix(s, "data:") && i > 0 {
+		s = s[i+1:]
+	}
+	if base64.StdEncoding.DecodedLen(len(s)) > maxSignatureBytes {
+		return nil, invalid("signature image is too large")
+	}
+	data, err := base64.StdEncoding.DecodeString(strings.TrimSpace(s))
+	if err != nil || len(data) == 0 {
+		return nil, invalid("signature image must be a base64-encoded PNG")
+	}
+	cfg, err := png.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, invalid("signature image must be a base64-encoded PNG")
+	}
+	if cfg.Width == 0 || cfg.Height == 0 || cfg.Width > maxSignatureSide || cfg.Height > maxSignatureSide {
+		return nil, invalid("signature image has invalid dimensions")
+	}
+	return data, nil
+}
+
+// writeReadOnly writes data to path through a temporary file and leaves it read-only
+func writeReadOnly(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".act-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0o444); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
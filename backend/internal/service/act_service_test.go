@@ -0,0 +1,311 @@
+package service_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"example.com/defect-control-system/internal/models"
+	"example.com/defect-control-system/internal/repository"
+	"example.com/defect-control-system/internal/service"
+)
+
+// actDefectRepo serves defects by id
+type actDefectRepo struct {
+	mockDefectRepo
+	defects map[uint]*models.Defect
+}
+
+func (m *actDefectRepo) FindByID(ctx context.Context, id uint) (*models.Defect, error) {
+	d, ok := m.defects[id]
+	if !ok {
+		return nil, errors.New("not found")
+	}
+	c := *d
+	return &c, nil
+}
+
+// actUserRepo knows user 1 (engineer, member of project 7), 9 (manager) and 2 (outsider)
+type actUserRepo struct{ mockUserRepo }
+
+func (m *actUserRepo) FindByID(ctx context.Context, id uint) (*models.User, error) {
+	switch id {
+	case 1:
+		return &models.User{ID: 1, Name: "Иван  Петров", Email: "ivan@example.com", Role: "engineer"}, nil
+	case 9:
+		return &models.User{ID: 9, Name: "Anna Smirnova", Email: "anna@example.com", Role: "manager"}, nil
+	case 2:
+		return &models.User{ID: 2, Name: "Outsider", Role: "engineer"}, nil
+	}
+	return nil, errors.New("not found")
+}
+
+// mockActRepo keeps acts in memory. mu stands for the row lock of Sign;
+// with barrier set, the first two Sign calls wait for each other so both
+// signers read the act before either writes
+type mockActRepo struct {
+	mu      sync.Mutex
+	acts    map[uint]*models.AcceptanceAct
+	barrier *sync.WaitGroup
+	calls   atomic.Int32
+}
+
+func (m *mockActRepo) Create(ctx context.Context, a *models.AcceptanceAct) error {
+	if m.acts == nil {
+		m.acts = map[uint]*models.AcceptanceAct{}
+	}
+	a.ID, a.Number = uint(len(m.acts)+1), len(m.acts)+1
+	for i := range a.Signatories {
+		a.Signatories[i].ID, a.Signatories[i].ActID = uint(i+1), a.ID
+	}
+	m.acts[a.ID] = a
+	return nil
+}
+func (m *mockActRepo) FindByID(ctx context.Context, id uint) (*models.AcceptanceAct, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	a, ok := m.acts[id]
+	if !ok {
+		return nil, errors.New("not found")
+	}
+	c := *a
+	c.Signatories = append([]models.ActSignatory{}, a.Signatories...)
+	return &c, nil
+}
+func (m *mockActRepo) ListByProject(ctx context.Context, projectID uint) ([]*models.AcceptanceAct, error) {
+	var list []*models.AcceptanceAct
+	for _, a := range m.acts {
+		if a.ProjectID == projectID {
+			list = append(list, a)
+		}
+	}
+	return list, nil
+}
+func (m *mockActRepo) Sign(ctx context.Context, s *models.ActSignatory, sealed *models.AcceptanceAct) error {
+	if m.barrier != nil && m.calls.Add(1) <= 2 {
+		m.barrier.Done()
+		m.barrier.Wait()
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	a := m.acts[s.ActID]
+	if a.Status != models.ActDraft {
+		return repository.ErrVersionConflict
+	}
+	unsigned := 0
+	for i := range a.Signatories {
+		if a.Signatories[i].ID == s.ID {
+			if a.Signatories[i].SignedAt != nil {
+				return repository.ErrVersionConflict
+			}
+			continue
+		}
+		if a.Signatories[i].SignedAt == nil {
+			unsigned++
+		}
+	}
+	if (sealed == nil) != (unsigned > 0) {
+		return repository.ErrVersionConflict
+	}
+	for i := range a.Signatories {
+		if a.Signatories[i].ID == s.ID {
+			a.Signatories[i] = *s
+		}
+	}
+	if sealed != nil {
+		a.Status, a.Path, a.SHA256, a.Size, a.SignedAt = sealed.Status, sealed.Path, sealed.SHA256, sealed.Size, sealed.SignedAt
+	}
+	return nil
+}
+
+// countingRenderer renders the status and number of signatures
+type countingRenderer struct{ calls int }
+
+func (r *countingRenderer) Render(a *models.AcceptanceAct, p *models.Project) ([]byte, error) {
+	r.calls++
+	signed := 0
+	for _, s := range a.Signatories {
+		if s.SignedAt != nil {
+			signed++
+		}
+	}
+	return []byte(a.Status + string(rune('0'+signed))), nil
+}
+
+func signaturePNG(t *testing.T) string {
+	img := image.NewNRGBA(image.Rect(0, 0, 40, 12))
+	for x := 0; x < 40; x++ {
+		img.Set(x, 6, color.NRGBA{A: 255})
+	}
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, img))
+	return "data:image/png;base64," + base64.StdEncoding.EncodeToString(buf.Bytes())
+}
+
+func newActService(t *testing.T, renderer service.ActRenderer) (service.ActService, *actDefectRepo, string) {
+	s, defects, _, dir := newActServiceRepo(t, renderer)
+	return s, defects, dir
+}
+
+func newActServiceRepo(t *testing.T, renderer service.ActRenderer) (service.ActService, *actDefectRepo, *mockActRepo, string) {
+	defects := &actDefectRepo{defects: map[uint]*models.Defect{
+		1: {ID: 1, ProjectID: 7, Key: "TWR-1", Title: "Трещина в стене", Status: "closed"},
+		2: {ID: 2, ProjectID: 7, Key: "TWR-2", Title: "Протечка", Status: "in_progress"},
+		3: {ID: 3, ProjectID: 8, Key: "NRD-1", Status: "closed"},
+		4: {ID: 4, ProjectID: 7, Key: "TWR-4", Title: "Нет заземления", Status: "closed"},
+	}}
+	members := service.NewMembershipService(&mockMemberRepo{}, &mockProjectRepo{}, &actUserRepo{})
+	dir := t.TempDir()
+	repo := &mockActRepo{}
+	s := service.NewActService(repo, defects, &mockProjectRepo{}, &actUserRepo{}, members, renderer, dir)
+	return s, defects, repo, dir
+}
+
+func TestActs_SignAndSeal(t *testing.T) {
+	renderer := &countingRenderer{}
+	s, defects, dir := newActService(t, renderer)
+	ctx := context.Background()
+	signatories := []service.ActSignatoryDTO{{UserID: 9, Party: "Заказчик"}, {UserID: 1, Party: "Подрядчик"}}
+
+	// only managers draft, only closed defects of the project, signatories need access
+	_, err := s.Create(ctx, 1, "engineer", 7, service.CreateActDTO{DefectIDs: []uint{1}, Signatories: signatories})
+	assert.ErrorIs(t, err, service.ErrForbidden)
+	_, err = s.Create(ctx, 9, "manager", 7, service.CreateActDTO{DefectIDs: []uint{1, 2}, Signatories: signatories})
+	assert.Error(t, err)
+	_, err = s.Create(ctx, 9, "manager", 7, service.CreateActDTO{DefectIDs: []uint{3}, Signatories: signatories})
+	assert.Error(t, err)
+	_, err = s.Create(ctx, 9, "manager", 7, service.CreateActDTO{DefectIDs: []uint{1}, Signatories: []service.ActSignatoryDTO{{UserID: 2, Party: "x"}}})
+	assert.Error(t, err)
+
+	a, err := s.Create(ctx, 9, "manager", 7, service.CreateActDTO{DefectIDs: []uint{1, 4, 1}, Signatories: signatories})
+	require.NoError(t, err)
+	assert.Equal(t, models.ActDraft, a.Status)
+	assert.Len(t, a.Defects, 2)
+	assert.NotEmpty(t, a.Title)
+
+	// drafts are previewed on the fly
+	_, data, err := s.PDF(ctx, 1, "engineer", 7, a.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "draft0", string(data))
+
+	// a typed signature must be the account's name, outsiders cannot sign
+	_, err = s.Sign(ctx, 1, "engineer", 7, a.ID, service.SignActDTO{Method: "typed", TypedName: "Пётр Иванов"})
+	assert.Error(t, err)
+	_, err = s.Sign(ctx, 2, "admin", 7, a.ID, service.SignActDTO{Method: "typed", TypedName: "Outsider"})
+	assert.ErrorIs(t, err, service.ErrForbidden)
+	_, err = s.Sign(ctx, 9, "manager", 7, a.ID, service.SignActDTO{Method: "drawn", Image: "bm90IGEgcG5n"})
+	assert.Error(t, err)
+
+	a, err = s.Sign(ctx, 1, "engineer", 7, a.ID, service.SignActDTO{Method: "typed", TypedName: "иван петров"})
+	require.NoError(t, err)
+	assert.Equal(t, models.ActDraft, a.Status)
+	_, err = s.Sign(ctx, 1, "engineer", 7, a.ID, service.SignActDTO{Method: "typed", TypedName: "Иван Петров"})
+	assert.Error(t, err, "signing twice")
+
+	// a defect reopened meanwhile blocks sealing
+	defects.defects[4].Status = "in_progress"
+	_, err = s.Sign(ctx, 9, "manager", 7, a.ID, service.SignActDTO{Method: "drawn", Image: signaturePNG(t)})
+	assert.Error(t, err)
+	defects.defects[4].Status = "closed"
+
+	a, err = s.Sign(ctx, 9, "manager", 7, a.ID, service.SignActDTO{Method: "drawn", Image: signaturePNG(t)})
+	require.NoError(t, err)
+	assert.Equal(t, models.ActSigned, a.Status)
+	assert.NotNil(t, a.SignedAt)
+	sum := sha256.Sum256([]byte("signed2"))
+	assert.Equal(t, hex.EncodeToString(sum[:]), a.SHA256)
+
+	// the stored file is served as is and checked against its hash
+	calls := renderer.calls
+	_, data, err = s.PDF(ctx, 2, "manager", 7, a.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "signed2", string(data))
+	assert.Equal(t, calls, renderer.calls)
+	_, err = s.Sign(ctx, 9, "manager", 7, a.ID, service.SignActDTO{Method: "typed", TypedName: "Anna Smirnova"})
+	assert.Error(t, err)
+
+	full := filepath.Join(dir, a.Path)
+	require.NoError(t, os.Chmod(full, 0o644))
+	require.NoError(t, os.WriteFile(full, []byte("tampered"), 0o644))
+	_, _, err = s.PDF(ctx, 9, "manager", 7, a.ID)
+	assert.ErrorIs(t, err, service.ErrActIntegrity)
+
+	_, err = s.List(ctx, 2, "engineer", 7)
+	assert.ErrorIs(t, err, service.ErrForbidden)
+	list, err := s.List(ctx, 1, "engineer", 7)
+	assert.NoError(t, err)
+	assert.Len(t, list, 1)
+}
+
+func TestActs_LastSignersConcurrently(t *testing.T) {
+	s, _, repo, _ := newActServiceRepo(t, &countingRenderer{})
+	ctx := context.Background()
+	a, err := s.Create(ctx, 9, "manager", 7, service.CreateActDTO{DefectIDs: []uint{1}, Signatories: []service.ActSignatoryDTO{{UserID: 9, Party: "Заказчик"}, {UserID: 1, Party: "Подрядчик"}}})
+	require.NoError(t, err)
+
+	// both read the act with two signatures pending before either writes
+	repo.barrier = &sync.WaitGroup{}
+	repo.barrier.Add(2)
+	var wg sync.WaitGroup
+	errs := make([]error, 2)
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		_, errs[0] = s.Sign(ctx, 9, "manager", 7, a.ID, service.SignActDTO{Method: "typed", TypedName: "Anna Smirnova"})
+	}()
+	go func() {
+		defer wg.Done()
+		_, errs[1] = s.Sign(ctx, 1, "engineer", 7, a.ID, service.SignActDTO{Method: "typed", TypedName: "Иван Петров"})
+	}()
+	wg.Wait()
+	assert.NoError(t, errs[0])
+	assert.NoError(t, errs[1])
+
+	a, err = s.Get(ctx, 9, "manager", 7, a.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.ActSigned, a.Status, "the later signer seals the act")
+	assert.NotEmpty(t, a.SHA256)
+	for _, sig := range a.Signatories {
+		assert.NotNil(t, sig.SignedAt)
+	}
+}
+
+func TestActs_PDFRenderer(t *testing.T) {
+	if _, err := os.Stat(service.DefaultActFont); err != nil {
+		t.Skip("act font not installed")
+	}
+	s, _, dir := newActService(t, service.NewPDFActRenderer(""))
+	ctx := context.Background()
+
+	a, err := s.Create(ctx, 9, "manager", 7, service.CreateActDTO{
+		DefectIDs:   []uint{1, 4},
+		Signatories: []service.ActSignatoryDTO{{UserID: 9, Party: "Заказчик"}, {UserID: 1, Party: "Подрядчик"}},
+	})
+	require.NoError(t, err)
+	_, err = s.Sign(ctx, 9, "manager", 7, a.ID, service.SignActDTO{Method: "drawn", Image: signaturePNG(t)})
+	require.NoError(t, err)
+	a, err = s.Sign(ctx, 1, "engineer", 7, a.ID, service.SignActDTO{Method: "typed", TypedName: "Иван Петров"})
+	require.NoError(t, err)
+
+	data, err := os.ReadFile(filepath.Join(dir, a.Path))
+	require.NoError(t, err)
+	assert.True(t, bytes.HasPrefix(data, []byte("%PDF-")))
+	assert.Equal(t, int64(len(data)), a.Size)
+	info, err := os.Stat(filepath.Join(dir, a.Path))
+	require.NoError(t, err)
+	assert.Zero(t, info.Mode().Perm()&0o222, "signed acts are stored read-only")
+}
@@ -0,0 +1,354 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"math"
+	"regexp"
+	"slices"
+	"strconv"
+	"sync"
+	"unicode/utf8"
+
+	"example.com/defect-control-system/internal/models"
+	"example.com/defect-control-system/internal/pdf"
+	"example.com/defect-control-system/internal/raster"
+	"example.com/defect-control-system/internal/repository"
+)
+
+// Annotation limits
+const (
+	maxAnnotationShapes = 500
+	maxShapePoints      = 5000
+	maxAnnotationText   = 500
+	maxStrokeWidth      = 200
+	maxFontSize         = 500
+	// maxCoordinate bounds points, generously past any photo
+	maxCoordinate = 100000
+	// defaultStrokeWidth and defaultFontSize apply when a shape leaves them 0
+	defaultStrokeWidth = 4
+	defaultFontSize    = 24
+	// maxRenders bounds concurrent renders, each holding a few decoded copies
+	// of a photo of up to raster.MaxPixels
+	maxRenders = 2
+)
+
+// PutAnnotationDTO replaces the shapes drawn over a photo
+type PutAnnotationDTO struct {
+	Shapes []models.AnnotationShape `json:"shapes"`
+	// Version, when set, must equal the stored version, 0 for a photo not
+	// annotated yet (the handler fills it from If-Match)
+	Version *int `json:"version,omitempty"`
+}
+
+// AnnotationService keeps the vector annotations of image attachments and
+// renders them burnt into a copy of the photo. Access follows the project of
+// the attachment's defect.
+type AnnotationService interface {
+	// Get returns the annotation of the photo; one not annotated yet has no
+	// shapes and version 0
+	Get(ctx context.Context, userID uint, role string, attachmentID uint) (*models.Annotation, error)
+	// Put creates or replaces the annotation
+	Put(ctx context.Context, userID uint, role string, attachmentID uint, dto PutAnnotationDTO) (*models.Annotation, error)
+	Delete(ctx context.Context, userID uint, role string, attachmentID uint, version *int) error
+	// Render draws the annotation over the upright photo and encodes the
+	// result as JPEG. The stored file is left as it is. Renders beyond a few
+	// concurrent ones wait for a slot
+	Render(ctx context.Context, userID uint, role string, attachmentID uint) ([]byte, error)
+}
+
+type annotationService struct {
+	repo       repository.AnnotationRepository
+	attachRepo repository.AttachmentRepository
+	defectRepo repository.DefectRepository
+	members    MembershipService
+	storage    StorageService
+	fontPath   string
+	once       sync.Once
+	font       *pdf.Font
+	fontErr    error
+	renders    chan struct{}
+}
+
+// NewAnnotationService sets annotation text in the TrueType font at fontPath,
+// DefaultActFont if empty. The font is loaded on first use.
+func NewAnnotationService(r repository.AnnotationRepository, ar repository.AttachmentRepository, dr repository.DefectRepository, m MembershipService, st StorageService, fontPath string) AnnotationService {
+	if fontPath == "" {
+		fontPath = DefaultActFont
+	}
+	return &annotationService{repo: r, attachRepo: ar, defectRepo: dr, members: m, storage: st, fontPath: fontPath,
+		renders: make(chan struct{}, maxRenders)}
+}
+
+// attachment loads an image attachment the user may see
+func (s *annotationService) attachment(ctx context.Context, userID uint, role string, id uint) (*models.Attachment, error) {
+	a, err := s.attachRepo.FindByID(ctx, id)
+	if err != nil || a == nil {
+		return nil, ErrNotFound
+	}
+	d, err := s.defectRepo.FindByID(ctx, a.DefectID)
+	if err != nil || d == nil {
+		return nil, ErrNotFound
+	}
+	ok, err := s.members.CanAccessProject(ctx, userID, role, d.ProjectID)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrForbidden
+	}
+	if !a.IsImage() {
+		return nil, invalid("only images can be annotated")
+	}
+	return a, nil
+}
+
+func (s *annotationService) Get(ctx context.Context, userID uint, role string, attachmentID uint) (*models.Annotation, error) {
+	if _, err := s.attachment(ctx, userID, role, attachmentID); err != nil {
+		return nil, err
+	}
+	an, err := s.repo.FindByAttachment(ctx, attachmentID)
+	if err != nil {
+		return nil, err
+	}
+	if an == nil {
+		an = &models.Annotation{AttachmentID: attachmentID, Shapes: models.AnnotationShapes{}}
+	}
+	return an, nil
+}
+
+func (s *annotationService) Put(ctx context.Context, userID uint, role string, attachmentID uint, dto PutAnnotationDTO) (*models.Annotation, error) {
+	if _, err := s.attachment(ctx, userID, role, attachmentID); err != nil {
+		return nil, err
+	}
+	shapes, err := normalizeShapes(dto.Shapes)
+	if err != nil {
+		return nil, err
+	}
+	an, err := s.repo.FindByAttachment(ctx, attachmentID)
+	if err != nil {
+		return nil, err
+	}
+	if an == nil {
+		if err := checkVersion(dto.Version, 0); err != nil {
+			return nil, err
+		}
+		an = &models.Annotation{AttachmentID: attachmentID, Shapes: shapes, UpdatedByID: userID, Version: 1}
+		if err := s.repo.Create(ctx, an); err != nil {
+			return nil, versionError(err)
+		}
+		return an, nil
+	}
+	if err := checkVersion(dto.Version, an.Version); err != nil {
+		return nil, err
+	}
+	an.Shapes, an.UpdatedByID = shapes, userID
+	if err := s.repo.Update(ctx, an); err != nil {
+		return nil, versionError(err)
+	}
+	return an, nil
+}
+
+func (s *annotationService) Delete(ctx context.Context, userID uint, role string, attachmentID uint, version *int) error {
+	if _, err := s.attachment(ctx, userID, role, attachmentID); err != nil {
+		return err
+	}
+	an, err := s.repo.FindByAttachment(ctx, attachmentID)
+	if err != nil {
+		return err
+	}
+	if an == nil {
+		return ErrNotFound
+	}
+	if err := checkVersion(version, an.Version); err != nil {
+		return err
+	}
+	return versionError(s.repo.Delete(ctx, an))
+}
+
+var annotationColor = regexp.MustCompile(`^#([0-9a-fA-F]{3}|[0-9a-fA-F]{6}|[0-9a-fA-F]{8})$`)
+
+// parseColor reads #RGB, #RRGGBB or #RRGGBBAA
+func parseColor(s string) (color.NRGBA, bool) {
+	if !annotationColor.MatchString(s) {
+		return color.NRGBA{}, false
+	}
+	h := s[1:]
+	if len(h) == 3 {
+		h = string([]byte{h[0], h[0], h[1], h[1], h[2], h[2]})
+	}
+	if len(h) == 6 {
+		h += "ff"
+	}
+	v, _ := strconv.ParseUint(h, 16, 32)
+	return color.NRGBA{R: uint8(v >> 24), G: uint8(v >> 16), B: uint8(v >> 8), A: uint8(v)}, true
+}
+
+// normalizeShapes validates shapes and fills in default sizes
+func normalizeShapes(in []models.AnnotationShape) (models.AnnotationShapes, error) {
+	if len(in) > maxAnnotationShapes {
+		return nil, invalid("at most %d shapes per photo", maxAnnotationShapes)
+	}
+	out := make(models.AnnotationShapes, 0, len(in))
+	for i, sh := range in {
+		fail := func(msg string) error { return invalid("shape %d: %s", i+1, msg) }
+		if !slices.Contains(models.AnnotationShapeTypes, sh.Type) {
+			return nil, fail(fmt.Sprintf("unknown type %q", sh.Type))
+		}
+		if _, ok := parseColor(sh.Color); !ok {
+			return nil, fail("color must be #RGB, #RRGGBB or #RRGGBBAA")
+		}
+		if _, ok := parseColor(sh.Fill); sh.Fill != "" && !ok {
+			return nil, fail("fill must be #RGB, #RRGGBB or #RRGGBBAA")
+		}
+		switch sh.Type {
+		case models.ShapeArrow, models.ShapeRect, models.ShapeEllipse:
+			if len(sh.Points) != 2 {
+				return nil, fail("needs exactly 2 points")
+			}
+		case models.ShapeText:
+			if len(sh.Points) != 1 {
+				return nil, fail("needs exactly 1 point")
+			}
+		default:
+			if len(sh.Points) < 1 || len(sh.Points) > maxShapePoints {
+				return nil, fail(fmt.Sprintf("needs 1 to %d points", maxShapePoints))
+			}
+		}
+		for _, p := range sh.Points {
+			if math.IsNaN(p.X) || math.IsNaN(p.Y) || math.Abs(p.X) > maxCoordinate || math.Abs(p.Y) > maxCoordinate {
+				return nil, fail("point out of range")
+			}
+		}
+		if sh.Type == models.ShapeText {
+			if sh.Text == "" {
+				return nil, fail("text is required")
+			}
+			if utf8.RuneCountInString(sh.Text) > maxAnnotationText {
+				return nil, fail(fmt.Sprintf("text is longer than %d characters", maxAnnotationText))
+			}
+			if sh.FontSize == 0 {
+				sh.FontSize = defaultFontSize
+			}
+			if sh.FontSize < 0 || sh.FontSize > maxFontSize {
+				return nil, fail(fmt.Sprintf("font_size must be up to %d", maxFontSize))
+			}
+			sh.StrokeWidth = 0
+		} else {
+			if sh.Text != "" || sh.FontSize != 0 {
+				return nil, fail("only text shapes take text")
+			}
+			if sh.StrokeWidth == 0 {
+				sh.StrokeWidth = defaultStrokeWidth
+			}
+			if sh.StrokeWidth < 0 || sh.StrokeWidth > maxStrokeWidth {
+				return nil, fail(fmt.Sprintf("stroke_width must be up to %d", maxStrokeWidth))
+			}
+		}
+		out = append(out, sh)
+	}
+	return out, nil
+}
+
+func (s *annotationService) Render(ctx context.Context, userID uint, role string, attachmentID uint) ([]byte, error) {
+	a, err := s.attachment(ctx, userID, role, attachmentID)
+	if err != nil {
+		return nil, err
+	}
+	if !a.Servable() {
+		return nil, ErrForbidden
+	}
+	an, err := s.repo.FindByAttachment(ctx, attachmentID)
+	if err != nil {
+		return nil, err
+	}
+	f, err := s.storage.OpenFile(a.Path)
+	if err != nil {
+		return nil, ErrNotFound
+	}
+	defer f.Close()
+	select {
+	case s.renders <- struct{}{}:
+		defer func() { <-s.renders }()
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	img, err := raster.DecodeOriented(f)
+	if err != nil {
+		return nil, invalid("cannot decode the photo: %w", err)
+	}
+	if an != nil {
+		for _, sh := range an.Shapes {
+			if err := s.draw(img, sh); err != nil {
+				return nil, err
+			}
+		}
+	}
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 90}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// draw burns one shape into img
+func (s *annotationService) draw(img *image.RGBA, sh models.AnnotationShape) error {
+	stroke, _ := parseColor(sh.Color)
+	fill, hasFill := parseColor(sh.Fill)
+	pts := make([]raster.Point, len(sh.Points))
+	for i, p := range sh.Points {
+		pts[i] = raster.Point{X: p.X, Y: p.Y}
+	}
+	w := sh.StrokeWidth
+	switch sh.Type {
+	case models.ShapeArrow:
+		from, to := pts[0], pts[1]
+		dx, dy := to.X-from.X, to.Y-from.Y
+		l := math.Hypot(dx, dy)
+		if l == 0 {
+			break
+		}
+		ux, uy := dx/l, dy/l
+		// the head grows with the line but not past the arrow's length
+		head := math.Min(math.Max(4*w, 12), l)
+		base := raster.Point{X: to.X - ux*head, Y: to.Y - uy*head}
+		shaft := raster.Point{X: base.X + ux*math.Min(w/2, head/2), Y: base.Y + uy*math.Min(w/2, head/2)}
+		raster.Fill(img, raster.Stroke([]raster.Point{from, shaft}, w, false), stroke)
+		wing := head * 0.5
+		raster.Fill(img, raster.Path{{to, {X: base.X - uy*wing, Y: base.Y + ux*wing}, {X: base.X + uy*wing, Y: base.Y - ux*wing}}}, stroke)
+	case models.ShapeRect:
+		x0, x1 := math.Min(pts[0].X, pts[1].X), math.Max(pts[0].X, pts[1].X)
+		y0, y1 := math.Min(pts[0].Y, pts[1].Y), math.Max(pts[0].Y, pts[1].Y)
+		box := []raster.Point{{X: x0, Y: y0}, {X: x1, Y: y0}, {X: x1, Y: y1}, {X: x0, Y: y1}}
+		if hasFill {
+			raster.Fill(img, raster.Path{box}, fill)
+		}
+		raster.Fill(img, raster.Stroke(box, w, true), stroke)
+	case models.ShapeEllipse:
+		cx, cy := (pts[0].X+pts[1].X)/2, (pts[0].Y+pts[1].Y)/2
+		e := raster.Ellipse(cx, cy, (pts[1].X-pts[0].X)/2, (pts[1].Y-pts[0].Y)/2)
+		if hasFill {
+			raster.Fill(img, raster.Path{e}, fill)
+		}
+		raster.Fill(img, raster.Stroke(e, w, true), stroke)
+	case models.ShapeFreehand:
+		raster.Fill(img, raster.Stroke(pts, w, false), stroke)
+	case models.ShapeText:
+		s.once.Do(func() { s.font, s.fontErr = pdf.LoadFont(s.fontPath) })
+		if s.fontErr != nil {
+			return fmt.Errorf("annotation font: %w", s.fontErr)
+		}
+		glyphs, tw, th := raster.Text(s.font, pts[0].X, pts[0].Y, sh.FontSize, sh.Text)
+		if hasFill {
+			pad := sh.FontSize / 5
+			x0, y0, x1, y1 := pts[0].X-pad, pts[0].Y-pad, pts[0].X+tw+pad, pts[0].Y+th+pad
+			raster.Fill(img, raster.Path{{{X: x0, Y: y0}, {X: x1, Y: y0}, {X: x1, Y: y1}, {X: x0, Y: y1}}}, fill)
+		}
+		raster.Fill(img, glyphs, stroke)
+	}
+	return nil
+}
@@ -0,0 +1,157 @@
+package service_test
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"example.com/defect-control-system/internal/models"
+	"example.com/defect-control-system/internal/repository"
+	"example.com/defect-control-system/internal/service"
+)
+
+// memAnnotationRepo keeps annotations by attachment
+type memAnnotationRepo struct{ byAttachment map[uint]*models.Annotation }
+
+func (m *memAnnotationRepo) FindByAttachment(ctx context.Context, attachmentID uint) (*models.Annotation, error) {
+	a, ok := m.byAttachment[attachmentID]
+	if !ok {
+		return nil, nil
+	}
+	c := *a
+	return &c, nil
+}
+func (m *memAnnotationRepo) Create(ctx context.Context, a *models.Annotation) error {
+	if _, ok := m.byAttachment[a.AttachmentID]; ok {
+		return repository.ErrVersionConflict
+	}
+	a.ID = uint(len(m.byAttachment) + 1)
+	c := *a
+	m.byAttachment[a.AttachmentID] = &c
+	return nil
+}
+func (m *memAnnotationRepo) Update(ctx context.Context, a *models.Annotation) error {
+	if m.byAttachment[a.AttachmentID].Version != a.Version {
+		return repository.ErrVersionConflict
+	}
+	a.Version++
+	c := *a
+	m.byAttachment[a.AttachmentID] = &c
+	return nil
+}
+func (m *memAnnotationRepo) Delete(ctx context.Context, a *models.Annotation) error {
+	if m.byAttachment[a.AttachmentID].Version != a.Version {
+		return repository.ErrVersionConflict
+	}
+	delete(m.byAttachment, a.AttachmentID)
+	return nil
+}
+
+func TestAnnotations_EditAndRender(t *testing.T) {
+	dir := t.TempDir()
+	viper.Set("uploads.path", dir)
+	photo := image.NewRGBA(image.Rect(0, 0, 200, 100))
+	for i := range photo.Pix {
+		photo.Pix[i] = 255
+	}
+	var buf bytes.Buffer
+	require.NoError(t, jpeg.Encode(&buf, photo, &jpeg.Options{Quality: 95}))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "photo.jpg"), buf.Bytes(), 0o644))
+	original := buf.Bytes()
+
+	attachments := &memAttachRepo{list: []*models.Attachment{
+		{ID: 1, DefectID: 1, UploaderID: 1, Path: "photo.jpg", Filename: "photo.jpg", ContentType: "image/jpeg"},
+		{ID: 2, DefectID: 1, UploaderID: 1, Path: "plan.pdf", Filename: "plan.pdf", ContentType: "application/pdf"},
+	}}
+	defects := &actDefectRepo{defects: map[uint]*models.Defect{1: {ID: 1, ProjectID: 7}}}
+	members := service.NewMembershipService(&mockMemberRepo{}, &mockProjectRepo{}, &mockUserRepo{})
+	repo := &memAnnotationRepo{byAttachment: map[uint]*models.Annotation{}}
+	s := service.NewAnnotationService(repo, attachments, defects, members, service.NewLocalStorage(), "")
+	ctx := context.Background()
+	version := func(v int) *int { return &v }
+
+	an, err := s.Get(ctx, 1, "engineer", 1)
+	require.NoError(t, err)
+	assert.Equal(t, 0, an.Version)
+	assert.Empty(t, an.Shapes)
+	_, err = s.Get(ctx, 2, "engineer", 1)
+	assert.ErrorIs(t, err, service.ErrForbidden)
+	_, err = s.Get(ctx, 1, "engineer", 2)
+	assert.Error(t, err, "only images are annotated")
+
+	for _, bad := range []models.AnnotationShape{
+		{Type: "star", Color: "#f00", Points: []models.AnnotationPoint{{X: 1, Y: 1}}},
+		{Type: models.ShapeRect, Color: "red", Points: []models.AnnotationPoint{{X: 1, Y: 1}, {X: 2, Y: 2}}},
+		{Type: models.ShapeArrow, Color: "#f00", Points: []models.AnnotationPoint{{X: 1, Y: 1}}},
+		{Type: models.ShapeText, Color: "#f00", Points: []models.AnnotationPoint{{X: 1, Y: 1}}},
+		{Type: models.ShapeFreehand, Color: "#f00", StrokeWidth: 500, Points: []models.AnnotationPoint{{X: 1, Y: 1}}},
+	} {
+		_, err := s.Put(ctx, 1, "engineer", 1, service.PutAnnotationDTO{Shapes: []models.AnnotationShape{bad}})
+		assert.Error(t, err, bad.Type)
+	}
+
+	rect := models.AnnotationShape{Type: models.ShapeRect, Color: "#ff0000", StrokeWidth: 6, Points: []models.AnnotationPoint{{X: 20, Y: 20}, {X: 80, Y: 80}}}
+	an, err = s.Put(ctx, 1, "engineer", 1, service.PutAnnotationDTO{Shapes: []models.AnnotationShape{rect}, Version: version(0)})
+	require.NoError(t, err)
+	assert.Equal(t, 1, an.Version)
+	// the second editor still thinks the photo is bare
+	_, err = s.Put(ctx, 1, "engineer", 1, service.PutAnnotationDTO{Shapes: []models.AnnotationShape{rect}, Version: version(0)})
+	assert.ErrorIs(t, err, service.ErrPreconditionFailed)
+
+	shapes := []models.AnnotationShape{rect,
+		{Type: models.ShapeEllipse, Color: "#00f", Fill: "#0000ff", Points: []models.AnnotationPoint{{X: 120, Y: 30}, {X: 180, Y: 70}}},
+		{Type: models.ShapeArrow, Color: "#00ff0080", Points: []models.AnnotationPoint{{X: 100, Y: 95}, {X: 150, Y: 95}}},
+	}
+	if _, err := os.Stat(service.DefaultActFont); err == nil {
+		shapes = append(shapes, models.AnnotationShape{Type: models.ShapeText, Color: "#000", Text: "Трещина", Points: []models.AnnotationPoint{{X: 30, Y: 35}}})
+	}
+	an, err = s.Put(ctx, 1, "engineer", 1, service.PutAnnotationDTO{Shapes: shapes, Version: version(1)})
+	require.NoError(t, err)
+	assert.Equal(t, 2, an.Version)
+	assert.Equal(t, 4.0, an.Shapes[1].StrokeWidth, "default stroke width")
+
+	out, err := s.Render(ctx, 1, "engineer", 1)
+	require.NoError(t, err)
+	img, err := jpeg.Decode(bytes.NewReader(out))
+	require.NoError(t, err)
+	assert.Equal(t, image.Rect(0, 0, 200, 100), img.Bounds())
+	red := func(x, y int) bool {
+		r, g, b, _ := color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA).RGBA()
+		return r > 0xc000 && g < 0x4000 && b < 0x4000
+	}
+	assert.True(t, red(20, 50), "rectangle edge")
+	assert.True(t, red(50, 80), "rectangle edge")
+	assert.False(t, red(50, 60), "rectangle inside stays clear")
+	r, g, b, _ := img.At(150, 50).RGBA()
+	assert.True(t, b > 0xc000 && r < 0x4000 && g < 0x4000, "filled ellipse")
+	if len(shapes) == 4 {
+		dark := 0
+		for x := 30; x < 80; x++ {
+			for y := 35; y < 60; y++ {
+				if r, _, _, _ := img.At(x, y).RGBA(); r < 0x4000 {
+					dark++
+				}
+			}
+		}
+		assert.Greater(t, dark, 40, "text is drawn")
+	}
+	stored, err := os.ReadFile(filepath.Join(dir, "photo.jpg"))
+	require.NoError(t, err)
+	assert.Equal(t, original, stored, "the original is untouched")
+
+	assert.ErrorIs(t, s.Delete(ctx, 1, "engineer", 1, version(1)), service.ErrPreconditionFailed)
+	require.NoError(t, s.Delete(ctx, 1, "engineer", 1, version(2)))
+	an, err = s.Get(ctx, 1, "engineer", 1)
+	require.NoError(t, err)
+	assert.Equal(t, 0, an.Version)
+	assert.ErrorIs(t, s.Delete(ctx, 1, "engineer", 1, nil), service.ErrNotFound)
+}
@@ -0,0 +1,319 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"example.com/defect-control-system/internal/models"
+	"example.com/defect-control-system/internal/repository"
+)
+
+// ClassifyAttachmentDTO changes the kind of an attachment and its pairing;
+// BeforeID 0 unpairs it
+type ClassifyAttachmentDTO struct {
+	Kind     *string `json:"kind,omitempty" example:"remediation"`
+	BeforeID *uint   `json:"before_id,omitempty" example:"12"`
+}
+
+// PhotoPair is an evidence photo and the remediation photos showing it fixed
+type PhotoPair struct {
+	Before *models.Attachment   `json:"before"`
+	After  []*models.Attachment `json:"after"`
+}
+
+// PhotoPairs are the before/after photos of a defect
+type PhotoPairs struct {
+	DefectID uint        `json:"defect_id"`
+	Pairs    []PhotoPair `json:"pairs"`
+	// UnpairedAfter are remediation photos not linked to an evidence photo
+	UnpairedAfter []*models.Attachment `json:"unpaired_after"`
+}
+
+// AttachmentService gives attachments their meaning: a kind, and for
+// remediation photos the evidence photo they pair with
+type AttachmentService interface {
+	// Prepare defaults and validates kind and pairing of a new attachment
+	Prepare(ctx context.Context, a *models.Attachment, beforeID *uint) error
+	// Classify changes kind and pairing; the uploader or project managers may do it
+	Classify(ctx context.Context, userID uint, role string, id uint, dto ClassifyAttachmentDTO) (*models.Attachment, error)
+	// Pairs returns the before/after photos of a defect of the project
+	Pairs(ctx context.Context, userID uint, role string, projectID, defectID uint) (*PhotoPairs, error)
+	// Deletable returns the attachment if the user may delete it: the
+	// uploader within uploads.delete_window of the upload, or managers of
+	// the project
+	Deletable(ctx context.Context, userID uint, role string, id uint) (*models.Attachment, error)
+	// PrepareVersion makes a a new version of the file attachment id belongs
+	// to, following its latest version: defect, kind and pairing carry over.
+	// Like Prepare it leaves access checks to the caller
+	PrepareVersion(ctx context.Context, a *models.Attachment, id uint) error
+	// Versions returns the versions of the file attachment id belongs to,
+	// newest first, to users with access to the project
+	Versions(ctx context.Context, userID uint, role string, id uint) ([]*models.Attachment, error)
+	// Archive returns the current attachments of a project, or of one of its
+	// defects when defectID is not zero, with their defects, for a bulk download
+	Archive(ctx context.Context, userID uint, role string, projectID, defectID uint) ([]*models.Attachment, error)
+}
+
+// DefaultDeleteWindow is how long uploaders may delete their own attachments
+// when uploads.delete_window is not set
+const DefaultDeleteWindow = 24 * time.Hour
+
+type attachmentService struct {
+	repo       repository.AttachmentRepository
+	defectRepo repository.DefectRepository
+	members    MembershipService
+}
+
+func NewAttachmentService(r repository.AttachmentRepository, dr repository.DefectRepository, m MembershipService) AttachmentService {
+	return &attachmentService{repo: r, defectRepo: dr, members: m}
+}
+
+func validKind(kind string) bool {
+	for _, k := range models.AttachmentKinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// checkPairing validates kind and the before photo of a
+func (s *attachmentService) checkPairing(ctx context.Context, a *models.Attachment) error {
+	if !validKind(a.Kind) {
+		return invalid("unknown attachment kind %q", a.Kind)
+	}
+	if a.BeforeID == nil {
+		return nil
+	}
+	if a.Kind != models.AttachmentRemediation || !a.IsImage() {
+		return invalid("only remediation photos pair with a before photo")
+	}
+	before, err := s.repo.FindByID(ctx, *a.BeforeID)
+	if err != nil || before.DefectID != a.DefectID {
+		return fmt.Errorf("attachment %d %w on the defect", *a.BeforeID, ErrNotFound)
+	}
+	if before.Kind != models.AttachmentEvidence || !before.IsImage() {
+		return invalid("attachment %d is not an evidence photo", before.ID)
+	}
+	return nil
+}
+
+func (s *attachmentService) Prepare(ctx context.Context, a *models.Attachment, beforeID *uint) error {
+	if a.Kind == "" {
+		switch {
+		case beforeID != nil:
+			a.Kind = models.AttachmentRemediation
+		case a.IsImage():
+			a.Kind = models.AttachmentEvidence
+		default:
+			a.Kind = models.AttachmentDocument
+		}
+	}
+	if beforeID != nil && *beforeID == 0 {
+		beforeID = nil
+	}
+	a.BeforeID = beforeID
+	return s.checkPairing(ctx, a)
+}
+
+func (s *attachmentService) Classify(ctx context.Context, userID uint, role string, id uint, dto ClassifyAttachmentDTO) (*models.Attachment, error) {
+	a, err := s.repo.FindByID(ctx, id)
+	if err != nil || a == nil {
+		return nil, ErrNotFound
+	}
+	d, err := s.defectRepo.FindByID(ctx, a.DefectID)
+	if err != nil || d == nil {
+		return nil, ErrNotFound
+	}
+	if a.UploaderID != userID {
+		ok, err := s.members.CanManageProject(ctx, userID, role, d.ProjectID)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, ErrForbidden
+		}
+	}
+	columns := []string{}
+	if dto.Kind != nil && *dto.Kind != a.Kind {
+		if a.Kind == models.AttachmentEvidence {
+			// keep remediation photos from pointing at a non-evidence attachment
+			siblings, err := s.repo.ListByDefect(ctx, a.DefectID)
+			if err != nil {
+				return nil, err
+			}
+			for _, o := range siblings {
+				if o.BeforeID != nil && *o.BeforeID == a.ID {
+					return nil, invalid("attachment %d is paired with it as the after photo", o.ID)
+				}
+			}
+		}
+		a.Kind = *dto.Kind
+		columns = append(columns, "kind")
+	}
+	if dto.BeforeID != nil {
+		if *dto.BeforeID == 0 {
+			a.BeforeID = nil
+		} else {
+			before := *dto.BeforeID
+			a.BeforeID = &before
+		}
+		columns = append(columns, "before_id")
+	}
+	if err := s.checkPairing(ctx, a); err != nil {
+		return nil, err
+	}
+	if len(columns) == 0 {
+		return a, nil
+	}
+	if err := s.repo.Update(ctx, a, columns); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+func (s *attachmentService) Pairs(ctx context.Context, userID uint, role string, projectID, defectID uint) (*PhotoPairs, error) {
+	ok, err := s.members.CanAccessProject(ctx, userID, role, projectID)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrForbidden
+	}
+	d, err := s.defectRepo.FindByID(ctx, defectID)
+	if err != nil || d == nil || d.ProjectID != projectID {
+		return nil, ErrNotFound
+	}
+	list, err := s.repo.ListByDefect(ctx, defectID)
+	if err != nil {
+		return nil, err
+	}
+	out := &PhotoPairs{DefectID: defectID, Pairs: []PhotoPair{}, UnpairedAfter: []*models.Attachment{}}
+	index := map[uint]int{}
+	for _, a := range list {
+		if a.Kind == models.AttachmentEvidence && a.IsImage() {
+			index[a.ID] = len(out.Pairs)
+			out.Pairs = append(out.Pairs, PhotoPair{Before: a, After: []*models.Attachment{}})
+		}
+	}
+	for _, a := range list {
+		if a.Kind != models.AttachmentRemediation || !a.IsImage() {
+			continue
+		}
+		if a.BeforeID != nil {
+			if i, ok := index[*a.BeforeID]; ok {
+				out.Pairs[i].After = append(out.Pairs[i].After, a)
+				continue
+			}
+		}
+		out.UnpairedAfter = append(out.UnpairedAfter, a)
+	}
+	return out, nil
+}
+
+func (s *attachmentService) Deletable(ctx context.Context, userID uint, role string, id uint) (*models.Attachment, error) {
+	a, err := s.repo.FindByID(ctx, id)
+	if err != nil || a == nil {
+		return nil, ErrNotFound
+	}
+	window := viper.GetDuration("uploads.delete_window")
+	if window <= 0 {
+		window = DefaultDeleteWindow
+	}
+	if a.UploaderID == userID && time.Since(a.CreatedAt) <= window {
+		return a, nil
+	}
+	d, err := s.defectRepo.FindByID(ctx, a.DefectID)
+	if err != nil || d == nil {
+		return nil, ErrNotFound
+	}
+	ok, err := s.members.CanManageProject(ctx, userID, role, d.ProjectID)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrForbidden
+	}
+	return a, nil
+}
+
+func (s *attachmentService) PrepareVersion(ctx context.Context, a *models.Attachment, id uint) error {
+	prev, err := s.repo.FindByID(ctx, id)
+	if err != nil || prev == nil {
+		return ErrNotFound
+	}
+	versions, err := s.repo.ListVersions(ctx, prev.Root())
+	if err != nil {
+		return err
+	}
+	if len(versions) > 0 {
+		prev = versions[0]
+	}
+	root := prev.Root()
+	a.DefectID, a.Kind, a.BeforeID = prev.DefectID, prev.Kind, prev.BeforeID
+	a.OriginID, a.Version = &root, prev.Version+1
+	return nil
+}
+
+func (s *attachmentService) Versions(ctx context.Context, userID uint, role string, id uint) ([]*models.Attachment, error) {
+	a, err := s.repo.FindByID(ctx, id)
+	if err != nil || a == nil {
+		return nil, ErrNotFound
+	}
+	d, err := s.defectRepo.FindByID(ctx, a.DefectID)
+	if err != nil || d == nil {
+		return nil, ErrNotFound
+	}
+	ok, err := s.members.CanAccessProject(ctx, userID, role, d.ProjectID)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrForbidden
+	}
+	return s.repo.ListVersions(ctx, a.Root())
+}
+
+// current drops superseded versions
+func current(list []*models.Attachment) []*models.Attachment {
+	out := list[:0]
+	for _, a := range list {
+		if !a.Superseded {
+			out = append(out, a)
+		}
+	}
+	return out
+}
+
+func (s *attachmentService) Archive(ctx context.Context, userID uint, role string, projectID, defectID uint) ([]*models.Attachment, error) {
+	ok, err := s.members.CanAccessProject(ctx, userID, role, projectID)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrForbidden
+	}
+	if defectID == 0 {
+		list, err := s.repo.ListByProject(ctx, projectID)
+		if err != nil {
+			return nil, err
+		}
+		return current(list), nil
+	}
+	d, err := s.defectRepo.FindByID(ctx, defectID)
+	if err != nil || d == nil || d.ProjectID != projectID {
+		return nil, ErrNotFound
+	}
+	list, err := s.repo.ListByDefect(ctx, defectID)
+	if err != nil {
+		return nil, err
+	}
+	list = current(list)
+	for _, a := range list {
+		a.Defect = *d
+	}
+	return list, nil
+}
@@ -0,0 +1,344 @@
+package service_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"io"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"example.com/defect-control-system/internal/models"
+	"example.com/defect-control-system/internal/service"
+)
+
+// memAttachRepo keeps attachments in memory; defects places them in projects
+type memAttachRepo struct {
+	list    []*models.Attachment
+	defects map[uint]*models.Defect
+}
+
+func (m *memAttachRepo) Create(ctx context.Context, a *models.Attachment) error {
+	a.ID = uint(len(m.list) + 1)
+	if a.Version == 0 {
+		// the column default
+		a.Version = 1
+	}
+	m.list = append(m.list, a)
+	return nil
+}
+func (m *memAttachRepo) FindByID(ctx context.Context, id uint) (*models.Attachment, error) {
+	for _, a := range m.list {
+		if a.ID == id {
+			c := *a
+			return &c, nil
+		}
+	}
+	return nil, errors.New("not found")
+}
+func (m *memAttachRepo) ListByDefect(ctx context.Context, defectID uint) ([]*models.Attachment, error) {
+	var out []*models.Attachment
+	for _, a := range m.list {
+		if a.DefectID == defectID {
+			out = append(out, a)
+		}
+	}
+	return out, nil
+}
+func (m *memAttachRepo) ListVersions(ctx context.Context, rootID uint) ([]*models.Attachment, error) {
+	var out []*models.Attachment
+	for _, a := range m.list {
+		if a.Root() == rootID {
+			c := *a
+			out = append(out, &c)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Version > out[j].Version })
+	return out, nil
+}
+func (m *memAttachRepo) ListByProject(ctx context.Context, projectID uint) ([]*models.Attachment, error) {
+	var out []*models.Attachment
+	for _, a := range m.list {
+		if d, ok := m.defects[a.DefectID]; ok && d.ProjectID == projectID {
+			c := *a
+			c.Defect = *d
+			out = append(out, &c)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Defect.Number < out[j].Defect.Number })
+	return out, nil
+}
+func (m *memAttachRepo) Update(ctx context.Context, a *models.Attachment, columns []string) error {
+	for i, o := range m.list {
+		if o.ID == a.ID {
+			c := *a
+			m.list[i] = &c
+		}
+	}
+	return nil
+}
+
+func TestAttachments_BeforeAfterPairs(t *testing.T) {
+	repo := &memAttachRepo{}
+	defects := &actDefectRepo{defects: map[uint]*models.Defect{1: {ID: 1, ProjectID: 7}, 2: {ID: 2, ProjectID: 7}}}
+	members := service.NewMembershipService(&mockMemberRepo{}, &mockProjectRepo{}, &mockUserRepo{})
+	s := service.NewAttachmentService(repo, defects, members)
+	ctx := context.Background()
+
+	upload := func(defectID uint, contentType, kind string, beforeID *uint) (*models.Attachment, error) {
+		a := &models.Attachment{DefectID: defectID, UploaderID: 1, ContentType: contentType, Kind: kind}
+		if err := s.Prepare(ctx, a, beforeID); err != nil {
+			return nil, err
+		}
+		return a, repo.Create(ctx, a)
+	}
+	before, err := upload(1, "image/jpeg", "", nil)
+	require.NoError(t, err)
+	assert.Equal(t, models.AttachmentEvidence, before.Kind)
+	doc, err := upload(1, "application/pdf", "", nil)
+	require.NoError(t, err)
+	assert.Equal(t, models.AttachmentDocument, doc.Kind)
+	other, err := upload(2, "image/jpeg", "", nil)
+	require.NoError(t, err)
+
+	// pairs only with evidence photos of the same defect
+	_, err = upload(1, "image/jpeg", "", &doc.ID)
+	assert.Error(t, err)
+	_, err = upload(1, "image/jpeg", "", &other.ID)
+	assert.Error(t, err)
+	_, err = upload(1, "image/jpeg", "drawing", &before.ID)
+	assert.Error(t, err)
+	_, err = upload(1, "image/jpeg", "sketch", nil)
+	assert.Error(t, err)
+
+	after, err := upload(1, "image/jpeg", "", &before.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.AttachmentRemediation, after.Kind)
+	loose, err := upload(1, "image/png", "remediation", nil)
+	require.NoError(t, err)
+
+	pairs, err := s.Pairs(ctx, 1, "engineer", 7, 1)
+	require.NoError(t, err)
+	require.Len(t, pairs.Pairs, 1)
+	assert.Equal(t, before.ID, pairs.Pairs[0].Before.ID)
+	require.Len(t, pairs.Pairs[0].After, 1)
+	assert.Equal(t, after.ID, pairs.Pairs[0].After[0].ID)
+	require.Len(t, pairs.UnpairedAfter, 1)
+	assert.Equal(t, loose.ID, pairs.UnpairedAfter[0].ID)
+
+	_, err = s.Pairs(ctx, 2, "engineer", 7, 1)
+	assert.ErrorIs(t, err, service.ErrForbidden)
+	_, err = s.Pairs(ctx, 1, "engineer", 7, 3)
+	assert.ErrorIs(t, err, service.ErrNotFound)
+
+	// pair the loose photo later; a paired before photo keeps its kind
+	_, err = s.Classify(ctx, 2, "engineer", loose.ID, service.ClassifyAttachmentDTO{BeforeID: &before.ID})
+	assert.ErrorIs(t, err, service.ErrForbidden)
+	_, err = s.Classify(ctx, 1, "engineer", loose.ID, service.ClassifyAttachmentDTO{BeforeID: &before.ID})
+	require.NoError(t, err)
+	drawing := models.AttachmentDrawing
+	_, err = s.Classify(ctx, 9, "manager", before.ID, service.ClassifyAttachmentDTO{Kind: &drawing})
+	assert.Error(t, err)
+
+	pairs, err = s.Pairs(ctx, 9, "manager", 7, 1)
+	require.NoError(t, err)
+	assert.Len(t, pairs.Pairs[0].After, 2)
+	assert.Empty(t, pairs.UnpairedAfter)
+}
+
+func TestAttachments_DeleteWindow(t *testing.T) {
+	repo := &memAttachRepo{}
+	defects := &actDefectRepo{defects: map[uint]*models.Defect{1: {ID: 1, ProjectID: 7}}}
+	members := service.NewMembershipService(&mockMemberRepo{}, &mockProjectRepo{}, &mockUserRepo{})
+	s := service.NewAttachmentService(repo, defects, members)
+	ctx := context.Background()
+
+	fresh := &models.Attachment{DefectID: 1, UploaderID: 1, CreatedAt: time.Now().Add(-time.Hour)}
+	old := &models.Attachment{DefectID: 1, UploaderID: 1, CreatedAt: time.Now().Add(-service.DefaultDeleteWindow - time.Hour)}
+	require.NoError(t, repo.Create(ctx, fresh))
+	require.NoError(t, repo.Create(ctx, old))
+
+	_, err := s.Deletable(ctx, 1, "engineer", fresh.ID)
+	assert.NoError(t, err)
+	_, err = s.Deletable(ctx, 2, "engineer", fresh.ID)
+	assert.ErrorIs(t, err, service.ErrForbidden, "only the uploader")
+	_, err = s.Deletable(ctx, 1, "engineer", old.ID)
+	assert.ErrorIs(t, err, service.ErrForbidden, "window passed")
+	_, err = s.Deletable(ctx, 2, "manager", old.ID)
+	assert.NoError(t, err)
+	_, err = s.Deletable(ctx, 1, "engineer", 99)
+	assert.ErrorIs(t, err, service.ErrNotFound)
+}
+
+func TestAttachments_ArchiveZip(t *testing.T) {
+	dir := t.TempDir()
+	viper.Set("uploads.path", dir)
+	viper.Set("uploads.allowed_types", nil)
+	storage := service.NewLocalStorage()
+	defects := map[uint]*models.Defect{
+		1: {ID: 1, ProjectID: 7, Number: 2, Key: "TWR-2", Title: "Трещина в стяжке"},
+		2: {ID: 2, ProjectID: 7, Number: 1, Key: "TWR-1", Title: "Leaking roof"},
+		3: {ID: 3, ProjectID: 8, Number: 1, Key: "OTH-1"},
+	}
+	repo := &memAttachRepo{defects: defects}
+	members := service.NewMembershipService(&mockMemberRepo{}, &mockProjectRepo{}, &mockUserRepo{})
+	s := service.NewAttachmentService(repo, &actDefectRepo{defects: defects}, members)
+	ctx := context.Background()
+
+	store := func(defectID uint, filename, content string, status string) {
+		st, err := storage.StageStream(strings.NewReader(content))
+		require.NoError(t, err)
+		require.NoError(t, storage.PlaceBlob(st))
+		require.NoError(t, repo.Create(ctx, &models.Attachment{DefectID: defectID, Filename: filename, ContentType: "image/jpeg",
+			Path: service.BlobPath(st.SHA256), SHA256: st.SHA256, Size: st.Size, UploaderID: 1, ScanStatus: status}))
+	}
+	store(1, "IMG_001.jpg", "crack", models.AttachmentClean)
+	store(1, "img_001.JPG", "crack, closer", models.AttachmentClean)
+	store(1, "../../etc/passwd", "sneaky", models.AttachmentClean)
+	store(2, "roof.jpg", "roof", models.AttachmentPendingScan)
+	store(3, "other.jpg", "other project", models.AttachmentClean)
+
+	_, err := s.Archive(ctx, 2, "engineer", 7, 0)
+	assert.ErrorIs(t, err, service.ErrForbidden)
+	_, err = s.Archive(ctx, 1, "engineer", 7, 3)
+	assert.ErrorIs(t, err, service.ErrNotFound, "defect of another project")
+
+	list, err := s.Archive(ctx, 1, "engineer", 7, 0)
+	require.NoError(t, err)
+	require.Len(t, list, 4)
+	var entries []service.ArchiveEntry
+	for _, a := range list {
+		e := service.ArchiveEntry{Attachment: a}
+		if !a.Servable() {
+			e.Skip = "not served: " + a.ScanStatus
+		}
+		entries = append(entries, e)
+	}
+	var buf bytes.Buffer
+	require.NoError(t, service.WriteAttachmentZip(&buf, storage, entries))
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+	files := map[string]string{}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		require.NoError(t, err)
+		b, _ := io.ReadAll(rc)
+		rc.Close()
+		files[f.Name] = string(b)
+	}
+	assert.Equal(t, "crack", files["TWR-2/IMG_001.jpg"])
+	assert.Equal(t, "crack, closer", files["TWR-2/img_001 (2).JPG"])
+	assert.Equal(t, "sneaky", files["TWR-2/passwd"])
+	assert.NotContains(t, files, "TWR-1/roof.jpg", "not scanned yet")
+	assert.Len(t, files, 4)
+
+	rows, err := csv.NewReader(strings.NewReader(strings.TrimPrefix(files[service.ZipManifest], "\ufeff"))).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, rows, 5)
+	assert.Equal(t, "defect_number", rows[0][0])
+	assert.Equal(t, []string{"1", "TWR-1", "Leaking roof"}, rows[1][:3], "ordered by defect number")
+	assert.Equal(t, "", rows[1][4])
+	assert.Equal(t, "not served: pending_scan", rows[1][12])
+	assert.Equal(t, "Трещина в стяжке", rows[2][2])
+	assert.Equal(t, "TWR-2/IMG_001.jpg", rows[2][4])
+}
+
+func TestAttachmentZip_ManifestFormulas(t *testing.T) {
+	viper.Set("uploads.path", t.TempDir())
+	a := &models.Attachment{ID: 1, DefectID: 1, Filename: "@SUM(A1:A9).jpg", ContentType: "-2+3",
+		Defect: models.Defect{Number: 1, Key: "TWR-1", Title: `=HYPERLINK("http://evil.example","open")`}}
+	b := &models.Attachment{ID: 2, DefectID: 1, Filename: "+7 floor.jpg", Defect: a.Defect}
+	c := &models.Attachment{ID: 3, DefectID: 1, Filename: "plan - rev 2.pdf", Defect: models.Defect{Number: 1, Title: "Crack at -1 level"}}
+	var buf bytes.Buffer
+	require.NoError(t, service.WriteAttachmentZip(&buf, service.NewLocalStorage(), []service.ArchiveEntry{
+		{Attachment: a, Skip: "skipped"}, {Attachment: b, Skip: "skipped"}, {Attachment: c, Skip: "skipped"},
+	}))
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+	require.Len(t, zr.File, 1)
+	rc, err := zr.File[0].Open()
+	require.NoError(t, err)
+	defer rc.Close()
+	manifest, _ := io.ReadAll(rc)
+	rows, err := csv.NewReader(strings.NewReader(strings.TrimPrefix(string(manifest), "\ufeff"))).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, rows, 4)
+	assert.Equal(t, `'=HYPERLINK("http://evil.example","open")`, rows[1][2])
+	assert.Equal(t, "'@SUM(A1:A9).jpg", rows[1][5])
+	assert.Equal(t, "'-2+3", rows[1][7])
+	assert.Equal(t, "'+7 floor.jpg", rows[2][5])
+	// only the first character counts
+	assert.Equal(t, "Crack at -1 level", rows[3][2])
+	assert.Equal(t, "plan - rev 2.pdf", rows[3][5])
+}
+
+func TestAttachments_Versions(t *testing.T) {
+	viper.Set("uploads.path", t.TempDir())
+	viper.Set("uploads.allowed_types", nil)
+	storage := service.NewLocalStorage()
+	repo := &memAttachRepo{}
+	blobRepo := &memBlobRepo{blobs: map[string]*models.Blob{}, attach: repo}
+	defects := &actDefectRepo{defects: map[uint]*models.Defect{1: {ID: 1, ProjectID: 7}}}
+	members := service.NewMembershipService(&mockMemberRepo{}, &mockProjectRepo{}, &mockUserRepo{})
+	s := service.NewAttachmentService(repo, defects, members)
+	blobs := service.NewBlobService(blobRepo, storage, service.NewScanService(nil, nil, storage), service.NewQuotaService(nil, nil, 0))
+	ctx := context.Background()
+	attach := func(a *models.Attachment, content string) error {
+		st, err := storage.StageStream(strings.NewReader(content))
+		require.NoError(t, err)
+		return blobs.Attach(ctx, a, st)
+	}
+
+	first := &models.Attachment{DefectID: 1, UploaderID: 1, Filename: "plan-rev0.pdf", ContentType: "application/pdf", Kind: models.AttachmentDrawing}
+	require.NoError(t, attach(first, "rev 0"))
+	assert.Equal(t, 1, first.Version)
+	second := &models.Attachment{UploaderID: 1, Filename: "plan-rev1.pdf", ContentType: "application/pdf"}
+	require.NoError(t, s.PrepareVersion(ctx, second, first.ID))
+	require.NoError(t, attach(second, "rev 1"))
+	assert.Equal(t, uint(1), second.DefectID)
+	assert.Equal(t, models.AttachmentDrawing, second.Kind, "kind carries over")
+	assert.Equal(t, 2, second.Version)
+	assert.Equal(t, first.ID, *second.OriginID)
+
+	// a version of any version follows the latest
+	third := &models.Attachment{UploaderID: 1, Filename: "plan-rev2.pdf", ContentType: "application/pdf"}
+	require.NoError(t, s.PrepareVersion(ctx, third, first.ID))
+	assert.Equal(t, 3, third.Version)
+	// another upload based on version 2 loses the race
+	stale := &models.Attachment{UploaderID: 1, Filename: "plan-rev2b.pdf", ContentType: "application/pdf"}
+	require.NoError(t, s.PrepareVersion(ctx, stale, second.ID))
+	require.NoError(t, attach(third, "rev 2"))
+	assert.ErrorIs(t, attach(stale, "rev 2b"), service.ErrPreconditionFailed)
+
+	versions, err := s.Versions(ctx, 1, "engineer", second.ID)
+	require.NoError(t, err)
+	require.Len(t, versions, 3)
+	assert.Equal(t, []int{3, 2, 1}, []int{versions[0].Version, versions[1].Version, versions[2].Version})
+	assert.Equal(t, []bool{false, true, true}, []bool{versions[0].Superseded, versions[1].Superseded, versions[2].Superseded})
+	_, err = s.Versions(ctx, 2, "engineer", second.ID)
+	assert.ErrorIs(t, err, service.ErrForbidden)
+
+	// the defect view and archives show the latest only
+	list, err := s.Archive(ctx, 1, "engineer", 7, 1)
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	assert.Equal(t, third.ID, list[0].ID)
+
+	// deleting the latest makes the one before current again
+	latest, _ := repo.FindByID(ctx, third.ID)
+	require.NoError(t, blobs.Delete(ctx, latest))
+	versions, err = s.Versions(ctx, 1, "engineer", first.ID)
+	require.NoError(t, err)
+	require.Len(t, versions, 2)
+	assert.False(t, versions[0].Superseded)
+	assert.Equal(t, 2, versions[0].Version)
+}
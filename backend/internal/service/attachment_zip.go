@@ -0,0 +1,142 @@
+package service
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"example.com/defect-control-system/internal/models"
+	"example.com/defect-control-system/internal/utils"
+)
+
+// ZipManifest is the name of the CSV listing the files of an attachment ZIP
+const ZipManifest = "manifest.csv"
+
+// ArchiveEntry is an attachment of a ZIP download. A non-empty Skip leaves
+// its file out and says why in the manifest
+type ArchiveEntry struct {
+	Attachment *models.Attachment
+	Skip       string
+}
+
+// WriteAttachmentZip streams a ZIP of the entries to w: one folder per defect,
+// named by its key (or number), and a manifest.csv listing every entry.
+// Files are copied from storage as they are written, so neither the archive
+// nor its files are buffered. Content that is compressed already is stored.
+func WriteAttachmentZip(w io.Writer, st StorageService, entries []ArchiveEntry) error {
+	zw := zip.NewWriter(w)
+	var manifest strings.Builder
+	mw := csv.NewWriter(&manifest)
+	mw.Write([]string{"defect_number", "defect_key", "defect_title", "attachment_id", "file", "filename", "kind", "content_type", "size", "sha256", "uploaded_at", "uploader_id", "note"})
+	used := map[string]bool{}
+	for _, e := range entries {
+		a := e.Attachment
+		name, note := "", e.Skip
+		if note == "" {
+			name = zipName(used, defectFolder(&a.Defect, a.DefectID), a)
+			if err := writeZipFile(zw, st, name, a); err != nil {
+				if _, ok := err.(*missingFileError); !ok {
+					return err
+				}
+				name, note = "", "file missing"
+			}
+		}
+		row := []string{
+			strconv.Itoa(a.Defect.Number), a.Defect.Key, a.Defect.Title,
+			strconv.FormatUint(uint64(a.ID), 10), name, a.Filename, a.Kind, a.ContentType,
+			strconv.FormatInt(a.Size, 10), a.SHA256, a.CreatedAt.UTC().Format(time.RFC3339),
+			strconv.FormatUint(uint64(a.UploaderID), 10), note,
+		}
+		mw.Write(utils.SpreadsheetRow(row))
+	}
+	mw.Flush()
+	f, err := zw.CreateHeader(&zip.FileHeader{Name: ZipManifest, Method: zip.Deflate, Modified: time.Now()})
+	if err != nil {
+		return err
+	}
+	// a BOM lets spreadsheet programs read the UTF-8 titles
+	if _, err := io.WriteString(f, "\ufeff"+manifest.String()); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// missingFileError is a file gone from storage, left out of the archive
+type missingFileError struct{ err error }
+
+func (e *missingFileError) Error() string { return e.err.Error() }
+
+func writeZipFile(zw *zip.Writer, st StorageService, name string, a *models.Attachment) error {
+	src, err := st.OpenFile(a.Path)
+	if err != nil {
+		return &missingFileError{err}
+	}
+	defer src.Close()
+	method := zip.Deflate
+	if precompressed(a.ContentType) {
+		method = zip.Store
+	}
+	f, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: method, Modified: a.CreatedAt})
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(f, src)
+	return err
+}
+
+// defectFolder names the folder of a defect's files
+func defectFolder(d *models.Defect, id uint) string {
+	switch {
+	case d.Key != "":
+		return zipSafe(d.Key)
+	case d.Number != 0:
+		return strconv.Itoa(d.Number)
+	}
+	return fmt.Sprintf("defect-%d", id)
+}
+
+// zipName is a unique path in folder for the file of a, keeping its original
+// name where possible
+func zipName(used map[string]bool, folder string, a *models.Attachment) string {
+	base := zipSafe(path.Base(strings.ReplaceAll(a.Filename, "\\", "/")))
+	if base == "" || base == "." || base == ".." {
+		base = fmt.Sprintf("attachment-%d", a.ID)
+	}
+	ext := path.Ext(base)
+	stem := strings.TrimSuffix(base, ext)
+	name := folder + "/" + base
+	for i := 2; used[strings.ToLower(name)]; i++ {
+		name = fmt.Sprintf("%s/%s (%d)%s", folder, stem, i, ext)
+	}
+	used[strings.ToLower(name)] = true
+	return name
+}
+
+// zipSafe drops characters unzip tools refuse or misread in names
+func zipSafe(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r < 0x20 || strings.ContainsRune(`/\:*?"<>|`, r) {
+			return '_'
+		}
+		return r
+	}, strings.TrimSpace(s))
+}
+
+// precompressed reports whether deflating content of the type gains nothing
+func precompressed(contentType string) bool {
+	switch {
+	case strings.HasPrefix(contentType, "video/"), strings.HasPrefix(contentType, "audio/"):
+		return true
+	}
+	switch contentType {
+	case "image/jpeg", "image/png", "image/gif", "image/webp", "image/heic",
+		"application/zip", "application/gzip", "application/x-7z-compressed", "application/vnd.rar":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,123 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"os"
+
+	"example.com/defect-control-system/internal/models"
+	"example.com/defect-control-system/internal/repository"
+)
+
+// BlobService stores attachment content once per SHA-256: attachments of the
+// same file share a blob, counted by reference, and the file is deleted with
+// the last attachment using it
+type BlobService interface {
+	// Attach creates attachment a with the content of a staged file, reusing
+	// identical content already stored, and submits it for scanning. The
+	// staged file is removed. ErrQuotaExceeded is returned if the file does
+	// not fit the storage quota, ErrPreconditionFailed if a is a new version
+	// of a file revised meanwhile
+	Attach(ctx context.Context, a *models.Attachment, st *StagedFile) error
+	// Delete removes attachment a; its content goes with the last reference.
+	// Photos backing a verification are ErrAttachmentInUse
+	Delete(ctx context.Context, a *models.Attachment) error
+	// Verify hashes the stored content of a and compares it to a.SHA256
+	Verify(ctx context.Context, a *models.Attachment) (bool, error)
+	// AdoptLegacy moves files stored before content addressing into blobs
+	// and returns how many were moved
+	AdoptLegacy(ctx context.Context) (int, error)
+}
+
+// ErrAttachmentInUse is returned for attachments a verification relies on
+var ErrAttachmentInUse = errors.New("the attachment is evidence of a verification and cannot be deleted")
+
+type blobService struct {
+	repo    repository.BlobRepository
+	storage StorageService
+	scans   ScanService
+	quotas  QuotaService
+}
+
+func NewBlobService(r repository.BlobRepository, st StorageService, sc ScanService, q QuotaService) BlobService {
+	return &blobService{repo: r, storage: st, scans: sc, quotas: q}
+}
+
+// blobOf is the blob record of staged content
+func blobOf(st *StagedFile) *models.Blob {
+	return &models.Blob{SHA256: st.SHA256, Size: st.Size, Path: BlobPath(st.SHA256)}
+}
+
+func (s *blobService) Attach(ctx context.Context, a *models.Attachment, st *StagedFile) error {
+	defer s.storage.RemoveFile(st.Path)
+	s.scans.Hold(a)
+	err := s.repo.Attach(ctx, a, blobOf(st), s.quotas.Guard(a.DefectID, st.Size), func() error { return s.storage.PlaceBlob(st) })
+	if err != nil {
+		// another version of the file came first
+		return versionError(err)
+	}
+	s.scans.Submit(a)
+	// warnings and failures are reported on QuotaService.Warnings; the
+	// upload does not wait on them
+	_ = s.quotas.Observe(ctx, a.DefectID)
+	return nil
+}
+
+func (s *blobService) Delete(ctx context.Context, a *models.Attachment) error {
+	err := s.repo.Detach(ctx, a, func(b *models.Blob) error { return s.storage.RemoveFile(b.Path) })
+	switch {
+	case errors.Is(err, repository.ErrInUse):
+		return ErrAttachmentInUse
+	case errors.Is(err, repository.ErrVersionConflict):
+		// deleted concurrently
+		return ErrNotFound
+	case err != nil:
+		return err
+	}
+	_ = s.quotas.Observe(ctx, a.DefectID)
+	return nil
+}
+
+func (s *blobService) Verify(ctx context.Context, a *models.Attachment) (bool, error) {
+	if a.SHA256 == "" {
+		return false, errors.New("the attachment has no content hash yet")
+	}
+	st, err := s.storage.StageFile(a.Path)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return st.SHA256 == a.SHA256 && st.Size == a.Size, nil
+}
+
+// adoptBatch is how many legacy attachments AdoptLegacy loads at a time
+const adoptBatch = 100
+
+func (s *blobService) AdoptLegacy(ctx context.Context) (int, error) {
+	n := 0
+	var after uint
+	for {
+		list, err := s.repo.Legacy(ctx, after, adoptBatch)
+		if err != nil || len(list) == 0 {
+			return n, err
+		}
+		for _, a := range list {
+			after = a.ID
+			old := a.Path
+			st, err := s.storage.StageFile(old)
+			if err != nil {
+				// a missing file stays as it is
+				continue
+			}
+			if err := s.repo.Attach(ctx, a, blobOf(st), nil, func() error { return s.storage.PlaceBlob(st) }); err != nil {
+				return n, err
+			}
+			if old != a.Path {
+				s.storage.RemoveFile(old)
+			}
+			n++
+		}
+	}
+}
@@ -0,0 +1,176 @@
+package service_test
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"example.com/defect-control-system/internal/models"
+	"example.com/defect-control-system/internal/repository"
+	"example.com/defect-control-system/internal/service"
+)
+
+// memBlobRepo counts blob references in memory, storing attachments in attach.
+// Quota guards run against quotas when set
+type memBlobRepo struct {
+	blobs  map[string]*models.Blob
+	attach *memAttachRepo
+	inUse  map[uint]bool
+	quotas *memQuotaRepo
+}
+
+func (m *memBlobRepo) Attach(ctx context.Context, a *models.Attachment, b *models.Blob, quota *repository.QuotaGuard, place func() error) error {
+	if m.quotas != nil {
+		if err := m.quotas.guard(ctx, quota); err != nil {
+			return err
+		}
+	}
+	if a.ID == 0 && a.OriginID != nil {
+		prev := m.version(a.Root(), a.Version-1)
+		if prev == nil || prev.Superseded {
+			return repository.ErrVersionConflict
+		}
+		prev.Superseded = true
+	}
+	if cur, ok := m.blobs[b.SHA256]; ok {
+		cur.RefCount++
+	} else {
+		b.RefCount = 1
+		m.blobs[b.SHA256] = b
+	}
+	if err := place(); err != nil {
+		return err
+	}
+	a.Path, a.SHA256, a.Size = b.Path, b.SHA256, b.Size
+	if a.ID != 0 {
+		return m.attach.Update(ctx, a, nil)
+	}
+	return m.attach.Create(ctx, a)
+}
+func (m *memBlobRepo) Detach(ctx context.Context, a *models.Attachment, remove func(b *models.Blob) error) error {
+	if m.inUse[a.ID] {
+		return repository.ErrInUse
+	}
+	for i, o := range m.attach.list {
+		if o.ID == a.ID {
+			m.attach.list = append(m.attach.list[:i], m.attach.list[i+1:]...)
+			break
+		}
+	}
+	if !a.Superseded {
+		var latest *models.Attachment
+		for _, o := range m.attach.list {
+			if o.Root() == a.Root() && (latest == nil || o.Version > latest.Version) {
+				latest = o
+			}
+		}
+		if latest != nil {
+			latest.Superseded = false
+		}
+	}
+	b, ok := m.blobs[a.SHA256]
+	if !ok {
+		return nil
+	}
+	if b.RefCount > 1 {
+		b.RefCount--
+		return nil
+	}
+	if err := remove(b); err != nil {
+		return err
+	}
+	delete(m.blobs, a.SHA256)
+	return nil
+}
+
+// version finds version n of the file with first version root
+func (m *memBlobRepo) version(root uint, n int) *models.Attachment {
+	for _, o := range m.attach.list {
+		if o.Root() == root && o.Version == n {
+			return o
+		}
+	}
+	return nil
+}
+func (m *memBlobRepo) Legacy(ctx context.Context, afterID uint, limit int) ([]*models.Attachment, error) {
+	var list []*models.Attachment
+	for _, a := range m.attach.list {
+		if a.SHA256 == "" && a.ID > afterID && len(list) < limit {
+			c := *a
+			list = append(list, &c)
+		}
+	}
+	return list, nil
+}
+
+func TestBlobs_DedupeAndDelete(t *testing.T) {
+	dir := t.TempDir()
+	viper.Set("uploads.path", dir)
+	viper.Set("uploads.allowed_types", nil)
+	storage := service.NewLocalStorage()
+	attach := &memAttachRepo{}
+	repo := &memBlobRepo{blobs: map[string]*models.Blob{}, attach: attach}
+	s := service.NewBlobService(repo, storage, service.NewScanService(nil, nil, storage), service.NewQuotaService(nil, nil, 0))
+	ctx := context.Background()
+	pdf := []byte("%PDF-1.4 handover documentation, section 2")
+
+	// a file stored before content addressing, with the same content
+	legacyPath := filepath.Join("2025", "10", "11", "handover.pdf")
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "2025", "10", "11"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, legacyPath), pdf, 0o644))
+	legacy := &models.Attachment{DefectID: 3, Path: legacyPath, Size: int64(len(pdf))}
+	require.NoError(t, attach.Create(ctx, legacy))
+
+	var list []*models.Attachment
+	for defectID := uint(1); defectID <= 2; defectID++ {
+		st, err := storage.StageStream(bytes.NewReader(pdf))
+		require.NoError(t, err)
+		a := &models.Attachment{DefectID: defectID, Filename: "handover.pdf"}
+		require.NoError(t, s.Attach(ctx, a, st))
+		_, err = os.Stat(filepath.Join(dir, st.Path))
+		assert.True(t, os.IsNotExist(err), "staged file removed")
+		list = append(list, a)
+	}
+	assert.Equal(t, list[0].SHA256, list[1].SHA256)
+	assert.Equal(t, list[0].Path, list[1].Path)
+	assert.Len(t, repo.blobs, 1)
+	ok, err := s.Verify(ctx, list[0])
+	require.NoError(t, err)
+	assert.True(t, ok)
+	_, err = s.Verify(ctx, legacy)
+	assert.Error(t, err, "no hash before adoption")
+
+	n, err := s.AdoptLegacy(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	legacy, _ = attach.FindByID(ctx, legacy.ID)
+	assert.Equal(t, list[0].Path, legacy.Path)
+	assert.Equal(t, 3, repo.blobs[legacy.SHA256].RefCount)
+	_, err = os.Stat(filepath.Join(dir, legacyPath))
+	assert.True(t, os.IsNotExist(err), "the duplicate is gone")
+
+	// the content stays until its last attachment is deleted
+	blob := filepath.Join(dir, list[0].Path)
+	repo.inUse = map[uint]bool{list[1].ID: true}
+	assert.ErrorIs(t, s.Delete(ctx, list[1]), service.ErrAttachmentInUse)
+	repo.inUse = nil
+	for _, a := range []*models.Attachment{list[0], legacy} {
+		require.NoError(t, s.Delete(ctx, a))
+		_, err = os.Stat(blob)
+		require.NoError(t, err)
+	}
+	require.NoError(t, os.WriteFile(blob, []byte("tampered"), 0o644))
+	ok, err = s.Verify(ctx, list[1])
+	require.NoError(t, err)
+	assert.False(t, ok)
+	require.NoError(t, s.Delete(ctx, list[1]))
+	_, err = os.Stat(blob)
+	assert.True(t, os.IsNotExist(err))
+	assert.Empty(t, repo.blobs)
+}
@@ -0,0 +1,419 @@
+package service
+
+import (
+	"context"
+	"encoding/csv"
+	"io"
+	"regexp"
+	"strings"
+
+	"example.com/defect-control-system/internal/models"
+	"example.com/defect-control-system/internal/repository"
+)
+
+type CategoryDTO struct {
+	Code string `json:"code" validate:"required"`
+	Name string `json:"name" validate:"required"`
+	// ParentID makes the entry a defect type under a top level category
+	ParentID              *uint  `json:"parent_id"`
+	DefaultSeverity       string `json:"default_severity"`
+	DefaultOrganizationID *uint  `json:"default_organization_id"`
+}
+
+// UpdateCategoryDTO changes a catalog entry; the code is immutable because
+// imported catalogs refer to it. Zero ids clear the parent or default organization.
+type UpdateCategoryDTO struct {
+	Name                  *string `json:"name"`
+	ParentID              *uint   `json:"parent_id"`
+	DefaultSeverity       *string `json:"default_severity"`
+	DefaultOrganizationID *uint   `json:"default_organization_id"`
+}
+
+// CategoryImportItem is one row of an imported catalog. Parents and organizations
+// are referenced by code and name so files can be prepared by hand.
+type CategoryImportItem struct {
+	Code                string `json:"code"`
+	Name                string `json:"name"`
+	ParentCode          string `json:"parent_code"`
+	DefaultSeverity     string `json:"default_severity"`
+	DefaultOrganization string `json:"default_organization"`
+}
+
+// ImportResult reports what a catalog import changed
+type ImportResult struct {
+	Created              int `json:"created"`
+	Updated              int `json:"updated"`
+	OrganizationsCreated int `json:"organizations_created"`
+}
+
+var categoryCodePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{0,49}$`)
+
+// CategoryService manages the company-wide defect classification catalog. The
+// catalog has two levels: categories (trades) and defect types under them.
+type CategoryService interface {
+	List(ctx context.Context) ([]*models.DefectCategory, error)
+	// Tree returns top level categories with their types in Children
+	Tree(ctx context.Context) ([]*models.DefectCategory, error)
+	Create(ctx context.Context, dto CategoryDTO) (*models.DefectCategory, error)
+	Update(ctx context.Context, id uint, dto UpdateCategoryDTO) (*models.DefectCategory, error)
+	// Delete removes an entry without child types; defects keep no category
+	Delete(ctx context.Context, id uint) error
+	// Import creates or updates entries by code. The whole file is validated
+	// before anything is written, and written in one transaction.
+	Import(ctx context.Context, items []CategoryImportItem) (*ImportResult, error)
+}
+
+type categoryService struct {
+	repo    repository.CategoryRepository
+	orgRepo repository.OrganizationRepository
+}
+
+func NewCategoryService(r repository.CategoryRepository, or repository.OrganizationRepository) CategoryService {
+	return &categoryService{repo: r, orgRepo: or}
+}
+
+func (s *categoryService) List(ctx context.Context) ([]*models.DefectCategory, error) {
+	return s.repo.List(ctx)
+}
+
+func (s *categoryService) Tree(ctx context.Context) ([]*models.DefectCategory, error) {
+	list, err := s.repo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	children := map[uint][]models.DefectCategory{}
+	for _, c := range list {
+		if c.ParentID != nil {
+			children[*c.ParentID] = append(children[*c.ParentID], *c)
+		}
+	}
+	roots := []*models.DefectCategory{}
+	for _, c := range list {
+		if c.ParentID == nil {
+			c.Children = children[c.ID]
+			roots = append(roots, c)
+		}
+	}
+	return roots, nil
+}
+
+// checkParent verifies that the parent exists and is a top level category
+func (s *categoryService) checkParent(ctx context.Context, parentID uint) error {
+	p, err := s.repo.FindByID(ctx, parentID)
+	if err != nil {
+		return invalid("parent category not found")
+	}
+	if p.ParentID != nil {
+		return invalid("defect types cannot have children")
+	}
+	return nil
+}
+
+func (s *categoryService) checkOrganization(ctx context.Context, id uint) error {
+	if _, err := s.orgRepo.FindByID(ctx, id); err != nil {
+		return invalid("organization not found")
+	}
+	return nil
+}
+
+func (s *categoryService) hasChildren(ctx context.Context, id uint) (bool, error) {
+	list, err := s.repo.List(ctx)
+	if err != nil {
+		return false, err
+	}
+	for _, c := range list {
+		if c.ParentID != nil && *c.ParentID == id {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (s *categoryService) Create(ctx context.Context, dto CategoryDTO) (*models.DefectCategory, error) {
+	code := strings.TrimSpace(dto.Code)
+	if !categoryCodePattern.MatchString(code) {
+		return nil, invalid("code must be 1-50 letters, digits, '.', '_' or '-'")
+	}
+	name := strings.TrimSpace(dto.Name)
+	if name == "" {
+		return nil, invalid("name is required")
+	}
+	if _, err := s.repo.FindByCode(ctx, code); err == nil {
+		return nil, invalid("category %s already exists", code)
+	}
+	if dto.ParentID != nil {
+		if err := s.checkParent(ctx, *dto.ParentID); err != nil {
+			return nil, err
+		}
+	}
+	if dto.DefaultOrganizationID != nil {
+		if err := s.checkOrganization(ctx, *dto.DefaultOrganizationID); err != nil {
+			return nil, err
+		}
+	}
+	c := &models.DefectCategory{
+		Code:                  code,
+		Name:                  name,
+		ParentID:              dto.ParentID,
+		DefaultSeverity:       strings.TrimSpace(dto.DefaultSeverity),
+		DefaultOrganizationID: dto.DefaultOrganizationID,
+	}
+	if err := s.repo.Create(ctx, c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (s *categoryService) Update(ctx context.Context, id uint, dto UpdateCategoryDTO) (*models.DefectCategory, error) {
+	c, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return nil, ErrNotFound
+	}
+	if dto.Name != nil {
+		name := strings.TrimSpace(*dto.Name)
+		if name == "" {
+			return nil, invalid("name is required")
+		}
+		c.Name = name
+	}
+	if dto.ParentID != nil {
+		if *dto.ParentID == 0 {
+			c.ParentID = nil
+		} else {
+			if *dto.ParentID == c.ID {
+				return nil, invalid("category cannot be its own parent")
+			}
+			if err := s.checkParent(ctx, *dto.ParentID); err != nil {
+				return nil, err
+			}
+			has, err := s.hasChildren(ctx, c.ID)
+			if err != nil {
+				return nil, err
+			}
+			if has {
+				return nil, invalid("a category with types cannot be moved under another category")
+			}
+			v := *dto.ParentID
+			c.ParentID = &v
+		}
+	}
+	if dto.DefaultSeverity != nil {
+		c.DefaultSeverity = strings.TrimSpace(*dto.DefaultSeverity)
+	}
+	if dto.DefaultOrganizationID != nil {
+		if *dto.DefaultOrganizationID == 0 {
+			c.DefaultOrganizationID = nil
+		} else {
+			if err := s.checkOrganization(ctx, *dto.DefaultOrganizationID); err != nil {
+				return nil, err
+			}
+			v := *dto.DefaultOrganizationID
+			c.DefaultOrganizationID = &v
+		}
+		c.DefaultOrganization = nil
+	}
+	if err := s.repo.Update(ctx, c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (s *categoryService) Delete(ctx context.Context, id uint) error {
+	if _, err := s.repo.FindByID(ctx, id); err != nil {
+		return ErrNotFound
+	}
+	has, err := s.hasChildren(ctx, id)
+	if err != nil {
+		return err
+	}
+	if has {
+		return invalid("delete the category's types first")
+	}
+	return s.repo.Delete(ctx, id)
+}
+
+func (s *categoryService) Import(ctx context.Context, items []CategoryImportItem) (*ImportResult, error) {
+	if len(items) == 0 {
+		return nil, invalid("catalog is empty")
+	}
+	existing, err := s.repo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	byCode := make(map[string]*models.DefectCategory, len(existing))
+	for _, c := range existing {
+		byCode[c.Code] = c
+	}
+	inFile := make(map[string]*CategoryImportItem, len(items))
+	for i := range items {
+		it := &items[i]
+		it.Code = strings.TrimSpace(it.Code)
+		it.Name = strings.TrimSpace(it.Name)
+		it.ParentCode = strings.TrimSpace(it.ParentCode)
+		it.DefaultSeverity = strings.TrimSpace(it.DefaultSeverity)
+		it.DefaultOrganization = strings.TrimSpace(it.DefaultOrganization)
+		if !categoryCodePattern.MatchString(it.Code) {
+			return nil, invalid("row %d: invalid code %q", i+1, it.Code)
+		}
+		if it.Name == "" {
+			return nil, invalid("row %d: name is required", i+1)
+		}
+		if _, dup := inFile[it.Code]; dup {
+			return nil, invalid("row %d: duplicate code %s", i+1, it.Code)
+		}
+		inFile[it.Code] = it
+	}
+	// the resulting catalog must keep two levels
+	isTopLevel := func(code string) (bool, bool) {
+		if it, ok := inFile[code]; ok {
+			return it.ParentCode == "", true
+		}
+		if c, ok := byCode[code]; ok {
+			return c.ParentID == nil, true
+		}
+		return false, false
+	}
+	hasChildren := map[string]bool{}
+	for _, it := range items {
+		if it.ParentCode != "" {
+			hasChildren[it.ParentCode] = true
+		}
+	}
+	for _, c := range existing {
+		if c.ParentID == nil {
+			continue
+		}
+		if _, moved := inFile[c.Code]; moved {
+			continue
+		}
+		for _, p := range existing {
+			if p.ID == *c.ParentID {
+				hasChildren[p.Code] = true
+			}
+		}
+	}
+	for i, it := range items {
+		if it.ParentCode == "" {
+			continue
+		}
+		if it.ParentCode == it.Code {
+			return nil, invalid("row %d: category cannot be its own parent", i+1)
+		}
+		top, ok := isTopLevel(it.ParentCode)
+		if !ok {
+			return nil, invalid("row %d: unknown parent_code %s", i+1, it.ParentCode)
+		}
+		if !top {
+			return nil, invalid("row %d: parent %s is a defect type", i+1, it.ParentCode)
+		}
+		if hasChildren[it.Code] {
+			return nil, invalid("row %d: %s has types and cannot have a parent", i+1, it.Code)
+		}
+	}
+
+	res := &ImportResult{}
+	orgs := map[string]*models.Organization{}
+	resolveOrg := func(name string) *models.Organization {
+		if name == "" {
+			return nil
+		}
+		key := strings.ToLower(name)
+		if o, ok := orgs[key]; ok {
+			return o
+		}
+		o, err := s.orgRepo.FindByName(ctx, name)
+		if err != nil {
+			// created by the repository along with the catalog
+			o = &models.Organization{Name: name}
+			res.OrganizationsCreated++
+		}
+		orgs[key] = o
+		return o
+	}
+	plan := make([]*models.DefectCategory, 0, len(items))
+	add := func(it CategoryImportItem) {
+		c, ok := byCode[it.Code]
+		if ok {
+			res.Updated++
+		} else {
+			c = &models.DefectCategory{Code: it.Code}
+			byCode[c.Code] = c
+			res.Created++
+		}
+		c.Name = it.Name
+		c.ParentID, c.Parent = nil, nil
+		if it.ParentCode != "" {
+			c.Parent = byCode[it.ParentCode]
+		}
+		c.DefaultSeverity = it.DefaultSeverity
+		c.DefaultOrganizationID, c.DefaultOrganization = nil, resolveOrg(it.DefaultOrganization)
+		plan = append(plan, c)
+	}
+	// parents first so children can reference their ids
+	for _, top := range []bool{true, false} {
+		for _, it := range items {
+			if (it.ParentCode == "") != top {
+				continue
+			}
+			add(it)
+		}
+	}
+	if err := s.repo.Import(ctx, plan); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// ParseCategoryCSV reads a catalog from CSV with a header row. Recognised columns
+// are code, name, parent_code, default_severity and default_organization; the
+// delimiter may be a comma or a semicolon.
+func ParseCategoryCSV(r io.Reader) ([]CategoryImportItem, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	text := strings.TrimPrefix(string(data), "\xef\xbb\xbf")
+	cr := csv.NewReader(strings.NewReader(text))
+	firstLine := text
+	if i := strings.IndexByte(text, '\n'); i >= 0 {
+		firstLine = text[:i]
+	}
+	if strings.Count(firstLine, ";") > strings.Count(firstLine, ",") {
+		cr.Comma = ';'
+	}
+	cr.TrimLeadingSpace = true
+	rows, err := cr.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, invalid("catalog is empty")
+	}
+	cols := map[string]int{}
+	for i, h := range rows[0] {
+		cols[strings.ToLower(strings.TrimSpace(h))] = i
+	}
+	if _, ok := cols["code"]; !ok {
+		return nil, invalid("missing column: code")
+	}
+	if _, ok := cols["name"]; !ok {
+		return nil, invalid("missing column: name")
+	}
+	get := func(row []string, col string) string {
+		if i, ok := cols[col]; ok && i < len(row) {
+			return row[i]
+		}
+		return ""
+	}
+	items := make([]CategoryImportItem, 0, len(rows)-1)
+	for _, row := range rows[1:] {
+		items = append(items, CategoryImportItem{
+			Code:                get(row, "code"),
+			Name:                get(row, "name"),
+			ParentCode:          get(row, "parent_code"),
+			DefaultSeverity:     get(row, "default_severity"),
+			DefaultOrganization: get(row, "default_organization"),
+		})
+	}
+	return items, nil
+}
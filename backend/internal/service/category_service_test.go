@@ -0,0 +1,190 @@
+package service_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"example.com/defect-control-system/internal/models"
+	"example.com/defect-control-system/internal/service"
+)
+
+type mockCategoryRepo struct {
+	list []*models.DefectCategory
+	orgs *mockOrgRepo
+	// failAfter makes Import fail once that many categories are saved
+	failAfter int
+}
+
+func (m *mockCategoryRepo) Create(ctx context.Context, c *models.DefectCategory) error {
+	c.ID = uint(len(m.list) + 1)
+	m.list = append(m.list, c)
+	return nil
+}
+func (m *mockCategoryRepo) FindByID(ctx context.Context, id uint) (*models.DefectCategory, error) {
+	for _, c := range m.list {
+		if c.ID == id {
+			return c, nil
+		}
+	}
+	return nil, assert.AnError
+}
+func (m *mockCategoryRepo) FindByCode(ctx context.Context, code string) (*models.DefectCategory, error) {
+	for _, c := range m.list {
+		if c.Code == code {
+			return c, nil
+		}
+	}
+	return nil, assert.AnError
+}
+func (m *mockCategoryRepo) List(ctx context.Context) ([]*models.DefectCategory, error) {
+	return m.list, nil
+}
+func (m *mockCategoryRepo) Update(ctx context.Context, c *models.DefectCategory) error { return nil }
+func (m *mockCategoryRepo) Delete(ctx context.Context, id uint) error                  { return nil }
+
+// Import keeps the saved categories only when the whole list is saved
+func (m *mockCategoryRepo) Import(ctx context.Context, list []*models.DefectCategory) error {
+	saved := append([]*models.DefectCategory(nil), m.list...)
+	for i, c := range list {
+		if m.failAfter > 0 && i == m.failAfter {
+			return assert.AnError
+		}
+		if o := c.DefaultOrganization; o != nil {
+			if o.ID == 0 && m.orgs != nil {
+				m.orgs.Create(ctx, o)
+			}
+			c.DefaultOrganizationID = &o.ID
+		}
+		if c.Parent != nil {
+			c.ParentID = &c.Parent.ID
+		}
+		if c.ID == 0 {
+			c.ID = uint(len(saved) + 1)
+			saved = append(saved, c)
+		}
+	}
+	m.list = saved
+	return nil
+}
+
+type mockOrgRepo struct{ list []*models.Organization }
+
+func (m *mockOrgRepo) Create(ctx context.Context, o *models.Organization) error {
+	o.ID = uint(len(m.list) + 1)
+	m.list = append(m.list, o)
+	return nil
+}
+func (m *mockOrgRepo) FindByID(ctx context.Context, id uint) (*models.Organization, error) {
+	for _, o := range m.list {
+		if o.ID == id {
+			return o, nil
+		}
+	}
+	return nil, assert.AnError
+}
+func (m *mockOrgRepo) FindByName(ctx context.Context, name string) (*models.Organization, error) {
+	for _, o := range m.list {
+		if strings.EqualFold(o.Name, name) {
+			return o, nil
+		}
+	}
+	return nil, assert.AnError
+}
+func (m *mockOrgRepo) List(ctx context.Context) ([]*models.Organization, error) { return m.list, nil }
+func (m *mockOrgRepo) Update(ctx context.Context, o *models.Organization) error { return nil }
+func (m *mockOrgRepo) Delete(ctx context.Context, id uint) error                { return nil }
+
+func TestCategoryImport_CSV(t *testing.T) {
+	orgs := &mockOrgRepo{}
+	cats := &mockCategoryRepo{orgs: orgs}
+	s := service.NewCategoryService(cats, orgs)
+	ctx := context.Background()
+
+	// children may come before their parents; semicolons are accepted
+	csv := "\xef\xbb\xbfcode;name;parent_code;default_severity;default_organization\n" +
+		"EL.01;Missing grounding;EL;high;ElectroMontazh\n" +
+		"EL;Electrical;;;\n" +
+		"RF;Roofing;;medium;Krovlya\n" +
+		"EL.02;Exposed wiring;EL;high;electromontazh\n"
+	items, err := service.ParseCategoryCSV(strings.NewReader(csv))
+	assert.NoError(t, err)
+	res, err := s.Import(ctx, items)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, res.Created)
+	assert.Equal(t, 2, res.OrganizationsCreated)
+
+	tree, err := s.Tree(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, tree, 2)
+	el, _ := cats.FindByCode(ctx, "EL.01")
+	assert.Equal(t, tree[0].ID, *el.ParentID)
+
+	// re-import updates by code
+	res, err = s.Import(ctx, []service.CategoryImportItem{{Code: "RF", Name: "Roofing works"}})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, res.Updated)
+}
+
+func TestCategoryImport_Validation(t *testing.T) {
+	cats := &mockCategoryRepo{}
+	s := service.NewCategoryService(cats, &mockOrgRepo{})
+	ctx := context.Background()
+
+	_, err := s.Import(ctx, []service.CategoryImportItem{{Code: "A", Name: "a"}, {Code: "A", Name: "b"}})
+	assert.ErrorContains(t, err, "duplicate")
+	_, err = s.Import(ctx, []service.CategoryImportItem{{Code: "A.1", Name: "a", ParentCode: "A"}})
+	assert.ErrorContains(t, err, "unknown parent_code")
+	// three levels are rejected
+	_, err = s.Import(ctx, []service.CategoryImportItem{
+		{Code: "A", Name: "a"}, {Code: "A.1", Name: "a1", ParentCode: "A"}, {Code: "A.1.1", Name: "a11", ParentCode: "A.1"}})
+	assert.Error(t, err)
+	// nothing was written by failed imports
+	assert.Empty(t, cats.list)
+}
+
+func TestCategoryImport_AllOrNothing(t *testing.T) {
+	cats := &mockCategoryRepo{failAfter: 2}
+	s := service.NewCategoryService(cats, &mockOrgRepo{})
+	ctx := context.Background()
+	items := []service.CategoryImportItem{
+		{Code: "EL", Name: "Electrical"}, {Code: "EL.01", Name: "Missing grounding", ParentCode: "EL"}, {Code: "RF", Name: "Roofing"}}
+
+	_, err := s.Import(ctx, items)
+	assert.Error(t, err)
+	assert.Empty(t, cats.list)
+
+	// a re-run imports the catalog once
+	cats.failAfter = 0
+	res, err := s.Import(ctx, items)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, res.Created)
+	assert.Len(t, cats.list, 3)
+}
+
+func TestCreateDefect_CategoryDefaults(t *testing.T) {
+	orgs := &mockOrgRepo{list: []*models.Organization{{ID: 1, Name: "ElectroMontazh"}, {ID: 2, Name: "General"}}}
+	orgID := uint(1)
+	cats := &mockCategoryRepo{list: []*models.DefectCategory{{ID: 1, Code: "EL", Name: "Electrical", DefaultSeverity: "critical", DefaultOrganizationID: &orgID}}}
+	s := service.NewDefectService(&mockDefectRepo{}, &mockProjectRepo{}, &mockUserRepo{}, service.WithCatalog(cats, orgs))
+	ctx := context.Background()
+
+	catID := uint(1)
+	d, err := s.Create(ctx, service.CreateDefectDTO{ProjectID: 1, Title: "x", CategoryID: &catID})
+	assert.NoError(t, err)
+	assert.Equal(t, "critical", d.Severity)
+	assert.Equal(t, uint(1), *d.ResponsibleOrgID)
+
+	// explicit values win over the defaults
+	other := uint(2)
+	d, err = s.Create(ctx, service.CreateDefectDTO{ProjectID: 1, Title: "x", Severity: "minor", CategoryID: &catID, ResponsibleOrgID: &other})
+	assert.NoError(t, err)
+	assert.Equal(t, "minor", d.Severity)
+	assert.Equal(t, uint(2), *d.ResponsibleOrgID)
+
+	missing := uint(9)
+	_, err = s.Create(ctx, service.CreateDefectDTO{ProjectID: 1, Title: "x", CategoryID: &missing})
+	assert.Error(t, err)
+}
@@ -0,0 +1,131 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// DefaultScanTimeout bounds one scan when scan.timeout is not set
+const DefaultScanTimeout = 2 * time.Minute
+
+// clamdChunk is the size of INSTREAM chunks sent to clamd
+const clamdChunk = 64 << 10
+
+// ErrScanRefused is returned when the scanner answers but will not scan the
+// file, e.g. because it exceeds clamd's StreamMaxLength. Retrying does not help
+var ErrScanRefused = errors.New("scanner refused the file")
+
+// ScanResult is the verdict on scanned content
+type ScanResult struct {
+	Infected bool
+	// Signature names what was found
+	Signature string
+}
+
+// Scanner checks file content for malware
+type Scanner interface {
+	Scan(ctx context.Context, r io.Reader) (*ScanResult, error)
+}
+
+// clamdScanner talks the clamd protocol
+// (https://docs.clamav.net/manual/Usage/Scanning.html#clamd) over TCP or a
+// Unix socket, streaming the content with INSTREAM
+type clamdScanner struct {
+	network string
+	addr    string
+	timeout time.Duration
+}
+
+// NewClamdScanner connects to clamd at address: tcp://host:port,
+// unix:///path/to/clamd.sock, or a bare host:port
+func NewClamdScanner(address string, timeout time.Duration) (Scanner, error) {
+	network, addr := "tcp", address
+	if scheme, rest, ok := strings.Cut(address, "://"); ok {
+		network, addr = scheme, rest
+	}
+	if network != "tcp" && network != "unix" {
+		return nil, fmt.Errorf("unsupported clamd address %q", address)
+	}
+	if addr == "" {
+		return nil, fmt.Errorf("empty clamd address")
+	}
+	if timeout <= 0 {
+		timeout = DefaultScanTimeout
+	}
+	return &clamdScanner{network: network, addr: addr, timeout: timeout}, nil
+}
+
+// NewScannerFromConfig returns the scanner configured with scan.clamd and
+// scan.timeout, or nil when scanning is off
+func NewScannerFromConfig() (Scanner, error) {
+	address := viper.GetString("scan.clamd")
+	if address == "" {
+		return nil, nil
+	}
+	return NewClamdScanner(address, viper.GetDuration("scan.timeout"))
+}
+
+func (s *clamdScanner) Scan(ctx context.Context, r io.Reader) (*ScanResult, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, s.network, s.addr)
+	if err != nil {
+		return nil, fmt.Errorf("clamd: %w", err)
+	}
+	defer conn.Close()
+	deadline, _ := ctx.Deadline()
+	conn.SetDeadline(deadline)
+
+	if _, err := conn.Write([]byte("zINSTREAM\x00")); err != nil {
+		return nil, fmt.Errorf("clamd: %w", err)
+	}
+	buf := make([]byte, 4+clamdChunk)
+	for {
+		n, rerr := io.ReadFull(r, buf[4:])
+		if n > 0 {
+			binary.BigEndian.PutUint32(buf, uint32(n))
+			if _, err := conn.Write(buf[:4+n]); err != nil {
+				// clamd closes the stream once over its size limit; its reply says so
+				break
+			}
+		}
+		if rerr == io.EOF || rerr == io.ErrUnexpectedEOF {
+			break
+		}
+		if rerr != nil {
+			return nil, rerr
+		}
+	}
+	conn.Write([]byte{0, 0, 0, 0})
+
+	reply, err := bufio.NewReader(conn).ReadString(0)
+	if err != nil && reply == "" {
+		return nil, fmt.Errorf("clamd: %w", err)
+	}
+	return parseClamdReply(strings.TrimRight(reply, "\x00\n"))
+}
+
+// parseClamdReply reads "stream: OK", "stream: <signature> FOUND" or
+// "<message> ERROR"
+func parseClamdReply(reply string) (*ScanResult, error) {
+	switch {
+	case strings.HasSuffix(reply, " FOUND"):
+		sig := strings.TrimSuffix(strings.TrimPrefix(reply, "stream: "), " FOUND")
+		return &ScanResult{Infected: true, Signature: sig}, nil
+	case strings.HasSuffix(reply, " OK"):
+		return &ScanResult{}, nil
+	case strings.HasSuffix(reply, " ERROR"):
+		return nil, fmt.Errorf("%w: %s", ErrScanRefused, strings.TrimSuffix(reply, " ERROR"))
+	}
+	return nil, fmt.Errorf("clamd: unexpected reply %q", reply)
+}
@@ -2,6 +2,7 @@ package service
 
 import (
 	"context"
+	"errors"
 
 	"example.com/defect-control-system/internal/models"
 	"example.com/defect-control-system/internal/repository"
@@ -10,11 +11,27 @@ import (
 type CreateCommentDTO struct {
 	DefectID uint   `json:"defect_id" validate:"required"`
 	Body     string `json:"body" validate:"required"`
+	// Visibility is "public" (default) or "internal"
+	Visibility string `json:"visibility" validate:"omitempty,oneof=public internal"`
+}
+
+// ErrInvalidVisibility is returned when a comment visibility value is not recognised.
+var ErrInvalidVisibility = errors.New("invalid visibility")
+
+// contractorRoles are the roles of the contractor team. Only they may read and
+// write internal comments; stakeholders (customer side) see public ones only.
+var contractorRoles = map[string]struct{}{"engineer": {}, "manager": {}, "admin": {}}
+
+// CanViewInternalComments reports whether a user with the given role may read internal comments.
+func CanViewInternalComments(role string) bool {
+	_, ok := contractorRoles[role]
+	return ok
 }
 
 type CommentService interface {
-	Create(ctx context.Context, authorID uint, dto CreateCommentDTO) (*models.Comment, error)
-	ListByDefect(ctx context.Context, defectID uint) ([]*models.Comment, error)
+	Create(ctx context.Context, authorID uint, role string, dto CreateCommentDTO) (*models.Comment, error)
+	// ListByDefect returns comments visible to a caller with the given role
+	ListByDefect(ctx context.Context, defectID uint, role string) ([]*models.Comment, error)
 }
 
 type commentService struct {
@@ -25,13 +42,26 @@ func NewCommentService(r repository.CommentRepository) CommentService {
 	return &commentService{repo: r}
 }
 
-func (s *commentService) Create(ctx context.Context, authorID uint, dto CreateCommentDTO) (*models.Comment, error) {
+func (s *commentService) Create(ctx context.Context, authorID uint, role string, dto CreateCommentDTO) (*models.Comment, error) {
+	visibility := dto.Visibility
+	switch visibility {
+	case "":
+		visibility = models.CommentVisibilityPublic
+	case models.CommentVisibilityPublic:
+	case models.CommentVisibilityInternal:
+		// customer-side users cannot post into the contractor's internal thread
+		if !CanViewInternalComments(role) {
+			return nil, ErrForbidden
+		}
+	default:
+		return nil, ErrInvalidVisibility
+	}
 	var authorPtr *uint
 	if authorID != 0 {
 		v := authorID
 		authorPtr = &v
 	}
-	c := &models.Comment{DefectID: dto.DefectID, AuthorID: authorPtr, Body: dto.Body}
+	c := &models.Comment{DefectID: dto.DefectID, AuthorID: authorPtr, Body: dto.Body, Visibility: visibility}
 	if err := s.repo.Create(ctx, c); err != nil {
 		return nil, err
 	}
@@ -43,6 +73,6 @@ func (s *commentService) Create(ctx context.Context, authorID uint, dto CreateCo
 	return saved, nil
 }
 
-func (s *commentService) ListByDefect(ctx context.Context, defectID uint) ([]*models.Comment, error) {
-	return s.repo.ListByDefect(ctx, defectID)
+func (s *commentService) ListByDefect(ctx context.Context, defectID uint, role string) ([]*models.Comment, error) {
+	return s.repo.ListByDefect(ctx, defectID, CanViewInternalComments(role))
 }
@@ -0,0 +1,57 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"example.com/defect-control-system/internal/models"
+	"example.com/defect-control-system/internal/service"
+)
+
+// mockCommentRepo keeps comments in memory and applies the visibility filter like the PG repo
+type mockCommentRepo struct{ list []*models.Comment }
+
+func (m *mockCommentRepo) Create(ctx context.Context, c *models.Comment) error {
+	c.ID = uint(len(m.list) + 1)
+	m.list = append(m.list, c)
+	return nil
+}
+func (m *mockCommentRepo) ListByDefect(ctx context.Context, defectID uint, includeInternal bool) ([]*models.Comment, error) {
+	var out []*models.Comment
+	for _, c := range m.list {
+		if c.DefectID == defectID && (includeInternal || c.Visibility == models.CommentVisibilityPublic) {
+			out = append(out, c)
+		}
+	}
+	return out, nil
+}
+func (m *mockCommentRepo) FindByID(ctx context.Context, id uint) (*models.Comment, error) {
+	return m.list[id-1], nil
+}
+
+func TestCommentVisibility(t *testing.T) {
+	repo := &mockCommentRepo{}
+	s := service.NewCommentService(repo)
+	ctx := context.Background()
+
+	pub, err := s.Create(ctx, 1, "engineer", service.CreateCommentDTO{DefectID: 1, Body: "visible"})
+	assert.NoError(t, err)
+	assert.Equal(t, models.CommentVisibilityPublic, pub.Visibility)
+	_, err = s.Create(ctx, 1, "engineer", service.CreateCommentDTO{DefectID: 1, Body: "liability", Visibility: "internal"})
+	assert.NoError(t, err)
+
+	// stakeholders and anonymous callers only get public comments
+	list, _ := s.ListByDefect(ctx, 1, "stakeholder")
+	assert.Len(t, list, 1)
+	list, _ = s.ListByDefect(ctx, 1, "")
+	assert.Len(t, list, 1)
+	list, _ = s.ListByDefect(ctx, 1, "manager")
+	assert.Len(t, list, 2)
+
+	_, err = s.Create(ctx, 2, "stakeholder", service.CreateCommentDTO{DefectID: 1, Body: "x", Visibility: "internal"})
+	assert.ErrorIs(t, err, service.ErrForbidden)
+	_, err = s.Create(ctx, 1, "engineer", service.CreateCommentDTO{DefectID: 1, Body: "x", Visibility: "secret"})
+	assert.ErrorIs(t, err, service.ErrInvalidVisibility)
+}
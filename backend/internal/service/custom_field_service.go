@@ -0,0 +1,287 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"example.com/defect-control-system/internal/models"
+	"example.com/defect-control-system/internal/repository"
+)
+
+type CreateCustomFieldDTO struct {
+	Key      string   `json:"key" validate:"required"`
+	Label    string   `json:"label" validate:"required"`
+	Type     string   `json:"type" validate:"required,oneof=text number date enum user boolean"`
+	Required bool     `json:"required"`
+	Options  []string `json:"options"`
+	Position int      `json:"position"`
+}
+
+// UpdateCustomFieldDTO changes presentation and constraints; key and type are immutable
+// because stored values depend on them.
+type UpdateCustomFieldDTO struct {
+	Label    *string  `json:"label"`
+	Required *bool    `json:"required"`
+	Options  []string `json:"options"`
+	Position *int     `json:"position"`
+}
+
+var customFieldTypes = map[string]struct{}{
+	models.CustomFieldText: {}, models.CustomFieldNumber: {}, models.CustomFieldDate: {},
+	models.CustomFieldEnum: {}, models.CustomFieldUser: {}, models.CustomFieldBoolean: {},
+}
+
+// CustomFieldService manages custom field definitions of projects. Changes are
+// limited to project admins (see MembershipService.CanManageProject).
+type CustomFieldService interface {
+	List(ctx context.Context, projectID uint) ([]*models.CustomField, error)
+	ListByProjects(ctx context.Context, projectIDs []uint) ([]*models.CustomField, error)
+	Create(ctx context.Context, userID uint, role string, projectID uint, dto CreateCustomFieldDTO) (*models.CustomField, error)
+	Update(ctx context.Context, userID uint, role string, projectID, id uint, dto UpdateCustomFieldDTO) (*models.CustomField, error)
+	Delete(ctx context.Context, userID uint, role string, projectID, id uint) error
+}
+
+type customFieldService struct {
+	repo    repository.CustomFieldRepository
+	members MembershipService
+}
+
+func NewCustomFieldService(r repository.CustomFieldRepository, m MembershipService) CustomFieldService {
+	return &customFieldService{repo: r, members: m}
+}
+
+func (s *customFieldService) List(ctx context.Context, projectID uint) ([]*models.CustomField, error) {
+	return s.repo.ListByProject(ctx, projectID)
+}
+
+func (s *customFieldService) ListByProjects(ctx context.Context, projectIDs []uint) ([]*models.CustomField, error) {
+	return s.repo.ListByProjects(ctx, projectIDs)
+}
+
+func (s *customFieldService) authorize(ctx context.Context, userID uint, role string, projectID uint) error {
+	ok, err := s.members.CanManageProject(ctx, userID, role, projectID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrForbidden
+	}
+	return nil
+}
+
+func (s *customFieldService) Create(ctx context.Context, userID uint, role string, projectID uint, dto CreateCustomFieldDTO) (*models.CustomField, error) {
+	if err := s.authorize(ctx, userID, role, projectID); err != nil {
+		return nil, err
+	}
+	if !models.CustomFieldKeyPattern.MatchString(dto.Key) {
+		return nil, invalid("key must start with a letter and contain only a-z, 0-9 and _")
+	}
+	if _, ok := customFieldTypes[dto.Type]; !ok {
+		return nil, invalid("unsupported field type: %s", dto.Type)
+	}
+	if strings.TrimSpace(dto.Label) == "" {
+		return nil, invalid("label is required")
+	}
+	if err := checkOptions(dto.Type, dto.Options); err != nil {
+		return nil, err
+	}
+	f := &models.CustomField{
+		ProjectID: projectID,
+		Key:       dto.Key,
+		Label:     strings.TrimSpace(dto.Label),
+		Type:      dto.Type,
+		Required:  dto.Required,
+		Options:   dto.Options,
+		Position:  dto.Position,
+	}
+	if err := s.repo.Create(ctx, f); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (s *customFieldService) Update(ctx context.Context, userID uint, role string, projectID, id uint, dto UpdateCustomFieldDTO) (*models.CustomField, error) {
+	if err := s.authorize(ctx, userID, role, projectID); err != nil {
+		return nil, err
+	}
+	f, err := s.repo.FindByID(ctx, id)
+	if err != nil || f.ProjectID != projectID {
+		return nil, ErrNotFound
+	}
+	if dto.Label != nil {
+		if strings.TrimSpace(*dto.Label) == "" {
+			return nil, invalid("label is required")
+		}
+		f.Label = strings.TrimSpace(*dto.Label)
+	}
+	if dto.Required != nil {
+		f.Required = *dto.Required
+	}
+	if dto.Options != nil {
+		if err := checkOptions(f.Type, dto.Options); err != nil {
+			return nil, err
+		}
+		f.Options = dto.Options
+	}
+	if dto.Position != nil {
+		f.Position = *dto.Position
+	}
+	if err := s.repo.Update(ctx, f); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (s *customFieldService) Delete(ctx context.Context, userID uint, role string, projectID, id uint) error {
+	if err := s.authorize(ctx, userID, role, projectID); err != nil {
+		return err
+	}
+	f, err := s.repo.FindByID(ctx, id)
+	if err != nil || f.ProjectID != projectID {
+		return ErrNotFound
+	}
+	return s.repo.Delete(ctx, f)
+}
+
+func checkOptions(fieldType string, options []string) error {
+	if fieldType != models.CustomFieldEnum {
+		if len(options) > 0 {
+			return invalid("options are only allowed for enum fields")
+		}
+		return nil
+	}
+	if len(options) == 0 {
+		return invalid("enum fields need at least one option")
+	}
+	seen := make(map[string]struct{}, len(options))
+	for _, o := range options {
+		if strings.TrimSpace(o) == "" {
+			return invalid("enum options must not be empty")
+		}
+		if _, dup := seen[o]; dup {
+			return invalid("duplicate enum option: %s", o)
+		}
+		seen[o] = struct{}{}
+	}
+	return nil
+}
+
+// CustomFieldError describes an invalid custom field value
+type CustomFieldError struct {
+	Key    string
+	Reason string
+}
+
+func (e *CustomFieldError) Error() string {
+	return fmt.Sprintf("custom field %q: %s", e.Key, e.Reason)
+}
+
+// Is makes the error ErrInvalid
+func (e *CustomFieldError) Is(target error) bool { return target == ErrInvalid }
+
+// applyCustomFields validates values against the project's definitions and merges
+// them into current. A nil value clears the field. When creating, required fields
+// must end up set; when updating only explicit clearing of a required field fails.
+// userExists is used to check user reference fields.
+func applyCustomFields(defs []*models.CustomField, current models.JSONMap, values map[string]interface{}, creating bool,
+	userExists func(id uint) bool) (models.JSONMap, error) {
+	byKey := make(map[string]*models.CustomField, len(defs))
+	for _, d := range defs {
+		byKey[d.Key] = d
+	}
+	out := models.JSONMap{}
+	for k, v := range current {
+		out[k] = v
+	}
+	for key, raw := range values {
+		def, ok := byKey[key]
+		if !ok {
+			return nil, &CustomFieldError{Key: key, Reason: "unknown field"}
+		}
+		if raw == nil {
+			if def.Required {
+				return nil, &CustomFieldError{Key: key, Reason: "is required"}
+			}
+			delete(out, key)
+			continue
+		}
+		v, err := normalizeCustomValue(def, raw, userExists)
+		if err != nil {
+			return nil, err
+		}
+		out[key] = v
+	}
+	if creating {
+		for _, d := range defs {
+			if _, ok := out[d.Key]; d.Required && !ok {
+				return nil, &CustomFieldError{Key: d.Key, Reason: "is required"}
+			}
+		}
+	}
+	return out, nil
+}
+
+// normalizeCustomValue checks a decoded JSON value against the field type and
+// returns the canonical value to store
+func normalizeCustomValue(def *models.CustomField, raw interface{}, userExists func(id uint) bool) (interface{}, error) {
+	bad := func(reason string) error { return &CustomFieldError{Key: def.Key, Reason: reason} }
+	switch def.Type {
+	case models.CustomFieldText:
+		s, ok := raw.(string)
+		if !ok {
+			return nil, bad("must be a string")
+		}
+		if def.Required && strings.TrimSpace(s) == "" {
+			return nil, bad("is required")
+		}
+		return s, nil
+	case models.CustomFieldNumber:
+		n, ok := raw.(float64)
+		if !ok || math.IsNaN(n) || math.IsInf(n, 0) {
+			return nil, bad("must be a number")
+		}
+		return n, nil
+	case models.CustomFieldDate:
+		s, ok := raw.(string)
+		if !ok {
+			return nil, bad("must be a date string (YYYY-MM-DD)")
+		}
+		t, err := time.Parse("2006-01-02", s)
+		if err != nil {
+			if t, err = time.Parse(time.RFC3339, s); err != nil {
+				return nil, bad("must be a date string (YYYY-MM-DD)")
+			}
+		}
+		return t.Format("2006-01-02"), nil
+	case models.CustomFieldEnum:
+		s, ok := raw.(string)
+		if !ok {
+			return nil, bad("must be a string")
+		}
+		for _, o := range def.Options {
+			if o == s {
+				return s, nil
+			}
+		}
+		return nil, bad("must be one of " + strings.Join(def.Options, ", "))
+	case models.CustomFieldUser:
+		n, ok := raw.(float64)
+		if !ok || n <= 0 || n != math.Trunc(n) {
+			return nil, bad("must be a user id")
+		}
+		if userExists != nil && !userExists(uint(n)) {
+			return nil, bad("user not found")
+		}
+		return n, nil
+	case models.CustomFieldBoolean:
+		b, ok := raw.(bool)
+		if !ok {
+			return nil, bad("must be a boolean")
+		}
+		return b, nil
+	}
+	return nil, bad("unsupported field type")
+}
@@ -0,0 +1,59 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"example.com/defect-control-system/internal/models"
+	"example.com/defect-control-system/internal/service"
+)
+
+type mockFieldRepo struct{ defs []*models.CustomField }
+
+func (m *mockFieldRepo) Create(ctx context.Context, f *models.CustomField) error { return nil }
+func (m *mockFieldRepo) FindByID(ctx context.Context, id uint) (*models.CustomField, error) {
+	return m.defs[id-1], nil
+}
+func (m *mockFieldRepo) ListByProject(ctx context.Context, projectID uint) ([]*models.CustomField, error) {
+	return m.defs, nil
+}
+func (m *mockFieldRepo) ListByProjects(ctx context.Context, projectIDs []uint) ([]*models.CustomField, error) {
+	return m.defs, nil
+}
+func (m *mockFieldRepo) Update(ctx context.Context, f *models.CustomField) error { return nil }
+func (m *mockFieldRepo) Delete(ctx context.Context, f *models.CustomField) error { return nil }
+
+func TestCreateDefect_CustomFields(t *testing.T) {
+	fields := &mockFieldRepo{defs: []*models.CustomField{
+		{ID: 1, ProjectID: 1, Key: "clause", Type: models.CustomFieldEnum, Required: true, Options: []string{"5.1", "5.2"}},
+		{ID: 2, ProjectID: 1, Key: "cost", Type: models.CustomFieldNumber},
+		{ID: 3, ProjectID: 1, Key: "found_on", Type: models.CustomFieldDate},
+	}}
+	s := service.NewDefectService(&mockDefectRepo{}, &mockProjectRepo{}, &mockUserRepo{}, service.WithCustomFields(fields))
+	ctx := context.Background()
+
+	_, err := s.Create(ctx, service.CreateDefectDTO{ProjectID: 1, Title: "x"})
+	var fieldErr *service.CustomFieldError
+	assert.ErrorAs(t, err, &fieldErr)
+	assert.Equal(t, "clause", fieldErr.Key)
+
+	_, err = s.Create(ctx, service.CreateDefectDTO{ProjectID: 1, Title: "x", CustomFields: map[string]interface{}{"clause": "9.9"}})
+	assert.ErrorAs(t, err, &fieldErr)
+	_, err = s.Create(ctx, service.CreateDefectDTO{ProjectID: 1, Title: "x", CustomFields: map[string]interface{}{"clause": "5.1", "cost": "a lot"}})
+	assert.ErrorAs(t, err, &fieldErr)
+	_, err = s.Create(ctx, service.CreateDefectDTO{ProjectID: 1, Title: "x", CustomFields: map[string]interface{}{"clause": "5.1", "color": "red"}})
+	assert.ErrorAs(t, err, &fieldErr)
+
+	d, err := s.Create(ctx, service.CreateDefectDTO{ProjectID: 1, Title: "x",
+		CustomFields: map[string]interface{}{"clause": "5.2", "cost": 1500.5, "found_on": "2025-10-12T09:00:00Z"}})
+	assert.NoError(t, err)
+	assert.Equal(t, "2025-10-12", d.CustomFields["found_on"])
+
+	// clearing a required field on update fails
+	_, err = s.Update(ctx, 1, "engineer", 1, service.UpdateDefectDTO{CustomFields: map[string]interface{}{"clause": nil}})
+	assert.ErrorAs(t, err, &fieldErr)
+	_, err = s.Update(ctx, 1, "engineer", 1, service.UpdateDefectDTO{CustomFields: map[string]interface{}{"cost": nil}})
+	assert.NoError(t, err)
+}
@@ -0,0 +1,203 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"example.com/defect-control-system/internal/models"
+	"example.com/defect-control-system/internal/repository"
+)
+
+// maxBulkDefects bounds the number of defects changed by one bulk request
+const maxBulkDefects = 500
+
+// BulkDefectsDTO applies the same change to many defects of a project. Defects
+// are selected either by DefectIDs or by Filter, a query string in the syntax
+// of the defect list, e.g. "status=open&assignee_id=5".
+type BulkDefectsDTO struct {
+	DefectIDs []uint `json:"defect_ids"`
+	Filter    string `json:"filter"`
+	// Selection is Filter parsed by the handler
+	Selection *models.DefectFilter `json:"-"`
+	Status    *string              `json:"status"`
+	// AssigneeID reassigns the defects; 0 unassigns them
+	AssigneeID   *uint      `json:"assignee_id"`
+	DueDate      *time.Time `json:"due_date"`
+	Priority     *string    `json:"priority"`
+	AddLabels    []uint     `json:"add_labels"`
+	RemoveLabels []uint     `json:"remove_labels"`
+	// SkipInvalid applies the change to the defects passing the checks; by
+	// default nothing is changed when any defect fails them
+	SkipInvalid bool `json:"skip_invalid"`
+}
+
+// BulkItemResult reports the checks of one defect
+type BulkItemResult struct {
+	DefectID uint   `json:"defect_id"`
+	Key      string `json:"key,omitempty"`
+	OK       bool   `json:"ok"`
+	Error    string `json:"error,omitempty"`
+}
+
+// BulkResult is the per-defect report of a bulk operation. Applied is false
+// when nothing was written because some defects failed the checks.
+type BulkResult struct {
+	Applied bool             `json:"applied"`
+	Updated int              `json:"updated"`
+	Failed  int              `json:"failed"`
+	Items   []BulkItemResult `json:"items"`
+}
+
+func (s *defectService) Bulk(ctx context.Context, userID uint, role string, projectID uint, dto BulkDefectsDTO) (*BulkResult, error) {
+	if dto.Priority != nil && *dto.Priority == "" {
+		dto.Priority = nil
+	}
+	if dto.Status == nil && dto.AssigneeID == nil && dto.DueDate == nil && dto.Priority == nil &&
+		len(dto.AddLabels) == 0 && len(dto.RemoveLabels) == 0 {
+		return nil, invalid("nothing to change")
+	}
+	if (len(dto.DefectIDs) == 0) == (dto.Selection == nil) {
+		return nil, invalid("give either defect_ids or filter")
+	}
+	if len(dto.DefectIDs) > maxBulkDefects {
+		return nil, invalid("at most %d defects per request", maxBulkDefects)
+	}
+	if s.members != nil {
+		ok, err := s.members.CanAccessProject(ctx, userID, role, projectID)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, ErrForbidden
+		}
+	}
+	project, err := s.projectRepo.FindByID(ctx, projectID)
+	if err != nil || project == nil {
+		return nil, ErrNotFound
+	}
+
+	// checks that do not depend on the defect fail the whole request
+	var columns []string
+	var priority models.Level
+	if dto.Priority != nil {
+		if priority, err = resolveLevel("priority", project.Priorities(), *dto.Priority); err != nil {
+			return nil, err
+		}
+		columns = append(columns, "priority", "priority_rank")
+	}
+	var assignee *uint
+	if dto.AssigneeID != nil {
+		if *dto.AssigneeID != 0 {
+			if _, err := s.userRepo.FindByID(ctx, *dto.AssigneeID); err != nil {
+				return nil, invalid("assignee not found")
+			}
+			v := *dto.AssigneeID
+			assignee = &v
+		}
+		columns = append(columns, "assignee_id")
+	}
+	if dto.DueDate != nil {
+		columns = append(columns, "due_date")
+	}
+	manager := false
+	if dto.Status != nil {
+		if _, ok := defectWorkflow[*dto.Status]; !ok {
+			return nil, &WorkflowError{To: *dto.Status}
+		}
+		if manager, err = s.canManage(ctx, userID, role, projectID); err != nil {
+			return nil, err
+		}
+		columns = append(columns, "status")
+	}
+	if len(dto.AddLabels) > 0 || len(dto.RemoveLabels) > 0 {
+		if s.labelRepo == nil {
+			return nil, invalid("labels are not supported")
+		}
+		if err := checkProjectLabels(ctx, s.labelRepo, projectID, append(append([]uint{}, dto.AddLabels...), dto.RemoveLabels...)); err != nil {
+			return nil, err
+		}
+	}
+
+	filter := models.DefectFilter{}
+	var ids []uint
+	if dto.Selection != nil {
+		filter = *dto.Selection
+	} else {
+		ids = uniqueUints(dto.DefectIDs)
+		filter.IDs = ids
+	}
+	defects, err := s.repo.ListFiltered(ctx, repository.DefectQuery{ProjectIDs: []uint{projectID}, Filter: filter})
+	if err != nil {
+		return nil, err
+	}
+	if len(defects) > maxBulkDefects {
+		return nil, invalid("the filter selects %d defects, at most %d per request", len(defects), maxBulkDefects)
+	}
+
+	res := &BulkResult{Items: make([]BulkItemResult, 0, len(defects))}
+	valid := make([]*models.Defect, 0, len(defects))
+	found := make(map[uint]bool, len(defects))
+	for _, d := range defects {
+		found[d.ID] = true
+		item := BulkItemResult{DefectID: d.ID, Key: d.Key, OK: true}
+		if dto.Status != nil && *dto.Status != d.Status {
+			manage, err := checkTransition(d.Status, *dto.Status)
+			if err == nil && manage && !manager {
+				err = fmt.Errorf("%w: only project managers can change status to %s", ErrForbidden, *dto.Status)
+			}
+			if err != nil {
+				item.OK, item.Error = false, err.Error()
+			}
+		}
+		if item.OK {
+			valid = append(valid, d)
+		} else {
+			res.Failed++
+		}
+		res.Items = append(res.Items, item)
+	}
+	for _, id := range ids {
+		if !found[id] {
+			res.Items = append(res.Items, BulkItemResult{DefectID: id, Error: "defect not found in project"})
+			res.Failed++
+		}
+	}
+	if res.Failed > 0 && !dto.SkipInvalid {
+		return res, nil
+	}
+	res.Applied = true
+	if len(valid) == 0 {
+		return res, nil
+	}
+
+	reopened := false
+	for _, d := range valid {
+		if dto.Status != nil && *dto.Status != d.Status {
+			if d.Status == StatusClosed {
+				// a reopened defect needs a new verification; the columns are
+				// shared, other defects write back their own values
+				d.VerifiedByID, d.VerifiedAt = nil, nil
+				reopened = true
+			}
+			d.Status = *dto.Status
+		}
+		if dto.AssigneeID != nil {
+			d.AssigneeID = assignee
+		}
+		if dto.DueDate != nil {
+			d.DueDate = dto.DueDate
+		}
+		if dto.Priority != nil {
+			d.Priority, d.PriorityRank = priority.Value, priority.Rank
+		}
+	}
+	if reopened {
+		columns = append(columns, "verified_by_id", "verified_at")
+	}
+	if err := s.repo.BulkUpdate(ctx, valid, columns, dto.AddLabels, dto.RemoveLabels); err != nil {
+		return nil, versionError(err)
+	}
+	res.Updated = len(valid)
+	return res, nil
+}
@@ -0,0 +1,173 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"example.com/defect-control-system/internal/models"
+	"example.com/defect-control-system/internal/repository"
+	"example.com/defect-control-system/internal/service"
+)
+
+// bulkDefectRepo holds defects of project 7 and records bulk writes
+type bulkDefectRepo struct {
+	mockDefectRepo
+	defects []*models.Defect
+	written []*models.Defect
+	columns []string
+}
+
+func (m *bulkDefectRepo) ListFiltered(ctx context.Context, q repository.DefectQuery) ([]*models.Defect, error) {
+	var out []*models.Defect
+	for _, d := range m.defects {
+		match := len(q.Filter.IDs) == 0
+		for _, id := range q.Filter.IDs {
+			match = match || id == d.ID
+		}
+		for _, st := range q.Filter.Statuses {
+			match = match && st == d.Status
+		}
+		if match && q.ProjectIDs[0] == d.ProjectID {
+			c := *d
+			out = append(out, &c)
+		}
+	}
+	return out, nil
+}
+func (m *bulkDefectRepo) BulkUpdate(ctx context.Context, defects []*models.Defect, columns []string, addLabels, removeLabels []uint) error {
+	m.written, m.columns = defects, columns
+	return nil
+}
+
+func newBulkService(repo *bulkDefectRepo) service.DefectService {
+	members := service.NewMembershipService(&mockMemberRepo{}, &mockProjectRepo{}, &mockUserRepo{})
+	labels := &mockLabelRepo{labels: []*models.Label{{ID: 1, ProjectID: 7, Name: "roof"}}}
+	return service.NewDefectService(repo, &mockProjectRepo{}, &mockUserRepo{}, service.WithMembership(members), service.WithLabels(labels))
+}
+
+func TestBulk_AllOrNothing(t *testing.T) {
+	repo := &bulkDefectRepo{defects: []*models.Defect{
+		{ID: 1, ProjectID: 7, Key: "TWR-1", Status: "open"},
+		{ID: 2, ProjectID: 7, Key: "TWR-2", Status: "closed"},
+		{ID: 3, ProjectID: 8, Key: "NRD-1", Status: "open"},
+	}}
+	s := newBulkService(repo)
+	ctx := context.Background()
+	status := "in_progress"
+
+	// closed defects cannot go to in_progress, defect 3 is in another project
+	res, err := s.Bulk(ctx, 1, "engineer", 7, service.BulkDefectsDTO{DefectIDs: []uint{1, 2, 3}, Status: &status})
+	assert.NoError(t, err)
+	assert.False(t, res.Applied)
+	assert.Equal(t, 2, res.Failed)
+	assert.Len(t, res.Items, 3)
+	assert.True(t, res.Items[0].OK)
+	assert.Nil(t, repo.written)
+
+	res, err = s.Bulk(ctx, 1, "engineer", 7, service.BulkDefectsDTO{DefectIDs: []uint{1, 2, 3}, Status: &status, SkipInvalid: true})
+	assert.NoError(t, err)
+	assert.True(t, res.Applied)
+	assert.Equal(t, 1, res.Updated)
+	if assert.Len(t, repo.written, 1) {
+		assert.Equal(t, "in_progress", repo.written[0].Status)
+	}
+	assert.Equal(t, []string{"status"}, repo.columns)
+}
+
+func TestBulk_ReassignByFilter(t *testing.T) {
+	repo := &bulkDefectRepo{defects: []*models.Defect{
+		{ID: 1, ProjectID: 7, Status: "open"},
+		{ID: 2, ProjectID: 7, Status: "in_progress"},
+		{ID: 4, ProjectID: 7, Status: "open"},
+	}}
+	s := newBulkService(repo)
+	ctx := context.Background()
+	assignee, priority := uint(5), "high"
+
+	res, err := s.Bulk(ctx, 9, "manager", 7, service.BulkDefectsDTO{
+		Selection:  &models.DefectFilter{Statuses: []string{"open"}},
+		AssigneeID: &assignee,
+		Priority:   &priority,
+		AddLabels:  []uint{1},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, res.Updated)
+	for _, d := range repo.written {
+		assert.Equal(t, uint(5), *d.AssigneeID)
+		assert.Equal(t, 30, d.PriorityRank)
+	}
+
+	// unknown label, unknown priority, nothing to change, both selectors
+	_, err = s.Bulk(ctx, 9, "manager", 7, service.BulkDefectsDTO{DefectIDs: []uint{1}, AddLabels: []uint{2}})
+	assert.Error(t, err)
+	bad := "urgent"
+	_, err = s.Bulk(ctx, 9, "manager", 7, service.BulkDefectsDTO{DefectIDs: []uint{1}, Priority: &bad})
+	assert.Error(t, err)
+	_, err = s.Bulk(ctx, 9, "manager", 7, service.BulkDefectsDTO{DefectIDs: []uint{1}})
+	assert.Error(t, err)
+	_, err = s.Bulk(ctx, 9, "manager", 7, service.BulkDefectsDTO{DefectIDs: []uint{1}, Selection: &models.DefectFilter{}, Priority: &priority})
+	assert.Error(t, err)
+	// not a member of the project
+	_, err = s.Bulk(ctx, 2, "engineer", 7, service.BulkDefectsDTO{DefectIDs: []uint{1}, Priority: &priority})
+	assert.ErrorIs(t, err, service.ErrForbidden)
+}
+
+func TestWorkflow_CancelRequiresManager(t *testing.T) {
+	repo := &bulkDefectRepo{defects: []*models.Defect{{ID: 1, ProjectID: 7, Status: "in_progress"}}}
+	s := newBulkService(repo)
+	ctx := context.Background()
+	cancelled := "cancelled"
+
+	res, err := s.Bulk(ctx, 1, "engineer", 7, service.BulkDefectsDTO{DefectIDs: []uint{1}, Status: &cancelled})
+	assert.NoError(t, err)
+	assert.False(t, res.Applied)
+	assert.Contains(t, res.Items[0].Error, "forbidden")
+
+	res, err = s.Bulk(ctx, 9, "manager", 7, service.BulkDefectsDTO{DefectIDs: []uint{1}, Status: &cancelled})
+	assert.NoError(t, err)
+	assert.True(t, res.Applied)
+
+	// single updates follow the same workflow
+	single := service.NewDefectService(&mockDefectRepo{}, &mockProjectRepo{}, &mockUserRepo{})
+	_, err = single.Update(ctx, 1, "engineer", 1, service.UpdateDefectDTO{Status: &cancelled})
+	assert.ErrorIs(t, err, service.ErrForbidden)
+	unknown := "done"
+	_, err = single.Update(ctx, 1, "manager", 1, service.UpdateDefectDTO{Status: &unknown})
+	var flowErr *service.WorkflowError
+	assert.ErrorAs(t, err, &flowErr)
+	_, err = single.Update(ctx, 1, "manager", 1, service.UpdateDefectDTO{Status: &cancelled})
+	assert.NoError(t, err)
+	// closing goes through verification, even for managers
+	closed := "closed"
+	_, err = single.Update(ctx, 1, "manager", 1, service.UpdateDefectDTO{Status: &closed})
+	assert.ErrorAs(t, err, &flowErr)
+}
+
+func TestBulk_ReopenClearsVerification(t *testing.T) {
+	verifier, at := uint(5), time.Now()
+	repo := &bulkDefectRepo{defects: []*models.Defect{
+		{ID: 1, ProjectID: 7, Status: "closed", VerifiedByID: &verifier, VerifiedAt: &at},
+		{ID: 2, ProjectID: 7, Status: "cancelled"},
+	}}
+	s := newBulkService(repo)
+	ctx := context.Background()
+	open := "open"
+
+	res, err := s.Bulk(ctx, 9, "manager", 7, service.BulkDefectsDTO{DefectIDs: []uint{1, 2}, Status: &open})
+	assert.NoError(t, err)
+	assert.True(t, res.Applied)
+	if assert.Len(t, repo.written, 2) {
+		assert.Nil(t, repo.written[0].VerifiedByID)
+		assert.Nil(t, repo.written[0].VerifiedAt)
+	}
+	assert.Equal(t, []string{"status", "verified_by_id", "verified_at"}, repo.columns)
+
+	// nothing reopened, nothing cleared
+	res, err = s.Bulk(ctx, 9, "manager", 7, service.BulkDefectsDTO{DefectIDs: []uint{2}, Status: &open})
+	assert.NoError(t, err)
+	assert.True(t, res.Applied)
+	assert.Equal(t, []string{"status"}, repo.columns)
+}
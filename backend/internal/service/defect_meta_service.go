@@ -0,0 +1,165 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"example.com/defect-control-system/internal/models"
+	"example.com/defect-control-system/internal/repository"
+)
+
+// DefectMeta lists the allowed values of ordered defect attributes, highest rank first
+type DefectMeta struct {
+	Severities models.LevelList `json:"severities"`
+	Priorities models.LevelList `json:"priorities"`
+}
+
+// UpdateLevelsDTO overrides the project's levels. An omitted list is left as is;
+// an empty list restores the defaults.
+type UpdateLevelsDTO struct {
+	Severities *models.LevelList `json:"severities"`
+	Priorities *models.LevelList `json:"priorities"`
+}
+
+// LevelError reports a severity or priority value not allowed in the project
+type LevelError struct {
+	Field   string
+	Value   string
+	Allowed []string
+}
+
+func (e *LevelError) Error() string {
+	return fmt.Sprintf("invalid %s %q, allowed: %s", e.Field, e.Value, strings.Join(e.Allowed, ", "))
+}
+
+// Is makes the error ErrInvalid
+func (e *LevelError) Is(target error) bool { return target == ErrInvalid }
+
+var levelValuePattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,49}$`)
+
+// resolveLevel returns the level for value. An empty value selects the default
+// level, or no level when none is flagged as default.
+func resolveLevel(field string, levels models.LevelList, value string) (models.Level, error) {
+	if value == "" {
+		lv, _ := levels.DefaultLevel()
+		return lv, nil
+	}
+	if lv, ok := levels.Find(value); ok {
+		return lv, nil
+	}
+	allowed := make([]string, len(levels))
+	for i, lv := range levels {
+		allowed[i] = lv.Value
+	}
+	return models.Level{}, &LevelError{Field: field, Value: value, Allowed: allowed}
+}
+
+// normalizeLevels validates a level list and returns it sorted by rank, highest first
+func normalizeLevels(field string, levels models.LevelList) (models.LevelList, error) {
+	seen := map[string]bool{}
+	defaults := 0
+	out := make(models.LevelList, 0, len(levels))
+	for _, lv := range levels {
+		lv.Value = strings.TrimSpace(lv.Value)
+		lv.Label = strings.TrimSpace(lv.Label)
+		if !levelValuePattern.MatchString(lv.Value) {
+			return nil, invalid("%s: invalid value %q", field, lv.Value)
+		}
+		if seen[lv.Value] {
+			return nil, invalid("%s: duplicate value %q", field, lv.Value)
+		}
+		seen[lv.Value] = true
+		if lv.Rank <= 0 {
+			return nil, invalid("%s: rank of %q must be positive", field, lv.Value)
+		}
+		if lv.Label == "" {
+			lv.Label = lv.Value
+		}
+		if lv.Default {
+			defaults++
+		}
+		out = append(out, lv)
+	}
+	if defaults > 1 {
+		return nil, invalid("%s: only one default level is allowed", field)
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Rank > out[j].Rank })
+	return out, nil
+}
+
+// nilIfEmpty maps an empty override to nil, which stores NULL (use the defaults)
+func nilIfEmpty(l models.LevelList) models.LevelList {
+	if len(l) == 0 {
+		return nil
+	}
+	return l
+}
+
+// DefectMetaService exposes the allowed severity and priority values and lets
+// project admins override them per project.
+type DefectMetaService interface {
+	// Meta returns the levels of a project, or the defaults when projectID is 0
+	Meta(ctx context.Context, projectID uint) (*DefectMeta, error)
+	UpdateLevels(ctx context.Context, userID uint, role string, projectID uint, dto UpdateLevelsDTO) (*DefectMeta, error)
+}
+
+type defectMetaService struct {
+	projectRepo repository.ProjectRepository
+	defectRepo  repository.DefectRepository
+	members     MembershipService
+}
+
+func NewDefectMetaService(pr repository.ProjectRepository, dr repository.DefectRepository, m MembershipService) DefectMetaService {
+	return &defectMetaService{projectRepo: pr, defectRepo: dr, members: m}
+}
+
+func (s *defectMetaService) Meta(ctx context.Context, projectID uint) (*DefectMeta, error) {
+	if projectID == 0 {
+		return &DefectMeta{Severities: models.DefaultSeverityLevels, Priorities: models.DefaultPriorityLevels}, nil
+	}
+	p, err := s.projectRepo.FindByID(ctx, projectID)
+	if err != nil || p == nil {
+		return nil, ErrNotFound
+	}
+	return &DefectMeta{Severities: p.Severities(), Priorities: p.Priorities()}, nil
+}
+
+func (s *defectMetaService) UpdateLevels(ctx context.Context, userID uint, role string, projectID uint, dto UpdateLevelsDTO) (*DefectMeta, error) {
+	ok, err := s.members.CanManageProject(ctx, userID, role, projectID)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrForbidden
+	}
+	return retryUnconditional(nil, func() (*DefectMeta, error) { return s.updateLevels(ctx, projectID, dto) })
+}
+
+func (s *defectMetaService) updateLevels(ctx context.Context, projectID uint, dto UpdateLevelsDTO) (*DefectMeta, error) {
+	p, err := s.projectRepo.FindByID(ctx, projectID)
+	if err != nil || p == nil {
+		return nil, ErrNotFound
+	}
+	severities, priorities := p.SeverityLevels, p.PriorityLevels
+	if dto.Severities != nil {
+		if severities, err = normalizeLevels("severities", *dto.Severities); err != nil {
+			return nil, err
+		}
+	}
+	if dto.Priorities != nil {
+		if priorities, err = normalizeLevels("priorities", *dto.Priorities); err != nil {
+			return nil, err
+		}
+	}
+	p.SeverityLevels, p.PriorityLevels = nilIfEmpty(severities), nilIfEmpty(priorities)
+	if err := s.projectRepo.Update(ctx, p, []string{"severity_levels", "priority_levels"}); err != nil {
+		return nil, versionError(err)
+	}
+	if err := s.defectRepo.UpdateRanks(ctx, p.ID, p.Severities(), p.Priorities()); err != nil {
+		return nil, err
+	}
+	return &DefectMeta{Severities: p.Severities(), Priorities: p.Priorities()}, nil
+}
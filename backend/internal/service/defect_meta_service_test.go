@@ -0,0 +1,83 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"example.com/defect-control-system/internal/models"
+	"example.com/defect-control-system/internal/service"
+)
+
+// levelsProjectRepo keeps one project with custom severities
+type levelsProjectRepo struct {
+	mockProjectRepo
+	project *models.Project
+}
+
+func (m *levelsProjectRepo) FindByID(ctx context.Context, id uint) (*models.Project, error) {
+	return m.project, nil
+}
+func (m *levelsProjectRepo) Update(ctx context.Context, p *models.Project, columns []string) error {
+	m.project = p
+	return nil
+}
+
+func TestCreateDefect_Levels(t *testing.T) {
+	s := service.NewDefectService(&mockDefectRepo{}, &mockProjectRepo{}, &mockUserRepo{})
+	ctx := context.Background()
+
+	d, err := s.Create(ctx, service.CreateDefectDTO{ProjectID: 1, Title: "x"})
+	assert.NoError(t, err)
+	assert.Equal(t, "minor", d.Severity)
+	assert.Equal(t, "medium", d.Priority)
+	assert.Equal(t, 20, d.PriorityRank)
+
+	d, err = s.Create(ctx, service.CreateDefectDTO{ProjectID: 1, Title: "x", Severity: "critical", Priority: "high"})
+	assert.NoError(t, err)
+	assert.Equal(t, 30, d.SeverityRank)
+
+	_, err = s.Create(ctx, service.CreateDefectDTO{ProjectID: 1, Title: "x", Severity: "medium"})
+	var levelErr *service.LevelError
+	assert.ErrorAs(t, err, &levelErr)
+	assert.Equal(t, "severity", levelErr.Field)
+
+	bad := "urgent"
+	_, err = s.Update(ctx, 1, "engineer", 1, service.UpdateDefectDTO{Priority: &bad})
+	assert.ErrorAs(t, err, &levelErr)
+}
+
+func TestUpdateLevels(t *testing.T) {
+	projects := &levelsProjectRepo{project: &models.Project{ID: 7}}
+	members := service.NewMembershipService(&mockMemberRepo{}, projects, &mockUserRepo{})
+	s := service.NewDefectMetaService(projects, &mockDefectRepo{}, members)
+	ctx := context.Background()
+
+	sev := models.LevelList{{Value: "s2", Rank: 2}, {Value: "s1", Rank: 1, Default: true}, {Value: "s3", Rank: 3}}
+	_, err := s.UpdateLevels(ctx, 1, "engineer", 7, service.UpdateLevelsDTO{Severities: &sev})
+	assert.ErrorIs(t, err, service.ErrForbidden)
+
+	m, err := s.UpdateLevels(ctx, 9, "manager", 7, service.UpdateLevelsDTO{Severities: &sev})
+	assert.NoError(t, err)
+	assert.Equal(t, "s3", m.Severities[0].Value)
+	assert.Equal(t, "s2", m.Severities[1].Label)
+	assert.Equal(t, models.DefaultPriorityLevels, m.Priorities)
+
+	dup := models.LevelList{{Value: "a", Rank: 1}, {Value: "a", Rank: 2}}
+	_, err = s.UpdateLevels(ctx, 9, "manager", 7, service.UpdateLevelsDTO{Severities: &dup})
+	assert.Error(t, err)
+
+	// the project's levels now drive defect validation
+	ds := service.NewDefectService(&mockDefectRepo{}, projects, &mockUserRepo{})
+	d, err := ds.Create(ctx, service.CreateDefectDTO{ProjectID: 7, Title: "x"})
+	assert.NoError(t, err)
+	assert.Equal(t, "s1", d.Severity)
+	_, err = ds.Create(ctx, service.CreateDefectDTO{ProjectID: 7, Title: "x", Severity: "critical"})
+	assert.Error(t, err)
+
+	// an empty list restores the defaults
+	m, err = s.UpdateLevels(ctx, 9, "manager", 7, service.UpdateLevelsDTO{Severities: &models.LevelList{}})
+	assert.NoError(t, err)
+	assert.Equal(t, models.DefaultSeverityLevels, m.Severities)
+}
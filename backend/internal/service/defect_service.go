@@ -2,7 +2,8 @@ package service
 
 import (
 	"context"
-	"errors"
+	"fmt"
+	"strings"
 	"time"
 
 	"example.com/defect-control-system/internal/models"
@@ -17,53 +18,301 @@ type CreateDefectDTO struct {
 	AssigneeID  uint       `json:"assignee_id,omitempty"`
 	DueDate     *time.Time `json:"due_date,omitempty"`
 	Priority    string     `json:"priority"`
+	// CustomFields holds values of the project's custom fields keyed by field key
+	CustomFields map[string]interface{} `json:"custom_fields,omitempty"`
+	// CategoryID selects a catalog entry; its default severity and responsible
+	// organization are used when those are not given
+	CategoryID       *uint `json:"category_id,omitempty"`
+	ResponsibleOrgID *uint `json:"responsible_org_id,omitempty"`
+	// ClauseIDs cite violated clauses of normative documents
+	ClauseIDs []uint `json:"clause_ids,omitempty"`
+	// VerifierID designates the user who verifies the fix, e.g. the customer's
+	// technical supervisor. Designating one takes project management rights
+	VerifierID *uint `json:"verifier_id,omitempty"`
+	// UserID and Role are the creator, filled by the handler
+	UserID uint   `json:"-"`
+	Role   string `json:"-"`
 }
 
 type DefectService interface {
 	Create(ctx context.Context, dto CreateDefectDTO) (*models.Defect, error)
 	ListByProject(ctx context.Context, projectID uint) ([]*models.Defect, error)
+	// ListFiltered returns defects of the selected projects matching the filter
+	ListFiltered(ctx context.Context, q repository.DefectQuery) ([]*models.Defect, error)
 	FindByID(ctx context.Context, id uint) (*models.Defect, error)
-	Update(ctx context.Context, id uint, dto UpdateDefectDTO) (*models.Defect, error)
+	// FindByKey resolves a key such as "TWR-142"; the project part is case-insensitive
+	FindByKey(ctx context.Context, key string) (*models.Defect, error)
+	// Update changes a defect on behalf of the user; status changes follow the workflow
+	Update(ctx context.Context, userID uint, role string, id uint, dto UpdateDefectDTO) (*models.Defect, error)
+	// Bulk applies one change to many defects of a project in a single transaction
+	Bulk(ctx context.Context, userID uint, role string, projectID uint, dto BulkDefectsDTO) (*BulkResult, error)
 }
 
 type defectService struct {
 	repo        repository.DefectRepository
 	projectRepo repository.ProjectRepository
 	userRepo    repository.UserRepository
+	fieldRepo   repository.CustomFieldRepository
+	categories  repository.CategoryRepository
+	orgRepo     repository.OrganizationRepository
+	normatives  repository.NormativeRepository
+	members     MembershipService
+	labelRepo   repository.LabelRepository
 }
 
-func NewDefectService(r repository.DefectRepository, pr repository.ProjectRepository, ur repository.UserRepository) DefectService {
-	return &defectService{repo: r, projectRepo: pr, userRepo: ur}
+// DefectServiceOption configures optional dependencies of the defect service
+type DefectServiceOption func(*defectService)
+
+// WithCustomFields enables custom field values on defects, validated against
+// the project's definitions
+func WithCustomFields(r repository.CustomFieldRepository) DefectServiceOption {
+	return func(s *defectService) { s.fieldRepo = r }
+}
+
+// WithCatalog enables defect categories and responsible organizations
+func WithCatalog(cr repository.CategoryRepository, or repository.OrganizationRepository) DefectServiceOption {
+	return func(s *defectService) { s.categories = cr; s.orgRepo = or }
+}
+
+// WithNormatives enables citing clauses of normative documents on defects
+func WithNormatives(r repository.NormativeRepository) DefectServiceOption {
+	return func(s *defectService) { s.normatives = r }
+}
+
+// WithMembership checks project management rights with project membership;
+// without it only the global manager and admin roles manage projects
+func WithMembership(m MembershipService) DefectServiceOption {
+	return func(s *defectService) { s.members = m }
+}
+
+// WithLabels enables label changes in bulk operations
+func WithLabels(r repository.LabelRepository) DefectServiceOption {
+	return func(s *defectService) { s.labelRepo = r }
+}
+
+func NewDefectService(r repository.DefectRepository, pr repository.ProjectRepository, ur repository.UserRepository, opts ...DefectServiceOption) DefectService {
+	s := &defectService{repo: r, projectRepo: pr, userRepo: ur}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// canManage reports whether the user may perform restricted workflow transitions
+func (s *defectService) canManage(ctx context.Context, userID uint, role string, projectID uint) (bool, error) {
+	if s.members == nil {
+		return role == "manager" || role == "admin", nil
+	}
+	return s.members.CanManageProject(ctx, userID, role, projectID)
+}
+
+// checkStatus validates a status change of d by the user
+func (s *defectService) checkStatus(ctx context.Context, userID uint, role string, d *models.Defect, status string) error {
+	manage, err := checkTransition(d.Status, status)
+	if err != nil || !manage {
+		return err
+	}
+	ok, err := s.canManage(ctx, userID, role, d.ProjectID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("%w: only project managers can change status to %s", ErrForbidden, status)
+	}
+	return nil
+}
+
+// checkVerifier validates designating user id as the verifier of a defect of
+// the project: only project managers designate, and the verifier needs access
+// to the project
+func (s *defectService) checkVerifier(ctx context.Context, userID uint, role string, projectID, id uint) error {
+	ok, err := s.canManage(ctx, userID, role, projectID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("%w: only project managers designate the verifier", ErrForbidden)
+	}
+	if id == 0 {
+		return nil
+	}
+	u, err := s.userRepo.FindByID(ctx, id)
+	if err != nil || u == nil {
+		return invalid("verifier not found")
+	}
+	if s.members == nil {
+		return nil
+	}
+	ok, err = s.members.CanAccessProject(ctx, u.ID, u.Role, projectID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return invalid("the verifier has no access to the project")
+	}
+	return nil
+}
+
+// customFields validates values against the project's custom field definitions
+// and merges them into current
+func (s *defectService) customFields(ctx context.Context, projectID uint, current models.JSONMap, values map[string]interface{}, creating bool) (models.JSONMap, error) {
+	if s.fieldRepo == nil {
+		if len(values) > 0 {
+			return nil, invalid("custom fields are not supported")
+		}
+		return current, nil
+	}
+	defs, err := s.fieldRepo.ListByProject(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	userExists := func(id uint) bool {
+		_, err := s.userRepo.FindByID(ctx, id)
+		return err == nil
+	}
+	return applyCustomFields(defs, current, values, creating, userExists)
+}
+
+func (s *defectService) findCategory(ctx context.Context, id uint) (*models.DefectCategory, error) {
+	if s.categories == nil {
+		return nil, invalid("defect categories are not supported")
+	}
+	c, err := s.categories.FindByID(ctx, id)
+	if err != nil {
+		return nil, invalid("category not found")
+	}
+	return c, nil
+}
+
+func (s *defectService) checkOrganization(ctx context.Context, id uint) error {
+	if s.orgRepo == nil {
+		return invalid("organizations are not supported")
+	}
+	if _, err := s.orgRepo.FindByID(ctx, id); err != nil {
+		return invalid("organization not found")
+	}
+	return nil
+}
+
+// resolveClauses loads the cited clauses. Clauses of obsolete documents are
+// accepted only if the defect already cites them.
+func (s *defectService) resolveClauses(ctx context.Context, ids []uint, current []models.NormativeClause) ([]models.NormativeClause, error) {
+	if len(ids) == 0 {
+		return []models.NormativeClause{}, nil
+	}
+	if s.normatives == nil {
+		return nil, invalid("normative references are not supported")
+	}
+	found, err := s.normatives.FindClauses(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	known := make(map[uint]bool, len(found))
+	for _, c := range found {
+		known[c.ID] = true
+	}
+	for _, id := range ids {
+		if !known[id] {
+			return nil, invalid("clause %d not found", id)
+		}
+	}
+	cited := make(map[uint]bool, len(current))
+	for _, c := range current {
+		cited[c.ID] = true
+	}
+	out := make([]models.NormativeClause, 0, len(found))
+	for _, c := range found {
+		if c.Document != nil && c.Document.Obsolete && !cited[c.ID] {
+			return nil, invalid("%s is obsolete", c.Document.Code)
+		}
+		out = append(out, *c)
+	}
+	return out, nil
 }
 
 func (s *defectService) Create(ctx context.Context, dto CreateDefectDTO) (*models.Defect, error) {
 	// ensure project exists
-	if _, err := s.projectRepo.FindByID(ctx, dto.ProjectID); err != nil {
-		return nil, errors.New("project not found")
+	project, err := s.projectRepo.FindByID(ctx, dto.ProjectID)
+	if err != nil || project == nil {
+		return nil, invalid("project not found")
 	}
 
 	var assigneePtr *uint
 	if dto.AssigneeID != 0 {
 		// validate assignee exists
 		if _, err := s.userRepo.FindByID(ctx, dto.AssigneeID); err != nil {
-			return nil, errors.New("assignee not found")
+			return nil, invalid("assignee not found")
 		}
 		v := dto.AssigneeID
 		assigneePtr = &v
 	}
+	custom, err := s.customFields(ctx, dto.ProjectID, nil, dto.CustomFields, true)
+	if err != nil {
+		return nil, err
+	}
+	severity, orgID := dto.Severity, dto.ResponsibleOrgID
+	if dto.CategoryID != nil {
+		cat, err := s.findCategory(ctx, *dto.CategoryID)
+		if err != nil {
+			return nil, err
+		}
+		if severity == "" {
+			severity = cat.DefaultSeverity
+		}
+		if orgID == nil {
+			orgID = cat.DefaultOrganizationID
+		}
+	}
+	if dto.ResponsibleOrgID != nil {
+		if err := s.checkOrganization(ctx, *dto.ResponsibleOrgID); err != nil {
+			return nil, err
+		}
+	}
+	sev, err := resolveLevel("severity", project.Severities(), severity)
+	if err != nil {
+		return nil, err
+	}
+	prio, err := resolveLevel("priority", project.Priorities(), dto.Priority)
+	if err != nil {
+		return nil, err
+	}
+	clauses, err := s.resolveClauses(ctx, dto.ClauseIDs, nil)
+	if err != nil {
+		return nil, err
+	}
+	if dto.VerifierID != nil && *dto.VerifierID == 0 {
+		dto.VerifierID = nil
+	}
+	if dto.VerifierID != nil {
+		if err := s.checkVerifier(ctx, dto.UserID, dto.Role, dto.ProjectID, *dto.VerifierID); err != nil {
+			return nil, err
+		}
+	}
 	d := &models.Defect{
-		ProjectID:   dto.ProjectID,
-		Title:       dto.Title,
-		Description: dto.Description,
-		Severity:    dto.Severity,
-		Status:      "open",
-		AssigneeID:  assigneePtr,
-		DueDate:     dto.DueDate,
-		Priority:    dto.Priority,
+		ProjectID:        dto.ProjectID,
+		Title:            dto.Title,
+		Description:      dto.Description,
+		Severity:         sev.Value,
+		SeverityRank:     sev.Rank,
+		Status:           StatusOpen,
+		AssigneeID:       assigneePtr,
+		VerifierID:       dto.VerifierID,
+		DueDate:          dto.DueDate,
+		Priority:         prio.Value,
+		PriorityRank:     prio.Rank,
+		CategoryID:       dto.CategoryID,
+		ResponsibleOrgID: orgID,
+		CustomFields:     custom,
 	}
 	if err := s.repo.Create(ctx, d); err != nil {
 		return nil, err
 	}
+	if len(clauses) > 0 {
+		if err := s.normatives.SetDefectClauses(ctx, d.ID, dto.ClauseIDs); err != nil {
+			return nil, err
+		}
+	}
+	d.Clauses = clauses
 	return d, nil
 }
 
@@ -75,44 +324,160 @@ type UpdateDefectDTO struct {
 	DueDate     *time.Time `json:"due_date"`
 	Priority    *string    `json:"priority"`
 	Status      *string    `json:"status"`
+	// CustomFields sets the given custom field values; null clears a field
+	CustomFields map[string]interface{} `json:"custom_fields"`
+	// CategoryID and ResponsibleOrgID change the classification; 0 clears them
+	CategoryID       *uint `json:"category_id"`
+	ResponsibleOrgID *uint `json:"responsible_org_id"`
+	// ClauseIDs replaces the cited clauses when present; an empty list clears them
+	ClauseIDs *[]uint `json:"clause_ids"`
+	// VerifierID designates the verifier of the fix; 0 clears it
+	VerifierID *uint `json:"verifier_id"`
+	// Version, when set, must equal the stored version (the handler fills it from If-Match)
+	Version *int `json:"version,omitempty"`
+}
+
+func (s *defectService) Update(ctx context.Context, userID uint, role string, id uint, dto UpdateDefectDTO) (*models.Defect, error) {
+	return retryUnconditional(dto.Version, func() (*models.Defect, error) { return s.update(ctx, userID, role, id, dto) })
 }
 
-func (s *defectService) Update(ctx context.Context, id uint, dto UpdateDefectDTO) (*models.Defect, error) {
+func (s *defectService) update(ctx context.Context, userID uint, role string, id uint, dto UpdateDefectDTO) (*models.Defect, error) {
 	d, err := s.repo.FindByID(ctx, id)
 	if err != nil || d == nil {
-		return nil, errors.New("defect not found")
+		return nil, invalid("defect not found")
 	}
+	if err := checkVersion(dto.Version, d.Version); err != nil {
+		return nil, err
+	}
+	// only the columns present in the request are written, so concurrent edits
+	// of different fields do not overwrite each other
+	var columns []string
 	if dto.Title != nil {
 		d.Title = *dto.Title
+		columns = append(columns, "title")
 	}
 	if dto.Description != nil {
 		d.Description = *dto.Description
+		columns = append(columns, "description")
 	}
-	if dto.Severity != nil {
-		d.Severity = *dto.Severity
+	if dto.Severity != nil || dto.Priority != nil {
+		project, err := s.projectRepo.FindByID(ctx, d.ProjectID)
+		if err != nil || project == nil {
+			return nil, invalid("project not found")
+		}
+		// an empty value keeps the current one rather than resetting to the default
+		if dto.Severity != nil && *dto.Severity != "" {
+			lv, err := resolveLevel("severity", project.Severities(), *dto.Severity)
+			if err != nil {
+				return nil, err
+			}
+			d.Severity, d.SeverityRank = lv.Value, lv.Rank
+			columns = append(columns, "severity", "severity_rank")
+		}
+		if dto.Priority != nil && *dto.Priority != "" {
+			lv, err := resolveLevel("priority", project.Priorities(), *dto.Priority)
+			if err != nil {
+				return nil, err
+			}
+			d.Priority, d.PriorityRank = lv.Value, lv.Rank
+			columns = append(columns, "priority", "priority_rank")
+		}
 	}
 	if dto.AssigneeID != nil {
 		if *dto.AssigneeID == 0 {
 			d.AssigneeID = nil
 		} else {
 			if _, err := s.userRepo.FindByID(ctx, *dto.AssigneeID); err != nil {
-				return nil, errors.New("assignee not found")
+				return nil, invalid("assignee not found")
 			}
 			v := *dto.AssigneeID
 			d.AssigneeID = &v
 		}
+		columns = append(columns, "assignee_id")
 	}
 	if dto.DueDate != nil {
 		d.DueDate = dto.DueDate
+		columns = append(columns, "due_date")
 	}
-	if dto.Priority != nil {
-		d.Priority = *dto.Priority
-	}
-	if dto.Status != nil {
+	if dto.Status != nil && *dto.Status != d.Status {
+		if err := s.checkStatus(ctx, userID, role, d, *dto.Status); err != nil {
+			return nil, err
+		}
+		if d.Status == StatusClosed {
+			// a reopened defect needs a new verification
+			d.VerifiedByID, d.VerifiedAt = nil, nil
+			columns = append(columns, "verified_by_id", "verified_at")
+		}
 		d.Status = *dto.Status
+		columns = append(columns, "status")
 	}
-	if err := s.repo.Update(ctx, d); err != nil {
-		return nil, err
+	if dto.VerifierID != nil {
+		if err := s.checkVerifier(ctx, userID, role, d.ProjectID, *dto.VerifierID); err != nil {
+			return nil, err
+		}
+		if *dto.VerifierID == 0 {
+			d.VerifierID = nil
+		} else {
+			v := *dto.VerifierID
+			d.VerifierID = &v
+		}
+		d.Verifier = nil
+		columns = append(columns, "verifier_id")
+	}
+	if dto.CategoryID != nil {
+		if *dto.CategoryID == 0 {
+			d.CategoryID = nil
+		} else {
+			if _, err := s.findCategory(ctx, *dto.CategoryID); err != nil {
+				return nil, err
+			}
+			v := *dto.CategoryID
+			d.CategoryID = &v
+		}
+		d.Category = nil
+		columns = append(columns, "category_id")
+	}
+	if dto.ResponsibleOrgID != nil {
+		if *dto.ResponsibleOrgID == 0 {
+			d.ResponsibleOrgID = nil
+		} else {
+			if err := s.checkOrganization(ctx, *dto.ResponsibleOrgID); err != nil {
+				return nil, err
+			}
+			v := *dto.ResponsibleOrgID
+			d.ResponsibleOrgID = &v
+		}
+		d.ResponsibleOrg = nil
+		columns = append(columns, "responsible_org_id")
+	}
+	if dto.CustomFields != nil {
+		custom, err := s.customFields(ctx, d.ProjectID, d.CustomFields, dto.CustomFields, false)
+		if err != nil {
+			return nil, err
+		}
+		d.CustomFields = custom
+		columns = append(columns, "custom_fields")
+	}
+	var clauses []models.NormativeClause
+	if dto.ClauseIDs != nil {
+		if clauses, err = s.resolveClauses(ctx, *dto.ClauseIDs, d.Clauses); err != nil {
+			return nil, err
+		}
+	}
+	if len(columns) == 0 && dto.ClauseIDs == nil {
+		return d, nil
+	}
+	// a clause change alone still bumps the version
+	if err := s.repo.Update(ctx, d, columns); err != nil {
+		return nil, versionError(err)
+	}
+	if dto.ClauseIDs != nil {
+		if s.normatives != nil {
+			if err := s.normatives.SetDefectClauses(ctx, d.ID, *dto.ClauseIDs); err != nil {
+				return nil, err
+			}
+		}
+		d.Clauses = clauses
 	}
 	return d, nil
 }
@@ -121,6 +486,22 @@ func (s *defectService) ListByProject(ctx context.Context, projectID uint) ([]*m
 	return s.repo.ListByProject(ctx, projectID)
 }
 
+func (s *defectService) ListFiltered(ctx context.Context, q repository.DefectQuery) ([]*models.Defect, error) {
+	return s.repo.ListFiltered(ctx, q)
+}
+
 func (s *defectService) FindByID(ctx context.Context, id uint) (*models.Defect, error) {
 	return s.repo.FindByID(ctx, id)
 }
+
+func (s *defectService) FindByKey(ctx context.Context, key string) (*models.Defect, error) {
+	prefix, num, ok := strings.Cut(strings.TrimSpace(key), "-")
+	if !ok || prefix == "" || num == "" {
+		return nil, ErrNotFound
+	}
+	d, err := s.repo.FindByKey(ctx, strings.ToUpper(prefix)+"-"+num)
+	if err != nil || d == nil {
+		return nil, ErrNotFound
+	}
+	return d, nil
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/stretchr/testify/assert"
 
 	"example.com/defect-control-system/internal/models"
+	"example.com/defect-control-system/internal/repository"
 	"example.com/defect-control-system/internal/service"
 )
 
@@ -20,7 +21,21 @@ func (m *mockDefectRepo) FindByID(ctx context.Context, id uint) (*models.Defect,
 func (m *mockDefectRepo) ListByProject(ctx context.Context, projectID uint) ([]*models.Defect, error) {
 	return []*models.Defect{}, nil
 }
-func (m *mockDefectRepo) Update(ctx context.Context, d *models.Defect) error { return nil }
+func (m *mockDefectRepo) UpdateRanks(ctx context.Context, projectID uint, severities, priorities models.LevelList) error {
+	return nil
+}
+func (m *mockDefectRepo) ListFiltered(ctx context.Context, q repository.DefectQuery) ([]*models.Defect, error) {
+	return []*models.Defect{}, nil
+}
+func (m *mockDefectRepo) Update(ctx context.Context, d *models.Defect, columns []string) error {
+	return nil
+}
+func (m *mockDefectRepo) BulkUpdate(ctx context.Context, defects []*models.Defect, columns []string, addLabels, removeLabels []uint) error {
+	return nil
+}
+func (m *mockDefectRepo) FindByKey(ctx context.Context, key string) (*models.Defect, error) {
+	return nil, errors.New("not found")
+}
 
 type mockProjectRepoNotFound struct{}
 
@@ -33,9 +48,12 @@ func (m *mockProjectRepoNotFound) FindByID(ctx context.Context, id uint) (*model
 func (m *mockProjectRepoNotFound) List(ctx context.Context) ([]*models.Project, error) {
 	return nil, errors.New("not implemented")
 }
-func (m *mockProjectRepoNotFound) Update(ctx context.Context, p *models.Project) error {
+func (m *mockProjectRepoNotFound) Update(ctx context.Context, p *models.Project, columns []string) error {
 	return errors.New("not implemented")
 }
+func (m *mockProjectRepoNotFound) FindByKey(ctx context.Context, key string) (*models.Project, error) {
+	return nil, errors.New("not found")
+}
 
 func TestCreateDefect_ProjectNotFound(t *testing.T) {
 	repo := &mockDefectRepo{}
@@ -67,6 +85,98 @@ func (m *mockProjectRepo) FindByID(ctx context.Context, id uint) (*models.Projec
 func (m *mockProjectRepo) List(ctx context.Context) ([]*models.Project, error) {
 	return []*models.Project{}, nil
 }
-func (m *mockProjectRepo) Update(ctx context.Context, p *models.Project) error { return nil }
+func (m *mockProjectRepo) Update(ctx context.Context, p *models.Project, columns []string) error {
+	return nil
+}
+func (m *mockProjectRepo) FindByKey(ctx context.Context, key string) (*models.Project, error) {
+	return nil, errors.New("not found")
+}
 
 // note: mockUserRepo type is provided in auth_service_test.go
+
+// versionedDefectRepo stores one defect at version 3 and records written columns.
+// The next races writes lose to a concurrent one
+type versionedDefectRepo struct {
+	mockDefectRepo
+	stored  int
+	races   int
+	columns []string
+}
+
+func (m *versionedDefectRepo) FindByID(ctx context.Context, id uint) (*models.Defect, error) {
+	return &models.Defect{ID: id, ProjectID: 1, Title: "x", Version: 3}, nil
+}
+func (m *versionedDefectRepo) Update(ctx context.Context, d *models.Defect, columns []string) error {
+	if d.Version != m.stored {
+		return repository.ErrVersionConflict
+	}
+	if m.races > 0 {
+		m.races--
+		return repository.ErrVersionConflict
+	}
+	m.columns = columns
+	d.Version++
+	return nil
+}
+
+func TestUpdateDefect_Version(t *testing.T) {
+	repo := &versionedDefectRepo{stored: 3}
+	s := service.NewDefectService(repo, &mockProjectRepo{}, &mockUserRepo{})
+	ctx := context.Background()
+	title, status := "y", "in_progress"
+
+	stale := 2
+	_, err := s.Update(ctx, 1, "engineer", 1, service.UpdateDefectDTO{Title: &title, Version: &stale})
+	assert.ErrorIs(t, err, service.ErrPreconditionFailed)
+
+	current := 3
+	d, err := s.Update(ctx, 1, "engineer", 1, service.UpdateDefectDTO{Title: &title, Status: &status, Version: &current})
+	assert.NoError(t, err)
+	assert.Equal(t, 4, d.Version)
+	assert.Equal(t, []string{"title", "status"}, repo.columns)
+
+	// a conditional write that loses a race fails its precondition
+	repo.races = 1
+	_, err = s.Update(ctx, 1, "engineer", 1, service.UpdateDefectDTO{Title: &title, Version: &current})
+	assert.ErrorIs(t, err, service.ErrPreconditionFailed)
+
+	// an unconditional one is applied again to the fresh row
+	repo.races = 1
+	d, err = s.Update(ctx, 1, "engineer", 1, service.UpdateDefectDTO{Title: &title})
+	assert.NoError(t, err)
+	assert.Equal(t, 4, d.Version)
+
+	// the row keeps changing between read and write
+	repo.stored = 4
+	_, err = s.Update(ctx, 1, "engineer", 1, service.UpdateDefectDTO{Title: &title})
+	assert.ErrorIs(t, err, service.ErrConflict)
+	assert.NotErrorIs(t, err, service.ErrPreconditionFailed)
+}
+
+func TestDefects_DesignateVerifier(t *testing.T) {
+	defects := &storedDefectRepo{defect: &models.Defect{ID: 1, ProjectID: 7, Status: "open"}}
+	members := service.NewMembershipService(&mockMemberRepo{}, &mockProjectRepo{}, &mockUserRepo{})
+	s := service.NewDefectService(defects, &mockProjectRepo{}, &mockUserRepo{}, service.WithMembership(members))
+	ctx := context.Background()
+	member, outsider, none := uint(1), uint(2), uint(0)
+
+	// an engineer cannot name a verifier, themselves included
+	_, err := s.Update(ctx, 1, "engineer", 1, service.UpdateDefectDTO{VerifierID: &member})
+	assert.ErrorIs(t, err, service.ErrForbidden)
+	_, err = s.Update(ctx, 1, "engineer", 1, service.UpdateDefectDTO{VerifierID: &none})
+	assert.ErrorIs(t, err, service.ErrForbidden)
+	_, err = s.Create(ctx, service.CreateDefectDTO{ProjectID: 7, Title: "x", VerifierID: &member, UserID: 1, Role: "engineer"})
+	assert.ErrorIs(t, err, service.ErrForbidden)
+
+	// the verifier needs access to the project
+	_, err = s.Update(ctx, 9, "manager", 1, service.UpdateDefectDTO{VerifierID: &outsider})
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, service.ErrForbidden)
+
+	d, err := s.Update(ctx, 9, "manager", 1, service.UpdateDefectDTO{VerifierID: &member})
+	assert.NoError(t, err)
+	assert.Equal(t, member, *d.VerifierID)
+	d, err = s.Create(ctx, service.CreateDefectDTO{ProjectID: 7, Title: "x", VerifierID: &member, UserID: 9, Role: "manager"})
+	assert.NoError(t, err)
+	assert.Equal(t, member, *d.VerifierID)
+}
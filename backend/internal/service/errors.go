@@ -0,0 +1,70 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+
+	"example.com/defect-control-system/internal/repository"
+)
+
+// ErrForbidden is returned when the caller's role does not permit the operation.
+var ErrForbidden = errors.New("forbidden")
+
+// ErrNotFound is returned when the requested entity does not exist or is not visible to the caller.
+var ErrNotFound = errors.New("not found")
+
+// ErrPreconditionFailed is returned when an update carries a version (If-Match)
+// that no longer matches the stored one.
+var ErrPreconditionFailed = errors.New("the entity was modified by someone else, reload and retry")
+
+// ErrConflict is returned when a concurrent request is changing the same
+// entity and the operation cannot proceed meanwhile; retrying later may succeed
+var ErrConflict = errors.New("the entity is being changed concurrently, try again")
+
+// ErrInvalid marks errors caused by the input of a request; handlers answer
+// them with 400 and anything else unexpected with 500
+var ErrInvalid = errors.New("invalid request")
+
+// invalidError is an ErrInvalid carrying its own message
+type invalidError struct{ err error }
+
+func (e *invalidError) Error() string        { return e.err.Error() }
+func (e *invalidError) Unwrap() error        { return e.err }
+func (e *invalidError) Is(target error) bool { return target == ErrInvalid }
+
+// invalid formats a validation error like fmt.Errorf; the result is ErrInvalid
+func invalid(format string, args ...interface{}) error {
+	return &invalidError{fmt.Errorf(format, args...)}
+}
+
+// checkVersion compares the version the caller read, if given, to the current one
+func checkVersion(expected *int, current int) error {
+	if expected != nil && *expected != current {
+		return ErrPreconditionFailed
+	}
+	return nil
+}
+
+// retryUnconditional runs a read-modify-write update. When the caller set no
+// expected version and the write lost a race, the update runs once more on the
+// fresh row; losing again is ErrConflict. ErrPreconditionFailed stays reserved
+// for requests carrying If-Match
+func retryUnconditional[T any](expected *int, update func() (T, error)) (T, error) {
+	v, err := update()
+	if expected != nil || !errors.Is(err, ErrPreconditionFailed) {
+		return v, err
+	}
+	v, err = update()
+	if errors.Is(err, ErrPreconditionFailed) {
+		return v, ErrConflict
+	}
+	return v, err
+}
+
+// versionError turns a repository version conflict into ErrPreconditionFailed
+func versionError(err error) error {
+	if errors.Is(err, repository.ErrVersionConflict) {
+		return ErrPreconditionFailed
+	}
+	return err
+}
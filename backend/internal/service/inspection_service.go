@@ -0,0 +1,447 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"example.com/defect-control-system/internal/models"
+	"example.com/defect-control-system/internal/repository"
+)
+
+const maxChecklistItems = 200
+
+// TemplateItemDTO is one check of a checklist template
+type TemplateItemDTO struct {
+	Text string `json:"text" validate:"required" example:"Гидроизоляция заведена на стены не менее 200 мм"`
+	// Severity, CategoryID and ClauseID are given to the defect raised when the check fails
+	Severity   string `json:"severity,omitempty" example:"major"`
+	CategoryID *uint  `json:"category_id,omitempty"`
+	ClauseID   *uint  `json:"clause_id,omitempty"`
+}
+
+// TemplateDTO creates or replaces a checklist template; items are kept in order
+type TemplateDTO struct {
+	Name        string            `json:"name" validate:"required" example:"Приёмка гидроизоляции"`
+	Description string            `json:"description"`
+	Items       []TemplateItemDTO `json:"items" validate:"required"`
+}
+
+// ScheduleInspectionDTO plans an inspection of a location by a checklist template
+type ScheduleInspectionDTO struct {
+	TemplateID  uint      `json:"template_id" validate:"required"`
+	Title       string    `json:"title"`
+	Location    string    `json:"location" validate:"required" example:"Секция 2, этаж 5, кв. 51"`
+	ScheduledAt time.Time `json:"scheduled_at" validate:"required"`
+	InspectorID *uint     `json:"inspector_id,omitempty"`
+}
+
+// InspectionResultDTO records the outcome of one checklist item
+type InspectionResultDTO struct {
+	ResultID uint   `json:"result_id" validate:"required"`
+	Outcome  string `json:"outcome" validate:"required" example:"fail"`
+	Note     string `json:"note,omitempty" example:"Примыкание не проклеено у стояка"`
+}
+
+// RecordResultsDTO records outcomes; items not listed keep their outcome
+type RecordResultsDTO struct {
+	Results []InspectionResultDTO `json:"results" validate:"required"`
+}
+
+// CompleteInspectionDTO sets fields of the defects raised for failed items
+type CompleteInspectionDTO struct {
+	AssigneeID   uint                   `json:"assignee_id,omitempty"`
+	VerifierID   *uint                  `json:"verifier_id,omitempty"`
+	Priority     string                 `json:"priority,omitempty"`
+	DueDate      *time.Time             `json:"due_date,omitempty"`
+	CustomFields map[string]interface{} `json:"custom_fields,omitempty"`
+}
+
+// InspectionService manages checklist templates and runs inspections: an
+// inspection is scheduled from a template, its items are checked as pass, fail
+// or not applicable, and completing it raises a defect through DefectService
+// for every failed item.
+type InspectionService interface {
+	// CreateTemplate creates a template of the project, or a shared one when projectID is nil
+	CreateTemplate(ctx context.Context, userID uint, role string, projectID *uint, dto TemplateDTO) (*models.InspectionTemplate, error)
+	UpdateTemplate(ctx context.Context, userID uint, role string, id uint, dto TemplateDTO) (*models.InspectionTemplate, error)
+	// ListTemplates returns the shared templates and those of the project
+	ListTemplates(ctx context.Context, userID uint, role string, projectID uint) ([]*models.InspectionTemplate, error)
+
+	Schedule(ctx context.Context, userID uint, role string, projectID uint, dto ScheduleInspectionDTO) (*models.Inspection, error)
+	List(ctx context.Context, userID uint, role string, projectID uint, status string) ([]*models.Inspection, error)
+	Get(ctx context.Context, userID uint, role string, projectID, id uint) (*models.Inspection, error)
+	RecordResults(ctx context.Context, userID uint, role string, projectID, id uint, dto RecordResultsDTO) (*models.Inspection, error)
+	// Complete requires every item checked and raises the defects of failed items.
+	// Defects already raised are kept, so a failed completion can be retried.
+	Complete(ctx context.Context, userID uint, role string, projectID, id uint, dto CompleteInspectionDTO) (*models.Inspection, error)
+	Cancel(ctx context.Context, userID uint, role string, projectID, id uint) (*models.Inspection, error)
+}
+
+type inspectionService struct {
+	repo        repository.InspectionRepository
+	defects     DefectService
+	projectRepo repository.ProjectRepository
+	userRepo    repository.UserRepository
+	members     MembershipService
+}
+
+func NewInspectionService(r repository.InspectionRepository, ds DefectService, pr repository.ProjectRepository, ur repository.UserRepository, m MembershipService) InspectionService {
+	return &inspectionService{repo: r, defects: ds, projectRepo: pr, userRepo: ur, members: m}
+}
+
+// canManage reports whether the user may edit templates of the project, or
+// shared templates when projectID is nil
+func (s *inspectionService) canManage(ctx context.Context, userID uint, role string, projectID *uint) error {
+	if projectID == nil {
+		if _, ok := globalProjectRoles[role]; !ok {
+			return fmt.Errorf("%w: only managers edit shared templates", ErrForbidden)
+		}
+		return nil
+	}
+	ok, err := s.members.CanManageProject(ctx, userID, role, *projectID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrForbidden
+	}
+	return nil
+}
+
+func (s *inspectionService) canAccess(ctx context.Context, userID uint, role string, projectID uint) error {
+	ok, err := s.members.CanAccessProject(ctx, userID, role, projectID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrForbidden
+	}
+	return nil
+}
+
+func templateItems(dto TemplateDTO) ([]models.InspectionTemplateItem, error) {
+	if len(dto.Items) == 0 {
+		return nil, invalid("a checklist needs at least one item")
+	}
+	if len(dto.Items) > maxChecklistItems {
+		return nil, invalid("a checklist has at most %d items", maxChecklistItems)
+	}
+	items := make([]models.InspectionTemplateItem, len(dto.Items))
+	for i, it := range dto.Items {
+		text := strings.TrimSpace(it.Text)
+		if text == "" {
+			return nil, invalid("item %d: text is required", i+1)
+		}
+		items[i] = models.InspectionTemplateItem{
+			Position:   i,
+			Text:       text,
+			Severity:   strings.TrimSpace(it.Severity),
+			CategoryID: it.CategoryID,
+			ClauseID:   it.ClauseID,
+		}
+	}
+	return items, nil
+}
+
+func (s *inspectionService) CreateTemplate(ctx context.Context, userID uint, role string, projectID *uint, dto TemplateDTO) (*models.InspectionTemplate, error) {
+	if err := s.canManage(ctx, userID, role, projectID); err != nil {
+		return nil, err
+	}
+	name := strings.TrimSpace(dto.Name)
+	if name == "" {
+		return nil, invalid("name is required")
+	}
+	items, err := templateItems(dto)
+	if err != nil {
+		return nil, err
+	}
+	t := &models.InspectionTemplate{ProjectID: projectID, Name: name, Description: dto.Description, Items: items}
+	if err := s.repo.CreateTemplate(ctx, t); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func (s *inspectionService) UpdateTemplate(ctx context.Context, userID uint, role string, id uint, dto TemplateDTO) (*models.InspectionTemplate, error) {
+	t, err := s.repo.FindTemplate(ctx, id)
+	if err != nil {
+		return nil, ErrNotFound
+	}
+	if err := s.canManage(ctx, userID, role, t.ProjectID); err != nil {
+		return nil, err
+	}
+	name := strings.TrimSpace(dto.Name)
+	if name == "" {
+		return nil, invalid("name is required")
+	}
+	items, err := templateItems(dto)
+	if err != nil {
+		return nil, err
+	}
+	t.Name, t.Description, t.Items = name, dto.Description, items
+	if err := s.repo.UpdateTemplate(ctx, t); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func (s *inspectionService) ListTemplates(ctx context.Context, userID uint, role string, projectID uint) ([]*models.InspectionTemplate, error) {
+	if err := s.canAccess(ctx, userID, role, projectID); err != nil {
+		return nil, err
+	}
+	return s.repo.ListTemplates(ctx, projectID)
+}
+
+func (s *inspectionService) Schedule(ctx context.Context, userID uint, role string, projectID uint, dto ScheduleInspectionDTO) (*models.Inspection, error) {
+	if err := s.canManage(ctx, userID, role, &projectID); err != nil {
+		return nil, err
+	}
+	t, err := s.repo.FindTemplate(ctx, dto.TemplateID)
+	if err != nil || (t.ProjectID != nil && *t.ProjectID != projectID) {
+		return nil, invalid("template not found")
+	}
+	location := strings.TrimSpace(dto.Location)
+	if location == "" {
+		return nil, invalid("location is required")
+	}
+	if dto.ScheduledAt.IsZero() {
+		return nil, invalid("scheduled_at is required")
+	}
+	if dto.InspectorID != nil {
+		u, err := s.userRepo.FindByID(ctx, *dto.InspectorID)
+		if err != nil || u == nil {
+			return nil, invalid("inspector not found")
+		}
+		ok, err := s.members.CanAccessProject(ctx, u.ID, u.Role, projectID)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, invalid("inspector has no access to the project")
+		}
+	}
+	title := strings.TrimSpace(dto.Title)
+	if title == "" {
+		title = t.Name
+	}
+	results := make([]models.InspectionResult, len(t.Items))
+	for i, it := range t.Items {
+		results[i] = models.InspectionResult{
+			Position:   i,
+			Text:       it.Text,
+			Severity:   it.Severity,
+			CategoryID: it.CategoryID,
+			ClauseID:   it.ClauseID,
+		}
+	}
+	templateID := t.ID
+	in := &models.Inspection{
+		ProjectID:   projectID,
+		TemplateID:  &templateID,
+		Title:       title,
+		Location:    location,
+		ScheduledAt: dto.ScheduledAt,
+		InspectorID: dto.InspectorID,
+		Status:      models.InspectionScheduled,
+		Results:     results,
+		CreatedByID: userID,
+	}
+	if err := s.repo.Create(ctx, in); err != nil {
+		return nil, err
+	}
+	return in, nil
+}
+
+func (s *inspectionService) List(ctx context.Context, userID uint, role string, projectID uint, status string) ([]*models.Inspection, error) {
+	if err := s.canAccess(ctx, userID, role, projectID); err != nil {
+		return nil, err
+	}
+	return s.repo.ListByProject(ctx, projectID, status)
+}
+
+func (s *inspectionService) Get(ctx context.Context, userID uint, role string, projectID, id uint) (*models.Inspection, error) {
+	if err := s.canAccess(ctx, userID, role, projectID); err != nil {
+		return nil, err
+	}
+	in, err := s.repo.FindByID(ctx, id)
+	if err != nil || in == nil || in.ProjectID != projectID {
+		return nil, ErrNotFound
+	}
+	return in, nil
+}
+
+// open loads an inspection the user conducts: its inspector or, without one, a
+// project manager; managers may always step in
+func (s *inspectionService) open(ctx context.Context, userID uint, role string, projectID, id uint) (*models.Inspection, error) {
+	in, err := s.Get(ctx, userID, role, projectID, id)
+	if err != nil {
+		return nil, err
+	}
+	if in.InspectorID == nil || *in.InspectorID != userID {
+		if err := s.canManage(ctx, userID, role, &projectID); err != nil {
+			return nil, fmt.Errorf("%w: only the inspector or project managers conduct the inspection", ErrForbidden)
+		}
+	}
+	if in.Status == models.InspectionCompleted || in.Status == models.InspectionCancelled {
+		return nil, invalid("the inspection is %s", in.Status)
+	}
+	return in, nil
+}
+
+func (s *inspectionService) RecordResults(ctx context.Context, userID uint, role string, projectID, id uint, dto RecordResultsDTO) (*models.Inspection, error) {
+	in, err := s.open(ctx, userID, role, projectID, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(dto.Results) == 0 {
+		return nil, invalid("no results given")
+	}
+	byID := make(map[uint]*models.InspectionResult, len(in.Results))
+	for i := range in.Results {
+		byID[in.Results[i].ID] = &in.Results[i]
+	}
+	changed := make([]*models.InspectionResult, 0, len(dto.Results))
+	for _, r := range dto.Results {
+		res, ok := byID[r.ResultID]
+		if !ok {
+			return nil, invalid("result %d not found in the inspection", r.ResultID)
+		}
+		switch r.Outcome {
+		case models.OutcomePass, models.OutcomeFail, models.OutcomeNA:
+		default:
+			return nil, invalid("result %d: outcome must be pass, fail or na", r.ResultID)
+		}
+		if res.DefectID != nil && r.Outcome != models.OutcomeFail {
+			return nil, invalid("result %d already raised a defect", r.ResultID)
+		}
+		res.Outcome, res.Note = r.Outcome, strings.TrimSpace(r.Note)
+		changed = append(changed, res)
+	}
+	if in.Status == models.InspectionScheduled {
+		now := time.Now()
+		in.Status, in.StartedAt = models.InspectionInProgress, &now
+	}
+	if err := s.repo.SaveResults(ctx, in, changed); err != nil {
+		return nil, err
+	}
+	return in, nil
+}
+
+// completionClaimTTL is how long a completion may hold its claim before
+// another one takes over
+const completionClaimTTL = 5 * time.Minute
+
+func (s *inspectionService) Complete(ctx context.Context, userID uint, role string, projectID, id uint, dto CompleteInspectionDTO) (*models.Inspection, error) {
+	in, err := s.open(ctx, userID, role, projectID, id)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range in.Results {
+		if r.Outcome == "" {
+			return nil, invalid("item %d is not checked", r.Position+1)
+		}
+	}
+	project, err := s.projectRepo.FindByID(ctx, projectID)
+	if err != nil {
+		return nil, ErrNotFound
+	}
+	// one completion at a time raises defects; a crashed one is taken over
+	// once its claim is stale
+	claimed, err := s.repo.ClaimCompletion(ctx, in.ID, time.Now().Add(-completionClaimTTL))
+	if err != nil {
+		return nil, err
+	}
+	if !claimed {
+		if in, err = s.repo.FindByID(ctx, id); err == nil && in.Status == models.InspectionCompleted {
+			return nil, invalid("the inspection is %s", in.Status)
+		}
+		return nil, fmt.Errorf("%w: the inspection is being completed", ErrConflict)
+	}
+	defer s.repo.ReleaseCompletion(context.WithoutCancel(ctx), id)
+	// read again under the claim: an earlier completion may have linked
+	// defects since
+	if in, err = s.repo.FindByID(ctx, id); err != nil {
+		return nil, err
+	}
+	for i := range in.Results {
+		r := &in.Results[i]
+		if r.Outcome != models.OutcomeFail || r.DefectID != nil {
+			continue
+		}
+		d, err := s.defects.Create(ctx, s.defectFor(in, r, project, dto))
+		if err != nil {
+			return nil, fmt.Errorf("item %d: %w", r.Position+1, err)
+		}
+		// link at once so a retry after a later failure does not raise it again
+		if err := s.repo.SetResultDefect(ctx, r.ID, d.ID); err != nil {
+			return nil, err
+		}
+		r.DefectID = &d.ID
+	}
+	now := time.Now()
+	if in.StartedAt == nil {
+		in.StartedAt = &now
+	}
+	in.Status, in.CompletedAt = models.InspectionCompleted, &now
+	if err := s.repo.UpdateStatus(ctx, in); err != nil {
+		return nil, err
+	}
+	return in, nil
+}
+
+// defectFor builds the defect of a failed item, describing where and by which
+// checklist it was found
+func (s *inspectionService) defectFor(in *models.Inspection, r *models.InspectionResult, project *models.Project, dto CompleteInspectionDTO) CreateDefectDTO {
+	var desc strings.Builder
+	fmt.Fprintf(&desc, "Inspection #%d \"%s\", %s\n", in.ID, in.Title, in.ScheduledAt.Format("2006-01-02"))
+	fmt.Fprintf(&desc, "Location: %s\n", in.Location)
+	fmt.Fprintf(&desc, "Checklist item %d: %s", r.Position+1, r.Text)
+	if r.Note != "" {
+		fmt.Fprintf(&desc, "\nNote: %s", r.Note)
+	}
+	title := r.Text
+	if n := []rune(title); len(n) > 200 {
+		title = string(n[:200]) + "…"
+	}
+	severity := r.Severity
+	// a shared template may use a severity the project does not define
+	if _, ok := project.Severities().Find(severity); !ok {
+		severity = ""
+	}
+	out := CreateDefectDTO{
+		ProjectID:    in.ProjectID,
+		Title:        title,
+		Description:  desc.String(),
+		Severity:     severity,
+		AssigneeID:   dto.AssigneeID,
+		VerifierID:   dto.VerifierID,
+		Priority:     dto.Priority,
+		DueDate:      dto.DueDate,
+		CustomFields: dto.CustomFields,
+		CategoryID:   r.CategoryID,
+	}
+	if r.ClauseID != nil {
+		out.ClauseIDs = []uint{*r.ClauseID}
+	}
+	return out
+}
+
+func (s *inspectionService) Cancel(ctx context.Context, userID uint, role string, projectID, id uint) (*models.Inspection, error) {
+	in, err := s.Get(ctx, userID, role, projectID, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.canManage(ctx, userID, role, &projectID); err != nil {
+		return nil, err
+	}
+	if in.Status == models.InspectionCompleted || in.Status == models.InspectionCancelled {
+		return nil, invalid("the inspection is %s", in.Status)
+	}
+	in.Status = models.InspectionCancelled
+	if err := s.repo.UpdateStatus(ctx, in); err != nil {
+		return nil, err
+	}
+	return in, nil
+}
@@ -0,0 +1,214 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"example.com/defect-control-system/internal/models"
+	"example.com/defect-control-system/internal/service"
+)
+
+// createdDefectRepo records created defects and fails on demand
+type createdDefectRepo struct {
+	mockDefectRepo
+	created []*models.Defect
+	failAt  int
+}
+
+func (m *createdDefectRepo) Create(ctx context.Context, d *models.Defect) error {
+	if m.failAt > 0 && len(m.created)+1 == m.failAt {
+		m.failAt = 0
+		return errors.New("db down")
+	}
+	m.created = append(m.created, d)
+	d.ID = uint(100 + len(m.created))
+	return nil
+}
+
+// mockInspectionRepo keeps templates and inspections in memory
+type mockInspectionRepo struct {
+	templates   map[uint]*models.InspectionTemplate
+	inspections map[uint]*models.Inspection
+	claimed     map[uint]bool
+}
+
+func (m *mockInspectionRepo) CreateTemplate(ctx context.Context, t *models.InspectionTemplate) error {
+	if m.templates == nil {
+		m.templates = map[uint]*models.InspectionTemplate{}
+	}
+	t.ID = uint(len(m.templates) + 1)
+	m.templates[t.ID] = t
+	return nil
+}
+func (m *mockInspectionRepo) UpdateTemplate(ctx context.Context, t *models.InspectionTemplate) error {
+	m.templates[t.ID] = t
+	return nil
+}
+func (m *mockInspectionRepo) FindTemplate(ctx context.Context, id uint) (*models.InspectionTemplate, error) {
+	t, ok := m.templates[id]
+	if !ok {
+		return nil, errors.New("not found")
+	}
+	c := *t
+	return &c, nil
+}
+func (m *mockInspectionRepo) ListTemplates(ctx context.Context, projectID uint) ([]*models.InspectionTemplate, error) {
+	var list []*models.InspectionTemplate
+	for _, t := range m.templates {
+		if t.ProjectID == nil || *t.ProjectID == projectID {
+			list = append(list, t)
+		}
+	}
+	return list, nil
+}
+func (m *mockInspectionRepo) Create(ctx context.Context, in *models.Inspection) error {
+	if m.inspections == nil {
+		m.inspections = map[uint]*models.Inspection{}
+	}
+	in.ID = uint(len(m.inspections) + 1)
+	for i := range in.Results {
+		in.Results[i].ID, in.Results[i].InspectionID = uint(10*in.ID)+uint(i), in.ID
+	}
+	m.inspections[in.ID] = in
+	return nil
+}
+func (m *mockInspectionRepo) FindByID(ctx context.Context, id uint) (*models.Inspection, error) {
+	in, ok := m.inspections[id]
+	if !ok {
+		return nil, errors.New("not found")
+	}
+	c := *in
+	c.Results = append([]models.InspectionResult{}, in.Results...)
+	return &c, nil
+}
+func (m *mockInspectionRepo) ListByProject(ctx context.Context, projectID uint, status string) ([]*models.Inspection, error) {
+	return nil, nil
+}
+func (m *mockInspectionRepo) SaveResults(ctx context.Context, in *models.Inspection, results []*models.InspectionResult) error {
+	m.inspections[in.ID] = in
+	return nil
+}
+func (m *mockInspectionRepo) SetResultDefect(ctx context.Context, resultID, defectID uint) error {
+	for _, in := range m.inspections {
+		for i := range in.Results {
+			if in.Results[i].ID == resultID {
+				in.Results[i].DefectID = &defectID
+			}
+		}
+	}
+	return nil
+}
+func (m *mockInspectionRepo) ClaimCompletion(ctx context.Context, id uint, staleBefore time.Time) (bool, error) {
+	if s := m.inspections[id].Status; m.claimed[id] || s == models.InspectionCompleted || s == models.InspectionCancelled {
+		return false, nil
+	}
+	if m.claimed == nil {
+		m.claimed = map[uint]bool{}
+	}
+	m.claimed[id] = true
+	return true, nil
+}
+func (m *mockInspectionRepo) ReleaseCompletion(ctx context.Context, id uint) error {
+	delete(m.claimed, id)
+	return nil
+}
+func (m *mockInspectionRepo) UpdateStatus(ctx context.Context, in *models.Inspection) error {
+	m.inspections[in.ID].Status = in.Status
+	return nil
+}
+
+func TestInspection_FailedItemsRaiseDefects(t *testing.T) {
+	repo := &mockInspectionRepo{}
+	defects := &createdDefectRepo{}
+	members := service.NewMembershipService(&mockMemberRepo{}, &mockProjectRepo{}, &actUserRepo{})
+	ds := service.NewDefectService(defects, &mockProjectRepo{}, &actUserRepo{}, service.WithMembership(members))
+	s := service.NewInspectionService(repo, ds, &mockProjectRepo{}, &actUserRepo{}, members)
+	ctx := context.Background()
+
+	// shared templates are for managers, items need text
+	_, err := s.CreateTemplate(ctx, 1, "engineer", nil, service.TemplateDTO{Name: "x", Items: []service.TemplateItemDTO{{Text: "a"}}})
+	assert.ErrorIs(t, err, service.ErrForbidden)
+	_, err = s.CreateTemplate(ctx, 9, "manager", nil, service.TemplateDTO{Name: "x", Items: []service.TemplateItemDTO{{Text: " "}}})
+	assert.Error(t, err)
+	tpl, err := s.CreateTemplate(ctx, 9, "manager", nil, service.TemplateDTO{Name: "Waterproofing", Items: []service.TemplateItemDTO{
+		{Text: "Membrane overlaps at least 100 mm", Severity: "critical"},
+		{Text: "Upturn on walls at least 200 mm", Severity: "no-such-level"},
+		{Text: "Drain flanges sealed"},
+	}})
+	require.NoError(t, err)
+
+	inspector := uint(1)
+	when := time.Date(2025, 10, 21, 9, 0, 0, 0, time.UTC)
+	_, err = s.Schedule(ctx, 1, "engineer", 7, service.ScheduleInspectionDTO{TemplateID: tpl.ID, Location: "Roof", ScheduledAt: when})
+	assert.ErrorIs(t, err, service.ErrForbidden)
+	_, err = s.Schedule(ctx, 9, "manager", 7, service.ScheduleInspectionDTO{TemplateID: tpl.ID, Location: "Roof", ScheduledAt: when, InspectorID: new(uint)})
+	assert.Error(t, err, "unknown inspector")
+	in, err := s.Schedule(ctx, 9, "manager", 7, service.ScheduleInspectionDTO{TemplateID: tpl.ID, Location: "Section 2, roof", ScheduledAt: when, InspectorID: &inspector})
+	require.NoError(t, err)
+	assert.Equal(t, models.InspectionScheduled, in.Status)
+	require.Len(t, in.Results, 3)
+
+	// template edits do not touch the scheduled checklist
+	_, err = s.UpdateTemplate(ctx, 9, "manager", tpl.ID, service.TemplateDTO{Name: "Waterproofing v2", Items: []service.TemplateItemDTO{{Text: "Only item"}}})
+	require.NoError(t, err)
+	in, err = s.Get(ctx, 1, "engineer", 7, in.ID)
+	require.NoError(t, err)
+	assert.Len(t, in.Results, 3)
+
+	r := in.Results
+	_, err = s.RecordResults(ctx, 2, "admin", 8, in.ID, service.RecordResultsDTO{Results: []service.InspectionResultDTO{{ResultID: r[0].ID, Outcome: "pass"}}})
+	assert.ErrorIs(t, err, service.ErrNotFound, "inspection of another project")
+	_, err = s.RecordResults(ctx, 1, "engineer", 7, in.ID, service.RecordResultsDTO{Results: []service.InspectionResultDTO{{ResultID: r[0].ID, Outcome: "ok"}}})
+	assert.Error(t, err)
+	in, err = s.RecordResults(ctx, 1, "engineer", 7, in.ID, service.RecordResultsDTO{Results: []service.InspectionResultDTO{
+		{ResultID: r[0].ID, Outcome: "fail", Note: "Overlap 60 mm near the parapet"},
+		{ResultID: r[1].ID, Outcome: "fail"},
+	}})
+	require.NoError(t, err)
+	assert.Equal(t, models.InspectionInProgress, in.Status)
+	assert.NotNil(t, in.StartedAt)
+
+	_, err = s.Complete(ctx, 1, "engineer", 7, in.ID, service.CompleteInspectionDTO{})
+	assert.Error(t, err, "item 3 is not checked")
+	_, err = s.RecordResults(ctx, 1, "engineer", 7, in.ID, service.RecordResultsDTO{Results: []service.InspectionResultDTO{{ResultID: r[2].ID, Outcome: "na"}}})
+	require.NoError(t, err)
+
+	// a completion running meanwhile, e.g. a double tap, raises the defects alone
+	claimed, err := repo.ClaimCompletion(ctx, in.ID, time.Now())
+	require.NoError(t, err)
+	require.True(t, claimed)
+	_, err = s.Complete(ctx, 1, "engineer", 7, in.ID, service.CompleteInspectionDTO{})
+	assert.ErrorIs(t, err, service.ErrConflict)
+	assert.Empty(t, defects.created)
+	require.NoError(t, repo.ReleaseCompletion(ctx, in.ID))
+
+	// the second defect fails to save; retrying does not duplicate the first
+	defects.failAt = 2
+	_, err = s.Complete(ctx, 1, "engineer", 7, in.ID, service.CompleteInspectionDTO{})
+	assert.Error(t, err)
+	assert.Len(t, defects.created, 1)
+	in, err = s.Complete(ctx, 1, "engineer", 7, in.ID, service.CompleteInspectionDTO{})
+	require.NoError(t, err)
+	assert.Equal(t, models.InspectionCompleted, in.Status)
+	require.Len(t, defects.created, 2)
+
+	first := defects.created[0]
+	assert.Equal(t, "Membrane overlaps at least 100 mm", first.Title)
+	assert.Equal(t, "critical", first.Severity)
+	assert.Contains(t, first.Description, "Section 2, roof")
+	assert.Contains(t, first.Description, "Overlap 60 mm near the parapet")
+	assert.Equal(t, "minor", defects.created[1].Severity, "unknown severity falls back to the project default")
+	assert.Equal(t, uint(101), *in.Results[0].DefectID)
+	assert.Nil(t, in.Results[2].DefectID)
+
+	_, err = s.RecordResults(ctx, 1, "engineer", 7, in.ID, service.RecordResultsDTO{Results: []service.InspectionResultDTO{{ResultID: r[2].ID, Outcome: "pass"}}})
+	assert.Error(t, err, "completed inspections are closed")
+	_, err = s.Complete(ctx, 1, "engineer", 7, in.ID, service.CompleteInspectionDTO{})
+	assert.Error(t, err)
+	assert.Len(t, defects.created, 2, "completing again raises nothing")
+}
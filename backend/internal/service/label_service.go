@@ -0,0 +1,193 @@
+package service
+
+import (
+	"context"
+	"strings"
+
+	"example.com/defect-control-system/internal/models"
+	"example.com/defect-control-system/internal/repository"
+)
+
+type LabelDTO struct {
+	Name  string `json:"name" validate:"required"`
+	Color string `json:"color" validate:"omitempty,hexcolor"`
+}
+
+type UpdateLabelDTO struct {
+	Name  *string `json:"name"`
+	Color *string `json:"color"`
+}
+
+// BulkLabelsDTO adds and removes labels on a list of defects of one project
+type BulkLabelsDTO struct {
+	DefectIDs []uint `json:"defect_ids" validate:"required"`
+	Add       []uint `json:"add"`
+	Remove    []uint `json:"remove"`
+}
+
+const defaultLabelColor = "#6b7280"
+
+// LabelService manages project labels. Label definitions are changed by project
+// admins; any project member with a contractor role can put labels on defects.
+type LabelService interface {
+	List(ctx context.Context, projectID uint) ([]*models.Label, error)
+	Create(ctx context.Context, userID uint, role string, projectID uint, dto LabelDTO) (*models.Label, error)
+	Update(ctx context.Context, userID uint, role string, projectID, id uint, dto UpdateLabelDTO) (*models.Label, error)
+	Delete(ctx context.Context, userID uint, role string, projectID, id uint) error
+	// Bulk applies the label changes to all defects and returns the number of defects touched
+	Bulk(ctx context.Context, userID uint, role string, projectID uint, dto BulkLabelsDTO) (int, error)
+}
+
+type labelService struct {
+	repo       repository.LabelRepository
+	defectRepo repository.DefectRepository
+	members    MembershipService
+}
+
+func NewLabelService(r repository.LabelRepository, dr repository.DefectRepository, m MembershipService) LabelService {
+	return &labelService{repo: r, defectRepo: dr, members: m}
+}
+
+func (s *labelService) List(ctx context.Context, projectID uint) ([]*models.Label, error) {
+	return s.repo.ListByProject(ctx, projectID)
+}
+
+func (s *labelService) authorize(ctx context.Context, userID uint, role string, projectID uint) error {
+	ok, err := s.members.CanManageProject(ctx, userID, role, projectID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrForbidden
+	}
+	return nil
+}
+
+func normalizeLabel(name, color string) (string, string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", "", invalid("name is required")
+	}
+	if color == "" {
+		color = defaultLabelColor
+	}
+	if !models.LabelColorPattern.MatchString(color) {
+		return "", "", invalid("color must be #RRGGBB")
+	}
+	return name, strings.ToLower(color), nil
+}
+
+func (s *labelService) Create(ctx context.Context, userID uint, role string, projectID uint, dto LabelDTO) (*models.Label, error) {
+	if err := s.authorize(ctx, userID, role, projectID); err != nil {
+		return nil, err
+	}
+	name, color, err := normalizeLabel(dto.Name, dto.Color)
+	if err != nil {
+		return nil, err
+	}
+	l := &models.Label{ProjectID: projectID, Name: name, Color: color}
+	if err := s.repo.Create(ctx, l); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+func (s *labelService) Update(ctx context.Context, userID uint, role string, projectID, id uint, dto UpdateLabelDTO) (*models.Label, error) {
+	if err := s.authorize(ctx, userID, role, projectID); err != nil {
+		return nil, err
+	}
+	l, err := s.repo.FindByID(ctx, id)
+	if err != nil || l.ProjectID != projectID {
+		return nil, ErrNotFound
+	}
+	name, color := l.Name, l.Color
+	if dto.Name != nil {
+		name = *dto.Name
+	}
+	if dto.Color != nil {
+		color = *dto.Color
+	}
+	if l.Name, l.Color, err = normalizeLabel(name, color); err != nil {
+		return nil, err
+	}
+	if err := s.repo.Update(ctx, l); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+func (s *labelService) Delete(ctx context.Context, userID uint, role string, projectID, id uint) error {
+	if err := s.authorize(ctx, userID, role, projectID); err != nil {
+		return err
+	}
+	l, err := s.repo.FindByID(ctx, id)
+	if err != nil || l.ProjectID != projectID {
+		return ErrNotFound
+	}
+	return s.repo.Delete(ctx, id)
+}
+
+func (s *labelService) Bulk(ctx context.Context, userID uint, role string, projectID uint, dto BulkLabelsDTO) (int, error) {
+	if len(dto.DefectIDs) == 0 {
+		return 0, invalid("defect_ids is required")
+	}
+	if len(dto.Add) == 0 && len(dto.Remove) == 0 {
+		return 0, invalid("nothing to add or remove")
+	}
+	ok, err := s.members.CanAccessProject(ctx, userID, role, projectID)
+	if err != nil {
+		return 0, err
+	}
+	if !ok {
+		return 0, ErrForbidden
+	}
+	if err := checkProjectLabels(ctx, s.repo, projectID, append(append([]uint{}, dto.Add...), dto.Remove...)); err != nil {
+		return 0, err
+	}
+	// keep only defects of this project so labels never cross project boundaries
+	ids := uniqueUints(dto.DefectIDs)
+	defects, err := s.defectRepo.ListFiltered(ctx, repository.DefectQuery{ProjectIDs: []uint{projectID}, Filter: models.DefectFilter{IDs: ids}})
+	if err != nil {
+		return 0, err
+	}
+	if len(defects) != len(ids) {
+		return 0, invalid("some defects do not belong to the project")
+	}
+	if err := s.repo.AddToDefects(ctx, ids, dto.Add); err != nil {
+		return 0, err
+	}
+	if err := s.repo.RemoveFromDefects(ctx, ids, dto.Remove); err != nil {
+		return 0, err
+	}
+	return len(defects), nil
+}
+
+// checkProjectLabels verifies that all label ids belong to the project
+func checkProjectLabels(ctx context.Context, repo repository.LabelRepository, projectID uint, ids []uint) error {
+	labels, err := repo.ListByProject(ctx, projectID)
+	if err != nil {
+		return err
+	}
+	known := make(map[uint]struct{}, len(labels))
+	for _, l := range labels {
+		known[l.ID] = struct{}{}
+	}
+	for _, id := range ids {
+		if _, ok := known[id]; !ok {
+			return invalid("label %d not found in project", id)
+		}
+	}
+	return nil
+}
+
+func uniqueUints(ids []uint) []uint {
+	seen := make(map[uint]struct{}, len(ids))
+	out := make([]uint, 0, len(ids))
+	for _, id := range ids {
+		if _, ok := seen[id]; !ok {
+			seen[id] = struct{}{}
+			out = append(out, id)
+		}
+	}
+	return out
+}
@@ -0,0 +1,97 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"example.com/defect-control-system/internal/models"
+	"example.com/defect-control-system/internal/repository"
+	"example.com/defect-control-system/internal/service"
+)
+
+type mockLabelRepo struct {
+	labels []*models.Label
+	added  []uint
+}
+
+func (m *mockLabelRepo) Create(ctx context.Context, l *models.Label) error { return nil }
+func (m *mockLabelRepo) FindByID(ctx context.Context, id uint) (*models.Label, error) {
+	for _, l := range m.labels {
+		if l.ID == id {
+			return l, nil
+		}
+	}
+	return nil, assert.AnError
+}
+func (m *mockLabelRepo) ListByProject(ctx context.Context, projectID uint) ([]*models.Label, error) {
+	var out []*models.Label
+	for _, l := range m.labels {
+		if l.ProjectID == projectID {
+			out = append(out, l)
+		}
+	}
+	return out, nil
+}
+func (m *mockLabelRepo) Update(ctx context.Context, l *models.Label) error { return nil }
+func (m *mockLabelRepo) Delete(ctx context.Context, id uint) error         { return nil }
+func (m *mockLabelRepo) AddToDefects(ctx context.Context, defectIDs, labelIDs []uint) error {
+	m.added = append(m.added, defectIDs...)
+	return nil
+}
+func (m *mockLabelRepo) RemoveFromDefects(ctx context.Context, defectIDs, labelIDs []uint) error {
+	return nil
+}
+
+// projectDefectRepo knows defects 1 and 2 of project 7
+type projectDefectRepo struct{ mockDefectRepo }
+
+func (m *projectDefectRepo) ListFiltered(ctx context.Context, q repository.DefectQuery) ([]*models.Defect, error) {
+	var out []*models.Defect
+	for _, id := range q.Filter.IDs {
+		if (id == 1 || id == 2) && len(q.ProjectIDs) == 1 && q.ProjectIDs[0] == 7 {
+			out = append(out, &models.Defect{ID: id, ProjectID: 7})
+		}
+	}
+	return out, nil
+}
+
+func TestLabels_CreateRequiresProjectAdmin(t *testing.T) {
+	members := service.NewMembershipService(&mockMemberRepo{}, &mockProjectRepo{}, &mockUserRepo{})
+	s := service.NewLabelService(&mockLabelRepo{}, &projectDefectRepo{}, members)
+	ctx := context.Background()
+
+	_, err := s.Create(ctx, 1, "engineer", 7, service.LabelDTO{Name: "roof"})
+	assert.ErrorIs(t, err, service.ErrForbidden)
+
+	l, err := s.Create(ctx, 9, "manager", 7, service.LabelDTO{Name: " roof ", Color: "#2563EB"})
+	assert.NoError(t, err)
+	assert.Equal(t, "roof", l.Name)
+	assert.Equal(t, "#2563eb", l.Color)
+
+	_, err = s.Create(ctx, 9, "manager", 7, service.LabelDTO{Name: "roof", Color: "blue"})
+	assert.Error(t, err)
+}
+
+func TestLabels_BulkStaysInProject(t *testing.T) {
+	repo := &mockLabelRepo{labels: []*models.Label{{ID: 1, ProjectID: 7, Name: "roof"}, {ID: 2, ProjectID: 8, Name: "other"}}}
+	members := service.NewMembershipService(&mockMemberRepo{}, &mockProjectRepo{}, &mockUserRepo{})
+	s := service.NewLabelService(repo, &projectDefectRepo{}, members)
+	ctx := context.Background()
+
+	// label of another project
+	_, err := s.Bulk(ctx, 1, "engineer", 7, service.BulkLabelsDTO{DefectIDs: []uint{1}, Add: []uint{2}})
+	assert.Error(t, err)
+	// defect of another project
+	_, err = s.Bulk(ctx, 1, "engineer", 7, service.BulkLabelsDTO{DefectIDs: []uint{1, 3}, Add: []uint{1}})
+	assert.Error(t, err)
+	// not a member
+	_, err = s.Bulk(ctx, 2, "engineer", 7, service.BulkLabelsDTO{DefectIDs: []uint{1}, Add: []uint{1}})
+	assert.ErrorIs(t, err, service.ErrForbidden)
+
+	n, err := s.Bulk(ctx, 1, "engineer", 7, service.BulkLabelsDTO{DefectIDs: []uint{1, 2, 2}, Add: []uint{1}})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, n)
+	assert.NotEmpty(t, repo.added)
+}
@@ -0,0 +1,109 @@
+package service
+
+import (
+	"context"
+
+	"example.com/defect-control-system/internal/models"
+	"example.com/defect-control-system/internal/repository"
+)
+
+type AddMemberDTO struct {
+	UserID uint   `json:"user_id" validate:"required"`
+	Role   string `json:"role" validate:"omitempty,oneof=member admin"`
+}
+
+// globalProjectRoles may read every project without being a member of it
+var globalProjectRoles = map[string]struct{}{"manager": {}, "admin": {}}
+
+// MembershipService manages project members and answers project access questions.
+type MembershipService interface {
+	AddMember(ctx context.Context, projectID uint, dto AddMemberDTO) (*models.ProjectMember, error)
+	RemoveMember(ctx context.Context, projectID, userID uint) error
+	// ListMembers lists the team of a project the user can access
+	ListMembers(ctx context.Context, userID uint, role string, projectID uint) ([]*models.ProjectMember, error)
+	// AccessibleProjectIDs returns ids of projects the user can read. all is true
+	// for global roles (manager, admin) that can read every project.
+	AccessibleProjectIDs(ctx context.Context, userID uint, role string) (ids []uint, all bool, err error)
+	CanAccessProject(ctx context.Context, userID uint, role string, projectID uint) (bool, error)
+	// CanManageProject reports whether the user administers the project: global
+	// managers and admins, or members with the project role "admin"
+	CanManageProject(ctx context.Context, userID uint, role string, projectID uint) (bool, error)
+}
+
+type membershipService struct {
+	repo        repository.ProjectMemberRepository
+	projectRepo repository.ProjectRepository
+	userRepo    repository.UserRepository
+}
+
+func NewMembershipService(r repository.ProjectMemberRepository, pr repository.ProjectRepository, ur repository.UserRepository) MembershipService {
+	return &membershipService{repo: r, projectRepo: pr, userRepo: ur}
+}
+
+func (s *membershipService) AddMember(ctx context.Context, projectID uint, dto AddMemberDTO) (*models.ProjectMember, error) {
+	role := dto.Role
+	if role == "" {
+		role = models.ProjectRoleMember
+	}
+	if role != models.ProjectRoleMember && role != models.ProjectRoleAdmin {
+		return nil, invalid("invalid project role")
+	}
+	if _, err := s.projectRepo.FindByID(ctx, projectID); err != nil {
+		return nil, invalid("project not found")
+	}
+	if _, err := s.userRepo.FindByID(ctx, dto.UserID); err != nil {
+		return nil, invalid("user not found")
+	}
+	m := &models.ProjectMember{ProjectID: projectID, UserID: dto.UserID, Role: role}
+	if err := s.repo.Add(ctx, m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (s *membershipService) RemoveMember(ctx context.Context, projectID, userID uint) error {
+	return s.repo.Remove(ctx, projectID, userID)
+}
+
+func (s *membershipService) ListMembers(ctx context.Context, userID uint, role string, projectID uint) ([]*models.ProjectMember, error) {
+	ok, err := s.CanAccessProject(ctx, userID, role, projectID)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrForbidden
+	}
+	return s.repo.ListByProject(ctx, projectID)
+}
+
+func (s *membershipService) AccessibleProjectIDs(ctx context.Context, userID uint, role string) ([]uint, bool, error) {
+	if _, ok := globalProjectRoles[role]; ok {
+		return nil, true, nil
+	}
+	ids, err := s.repo.ProjectIDsByUser(ctx, userID)
+	if err != nil {
+		return nil, false, err
+	}
+	return ids, false, nil
+}
+
+func (s *membershipService) CanAccessProject(ctx context.Context, userID uint, role string, projectID uint) (bool, error) {
+	if _, ok := globalProjectRoles[role]; ok {
+		return true, nil
+	}
+	if _, err := s.repo.Find(ctx, projectID, userID); err != nil {
+		return false, nil
+	}
+	return true, nil
+}
+
+func (s *membershipService) CanManageProject(ctx context.Context, userID uint, role string, projectID uint) (bool, error) {
+	if _, ok := globalProjectRoles[role]; ok {
+		return true, nil
+	}
+	m, err := s.repo.Find(ctx, projectID, userID)
+	if err != nil {
+		return false, nil
+	}
+	return m.Role == models.ProjectRoleAdmin, nil
+}
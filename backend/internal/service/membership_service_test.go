@@ -0,0 +1,24 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"example.com/defect-control-system/internal/service"
+)
+
+func TestMembership_ListMembersRequiresAccess(t *testing.T) {
+	s := service.NewMembershipService(&mockMemberRepo{}, &mockProjectRepo{}, &mockUserRepo{})
+	ctx := context.Background()
+
+	_, err := s.ListMembers(ctx, 2, "engineer", 7)
+	assert.ErrorIs(t, err, service.ErrForbidden, "not a member of the project")
+	_, err = s.ListMembers(ctx, 1, "engineer", 8)
+	assert.ErrorIs(t, err, service.ErrForbidden, "a member of another project")
+	_, err = s.ListMembers(ctx, 1, "engineer", 7)
+	assert.NoError(t, err)
+	_, err = s.ListMembers(ctx, 2, "manager", 7)
+	assert.NoError(t, err, "managers read every project")
+}
@@ -0,0 +1,214 @@
+package service
+
+import (
+	"context"
+	"strings"
+
+	"example.com/defect-control-system/internal/models"
+	"example.com/defect-control-system/internal/repository"
+)
+
+type NormativeDocumentDTO struct {
+	Code  string `json:"code" validate:"required"`
+	Title string `json:"title" validate:"required"`
+}
+
+type UpdateNormativeDocumentDTO struct {
+	Code     *string `json:"code"`
+	Title    *string `json:"title"`
+	Obsolete *bool   `json:"obsolete"`
+}
+
+type NormativeClauseDTO struct {
+	Number string `json:"number" validate:"required"`
+	Text   string `json:"text"`
+}
+
+type UpdateNormativeClauseDTO struct {
+	Number *string `json:"number"`
+	Text   *string `json:"text"`
+}
+
+type ClauseSearchDTO struct {
+	Query           string
+	DocumentID      *uint
+	IncludeObsolete bool
+	Limit           int
+	Offset          int
+}
+
+// NormativeService manages the catalog of building codes and their clauses
+// that defects cite as violated requirements.
+type NormativeService interface {
+	ListDocuments(ctx context.Context, includeObsolete bool) ([]*models.NormativeDocument, error)
+	GetDocument(ctx context.Context, id uint) (*models.NormativeDocument, error)
+	CreateDocument(ctx context.Context, dto NormativeDocumentDTO) (*models.NormativeDocument, error)
+	UpdateDocument(ctx context.Context, id uint, dto UpdateNormativeDocumentDTO) (*models.NormativeDocument, error)
+	// DeleteDocument removes the document with its clauses and their citations;
+	// mark it obsolete instead to keep existing citations
+	DeleteDocument(ctx context.Context, id uint) error
+	CreateClause(ctx context.Context, documentID uint, dto NormativeClauseDTO) (*models.NormativeClause, error)
+	UpdateClause(ctx context.Context, id uint, dto UpdateNormativeClauseDTO) (*models.NormativeClause, error)
+	DeleteClause(ctx context.Context, id uint) error
+	SearchClauses(ctx context.Context, dto ClauseSearchDTO) ([]*models.NormativeClause, error)
+}
+
+type normativeService struct {
+	repo repository.NormativeRepository
+}
+
+func NewNormativeService(r repository.NormativeRepository) NormativeService {
+	return &normativeService{repo: r}
+}
+
+func (s *normativeService) ListDocuments(ctx context.Context, includeObsolete bool) ([]*models.NormativeDocument, error) {
+	return s.repo.ListDocuments(ctx, includeObsolete)
+}
+
+func (s *normativeService) GetDocument(ctx context.Context, id uint) (*models.NormativeDocument, error) {
+	d, err := s.repo.FindDocument(ctx, id)
+	if err != nil {
+		return nil, ErrNotFound
+	}
+	return d, nil
+}
+
+func (s *normativeService) CreateDocument(ctx context.Context, dto NormativeDocumentDTO) (*models.NormativeDocument, error) {
+	code, title := strings.TrimSpace(dto.Code), strings.TrimSpace(dto.Title)
+	if code == "" || title == "" {
+		return nil, invalid("code and title are required")
+	}
+	if _, err := s.repo.FindDocumentByCode(ctx, code); err == nil {
+		return nil, invalid("document %s already exists", code)
+	}
+	d := &models.NormativeDocument{Code: code, Title: title}
+	if err := s.repo.CreateDocument(ctx, d); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func (s *normativeService) UpdateDocument(ctx context.Context, id uint, dto UpdateNormativeDocumentDTO) (*models.NormativeDocument, error) {
+	d, err := s.repo.FindDocument(ctx, id)
+	if err != nil {
+		return nil, ErrNotFound
+	}
+	if dto.Code != nil {
+		code := strings.TrimSpace(*dto.Code)
+		if code == "" {
+			return nil, invalid("code is required")
+		}
+		if other, err := s.repo.FindDocumentByCode(ctx, code); err == nil && other.ID != d.ID {
+			return nil, invalid("document %s already exists", code)
+		}
+		d.Code = code
+	}
+	if dto.Title != nil {
+		title := strings.TrimSpace(*dto.Title)
+		if title == "" {
+			return nil, invalid("title is required")
+		}
+		d.Title = title
+	}
+	if dto.Obsolete != nil {
+		d.Obsolete = *dto.Obsolete
+	}
+	if err := s.repo.UpdateDocument(ctx, d); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func (s *normativeService) DeleteDocument(ctx context.Context, id uint) error {
+	if _, err := s.repo.FindDocument(ctx, id); err != nil {
+		return ErrNotFound
+	}
+	return s.repo.DeleteDocument(ctx, id)
+}
+
+// checkClauseNumber rejects a number already used in the document
+func checkClauseNumber(doc *models.NormativeDocument, number string, exceptID uint) error {
+	for _, c := range doc.Clauses {
+		if c.Number == number && c.ID != exceptID {
+			return invalid("clause %s already exists in %s", number, doc.Code)
+		}
+	}
+	return nil
+}
+
+func (s *normativeService) CreateClause(ctx context.Context, documentID uint, dto NormativeClauseDTO) (*models.NormativeClause, error) {
+	doc, err := s.repo.FindDocument(ctx, documentID)
+	if err != nil {
+		return nil, ErrNotFound
+	}
+	number := strings.TrimSpace(dto.Number)
+	if number == "" {
+		return nil, invalid("number is required")
+	}
+	if err := checkClauseNumber(doc, number, 0); err != nil {
+		return nil, err
+	}
+	c := &models.NormativeClause{DocumentID: documentID, Number: number, Text: strings.TrimSpace(dto.Text)}
+	if err := s.repo.CreateClause(ctx, c); err != nil {
+		return nil, err
+	}
+	doc.Clauses = nil
+	c.Document = doc
+	return c, nil
+}
+
+func (s *normativeService) UpdateClause(ctx context.Context, id uint, dto UpdateNormativeClauseDTO) (*models.NormativeClause, error) {
+	c, err := s.repo.FindClause(ctx, id)
+	if err != nil {
+		return nil, ErrNotFound
+	}
+	if dto.Number != nil {
+		number := strings.TrimSpace(*dto.Number)
+		if number == "" {
+			return nil, invalid("number is required")
+		}
+		doc, err := s.repo.FindDocument(ctx, c.DocumentID)
+		if err != nil {
+			return nil, err
+		}
+		if err := checkClauseNumber(doc, number, c.ID); err != nil {
+			return nil, err
+		}
+		c.Number = number
+	}
+	if dto.Text != nil {
+		c.Text = strings.TrimSpace(*dto.Text)
+	}
+	if err := s.repo.UpdateClause(ctx, c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (s *normativeService) DeleteClause(ctx context.Context, id uint) error {
+	if _, err := s.repo.FindClause(ctx, id); err != nil {
+		return ErrNotFound
+	}
+	return s.repo.DeleteClause(ctx, id)
+}
+
+func (s *normativeService) SearchClauses(ctx context.Context, dto ClauseSearchDTO) ([]*models.NormativeClause, error) {
+	limit := dto.Limit
+	if limit <= 0 {
+		limit = 20
+	}
+	if limit > 100 {
+		limit = 100
+	}
+	offset := dto.Offset
+	if offset < 0 {
+		offset = 0
+	}
+	return s.repo.SearchClauses(ctx, repository.ClauseSearchParams{
+		Query:           strings.TrimSpace(dto.Query),
+		DocumentID:      dto.DocumentID,
+		IncludeObsolete: dto.IncludeObsolete,
+		Limit:           limit,
+		Offset:          offset,
+	})
+}
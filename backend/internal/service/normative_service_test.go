@@ -0,0 +1,124 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"example.com/defect-control-system/internal/models"
+	"example.com/defect-control-system/internal/repository"
+	"example.com/defect-control-system/internal/service"
+)
+
+type mockNormativeRepo struct {
+	docs    []*models.NormativeDocument
+	clauses []*models.NormativeClause
+	linked  map[uint][]uint
+}
+
+func (m *mockNormativeRepo) CreateDocument(ctx context.Context, d *models.NormativeDocument) error {
+	return nil
+}
+func (m *mockNormativeRepo) FindDocument(ctx context.Context, id uint) (*models.NormativeDocument, error) {
+	for _, d := range m.docs {
+		if d.ID == id {
+			doc := *d
+			for _, c := range m.clauses {
+				if c.DocumentID == id {
+					doc.Clauses = append(doc.Clauses, *c)
+				}
+			}
+			return &doc, nil
+		}
+	}
+	return nil, assert.AnError
+}
+func (m *mockNormativeRepo) FindDocumentByCode(ctx context.Context, code string) (*models.NormativeDocument, error) {
+	return nil, assert.AnError
+}
+func (m *mockNormativeRepo) ListDocuments(ctx context.Context, includeObsolete bool) ([]*models.NormativeDocument, error) {
+	return m.docs, nil
+}
+func (m *mockNormativeRepo) UpdateDocument(ctx context.Context, d *models.NormativeDocument) error {
+	return nil
+}
+func (m *mockNormativeRepo) DeleteDocument(ctx context.Context, id uint) error { return nil }
+func (m *mockNormativeRepo) CreateClause(ctx context.Context, c *models.NormativeClause) error {
+	c.ID = uint(len(m.clauses) + 1)
+	m.clauses = append(m.clauses, c)
+	return nil
+}
+func (m *mockNormativeRepo) FindClause(ctx context.Context, id uint) (*models.NormativeClause, error) {
+	return nil, assert.AnError
+}
+func (m *mockNormativeRepo) FindClauses(ctx context.Context, ids []uint) ([]*models.NormativeClause, error) {
+	var out []*models.NormativeClause
+	for _, c := range m.clauses {
+		for _, id := range ids {
+			if c.ID == id {
+				cl := *c
+				cl.Document, _ = m.FindDocument(ctx, c.DocumentID)
+				out = append(out, &cl)
+				break
+			}
+		}
+	}
+	return out, nil
+}
+func (m *mockNormativeRepo) UpdateClause(ctx context.Context, c *models.NormativeClause) error {
+	return nil
+}
+func (m *mockNormativeRepo) DeleteClause(ctx context.Context, id uint) error { return nil }
+func (m *mockNormativeRepo) SearchClauses(ctx context.Context, p repository.ClauseSearchParams) ([]*models.NormativeClause, error) {
+	return nil, nil
+}
+func (m *mockNormativeRepo) SetDefectClauses(ctx context.Context, defectID uint, clauseIDs []uint) error {
+	m.linked[defectID] = clauseIDs
+	return nil
+}
+
+func newNormativeRepo() *mockNormativeRepo {
+	return &mockNormativeRepo{
+		docs: []*models.NormativeDocument{
+			{ID: 1, Code: "СП 70.13330.2012", Title: "Несущие и ограждающие конструкции"},
+			{ID: 2, Code: "СНиП 3.03.01-87", Title: "Несущие и ограждающие конструкции", Obsolete: true},
+		},
+		clauses: []*models.NormativeClause{
+			{ID: 1, DocumentID: 1, Number: "5.3.1"},
+			{ID: 2, DocumentID: 2, Number: "2.110"},
+		},
+		linked: map[uint][]uint{},
+	}
+}
+
+func TestNormatives_ClauseNumbersUnique(t *testing.T) {
+	s := service.NewNormativeService(newNormativeRepo())
+	ctx := context.Background()
+
+	_, err := s.CreateClause(ctx, 1, service.NormativeClauseDTO{Number: "5.3.1"})
+	assert.Error(t, err)
+	c, err := s.CreateClause(ctx, 1, service.NormativeClauseDTO{Number: " 5.3.2 ", Text: "Допуск"})
+	assert.NoError(t, err)
+	assert.Equal(t, "СП 70.13330.2012, п. 5.3.2", c.Citation())
+	_, err = s.CreateClause(ctx, 9, service.NormativeClauseDTO{Number: "1"})
+	assert.ErrorIs(t, err, service.ErrNotFound)
+}
+
+func TestCreateDefect_Citations(t *testing.T) {
+	repo := newNormativeRepo()
+	s := service.NewDefectService(&mockDefectRepo{}, &mockProjectRepo{}, &mockUserRepo{}, service.WithNormatives(repo))
+	ctx := context.Background()
+
+	d, err := s.Create(ctx, service.CreateDefectDTO{ProjectID: 1, Title: "x", ClauseIDs: []uint{1}})
+	assert.NoError(t, err)
+	assert.Len(t, d.Clauses, 1)
+	assert.Equal(t, "СП 70.13330.2012, п. 5.3.1", d.Clauses[0].Citation())
+	assert.Equal(t, []uint{1}, repo.linked[d.ID])
+
+	_, err = s.Create(ctx, service.CreateDefectDTO{ProjectID: 1, Title: "x", ClauseIDs: []uint{7}})
+	assert.Error(t, err)
+	// superseded codes cannot be newly cited
+	_, err = s.Create(ctx, service.CreateDefectDTO{ProjectID: 1, Title: "x", ClauseIDs: []uint{2}})
+	assert.ErrorContains(t, err, "obsolete")
+}
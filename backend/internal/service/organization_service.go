@@ -0,0 +1,100 @@
+package service
+
+import (
+	"context"
+	"strings"
+
+	"example.com/defect-control-system/internal/models"
+	"example.com/defect-control-system/internal/repository"
+)
+
+type OrganizationDTO struct {
+	Name        string `json:"name" validate:"required"`
+	Description string `json:"description"`
+	// StorageQuota caps attachment bytes of the organization's projects; 0 for none
+	StorageQuota int64 `json:"storage_quota"`
+}
+
+type UpdateOrganizationDTO struct {
+	Name         *string `json:"name"`
+	Description  *string `json:"description"`
+	StorageQuota *int64  `json:"storage_quota"`
+}
+
+// OrganizationService manages the directory of organizations (contractors,
+// subcontractors, suppliers) that can be made responsible for defects.
+type OrganizationService interface {
+	List(ctx context.Context) ([]*models.Organization, error)
+	Create(ctx context.Context, dto OrganizationDTO) (*models.Organization, error)
+	Update(ctx context.Context, id uint, dto UpdateOrganizationDTO) (*models.Organization, error)
+	Delete(ctx context.Context, id uint) error
+}
+
+type organizationService struct {
+	repo repository.OrganizationRepository
+}
+
+func NewOrganizationService(r repository.OrganizationRepository) OrganizationService {
+	return &organizationService{repo: r}
+}
+
+func (s *organizationService) List(ctx context.Context) ([]*models.Organization, error) {
+	return s.repo.List(ctx)
+}
+
+func (s *organizationService) Create(ctx context.Context, dto OrganizationDTO) (*models.Organization, error) {
+	name := strings.TrimSpace(dto.Name)
+	if name == "" {
+		return nil, invalid("name is required")
+	}
+	if _, err := s.repo.FindByName(ctx, name); err == nil {
+		return nil, invalid("organization already exists")
+	}
+	if dto.StorageQuota < 0 {
+		return nil, invalid("storage quota must not be negative")
+	}
+	o := &models.Organization{Name: name, Description: dto.Description, StorageQuota: dto.StorageQuota}
+	if err := s.repo.Create(ctx, o); err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+func (s *organizationService) Update(ctx context.Context, id uint, dto UpdateOrganizationDTO) (*models.Organization, error) {
+	o, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return nil, ErrNotFound
+	}
+	if dto.Name != nil {
+		name := strings.TrimSpace(*dto.Name)
+		if name == "" {
+			return nil, invalid("name is required")
+		}
+		if other, err := s.repo.FindByName(ctx, name); err == nil && other.ID != o.ID {
+			return nil, invalid("organization already exists")
+		}
+		o.Name = name
+	}
+	if dto.Description != nil {
+		o.Description = *dto.Description
+	}
+	if dto.StorageQuota != nil {
+		if *dto.StorageQuota < 0 {
+			return nil, invalid("storage quota must not be negative")
+		}
+		o.StorageQuota = *dto.StorageQuota
+	}
+	if err := s.repo.Update(ctx, o); err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+// Delete removes the organization; defects and catalog entries referring to it
+// keep working with the reference cleared
+func (s *organizationService) Delete(ctx context.Context, id uint) error {
+	if _, err := s.repo.FindByID(ctx, id); err != nil {
+		return ErrNotFound
+	}
+	return s.repo.Delete(ctx, id)
+}
@@ -2,6 +2,9 @@ package service
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"strings"
 
 	"example.com/defect-control-system/internal/models"
 	"example.com/defect-control-system/internal/repository"
@@ -10,6 +13,8 @@ import (
 type CreateProjectDTO struct {
 	Name    string `json:"name" validate:"required"`
 	Address string `json:"address"`
+	// Key prefixes defect keys ("TWR" gives "TWR-1"); derived from the name when empty
+	Key string `json:"key"`
 }
 
 type ProjectService interface {
@@ -20,17 +25,30 @@ type ProjectService interface {
 }
 
 type projectService struct {
-	repo repository.ProjectRepository
+	repo    repository.ProjectRepository
+	orgRepo repository.OrganizationRepository
 }
 
-func NewProjectService(r repository.ProjectRepository) ProjectService {
-	return &projectService{repo: r}
+func NewProjectService(r repository.ProjectRepository, or repository.OrganizationRepository) ProjectService {
+	return &projectService{repo: r, orgRepo: or}
 }
 
 func (s *projectService) Create(ctx context.Context, dto CreateProjectDTO) (*models.Project, error) {
+	key := strings.ToUpper(strings.TrimSpace(dto.Key))
+	if key != "" {
+		if err := s.checkKey(ctx, key, 0); err != nil {
+			return nil, err
+		}
+	} else {
+		var err error
+		if key, err = s.deriveKey(ctx, dto.Name); err != nil {
+			return nil, err
+		}
+	}
 	p := &models.Project{
 		Name:    dto.Name,
 		Address: dto.Address,
+		Key:     key,
 	}
 	if err := s.repo.Create(ctx, p); err != nil {
 		return nil, err
@@ -49,21 +67,141 @@ func (s *projectService) List(ctx context.Context) ([]*models.Project, error) {
 type UpdateProjectDTO struct {
 	Name    *string `json:"name"`
 	Address *string `json:"address"`
+	// Key renames the project key; keys of existing defects change with it
+	Key *string `json:"key"`
+	// OrganizationID sets the organization running the project; 0 clears it
+	OrganizationID *uint `json:"organization_id"`
+	// StorageQuota caps attachment bytes of the project; 0 applies the default
+	StorageQuota *int64 `json:"storage_quota"`
+	// Version, when set, must equal the stored version (the handler fills it from If-Match)
+	Version *int `json:"version,omitempty"`
 }
 
 func (s *projectService) Update(ctx context.Context, id uint, dto UpdateProjectDTO) (*models.Project, error) {
+	return retryUnconditional(dto.Version, func() (*models.Project, error) { return s.update(ctx, id, dto) })
+}
+
+func (s *projectService) update(ctx context.Context, id uint, dto UpdateProjectDTO) (*models.Project, error) {
 	p, err := s.repo.FindByID(ctx, id)
 	if err != nil || p == nil {
+		return nil, ErrNotFound
+	}
+	if err := checkVersion(dto.Version, p.Version); err != nil {
 		return nil, err
 	}
-	if dto.Name != nil {
+	var columns []string
+	if dto.Key != nil {
+		key := strings.ToUpper(strings.TrimSpace(*dto.Key))
+		if key != p.Key {
+			if err := s.checkKey(ctx, key, p.ID); err != nil {
+				return nil, err
+			}
+			p.Key = key
+			columns = append(columns, "key")
+		}
+	}
+	if dto.Name != nil && *dto.Name != p.Name {
 		p.Name = *dto.Name
+		columns = append(columns, "name")
 	}
-	if dto.Address != nil {
+	if dto.Address != nil && *dto.Address != p.Address {
 		p.Address = *dto.Address
+		columns = append(columns, "address")
 	}
-	if err := s.repo.Update(ctx, p); err != nil {
-		return nil, err
+	if dto.OrganizationID != nil {
+		if *dto.OrganizationID == 0 {
+			p.OrganizationID = nil
+		} else {
+			if s.orgRepo == nil {
+				return nil, invalid("organizations are not supported")
+			}
+			if _, err := s.orgRepo.FindByID(ctx, *dto.OrganizationID); err != nil {
+				return nil, invalid("organization not found")
+			}
+			v := *dto.OrganizationID
+			p.OrganizationID = &v
+		}
+		p.Organization = nil
+		columns = append(columns, "organization_id")
+	}
+	if dto.StorageQuota != nil && *dto.StorageQuota != p.StorageQuota {
+		if *dto.StorageQuota < 0 {
+			return nil, invalid("storage quota must not be negative")
+		}
+		p.StorageQuota = *dto.StorageQuota
+		columns = append(columns, "storage_quota")
+	}
+	if len(columns) == 0 {
+		return p, nil
+	}
+	if err := s.repo.Update(ctx, p, columns); err != nil {
+		return nil, versionError(err)
 	}
 	return p, nil
 }
+
+// ErrInvalidProjectKey is returned for malformed or already used project keys
+var ErrInvalidProjectKey = errors.New("invalid project key")
+
+// checkKey validates a project key and makes sure no other project uses it
+func (s *projectService) checkKey(ctx context.Context, key string, projectID uint) error {
+	if !models.ProjectKeyPattern.MatchString(key) {
+		return fmt.Errorf("%w: use 2-10 latin letters or digits starting with a letter", ErrInvalidProjectKey)
+	}
+	if other, err := s.repo.FindByKey(ctx, key); err == nil && other.ID != projectID {
+		return fmt.Errorf("%w: %s is already used", ErrInvalidProjectKey, key)
+	}
+	return nil
+}
+
+// keyTranslit maps Cyrillic letters to Latin for keys derived from project names
+var keyTranslit = map[rune]string{
+	'А': "A", 'Б': "B", 'В': "V", 'Г': "G", 'Д': "D", 'Е': "E", 'Ё': "E", 'Ж': "ZH", 'З': "Z",
+	'И': "I", 'Й': "Y", 'К': "K", 'Л': "L", 'М': "M", 'Н': "N", 'О': "O", 'П': "P", 'Р': "R",
+	'С': "S", 'Т': "T", 'У': "U", 'Ф': "F", 'Х': "KH", 'Ц': "TS", 'Ч': "CH", 'Ш': "SH", 'Щ': "SCH",
+	'Ы': "Y", 'Э': "E", 'Ю': "YU", 'Я': "YA",
+}
+
+// deriveKey builds a free key from the project name: initials of a multi-word
+// name or the first letters of a single word, with a numeric suffix on clashes
+func (s *projectService) deriveKey(ctx context.Context, name string) (string, error) {
+	var words []string
+	for _, w := range strings.Fields(strings.ToUpper(name)) {
+		var b strings.Builder
+		for _, r := range w {
+			switch {
+			case r >= 'A' && r <= 'Z', r >= '0' && r <= '9' && b.Len() > 0:
+				b.WriteRune(r)
+			case keyTranslit[r] != "":
+				b.WriteString(keyTranslit[r])
+			}
+		}
+		if b.Len() > 0 {
+			words = append(words, b.String())
+		}
+	}
+	base := ""
+	if len(words) > 1 {
+		for _, w := range words {
+			base += w[:1]
+		}
+	} else if len(words) == 1 {
+		base = words[0]
+	}
+	if len(base) > 4 {
+		base = base[:4]
+	}
+	if len(base) < 2 || base[0] < 'A' || base[0] > 'Z' {
+		base = "PRJ"
+	}
+	key := base
+	for i := 2; ; i++ {
+		if _, err := s.repo.FindByKey(ctx, key); err != nil {
+			return key, nil
+		}
+		if i > 999 {
+			return "", errors.New("cannot derive a free project key, set one explicitly")
+		}
+		key = fmt.Sprintf("%s%d", base, i)
+	}
+}
@@ -0,0 +1,104 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"example.com/defect-control-system/internal/models"
+	"example.com/defect-control-system/internal/service"
+)
+
+// keysProjectRepo knows a fixed set of project keys
+type keysProjectRepo struct {
+	mockProjectRepo
+	keys    map[string]uint
+	columns []string
+}
+
+func (m *keysProjectRepo) FindByID(ctx context.Context, id uint) (*models.Project, error) {
+	return &models.Project{ID: id, Name: "Tower", Key: "TWR"}, nil
+}
+func (m *keysProjectRepo) FindByKey(ctx context.Context, key string) (*models.Project, error) {
+	if id, ok := m.keys[key]; ok {
+		return &models.Project{ID: id, Key: key}, nil
+	}
+	return nil, errors.New("not found")
+}
+func (m *keysProjectRepo) Update(ctx context.Context, p *models.Project, columns []string) error {
+	m.columns = columns
+	return nil
+}
+
+func TestCreateProject_DerivesKey(t *testing.T) {
+	repo := &keysProjectRepo{keys: map[string]uint{"ZKS": 2}}
+	s := service.NewProjectService(repo, nil)
+
+	p, err := s.Create(context.Background(), service.CreateProjectDTO{Name: "Tower"})
+	assert.NoError(t, err)
+	assert.Equal(t, "TOWE", p.Key)
+
+	p, err = s.Create(context.Background(), service.CreateProjectDTO{Name: "Жилой комплекс Север"})
+	assert.NoError(t, err)
+	assert.Equal(t, "ZKS2", p.Key)
+
+	p, err = s.Create(context.Background(), service.CreateProjectDTO{Name: "№ 1"})
+	assert.NoError(t, err)
+	assert.Equal(t, "PRJ", p.Key)
+}
+
+func TestCreateProject_ExplicitKey(t *testing.T) {
+	repo := &keysProjectRepo{keys: map[string]uint{"TWR": 2}}
+	s := service.NewProjectService(repo, nil)
+
+	p, err := s.Create(context.Background(), service.CreateProjectDTO{Name: "Tower", Key: "nrd"})
+	assert.NoError(t, err)
+	assert.Equal(t, "NRD", p.Key)
+
+	_, err = s.Create(context.Background(), service.CreateProjectDTO{Name: "Tower", Key: "TWR"})
+	assert.ErrorIs(t, err, service.ErrInvalidProjectKey)
+
+	_, err = s.Create(context.Background(), service.CreateProjectDTO{Name: "Tower", Key: "1-A"})
+	assert.ErrorIs(t, err, service.ErrInvalidProjectKey)
+}
+
+func TestUpdateProject_ChangeKey(t *testing.T) {
+	repo := &keysProjectRepo{keys: map[string]uint{"TWR": 1, "NRD": 2}}
+	s := service.NewProjectService(repo, nil)
+
+	key := "NRD"
+	_, err := s.Update(context.Background(), 1, service.UpdateProjectDTO{Key: &key})
+	assert.ErrorIs(t, err, service.ErrInvalidProjectKey)
+	assert.Nil(t, repo.columns)
+
+	key = "twr2"
+	p, err := s.Update(context.Background(), 1, service.UpdateProjectDTO{Key: &key})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"key"}, repo.columns)
+	assert.Equal(t, "TWR2", p.Key)
+}
+
+// keyedDefectRepo finds a single defect by key
+type keyedDefectRepo struct{ mockDefectRepo }
+
+func (m *keyedDefectRepo) FindByKey(ctx context.Context, key string) (*models.Defect, error) {
+	if key == "TWR-142" {
+		return &models.Defect{ID: 5, Number: 142, Key: key}, nil
+	}
+	return nil, errors.New("not found")
+}
+
+func TestDefectFindByKey(t *testing.T) {
+	s := service.NewDefectService(&keyedDefectRepo{}, &mockProjectRepo{}, &mockUserRepo{})
+
+	d, err := s.FindByKey(context.Background(), " twr-142 ")
+	assert.NoError(t, err)
+	assert.Equal(t, uint(5), d.ID)
+
+	_, err = s.FindByKey(context.Background(), "TWR-7")
+	assert.ErrorIs(t, err, service.ErrNotFound)
+	_, err = s.FindByKey(context.Background(), "142")
+	assert.ErrorIs(t, err, service.ErrNotFound)
+}
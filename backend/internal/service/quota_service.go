@@ -0,0 +1,205 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"example.com/defect-control-system/internal/models"
+	"example.com/defect-control-system/internal/repository"
+)
+
+// ErrQuotaExceeded is returned for uploads that do not fit the storage quota
+// of the project or its organization
+var ErrQuotaExceeded = errors.New("storage quota exceeded")
+
+// QuotaLevels are the shares of a quota, in percent, crossing which emits a
+// QuotaWarning
+var QuotaLevels = []int{80, 100}
+
+// QuotaWarning reports a project or organization crossing a quota level.
+// OrganizationID is set for organization quotas. A warning with Err reports
+// that the usage could not be recomputed after a change to DefectID instead;
+// the levels catch up with the next change
+type QuotaWarning struct {
+	ProjectID      uint
+	OrganizationID uint
+	Name           string
+	Level          int
+	Used           int64
+	Quota          int64
+	DefectID       uint
+	Err            error
+}
+
+// QuotaService enforces storage quotas of projects and of the organizations
+// running them. Usage is the sum of attachment sizes; uploads in progress
+// reserve their declared length.
+type QuotaService interface {
+	// Guard checks size more bytes on the defect against the quota of its
+	// project and organization, run by the repository in the transaction
+	// storing them so that concurrent uploads cannot overshoot together. It
+	// fails with ErrQuotaExceeded; nil when quotas are off
+	Guard(defectID uint, size int64) *repository.QuotaGuard
+	// Observe recomputes the usage of the defect's project and organization
+	// after attachments were added or removed. Crossing a level upwards
+	// emits a warning once; dropping below rearms it. An error is delivered
+	// on Warnings as well, for callers not waiting on the result
+	Observe(ctx context.Context, defectID uint) error
+	// Usage reports the storage of a project by content type and uploader
+	Usage(ctx context.Context, userID uint, role string, projectID uint) (*models.StorageUsage, error)
+	// Warnings delivers quota warnings; they are dropped while nobody reads
+	Warnings() <-chan QuotaWarning
+}
+
+type quotaService struct {
+	repo         repository.QuotaRepository
+	members      MembershipService
+	projectQuota int64
+	warnings     chan QuotaWarning
+}
+
+// NewQuotaService applies projectQuota to projects without a quota of their
+// own; 0 leaves them unlimited. A nil repository turns quotas off
+func NewQuotaService(r repository.QuotaRepository, m MembershipService, projectQuota int64) QuotaService {
+	return &quotaService{repo: r, members: m, projectQuota: projectQuota, warnings: make(chan QuotaWarning, 16)}
+}
+
+func (s *quotaService) Warnings() <-chan QuotaWarning { return s.warnings }
+
+// quotaOf is the effective quota of a project
+func (s *quotaService) quotaOf(p *models.Project) int64 {
+	if p.StorageQuota > 0 {
+		return p.StorageQuota
+	}
+	return s.projectQuota
+}
+
+func (s *quotaService) Guard(defectID uint, size int64) *repository.QuotaGuard {
+	if s.repo == nil {
+		return nil
+	}
+	return &repository.QuotaGuard{DefectID: defectID, Fits: func(p *models.Project, u repository.QuotaUsage) error {
+		if quota := s.quotaOf(p); quota > 0 && u.Used+u.Reserved+size > quota {
+			return fmt.Errorf("%w: project %s has %d of %d bytes free", ErrQuotaExceeded, p.Name, max(quota-u.Used-u.Reserved, 0), quota)
+		}
+		o := p.Organization
+		if o != nil && o.StorageQuota > 0 && u.OrganizationUsed+u.OrganizationReserved+size > o.StorageQuota {
+			return fmt.Errorf("%w: organization %s has %d of %d bytes free", ErrQuotaExceeded, o.Name,
+				max(o.StorageQuota-u.OrganizationUsed-u.OrganizationReserved, 0), o.StorageQuota)
+		}
+		return nil
+	}}
+}
+
+// quotaLevel is the highest of QuotaLevels reached by used, 0 if none
+func quotaLevel(used, quota int64) int {
+	level := 0
+	if quota <= 0 {
+		return level
+	}
+	for _, l := range QuotaLevels {
+		if used*100 >= quota*int64(l) {
+			level = l
+		}
+	}
+	return level
+}
+
+func percentOf(used, quota int64) float64 {
+	if quota <= 0 {
+		return 0
+	}
+	return float64(used) * 100 / float64(quota)
+}
+
+func (s *quotaService) Observe(ctx context.Context, defectID uint) error {
+	if s.repo == nil {
+		return nil
+	}
+	err := s.observe(ctx, defectID)
+	if err != nil {
+		s.warn(QuotaWarning{DefectID: defectID, Err: err})
+	}
+	return err
+}
+
+func (s *quotaService) observe(ctx context.Context, defectID uint) error {
+	p, err := s.repo.ProjectOfDefect(ctx, defectID)
+	if err != nil {
+		return err
+	}
+	used, _, err := s.repo.ProjectUsage(ctx, p.ID)
+	if err != nil {
+		return err
+	}
+	quota := s.quotaOf(p)
+	if level := quotaLevel(used, quota); level != p.QuotaLevel {
+		changed, err := s.repo.SetProjectLevel(ctx, p.ID, p.QuotaLevel, level)
+		if err != nil {
+			return err
+		}
+		if changed && level > p.QuotaLevel {
+			s.warn(QuotaWarning{ProjectID: p.ID, Name: p.Name, Level: level, Used: used, Quota: quota})
+		}
+	}
+	o := p.Organization
+	if o == nil {
+		return nil
+	}
+	used, _, err = s.repo.OrganizationUsage(ctx, o.ID)
+	if err != nil {
+		return err
+	}
+	if level := quotaLevel(used, o.StorageQuota); level != o.QuotaLevel {
+		changed, err := s.repo.SetOrganizationLevel(ctx, o.ID, o.QuotaLevel, level)
+		if err != nil {
+			return err
+		}
+		if changed && level > o.QuotaLevel {
+			s.warn(QuotaWarning{ProjectID: p.ID, OrganizationID: o.ID, Name: o.Name, Level: level, Used: used, Quota: o.StorageQuota})
+		}
+	}
+	return nil
+}
+
+func (s *quotaService) warn(w QuotaWarning) {
+	select {
+	case s.warnings <- w:
+	default:
+		// nobody is listening
+	}
+}
+
+func (s *quotaService) Usage(ctx context.Context, userID uint, role string, projectID uint) (*models.StorageUsage, error) {
+	ok, err := s.members.CanAccessProject(ctx, userID, role, projectID)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrForbidden
+	}
+	if s.repo == nil {
+		return &models.StorageUsage{ProjectID: projectID, ByContentType: []models.UsageBucket{}, ByUploader: []models.UsageBucket{}}, nil
+	}
+	p, err := s.repo.FindProject(ctx, projectID)
+	if err != nil {
+		return nil, ErrNotFound
+	}
+	u := &models.StorageUsage{ProjectID: p.ID, Quota: s.quotaOf(p)}
+	if u.Bytes, u.Reserved, err = s.repo.ProjectUsage(ctx, p.ID); err != nil {
+		return nil, err
+	}
+	u.Percent = percentOf(u.Bytes, u.Quota)
+	if err := s.repo.ProjectBreakdown(ctx, u); err != nil {
+		return nil, err
+	}
+	if o := p.Organization; o != nil {
+		u.Organization = &models.QuotaUsage{OrganizationID: o.ID, Name: o.Name, Quota: o.StorageQuota}
+		if u.Organization.Bytes, u.Organization.Reserved, err = s.repo.OrganizationUsage(ctx, o.ID); err != nil {
+			return nil, err
+		}
+		u.Organization.Percent = percentOf(u.Organization.Bytes, o.StorageQuota)
+	}
+	return u, nil
+}
@@ -0,0 +1,192 @@
+package service_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"example.com/defect-control-system/internal/models"
+	"example.com/defect-control-system/internal/repository"
+	"example.com/defect-control-system/internal/service"
+)
+
+// memQuotaRepo keeps project usage in memory; defect n belongs to project
+// defects[n]
+type memQuotaRepo struct {
+	projects map[uint]*models.Project
+	defects  map[uint]uint
+	used     map[uint]int64
+	reserved map[uint]int64
+}
+
+func (m *memQuotaRepo) FindProject(ctx context.Context, projectID uint) (*models.Project, error) {
+	p, ok := m.projects[projectID]
+	if !ok {
+		return nil, errors.New("not found")
+	}
+	c := *p
+	if p.Organization != nil {
+		o := *p.Organization
+		c.Organization = &o
+	}
+	return &c, nil
+}
+func (m *memQuotaRepo) ProjectOfDefect(ctx context.Context, defectID uint) (*models.Project, error) {
+	return m.FindProject(ctx, m.defects[defectID])
+}
+
+// guard runs g the way the repositories do inside their transactions
+func (m *memQuotaRepo) guard(ctx context.Context, g *repository.QuotaGuard) error {
+	if g == nil {
+		return nil
+	}
+	p, err := m.ProjectOfDefect(ctx, g.DefectID)
+	if err != nil {
+		return err
+	}
+	var u repository.QuotaUsage
+	u.Used, u.Reserved, _ = m.ProjectUsage(ctx, p.ID)
+	if p.Organization != nil {
+		u.OrganizationUsed, u.OrganizationReserved, _ = m.OrganizationUsage(ctx, p.Organization.ID)
+	}
+	return g.Fits(p, u)
+}
+func (m *memQuotaRepo) ProjectUsage(ctx context.Context, projectID uint) (int64, int64, error) {
+	return m.used[projectID], m.reserved[projectID], nil
+}
+func (m *memQuotaRepo) OrganizationUsage(ctx context.Context, orgID uint) (int64, int64, error) {
+	var used, reserved int64
+	for id, p := range m.projects {
+		if p.OrganizationID != nil && *p.OrganizationID == orgID {
+			used, reserved = used+m.used[id], reserved+m.reserved[id]
+		}
+	}
+	return used, reserved, nil
+}
+func (m *memQuotaRepo) ProjectBreakdown(ctx context.Context, u *models.StorageUsage) error {
+	u.ByContentType = []models.UsageBucket{{ContentType: "image/jpeg", Files: 2, Bytes: m.used[u.ProjectID]}}
+	u.ByUploader = []models.UsageBucket{{UploaderID: 1, Files: 2, Bytes: m.used[u.ProjectID]}}
+	u.Files = 2
+	return nil
+}
+func (m *memQuotaRepo) SetProjectLevel(ctx context.Context, projectID uint, from, to int) (bool, error) {
+	if m.projects[projectID].QuotaLevel != from {
+		return false, nil
+	}
+	m.projects[projectID].QuotaLevel = to
+	return true, nil
+}
+func (m *memQuotaRepo) SetOrganizationLevel(ctx context.Context, orgID uint, from, to int) (bool, error) {
+	for _, p := range m.projects {
+		if o := p.Organization; o != nil && o.ID == orgID {
+			// projects share the organization
+			if o.QuotaLevel != from {
+				return false, nil
+			}
+			o.QuotaLevel = to
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func TestQuotas_EnforceAndWarn(t *testing.T) {
+	org := &models.Organization{ID: 3, Name: "StroyInvest", StorageQuota: 3000}
+	orgID := org.ID
+	repo := &memQuotaRepo{
+		projects: map[uint]*models.Project{
+			7: {ID: 7, Name: "Tower", StorageQuota: 1000, OrganizationID: &orgID, Organization: org},
+			8: {ID: 8, Name: "Mall", OrganizationID: &orgID, Organization: org},
+		},
+		defects:  map[uint]uint{1: 7, 2: 8},
+		used:     map[uint]int64{7: 400, 8: 2000},
+		reserved: map[uint]int64{7: 200},
+	}
+	members := service.NewMembershipService(&mockMemberRepo{}, &mockProjectRepo{}, &mockUserRepo{})
+	s := service.NewQuotaService(repo, members, 0)
+	ctx := context.Background()
+	warnings := func() []service.QuotaWarning {
+		var out []service.QuotaWarning
+		for {
+			select {
+			case w := <-s.Warnings():
+				out = append(out, w)
+			default:
+				return out
+			}
+		}
+	}
+
+	assert.NoError(t, repo.guard(ctx, s.Guard(1, 400)))
+	// uploads in progress reserve their length
+	assert.ErrorIs(t, repo.guard(ctx, s.Guard(1, 401)), service.ErrQuotaExceeded)
+	// project 8 has no quota of its own, but its organization does
+	assert.NoError(t, repo.guard(ctx, s.Guard(2, 400)))
+	err := repo.guard(ctx, s.Guard(2, 401))
+	assert.ErrorIs(t, err, service.ErrQuotaExceeded)
+	assert.Contains(t, err.Error(), "StroyInvest")
+
+	// the guard runs in the transaction storing the file
+	viper.Set("uploads.path", t.TempDir())
+	storage := service.NewLocalStorage()
+	attach := &memAttachRepo{}
+	blobs := service.NewBlobService(&memBlobRepo{blobs: map[string]*models.Blob{}, attach: attach, quotas: repo}, storage,
+		service.NewScanService(nil, nil, storage), s)
+	st, err := storage.StageStream(bytes.NewReader(make([]byte, 401)))
+	require.NoError(t, err)
+	assert.ErrorIs(t, blobs.Attach(ctx, &models.Attachment{DefectID: 1, Filename: "scan.pdf"}, st), service.ErrQuotaExceeded)
+	assert.Empty(t, attach.list)
+
+	repo.used[7] = 800
+	require.NoError(t, s.Observe(ctx, 1))
+	ws := warnings()
+	require.Len(t, ws, 2)
+	assert.Equal(t, service.QuotaWarning{ProjectID: 7, Name: "Tower", Level: 80, Used: 800, Quota: 1000}, ws[0])
+	assert.Equal(t, service.QuotaWarning{ProjectID: 7, OrganizationID: 3, Name: "StroyInvest", Level: 80, Used: 2800, Quota: 3000}, ws[1])
+	require.NoError(t, s.Observe(ctx, 1))
+	assert.Empty(t, warnings(), "warned once per crossing")
+
+	repo.used[7] = 1000
+	require.NoError(t, s.Observe(ctx, 1))
+	ws = warnings()
+	require.Len(t, ws, 2)
+	assert.Equal(t, 100, ws[0].Level)
+	assert.Equal(t, service.QuotaWarning{ProjectID: 7, OrganizationID: 3, Name: "StroyInvest", Level: 100, Used: 3000, Quota: 3000}, ws[1], "the organization is full as well")
+
+	// dropping below rearms the warning
+	repo.used[7] = 100
+	require.NoError(t, s.Observe(ctx, 1))
+	assert.Empty(t, warnings())
+	repo.used[7] = 850
+	require.NoError(t, s.Observe(ctx, 1))
+	ws = warnings()
+	require.Len(t, ws, 2)
+	assert.Equal(t, 80, ws[0].Level)
+	assert.Equal(t, service.QuotaWarning{ProjectID: 7, OrganizationID: 3, Name: "StroyInvest", Level: 80, Used: 2850, Quota: 3000}, ws[1])
+
+	u, err := s.Usage(ctx, 1, "engineer", 7)
+	require.NoError(t, err)
+	assert.Equal(t, int64(850), u.Bytes)
+	assert.Equal(t, int64(200), u.Reserved)
+	assert.Equal(t, int64(1000), u.Quota)
+	assert.InDelta(t, 85.0, u.Percent, 0.01)
+	require.NotNil(t, u.Organization)
+	assert.Equal(t, int64(2850), u.Organization.Bytes)
+	_, err = s.Usage(ctx, 2, "engineer", 7)
+	assert.ErrorIs(t, err, service.ErrForbidden)
+
+	// a failed recount is reported to whoever logs the warnings
+	assert.Error(t, s.Observe(ctx, 9))
+	ws = warnings()
+	require.Len(t, ws, 1)
+	assert.Equal(t, uint(9), ws[0].DefectID)
+	assert.Error(t, ws[0].Err)
+
+	// without a repository quotas are off
+	assert.Nil(t, service.NewQuotaService(nil, nil, 0).Guard(1, 1<<40))
+}
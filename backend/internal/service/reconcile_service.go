@@ -0,0 +1,117 @@
+package service
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"example.com/defect-control-system/internal/models"
+	"example.com/defect-control-system/internal/repository"
+)
+
+// DefaultReconcileGrace keeps files younger than this out of the orphan list:
+// an upload may have written its file without committing the row yet
+const DefaultReconcileGrace = time.Hour
+
+// ReconcileReport lists where storage and the database disagree
+type ReconcileReport struct {
+	// OrphanFiles are files under the storage root no row points at
+	OrphanFiles []string `json:"orphan_files"`
+	// MissingFiles are rows whose file is gone. They are only reported:
+	// the file may still be restored from a backup
+	MissingFiles []repository.FileRef `json:"missing_files"`
+	// MiscountedBlobs are blobs whose ref_count disagrees with the attachments using them
+	MiscountedBlobs []repository.BlobUsage `json:"miscounted_blobs"`
+	// Repaired is set when orphan files were removed and blob counts corrected
+	Repaired bool `json:"repaired"`
+}
+
+// Clean reports whether nothing was found
+func (r *ReconcileReport) Clean() bool {
+	return len(r.OrphanFiles) == 0 && len(r.MissingFiles) == 0 && len(r.MiscountedBlobs) == 0
+}
+
+// ReconcileService finds files in storage without rows, rows without files
+// and wrong blob reference counts, and optionally repairs what can be
+type ReconcileService interface {
+	Run(ctx context.Context, repair bool) (*ReconcileReport, error)
+}
+
+type reconcileService struct {
+	repo     repository.ReconcileRepository
+	basePath string
+	grace    time.Duration
+}
+
+// NewReconcileService checks the files under basePath, ignoring files
+// modified within grace (DefaultReconcileGrace when zero)
+func NewReconcileService(r repository.ReconcileRepository, basePath string, grace time.Duration) ReconcileService {
+	if grace <= 0 {
+		grace = DefaultReconcileGrace
+	}
+	return &reconcileService{repo: r, basePath: basePath, grace: grace}
+}
+
+func (s *reconcileService) Run(ctx context.Context, repair bool) (*ReconcileReport, error) {
+	report := &ReconcileReport{OrphanFiles: []string{}, MissingFiles: []repository.FileRef{}}
+	refs, err := s.repo.FileRefs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	referenced := map[string]bool{}
+	for _, ref := range refs {
+		p := filepath.ToSlash(filepath.Clean(ref.Path))
+		referenced[p] = true
+		if _, err := os.Stat(filepath.Join(s.basePath, filepath.FromSlash(p))); os.IsNotExist(err) {
+			report.MissingFiles = append(report.MissingFiles, ref)
+		}
+	}
+	cutoff := time.Now().Add(-s.grace)
+	err = filepath.WalkDir(s.basePath, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(s.basePath, path)
+		if err != nil || referenced[filepath.ToSlash(rel)] {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil || info.ModTime().After(cutoff) {
+			return err
+		}
+		report.OrphanFiles = append(report.OrphanFiles, filepath.ToSlash(rel))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(report.OrphanFiles)
+	if report.MiscountedBlobs, err = s.repo.MiscountedBlobs(ctx); err != nil {
+		return nil, err
+	}
+	if !repair {
+		return report, nil
+	}
+	for _, p := range report.OrphanFiles {
+		if err := os.Remove(filepath.Join(s.basePath, filepath.FromSlash(p))); err != nil && !os.IsNotExist(err) {
+			return report, err
+		}
+	}
+	for _, b := range report.MiscountedBlobs {
+		err := s.repo.RepairBlob(ctx, b.SHA256, func(b *models.Blob) error {
+			err := os.Remove(filepath.Join(s.basePath, filepath.FromSlash(b.Path)))
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		})
+		if err != nil {
+			return report, err
+		}
+	}
+	report.Repaired = true
+	return report, nil
+}
@@ -0,0 +1,109 @@
+package service_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"example.com/defect-control-system/internal/models"
+	"example.com/defect-control-system/internal/repository"
+	"example.com/defect-control-system/internal/service"
+)
+
+// memReconcileRepo serves fixed references plus those of its blobs, and
+// recounts blobs from used
+type memReconcileRepo struct {
+	refs  []repository.FileRef
+	blobs map[string]*models.Blob
+	used  map[string]int
+}
+
+func (m *memReconcileRepo) FileRefs(ctx context.Context) ([]repository.FileRef, error) {
+	list := append([]repository.FileRef{}, m.refs...)
+	for sha, b := range m.blobs {
+		list = append(list, repository.FileRef{Table: "blobs", ID: sha, Path: b.Path})
+	}
+	return list, nil
+}
+func (m *memReconcileRepo) MiscountedBlobs(ctx context.Context) ([]repository.BlobUsage, error) {
+	var list []repository.BlobUsage
+	for sha, b := range m.blobs {
+		if b.RefCount != m.used[sha] {
+			list = append(list, repository.BlobUsage{SHA256: sha, Path: b.Path, RefCount: b.RefCount, Used: m.used[sha]})
+		}
+	}
+	return list, nil
+}
+func (m *memReconcileRepo) RepairBlob(ctx context.Context, sha string, remove func(b *models.Blob) error) error {
+	b := m.blobs[sha]
+	if m.used[sha] > 0 {
+		b.RefCount = m.used[sha]
+		return nil
+	}
+	if err := remove(b); err != nil {
+		return err
+	}
+	delete(m.blobs, sha)
+	return nil
+}
+
+func TestReconcile_ReportAndRepair(t *testing.T) {
+	dir := t.TempDir()
+	write := func(p string, age time.Duration) {
+		full := filepath.Join(dir, filepath.FromSlash(p))
+		require.NoError(t, os.MkdirAll(filepath.Dir(full), 0o755))
+		require.NoError(t, os.WriteFile(full, []byte(p), 0o644))
+		old := time.Now().Add(-age)
+		require.NoError(t, os.Chtimes(full, old, old))
+	}
+	write("blobs/aa/bb/aabb01", 48*time.Hour)
+	write("blobs/cc/dd/ccdd02", 48*time.Hour)
+	write("2025/10/11/handover.pdf", 48*time.Hour)
+	write(".staging/upload-123", 48*time.Hour)
+	write(".partial/fresh", time.Minute)
+
+	repo := &memReconcileRepo{
+		refs: []repository.FileRef{
+			{Table: "attachments", ID: "4", Path: "2025/10/11/handover.pdf"},
+			{Table: "acceptance_acts", ID: "2", Path: "acts/2.pdf"},
+		},
+		blobs: map[string]*models.Blob{
+			"aabb01": {SHA256: "aabb01", Path: "blobs/aa/bb/aabb01", RefCount: 3},
+			"ccdd02": {SHA256: "ccdd02", Path: "blobs/cc/dd/ccdd02", RefCount: 1},
+		},
+		used: map[string]int{"aabb01": 2},
+	}
+	s := service.NewReconcileService(repo, dir, time.Hour)
+	ctx := context.Background()
+
+	report, err := s.Run(ctx, false)
+	require.NoError(t, err)
+	assert.Equal(t, []string{".staging/upload-123"}, report.OrphanFiles, "recent files are left alone")
+	require.Len(t, report.MissingFiles, 1)
+	assert.Equal(t, "acceptance_acts", report.MissingFiles[0].Table)
+	assert.Len(t, report.MiscountedBlobs, 2)
+	assert.False(t, report.Repaired)
+	_, err = os.Stat(filepath.Join(dir, ".staging", "upload-123"))
+	require.NoError(t, err, "a report changes nothing")
+
+	report, err = s.Run(ctx, true)
+	require.NoError(t, err)
+	assert.True(t, report.Repaired)
+	_, err = os.Stat(filepath.Join(dir, ".staging", "upload-123"))
+	assert.True(t, os.IsNotExist(err))
+	_, err = os.Stat(filepath.Join(dir, "blobs", "cc", "dd", "ccdd02"))
+	assert.True(t, os.IsNotExist(err), "unused blob removed")
+	assert.Equal(t, 2, repo.blobs["aabb01"].RefCount)
+	assert.NotContains(t, repo.blobs, "ccdd02")
+
+	report, err = s.Run(ctx, false)
+	require.NoError(t, err)
+	assert.Empty(t, report.OrphanFiles)
+	assert.Empty(t, report.MiscountedBlobs)
+	assert.Len(t, report.MissingFiles, 1, "missing files are only reported")
+}
@@ -0,0 +1,192 @@
+package service
+
+import (
+	"context"
+	"strings"
+
+	"example.com/defect-control-system/internal/models"
+	"example.com/defect-control-system/internal/repository"
+)
+
+type CreateViewDTO struct {
+	Name string `json:"name" validate:"required"`
+	// ProjectID scopes the view to a project; omit for a global view across all of the user's projects
+	ProjectID *uint               `json:"project_id"`
+	Filter    models.DefectFilter `json:"filter"`
+	// Shared makes a project view visible to the project team
+	Shared    bool `json:"shared"`
+	IsDefault bool `json:"is_default"`
+}
+
+type UpdateViewDTO struct {
+	Name      *string              `json:"name"`
+	Filter    *models.DefectFilter `json:"filter"`
+	Shared    *bool                `json:"shared"`
+	IsDefault *bool                `json:"is_default"`
+}
+
+// SavedViewService manages saved defect filters. Views are resolved into defect
+// lists through Defects, which is also the entry point for exports.
+type SavedViewService interface {
+	Create(ctx context.Context, userID uint, role string, dto CreateViewDTO) (*models.SavedView, error)
+	Update(ctx context.Context, userID uint, id uint, dto UpdateViewDTO) (*models.SavedView, error)
+	Delete(ctx context.Context, userID uint, role string, id uint) error
+	Get(ctx context.Context, userID uint, role string, id uint) (*models.SavedView, error)
+	// List returns the caller's views and views shared with them; projectID narrows to one project
+	List(ctx context.Context, userID uint, role string, projectID *uint) ([]*models.SavedView, error)
+	// Defects returns the defects matched by the view for the calling user
+	Defects(ctx context.Context, userID uint, role string, id uint) (*models.SavedView, []*models.Defect, error)
+}
+
+type savedViewService struct {
+	repo      repository.SavedViewRepository
+	defectSvc DefectService
+	members   MembershipService
+}
+
+func NewSavedViewService(r repository.SavedViewRepository, ds DefectService, m MembershipService) SavedViewService {
+	return &savedViewService{repo: r, defectSvc: ds, members: m}
+}
+
+func (s *savedViewService) Create(ctx context.Context, userID uint, role string, dto CreateViewDTO) (*models.SavedView, error) {
+	name := strings.TrimSpace(dto.Name)
+	if name == "" {
+		return nil, invalid("name is required")
+	}
+	if dto.ProjectID != nil {
+		ok, err := s.members.CanAccessProject(ctx, userID, role, *dto.ProjectID)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, ErrForbidden
+		}
+	} else if dto.Shared {
+		return nil, invalid("only project views can be shared")
+	}
+	v := &models.SavedView{
+		OwnerID:   userID,
+		ProjectID: dto.ProjectID,
+		Name:      name,
+		Filter:    dto.Filter,
+		Shared:    dto.Shared,
+		IsDefault: dto.IsDefault,
+	}
+	if err := s.repo.Create(ctx, v); err != nil {
+		return nil, err
+	}
+	if v.IsDefault {
+		if err := s.repo.ClearDefault(ctx, userID, v.ProjectID, v.ID); err != nil {
+			return nil, err
+		}
+	}
+	return v, nil
+}
+
+func (s *savedViewService) Update(ctx context.Context, userID uint, id uint, dto UpdateViewDTO) (*models.SavedView, error) {
+	v, err := s.repo.FindByID(ctx, id)
+	if err != nil || v == nil {
+		return nil, ErrNotFound
+	}
+	// only the owner edits a view, even a shared one
+	if v.OwnerID != userID {
+		return nil, ErrForbidden
+	}
+	if dto.Name != nil {
+		name := strings.TrimSpace(*dto.Name)
+		if name == "" {
+			return nil, invalid("name is required")
+		}
+		v.Name = name
+	}
+	if dto.Filter != nil {
+		v.Filter = *dto.Filter
+	}
+	if dto.Shared != nil {
+		if *dto.Shared && v.ProjectID == nil {
+			return nil, invalid("only project views can be shared")
+		}
+		v.Shared = *dto.Shared
+	}
+	if dto.IsDefault != nil {
+		v.IsDefault = *dto.IsDefault
+	}
+	if err := s.repo.Update(ctx, v); err != nil {
+		return nil, err
+	}
+	if v.IsDefault {
+		if err := s.repo.ClearDefault(ctx, userID, v.ProjectID, v.ID); err != nil {
+			return nil, err
+		}
+	}
+	return v, nil
+}
+
+func (s *savedViewService) Delete(ctx context.Context, userID uint, role string, id uint) error {
+	v, err := s.repo.FindByID(ctx, id)
+	if err != nil || v == nil {
+		return ErrNotFound
+	}
+	if v.OwnerID != userID && role != "admin" {
+		return ErrForbidden
+	}
+	return s.repo.Delete(ctx, id)
+}
+
+func (s *savedViewService) Get(ctx context.Context, userID uint, role string, id uint) (*models.SavedView, error) {
+	v, err := s.repo.FindByID(ctx, id)
+	if err != nil || v == nil {
+		return nil, ErrNotFound
+	}
+	if v.OwnerID == userID {
+		return v, nil
+	}
+	if !v.Shared || v.ProjectID == nil {
+		return nil, ErrNotFound
+	}
+	ok, err := s.members.CanAccessProject(ctx, userID, role, *v.ProjectID)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return v, nil
+}
+
+func (s *savedViewService) List(ctx context.Context, userID uint, role string, projectID *uint) ([]*models.SavedView, error) {
+	ids, all, err := s.members.AccessibleProjectIDs(ctx, userID, role)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.ListVisible(ctx, userID, ids, all, projectID)
+}
+
+func (s *savedViewService) Defects(ctx context.Context, userID uint, role string, id uint) (*models.SavedView, []*models.Defect, error) {
+	v, err := s.Get(ctx, userID, role, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	q := repository.DefectQuery{Filter: v.Filter}
+	if v.ProjectID != nil {
+		// the owner may have lost access to the project since the view was saved
+		ok, err := s.members.CanAccessProject(ctx, userID, role, *v.ProjectID)
+		if err != nil {
+			return nil, nil, err
+		}
+		if !ok {
+			return nil, nil, ErrForbidden
+		}
+		q.ProjectIDs = []uint{*v.ProjectID}
+	} else {
+		q.ProjectIDs, q.AllProjects, err = s.members.AccessibleProjectIDs(ctx, userID, role)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	list, err := s.defectSvc.ListFiltered(ctx, q)
+	if err != nil {
+		return nil, nil, err
+	}
+	return v, list, nil
+}
@@ -0,0 +1,77 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"example.com/defect-control-system/internal/models"
+	"example.com/defect-control-system/internal/service"
+)
+
+type mockViewRepo struct{ views map[uint]*models.SavedView }
+
+func (m *mockViewRepo) Create(ctx context.Context, v *models.SavedView) error {
+	v.ID = uint(len(m.views) + 1)
+	m.views[v.ID] = v
+	return nil
+}
+func (m *mockViewRepo) FindByID(ctx context.Context, id uint) (*models.SavedView, error) {
+	if v, ok := m.views[id]; ok {
+		return v, nil
+	}
+	return nil, assert.AnError
+}
+func (m *mockViewRepo) Update(ctx context.Context, v *models.SavedView) error { return nil }
+func (m *mockViewRepo) Delete(ctx context.Context, id uint) error {
+	delete(m.views, id)
+	return nil
+}
+func (m *mockViewRepo) ListVisible(ctx context.Context, userID uint, sharedProjectIDs []uint, allShared bool, projectID *uint) ([]*models.SavedView, error) {
+	return nil, nil
+}
+func (m *mockViewRepo) ClearDefault(ctx context.Context, ownerID uint, projectID *uint, exceptID uint) error {
+	for id, v := range m.views {
+		if id != exceptID && v.OwnerID == ownerID && (v.ProjectID == nil) == (projectID == nil) {
+			v.IsDefault = false
+		}
+	}
+	return nil
+}
+
+func TestSavedViews_SharingAndDefault(t *testing.T) {
+	repo := &mockViewRepo{views: map[uint]*models.SavedView{}}
+	members := service.NewMembershipService(&mockMemberRepo{}, &mockProjectRepo{}, &mockUserRepo{})
+	s := service.NewSavedViewService(repo, &mockDefectSvc{}, members)
+	ctx := context.Background()
+	project := uint(7)
+
+	// global views cannot be shared
+	_, err := s.Create(ctx, 1, "engineer", service.CreateViewDTO{Name: "mine", Shared: true})
+	assert.Error(t, err)
+	// only project members can create project views
+	_, err = s.Create(ctx, 2, "engineer", service.CreateViewDTO{Name: "x", ProjectID: &project})
+	assert.ErrorIs(t, err, service.ErrForbidden)
+
+	shared, err := s.Create(ctx, 3, "manager", service.CreateViewDTO{Name: "open", ProjectID: &project, Shared: true, IsDefault: true,
+		Filter: models.DefectFilter{Statuses: []string{"open"}}})
+	assert.NoError(t, err)
+	second, err := s.Create(ctx, 3, "manager", service.CreateViewDTO{Name: "critical", ProjectID: &project, IsDefault: true})
+	assert.NoError(t, err)
+	assert.False(t, shared.IsDefault)
+	assert.True(t, second.IsDefault)
+
+	// project member sees the shared view, but not the private one, and cannot edit it
+	_, err = s.Get(ctx, 1, "engineer", shared.ID)
+	assert.NoError(t, err)
+	_, err = s.Get(ctx, 1, "engineer", second.ID)
+	assert.ErrorIs(t, err, service.ErrNotFound)
+	name := "renamed"
+	_, err = s.Update(ctx, 1, shared.ID, service.UpdateViewDTO{Name: &name})
+	assert.ErrorIs(t, err, service.ErrForbidden)
+
+	v, _, err := s.Defects(ctx, 1, "engineer", shared.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"open"}, v.Filter.Statuses)
+}
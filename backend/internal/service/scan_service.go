@@ -0,0 +1,115 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"example.com/defect-control-system/internal/models"
+	"example.com/defect-control-system/internal/repository"
+)
+
+// scanBatch is how many pending contents ScanPending loads at a time
+const scanBatch = 50
+
+// ScanService holds new attachments back until a Scanner has checked their
+// content. Clean content is released, infected content is quarantined, and
+// content the scanner refuses stays blocked. Without a scanner attachments
+// are clean from the start.
+type ScanService interface {
+	// Hold sets the scan status of an attachment about to be created
+	Hold(a *models.Attachment)
+	// Submit has a created attachment scanned soon
+	Submit(a *models.Attachment)
+	// ScanPending scans the content of all pending attachments and returns
+	// how many contents were settled
+	ScanPending(ctx context.Context) (int, error)
+	// Wake signals that attachments were submitted since ScanPending last ran
+	Wake() <-chan struct{}
+}
+
+type scanService struct {
+	repo    repository.ScanRepository
+	scanner Scanner
+	storage StorageService
+	wake    chan struct{}
+}
+
+// NewScanService scans with scanner; a nil scanner turns scanning off
+func NewScanService(r repository.ScanRepository, scanner Scanner, st StorageService) ScanService {
+	return &scanService{repo: r, scanner: scanner, storage: st, wake: make(chan struct{}, 1)}
+}
+
+func (s *scanService) Hold(a *models.Attachment) {
+	if s.scanner == nil {
+		a.ScanStatus = models.AttachmentClean
+		return
+	}
+	a.ScanStatus = models.AttachmentPendingScan
+}
+
+func (s *scanService) Submit(a *models.Attachment) {
+	if s.scanner == nil || a.ScanStatus != models.AttachmentPendingScan {
+		return
+	}
+	select {
+	case s.wake <- struct{}{}:
+	default:
+		// a wake-up is pending already
+	}
+}
+
+func (s *scanService) Wake() <-chan struct{} { return s.wake }
+
+func (s *scanService) ScanPending(ctx context.Context) (int, error) {
+	if s.scanner == nil {
+		return 0, nil
+	}
+	n := 0
+	for {
+		list, err := s.repo.Pending(ctx, scanBatch)
+		if err != nil || len(list) == 0 {
+			return n, err
+		}
+		for _, a := range list {
+			settled, err := s.scan(ctx, a.SHA256, a.Path)
+			if err != nil {
+				// the scanner is unreachable: try again next time
+				return n, err
+			}
+			if settled {
+				n++
+			}
+		}
+	}
+}
+
+// scan checks content sha stored at path and records the verdict. It reports
+// whether the content was settled
+func (s *scanService) scan(ctx context.Context, sha, path string) (bool, error) {
+	f, err := s.storage.OpenFile(path)
+	if err != nil {
+		// nothing to scan is nothing to serve
+		return true, s.repo.Settle(ctx, sha, models.AttachmentScanFailed, "file missing")
+	}
+	res, err := s.scanner.Scan(ctx, f)
+	f.Close()
+	switch {
+	case errors.Is(err, ErrScanRefused):
+		return true, s.repo.Settle(ctx, sha, models.AttachmentScanFailed, scanDetail(err.Error()))
+	case err != nil:
+		return false, err
+	case res.Infected:
+		return true, s.repo.Quarantine(ctx, sha, scanDetail(res.Signature), func(b *models.Blob) (string, error) {
+			return s.storage.Quarantine(path)
+		})
+	}
+	return true, s.repo.Settle(ctx, sha, models.AttachmentClean, "")
+}
+
+// scanDetail fits a message into Attachment.ScanDetail
+func scanDetail(msg string) string {
+	if len(msg) > 255 {
+		return msg[:255]
+	}
+	return msg
+}
@@ -0,0 +1,203 @@
+package service_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"example.com/defect-control-system/internal/models"
+	"example.com/defect-control-system/internal/service"
+)
+
+// eicar is the standard antivirus test file
+const eicar = `X5O!P%@AP[4\PZX54(P^)7CC)7}$EICAR-STANDARD-ANTIVIRUS-TEST-FILE!$H+H*`
+
+// fakeClamd answers INSTREAM like clamd: FOUND for the EICAR string, ERROR
+// past maxStream bytes, OK otherwise. It stops with the test
+func fakeClamd(t *testing.T, network, addr string, maxStream int) net.Listener {
+	l, err := net.Listen(network, addr)
+	require.NoError(t, err)
+	t.Cleanup(func() { l.Close() })
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn) {
+				defer conn.Close()
+				r := bufio.NewReader(conn)
+				if cmd, err := r.ReadString(0); err != nil || cmd != "zINSTREAM\x00" {
+					conn.Write([]byte("UNKNOWN COMMAND\x00"))
+					return
+				}
+				var data []byte
+				for {
+					var n uint32
+					if binary.Read(r, binary.BigEndian, &n) != nil {
+						return
+					}
+					if n == 0 {
+						break
+					}
+					chunk := make([]byte, n)
+					if _, err := io.ReadFull(r, chunk); err != nil {
+						return
+					}
+					data = append(data, chunk...)
+					if len(data) > maxStream {
+						conn.Write([]byte("INSTREAM size limit exceeded. ERROR\x00"))
+						return
+					}
+				}
+				if bytes.Contains(data, []byte("EICAR-STANDARD-ANTIVIRUS-TEST-FILE")) {
+					conn.Write([]byte("stream: Win.Test.EICAR_HDB-1 FOUND\x00"))
+					return
+				}
+				conn.Write([]byte("stream: OK\x00"))
+			}(conn)
+		}
+	}()
+	return l
+}
+
+// memScanRepo records verdicts on the attachments and blobs of a memBlobRepo
+type memScanRepo struct{ blobs *memBlobRepo }
+
+func (m *memScanRepo) Pending(ctx context.Context, limit int) ([]*models.Attachment, error) {
+	var list []*models.Attachment
+	seen := map[string]bool{}
+	for _, a := range m.blobs.attach.list {
+		if a.ScanStatus == models.AttachmentPendingScan && !seen[a.SHA256] && len(list) < limit {
+			seen[a.SHA256] = true
+			list = append(list, a)
+		}
+	}
+	return list, nil
+}
+func (m *memScanRepo) Settle(ctx context.Context, sha, status, detail string) error {
+	for _, a := range m.blobs.attach.list {
+		if a.SHA256 == sha && a.ScanStatus == models.AttachmentPendingScan {
+			a.ScanStatus, a.ScanDetail = status, detail
+		}
+	}
+	return nil
+}
+func (m *memScanRepo) Quarantine(ctx context.Context, sha, signature string, move func(b *models.Blob) (string, error)) error {
+	b := m.blobs.blobs[sha]
+	path, err := move(b)
+	if err != nil {
+		return err
+	}
+	b.Path = path
+	for _, a := range m.blobs.attach.list {
+		if a.SHA256 == sha {
+			a.ScanStatus, a.ScanDetail, a.Path = models.AttachmentInfected, signature, path
+		}
+	}
+	return nil
+}
+
+func TestClamdScanner(t *testing.T) {
+	ctx := context.Background()
+	sock := filepath.Join(t.TempDir(), "clamd.sock")
+	fakeClamd(t, "unix", sock, 1<<20)
+	tcp := fakeClamd(t, "tcp", "127.0.0.1:0", 1<<20)
+
+	for _, addr := range []string{"unix://" + sock, "tcp://" + tcp.Addr().String(), tcp.Addr().String()} {
+		sc, err := service.NewClamdScanner(addr, time.Second)
+		require.NoError(t, err)
+		res, err := sc.Scan(ctx, bytes.NewReader([]byte("%PDF-1.4 site diary")))
+		require.NoError(t, err, addr)
+		assert.False(t, res.Infected)
+		res, err = sc.Scan(ctx, bytes.NewReader([]byte(eicar)))
+		require.NoError(t, err, addr)
+		assert.True(t, res.Infected)
+		assert.Equal(t, "Win.Test.EICAR_HDB-1", res.Signature)
+	}
+
+	// larger than several chunks and over the daemon's limit
+	sc, _ := service.NewClamdScanner("unix://"+sock, time.Second)
+	_, err := sc.Scan(ctx, bytes.NewReader(make([]byte, 2<<20)))
+	assert.ErrorIs(t, err, service.ErrScanRefused)
+
+	_, err = service.NewClamdScanner("udp://127.0.0.1:3310", 0)
+	assert.Error(t, err)
+}
+
+func TestScans_HoldUntilClean(t *testing.T) {
+	dir := t.TempDir()
+	viper.Set("uploads.path", dir)
+	viper.Set("uploads.allowed_types", nil)
+	storage := service.NewLocalStorage()
+	repo := &memBlobRepo{blobs: map[string]*models.Blob{}, attach: &memAttachRepo{}}
+	l := fakeClamd(t, "tcp", "127.0.0.1:0", 1<<20)
+	scanner, err := service.NewClamdScanner(l.Addr().String(), time.Second)
+	require.NoError(t, err)
+	scans := service.NewScanService(&memScanRepo{blobs: repo}, scanner, storage)
+	blobs := service.NewBlobService(repo, storage, scans, service.NewQuotaService(nil, nil, 0))
+	ctx := context.Background()
+
+	attach := func(content string) *models.Attachment {
+		st, err := storage.StageStream(bytes.NewReader([]byte(content)))
+		require.NoError(t, err)
+		a := &models.Attachment{DefectID: 1, Filename: "file.txt"}
+		require.NoError(t, blobs.Attach(ctx, a, st))
+		return a
+	}
+	clean := attach("handover protocol, section 2")
+	infected := attach(eicar)
+	assert.Equal(t, models.AttachmentPendingScan, clean.ScanStatus)
+	assert.False(t, clean.Servable())
+	select {
+	case <-scans.Wake():
+	default:
+		t.Fatal("a submitted attachment wakes the scanner")
+	}
+
+	// the daemon is down: attachments wait
+	l.Close()
+	_, err = scans.ScanPending(ctx)
+	assert.Error(t, err)
+	assert.Equal(t, models.AttachmentPendingScan, clean.ScanStatus)
+
+	fakeClamd(t, "tcp", l.Addr().String(), 1<<20)
+	n, err := scans.ScanPending(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+	assert.True(t, clean.Servable())
+	assert.Equal(t, models.AttachmentInfected, infected.ScanStatus)
+	assert.Equal(t, "Win.Test.EICAR_HDB-1", infected.ScanDetail)
+	assert.Equal(t, filepath.Join(".quarantine", infected.SHA256), infected.Path)
+	_, err = os.Stat(filepath.Join(dir, service.BlobPath(infected.SHA256)))
+	assert.True(t, os.IsNotExist(err), "moved out of blobs")
+	_, err = os.Stat(filepath.Join(dir, infected.Path))
+	assert.NoError(t, err)
+
+	// the same content uploaded again is caught again
+	again := attach(eicar)
+	_, err = scans.ScanPending(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, models.AttachmentInfected, again.ScanStatus)
+	require.NoError(t, blobs.Delete(ctx, again))
+	require.NoError(t, blobs.Delete(ctx, infected))
+	_, err = os.Stat(filepath.Join(dir, ".quarantine", infected.SHA256))
+	assert.True(t, os.IsNotExist(err), "quarantined file goes with the last attachment")
+
+	// without a scanner everything is clean at once
+	off := service.NewScanService(nil, nil, storage)
+	a := &models.Attachment{}
+	off.Hold(a)
+	assert.Equal(t, models.AttachmentClean, a.ScanStatus)
+}
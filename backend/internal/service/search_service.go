@@ -0,0 +1,75 @@
+package service
+
+import (
+	"context"
+	"strings"
+
+	"example.com/defect-control-system/internal/models"
+	"example.com/defect-control-system/internal/repository"
+)
+
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 100
+)
+
+// SearchDTO carries a full-text query from the handler
+type SearchDTO struct {
+	Query  string
+	Kinds  []string
+	Limit  int
+	Offset int
+}
+
+type SearchService interface {
+	// Search runs a ranked full-text query limited to projects the caller can access
+	Search(ctx context.Context, userID uint, role string, dto SearchDTO) ([]*models.SearchHit, error)
+}
+
+type searchService struct {
+	repo    repository.SearchRepository
+	members MembershipService
+}
+
+func NewSearchService(r repository.SearchRepository, m MembershipService) SearchService {
+	return &searchService{repo: r, members: m}
+}
+
+func (s *searchService) Search(ctx context.Context, userID uint, role string, dto SearchDTO) ([]*models.SearchHit, error) {
+	q := strings.TrimSpace(dto.Query)
+	if q == "" {
+		return nil, invalid("query is required")
+	}
+	for _, k := range dto.Kinds {
+		if k != repository.SearchKindDefect && k != repository.SearchKindComment && k != repository.SearchKindAttachment {
+			return nil, invalid("invalid kind: %s", k)
+		}
+	}
+	limit := dto.Limit
+	if limit <= 0 {
+		limit = defaultSearchLimit
+	}
+	if limit > maxSearchLimit {
+		limit = maxSearchLimit
+	}
+	offset := dto.Offset
+	if offset < 0 {
+		offset = 0
+	}
+	ids, all, err := s.members.AccessibleProjectIDs(ctx, userID, role)
+	if err != nil {
+		return nil, err
+	}
+	if !all && len(ids) == 0 {
+		return []*models.SearchHit{}, nil
+	}
+	return s.repo.Search(ctx, repository.SearchParams{
+		Query:           q,
+		ProjectIDs:      ids,
+		AllProjects:     all,
+		IncludeInternal: CanViewInternalComments(role),
+		Kinds:           dto.Kinds,
+		Limit:           limit,
+		Offset:          offset,
+	})
+}
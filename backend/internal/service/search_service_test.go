@@ -0,0 +1,81 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"example.com/defect-control-system/internal/models"
+	"example.com/defect-control-system/internal/repository"
+	"example.com/defect-control-system/internal/service"
+)
+
+// mockSearchRepo records the params of the last query
+type mockSearchRepo struct {
+	last  *repository.SearchParams
+	calls int
+}
+
+func (m *mockSearchRepo) Search(ctx context.Context, p repository.SearchParams) ([]*models.SearchHit, error) {
+	m.last = &p
+	m.calls++
+	return []*models.SearchHit{{Kind: "defect", ID: 1}}, nil
+}
+
+// mockMemberRepo: user 1 is a member of project 7, everybody else of nothing
+type mockMemberRepo struct{}
+
+func (m *mockMemberRepo) Add(ctx context.Context, pm *models.ProjectMember) error { return nil }
+func (m *mockMemberRepo) Remove(ctx context.Context, projectID, userID uint) error {
+	return nil
+}
+func (m *mockMemberRepo) Find(ctx context.Context, projectID, userID uint) (*models.ProjectMember, error) {
+	if projectID == 7 && userID == 1 {
+		return &models.ProjectMember{ProjectID: 7, UserID: 1, Role: models.ProjectRoleMember}, nil
+	}
+	return nil, assert.AnError
+}
+func (m *mockMemberRepo) ListByProject(ctx context.Context, projectID uint) ([]*models.ProjectMember, error) {
+	return nil, nil
+}
+func (m *mockMemberRepo) ProjectIDsByUser(ctx context.Context, userID uint) ([]uint, error) {
+	if userID == 1 {
+		return []uint{7}, nil
+	}
+	return nil, nil
+}
+
+func TestSearch_AccessFiltering(t *testing.T) {
+	repo := &mockSearchRepo{}
+	members := service.NewMembershipService(&mockMemberRepo{}, &mockProjectRepo{}, &mockUserRepo{})
+	s := service.NewSearchService(repo, members)
+	ctx := context.Background()
+
+	_, err := s.Search(ctx, 1, "engineer", service.SearchDTO{Query: "  "})
+	assert.Error(t, err)
+
+	// stakeholder member: restricted to own projects, no internal comments
+	_, err = s.Search(ctx, 1, "stakeholder", service.SearchDTO{Query: "waterproofing", Limit: 1000})
+	assert.NoError(t, err)
+	assert.Equal(t, []uint{7}, repo.last.ProjectIDs)
+	assert.False(t, repo.last.AllProjects)
+	assert.False(t, repo.last.IncludeInternal)
+	assert.Equal(t, 100, repo.last.Limit)
+
+	// manager sees all projects
+	_, err = s.Search(ctx, 2, "manager", service.SearchDTO{Query: "waterproofing"})
+	assert.NoError(t, err)
+	assert.True(t, repo.last.AllProjects)
+	assert.True(t, repo.last.IncludeInternal)
+
+	// engineer without memberships gets nothing and the repo is not queried
+	calls := repo.calls
+	hits, err := s.Search(ctx, 3, "engineer", service.SearchDTO{Query: "waterproofing"})
+	assert.NoError(t, err)
+	assert.Empty(t, hits)
+	assert.Equal(t, calls, repo.calls)
+
+	_, err = s.Search(ctx, 1, "engineer", service.SearchDTO{Query: "x", Kinds: []string{"users"}})
+	assert.Error(t, err)
+}
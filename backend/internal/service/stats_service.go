@@ -0,0 +1,24 @@
+package service
+
+import (
+	"context"
+
+	"example.com/defect-control-system/internal/models"
+	"example.com/defect-control-system/internal/repository"
+)
+
+type StatsService interface {
+	ProjectStats(ctx context.Context, projectID uint) (*models.ProjectStats, error)
+}
+
+type statsService struct {
+	repo repository.StatsRepository
+}
+
+func NewStatsService(r repository.StatsRepository) StatsService {
+	return &statsService{repo: r}
+}
+
+func (s *statsService) ProjectStats(ctx context.Context, projectID uint) (*models.ProjectStats, error) {
+	return s.repo.ProjectStats(ctx, projectID)
+}
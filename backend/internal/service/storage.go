@@ -1,14 +1,19 @@
 package service
 
 import (
+	"bufio"
+	"bytes"
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
 	"mime/multipart"
 	"net/http"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
@@ -17,115 +22,315 @@ import (
 
 type StorageService interface {
 	SaveFile(fileHeader *multipart.FileHeader) (string, int64, error)
+	// StageStream writes a file read from r to a staging area as it arrives,
+	// hashing it on the way. It stops with ErrFileTooLarge as soon as
+	// uploads.max_size is exceeded and with ErrContentType when the first
+	// bytes are of a type not allowed
+	StageStream(r io.Reader) (*StagedFile, error)
+	// StageFile hashes a file already under the storage root, such as a
+	// finished partial upload, so it can be placed as a blob
+	StageFile(path string) (*StagedFile, error)
+	// PlaceBlob links a staged file at BlobPath of its hash, replacing an
+	// identical copy. The staged file stays until RemoveFile
+	PlaceBlob(st *StagedFile) error
+	// CreatePart starts an empty partial file for a resumable upload and
+	// returns its path relative to the storage root
+	CreatePart(id string) (string, error)
+	// AppendPart appends at most max bytes from r to a partial file holding
+	// offset bytes and returns how many were stored. Bytes past offset left by
+	// an interrupted earlier append are dropped first; a shorter file is
+	// ErrPartOffset. A body cut short still keeps what arrived
+	AppendPart(path string, offset int64, r io.Reader, max int64) (int64, error)
+	// RemoveFile removes a file under the storage root; a missing one is fine
+	RemoveFile(path string) error
+	// OpenFile opens a file under the storage root for reading
+	OpenFile(path string) (*os.File, error)
+	// Quarantine moves a file under the storage root aside, where it is not
+	// served, and returns its new path. A file moved already is fine
+	Quarantine(path string) (string, error)
 }
 
+// StagedFile is a file written to storage whose content hash is known
+type StagedFile struct {
+	// Path is relative to the storage root
+	Path   string
+	SHA256 string
+	Size   int64
+}
+
+// BlobPath is where content with the hex SHA-256 sha is stored, relative to
+// the storage root
+func BlobPath(sha string) string {
+	return filepath.Join("blobs", sha[:2], sha[2:4], sha)
+}
+
+var (
+	// ErrFileTooLarge is returned when a file exceeds uploads.max_size
+	ErrFileTooLarge = errors.New("file too large")
+	// ErrContentType is returned for files of a type not in uploads.allowed_types
+	ErrContentType = errors.New("disallowed content type")
+)
+
+// ErrPartOffset is returned when a partial file holds fewer bytes than the
+// upload offset says, i.e. it was lost or truncated on disk
+var ErrPartOffset = errors.New("partial upload does not match its offset")
+
+const (
+	// partialDir holds unfinished resumable uploads, relative to the storage root
+	partialDir = ".partial"
+	// stagingDir holds uploaded files until they are placed as blobs
+	stagingDir = ".staging"
+	// quarantineDir holds files the scanner found infected
+	quarantineDir = ".quarantine"
+)
+
 type localStorage struct {
 	basePath string
 }
 
 func NewLocalStorage() StorageService {
+	return &localStorage{basePath: UploadsPath()}
+}
+
+// UploadsPath returns the configured directory stored files live under
+func UploadsPath() string {
 	base := viper.GetString("uploads.path")
 	if base == "" {
 		base = "./uploads"
 	}
-	return &localStorage{basePath: base}
+	return base
 }
 
 func (s *localStorage) SaveFile(fh *multipart.FileHeader) (string, int64, error) {
+	// check configured max size (if provided)
+	max := viper.GetInt64("uploads.max_size")
+	if max > 0 && fh.Size > max {
+		return "", 0, ErrFileTooLarge
+	}
 	src, err := fh.Open()
 	if err != nil {
 		return "", 0, err
 	}
 	defer src.Close()
-	// check configured max size (if provided)
-	max := viper.GetInt64("uploads.max_size")
-	if max > 0 && fh.Size > 0 && fh.Size > max {
-		return "", 0, fmt.Errorf("file too large")
+	st, err := s.StageStream(src)
+	if err != nil {
+		return "", 0, err
 	}
-	// generate safe path
-	now := time.Now()
-	dir := filepath.Join(s.basePath, fmt.Sprintf("%04d", now.Year()), fmt.Sprintf("%02d", now.Month()), fmt.Sprintf("%02d", now.Day()))
-	if err := os.MkdirAll(dir, 0o755); err != nil {
+	full, err := s.newFilePath(filepath.Ext(fh.Filename))
+	if err != nil {
+		s.RemoveFile(st.Path)
 		return "", 0, err
 	}
-	// random filename
-	b := make([]byte, 12)
-	if _, err := rand.Read(b); err != nil {
+	if err := os.Rename(filepath.Join(s.basePath, st.Path), full); err != nil {
+		s.RemoveFile(st.Path)
 		return "", 0, err
 	}
-	ext := filepath.Ext(fh.Filename)
-	name := hex.EncodeToString(b) + ext
-	full := filepath.Join(dir, name)
-	dst, err := os.Create(full)
+	// return path relative to base
+	rel, err := filepath.Rel(s.basePath, full)
 	if err != nil {
-		return "", 0, err
+		rel = full
 	}
-	defer dst.Close()
+	return rel, st.Size, nil
+}
+
+func (s *localStorage) StageStream(r io.Reader) (*StagedFile, error) {
 	// read first bytes to detect content type
 	buf := make([]byte, 512)
-	nread, _ := io.ReadFull(src, buf)
+	nread, err := io.ReadFull(r, buf)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return nil, err
+	}
 	detected := http.DetectContentType(buf[:nread])
-	// allowed types check
+	if !typeAllowed(detected) {
+		return nil, fmt.Errorf("%w: %s", ErrContentType, detected)
+	}
+	if err := os.MkdirAll(filepath.Join(s.basePath, stagingDir), 0o755); err != nil {
+		return nil, err
+	}
+	dst, err := os.CreateTemp(filepath.Join(s.basePath, stagingDir), "upload-*")
+	if err != nil {
+		return nil, err
+	}
+	st := &StagedFile{Path: filepath.Join(stagingDir, filepath.Base(dst.Name()))}
+	src := io.MultiReader(bytes.NewReader(buf[:nread]), r)
+	max := viper.GetInt64("uploads.max_size")
+	if max > 0 {
+		// one byte over the limit is enough to know
+		src = io.LimitReader(src, max+1)
+	}
+	h := sha256.New()
+	st.Size, err = io.Copy(io.MultiWriter(dst, h), src)
+	if err == nil && max > 0 && st.Size > max {
+		err = ErrFileTooLarge
+	}
+	if cerr := dst.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(dst.Name())
+		return nil, err
+	}
+	st.SHA256 = hex.EncodeToString(h.Sum(nil))
+	return st, nil
+}
+
+func (s *localStorage) StageFile(path string) (*StagedFile, error) {
+	f, err := os.Open(filepath.Join(s.basePath, path))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	h := sha256.New()
+	n, err := io.Copy(h, f)
+	if err != nil {
+		return nil, err
+	}
+	return &StagedFile{Path: path, SHA256: hex.EncodeToString(h.Sum(nil)), Size: n}, nil
+}
+
+func (s *localStorage) PlaceBlob(st *StagedFile) error {
+	full := filepath.Join(s.basePath, BlobPath(st.SHA256))
+	if err := os.MkdirAll(filepath.Dir(full), 0o755); err != nil {
+		return err
+	}
+	// link under a temporary name, then rename over: readers of an existing
+	// copy never see a missing file
+	tmp := full + ".tmp" + strconv.FormatInt(time.Now().UnixNano(), 36)
+	if err := os.Link(filepath.Join(s.basePath, st.Path), tmp); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, full); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+// newFilePath picks a random name with the extension in today's directory,
+// creating the directory
+func (s *localStorage) newFilePath(ext string) (string, error) {
+	now := time.Now()
+	dir := filepath.Join(s.basePath, fmt.Sprintf("%04d", now.Year()), fmt.Sprintf("%02d", now.Month()), fmt.Sprintf("%02d", now.Day()))
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, hex.EncodeToString(b)+ext), nil
+}
+
+// typeAllowed checks a detected content type against uploads.allowed_types,
+// which may hold wildcards like image/*
+func typeAllowed(detected string) bool {
 	allowed := viper.GetStringSlice("uploads.allowed_types")
-	if len(allowed) > 0 {
-		ok := false
-		for _, a := range allowed {
-			if a == detected {
-				ok = true
-				break
-			}
-			// support wildcard like image/*
-			if strings.HasSuffix(a, "/*") {
-				prefix := strings.TrimSuffix(a, "*")
-				if strings.HasPrefix(detected, prefix) {
-					ok = true
-					break
-				}
-			}
+	if len(allowed) == 0 {
+		return true
+	}
+	for _, a := range allowed {
+		if a == detected {
+			return true
 		}
-		if !ok {
-			// cleanup
-			dst.Close()
-			os.Remove(full)
-			return "", 0, fmt.Errorf("disallowed content type: %s", detected)
+		if strings.HasSuffix(a, "/*") && strings.HasPrefix(detected, strings.TrimSuffix(a, "*")) {
+			return true
 		}
 	}
+	return false
+}
 
-	// write initial buffer and then the rest
-	wn, err := dst.Write(buf[:nread])
+func (s *localStorage) CreatePart(id string) (string, error) {
+	if err := os.MkdirAll(filepath.Join(s.basePath, partialDir), 0o755); err != nil {
+		return "", err
+	}
+	rel := filepath.Join(partialDir, filepath.Base(id))
+	f, err := os.OpenFile(filepath.Join(s.basePath, rel), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
 	if err != nil {
-		os.Remove(full)
-		return "", 0, err
+		return "", err
 	}
-	total := int64(wn)
-	// copy rest, but monitor max size if needed
-	if max > 0 {
-		// limited reader ensuring we don't read beyond max
-		lr := &io.LimitedReader{R: src, N: max - total}
-		m, err := io.Copy(dst, lr)
-		total += m
-		if err != nil && err != io.EOF {
-			os.Remove(full)
-			return "", 0, err
+	return rel, f.Close()
+}
+
+// readErr remembers why reading the request body stopped
+type readErr struct {
+	r   io.Reader
+	err error
+}
+
+func (r *readErr) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	if err != nil && err != io.EOF {
+		r.err = err
+	}
+	return n, err
+}
+
+func (s *localStorage) AppendPart(path string, offset int64, r io.Reader, max int64) (int64, error) {
+	f, err := os.OpenFile(filepath.Join(s.basePath, path), os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return 0, err
+	}
+	switch {
+	case fi.Size() < offset:
+		return 0, ErrPartOffset
+	case fi.Size() > offset:
+		// a previous append was stored but its offset was not
+		if err := f.Truncate(offset); err != nil {
+			return 0, err
 		}
-		if lr.N == 0 {
-			// exceeded
-			dst.Close()
-			os.Remove(full)
-			return "", 0, fmt.Errorf("file too large")
+	}
+	src := io.LimitReader(r, max)
+	if offset == 0 {
+		// the first chunk decides the content type, as in SaveFile
+		br := bufio.NewReaderSize(src, 512)
+		head, _ := br.Peek(512)
+		if detected := http.DetectContentType(head); !typeAllowed(detected) {
+			return 0, fmt.Errorf("%w: %s", ErrContentType, detected)
 		}
-	} else {
-		m, err := io.Copy(dst, src)
-		if err != nil {
-			os.Remove(full)
-			return "", 0, err
+		src = br
+	}
+	rr := &readErr{r: src}
+	n, err := io.Copy(f, rr)
+	if err != nil && rr.err == nil {
+		// the disk failed, not the client
+		return n, err
+	}
+	if err := f.Sync(); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+func (s *localStorage) RemoveFile(path string) error {
+	err := os.Remove(filepath.Join(s.basePath, path))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+func (s *localStorage) OpenFile(path string) (*os.File, error) {
+	return os.Open(filepath.Join(s.basePath, path))
+}
+
+func (s *localStorage) Quarantine(path string) (string, error) {
+	dst := filepath.Join(quarantineDir, filepath.Base(path))
+	if err := os.MkdirAll(filepath.Join(s.basePath, quarantineDir), 0o700); err != nil {
+		return "", err
+	}
+	err := os.Rename(filepath.Join(s.basePath, path), filepath.Join(s.basePath, dst))
+	if os.IsNotExist(err) {
+		if _, serr := os.Stat(filepath.Join(s.basePath, dst)); serr == nil {
+			return dst, nil
 		}
-		total += m
 	}
-	// return path relative to base
-	rel, err := filepath.Rel(s.basePath, full)
 	if err != nil {
-		rel = full
+		return "", err
 	}
-	return rel, total, nil
+	return dst, os.Chmod(filepath.Join(s.basePath, dst), 0o400)
 }
@@ -17,6 +17,7 @@ import (
 
 	"example.com/defect-control-system/internal/handler"
 	"example.com/defect-control-system/internal/models"
+	"example.com/defect-control-system/internal/repository"
 	"example.com/defect-control-system/internal/service"
 )
 
@@ -90,7 +91,7 @@ func TestDownloadHandler(t *testing.T) {
 	ar := &mockAttachRepoFile{path: filepath.Join("2025", "10", "11", "file.jpg"), fname: "file.jpg"}
 	ds := &mockDefectSvc{}
 	storage := service.NewLocalStorage()
-	h := handler.NewAttachmentHandler(storage, ar, ds)
+	h := handler.NewAttachmentHandler(storage, ar, ds, service.NewAttachmentService(ar, nil, nil), nil)
 
 	r := gin.Default()
 	// test-only middleware to inject authenticated user context
@@ -105,18 +106,38 @@ func TestDownloadHandler(t *testing.T) {
 	r.ServeHTTP(rr, req)
 	assert.Equal(t, http.StatusOK, rr.Code)
 	assert.Equal(t, string(content), rr.Body.String())
+
+	// held back until the virus scan passes
+	ar.status = models.AttachmentPendingScan
+	rr = httptest.NewRecorder()
+	r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/attachments/1", nil))
+	assert.Equal(t, http.StatusLocked, rr.Code)
+	assert.NotEmpty(t, rr.Header().Get("Retry-After"))
+	ar.status = models.AttachmentInfected
+	rr = httptest.NewRecorder()
+	r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/attachments/1", nil))
+	assert.Equal(t, http.StatusForbidden, rr.Code)
 }
 
 // mock repo returning our file
-type mockAttachRepoFile struct{ path, fname string }
+type mockAttachRepoFile struct{ path, fname, status string }
 
 func (m *mockAttachRepoFile) Create(ctx context.Context, a *models.Attachment) error { return nil }
 func (m *mockAttachRepoFile) FindByID(ctx context.Context, id uint) (*models.Attachment, error) {
-	return &models.Attachment{ID: id, Path: m.path, Filename: m.fname, ContentType: "text/plain", UploaderID: 1}, nil
+	return &models.Attachment{ID: id, Path: m.path, Filename: m.fname, ContentType: "text/plain", UploaderID: 1, ScanStatus: m.status}, nil
 }
 func (m *mockAttachRepoFile) ListByDefect(ctx context.Context, defectID uint) ([]*models.Attachment, error) {
 	return []*models.Attachment{{ID: 1, Path: m.path, Filename: m.fname}}, nil
 }
+func (m *mockAttachRepoFile) ListVersions(ctx context.Context, rootID uint) ([]*models.Attachment, error) {
+	return nil, nil
+}
+func (m *mockAttachRepoFile) ListByProject(ctx context.Context, projectID uint) ([]*models.Attachment, error) {
+	return nil, nil
+}
+func (m *mockAttachRepoFile) Update(ctx context.Context, a *models.Attachment, columns []string) error {
+	return nil
+}
 
 // reuse mockDefectSvc from handler tests
 type mockDefectSvc struct{}
@@ -127,9 +148,18 @@ func (m *mockDefectSvc) Create(ctx context.Context, dto service.CreateDefectDTO)
 func (m *mockDefectSvc) ListByProject(ctx context.Context, projectID uint) ([]*models.Defect, error) {
 	return []*models.Defect{}, nil
 }
+func (m *mockDefectSvc) ListFiltered(ctx context.Context, q repository.DefectQuery) ([]*models.Defect, error) {
+	return []*models.Defect{}, nil
+}
 func (m *mockDefectSvc) FindByID(ctx context.Context, id uint) (*models.Defect, error) {
 	return &models.Defect{ID: id, Title: "mock"}, nil
 }
-func (m *mockDefectSvc) Update(ctx context.Context, id uint, dto service.UpdateDefectDTO) (*models.Defect, error) {
+func (m *mockDefectSvc) FindByKey(ctx context.Context, key string) (*models.Defect, error) {
+	return &models.Defect{ID: 1, Key: key, Title: "mock"}, nil
+}
+func (m *mockDefectSvc) Bulk(ctx context.Context, userID uint, role string, projectID uint, dto service.BulkDefectsDTO) (*service.BulkResult, error) {
+	return &service.BulkResult{Applied: true}, nil
+}
+func (m *mockDefectSvc) Update(ctx context.Context, userID uint, role string, id uint, dto service.UpdateDefectDTO) (*models.Defect, error) {
 	return &models.Defect{ID: id, Title: "mock"}, nil
 }
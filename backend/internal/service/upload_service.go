@@ -0,0 +1,290 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"hash/fnv"
+	"io"
+	"mime"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"example.com/defect-control-system/internal/models"
+	"example.com/defect-control-system/internal/repository"
+)
+
+const (
+	// DefaultUploadMaxSize applies to resumable uploads when uploads.max_size is not set
+	DefaultUploadMaxSize = 2 << 30
+	// DefaultUploadTTL is how long an upload session survives without a chunk
+	DefaultUploadTTL = 24 * time.Hour
+)
+
+var (
+	// ErrUploadOffset is returned when a chunk does not start where the upload stands
+	ErrUploadOffset = errors.New("upload offset does not match")
+	// ErrUploadExpired is returned for chunks of a session past its expiry
+	ErrUploadExpired = errors.New("upload session expired")
+	// ErrUploadTooLarge is returned when the declared length exceeds the limit
+	ErrUploadTooLarge = errors.New("upload exceeds the maximum size")
+)
+
+// CreateUploadDTO declares a resumable upload of one file to a defect
+type CreateUploadDTO struct {
+	DefectID    uint
+	Filename    string
+	ContentType string
+	Kind        string
+	BeforeID    *uint
+	// Replaces makes the file a new version of that attachment; DefectID,
+	// Kind and BeforeID then come from it
+	Replaces uint
+	Length   int64
+}
+
+// UploadService runs resumable uploads of defect attachments: a session is
+// created with the file length, chunks are appended at the offset stored so
+// far, and the last chunk turns the file into an Attachment. Sessions belong
+// to the user who created them.
+type UploadService interface {
+	Create(ctx context.Context, userID uint, role string, dto CreateUploadDTO) (*models.UploadSession, error)
+	Get(ctx context.Context, userID uint, id string) (*models.UploadSession, error)
+	// Append stores the chunk read from r, which must start at offset
+	Append(ctx context.Context, userID uint, id string, offset int64, r io.Reader) (*models.UploadSession, error)
+	// Terminate drops a session and its partial file; a created attachment stays
+	Terminate(ctx context.Context, userID uint, id string) error
+	// ExpireSessions removes sessions past their expiry and returns how many
+	ExpireSessions(ctx context.Context) (int, error)
+	MaxSize() int64
+	TTL() time.Duration
+}
+
+type uploadService struct {
+	repo        repository.UploadRepository
+	storage     StorageService
+	attachments AttachmentService
+	defectRepo  repository.DefectRepository
+	members     MembershipService
+	scans       ScanService
+	quotas      QuotaService
+	maxSize     int64
+	ttl         time.Duration
+	// locks serialize chunks of the same session
+	locks [64]sync.Mutex
+}
+
+// NewUploadService limits uploads to maxSize bytes and expires sessions idle
+// for ttl; zero values select the defaults
+func NewUploadService(r repository.UploadRepository, st StorageService, as AttachmentService, dr repository.DefectRepository, m MembershipService, sc ScanService, q QuotaService, maxSize int64, ttl time.Duration) UploadService {
+	if maxSize <= 0 {
+		maxSize = DefaultUploadMaxSize
+	}
+	if ttl <= 0 {
+		ttl = DefaultUploadTTL
+	}
+	return &uploadService{repo: r, storage: st, attachments: as, defectRepo: dr, members: m, scans: sc, quotas: q, maxSize: maxSize, ttl: ttl}
+}
+
+func (s *uploadService) MaxSize() int64     { return s.maxSize }
+func (s *uploadService) TTL() time.Duration { return s.ttl }
+
+func (s *uploadService) lock(id string) func() {
+	h := fnv.New32a()
+	h.Write([]byte(id))
+	m := &s.locks[h.Sum32()%uint32(len(s.locks))]
+	m.Lock()
+	return m.Unlock
+}
+
+func (s *uploadService) Create(ctx context.Context, userID uint, role string, dto CreateUploadDTO) (*models.UploadSession, error) {
+	if dto.Length <= 0 {
+		return nil, invalid("upload length must be positive")
+	}
+	if dto.Length > s.maxSize {
+		return nil, ErrUploadTooLarge
+	}
+	filename := strings.TrimSpace(filepath.Base(strings.ReplaceAll(dto.Filename, "\\", "/")))
+	if filename == "" || filename == "." || filename == "/" {
+		filename = "upload"
+	}
+	contentType := dto.ContentType
+	if contentType == "" {
+		contentType = mime.TypeByExtension(filepath.Ext(filename))
+	}
+	a := &models.Attachment{DefectID: dto.DefectID, Filename: filename, ContentType: contentType, Kind: dto.Kind}
+	if dto.Replaces != 0 {
+		if err := s.attachments.PrepareVersion(ctx, a, dto.Replaces); err != nil {
+			return nil, err
+		}
+	}
+	d, err := s.defectRepo.FindByID(ctx, a.DefectID)
+	if err != nil || d == nil {
+		return nil, ErrNotFound
+	}
+	ok, err := s.members.CanAccessProject(ctx, userID, role, d.ProjectID)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrForbidden
+	}
+	if dto.Replaces == 0 {
+		if err := s.attachments.Prepare(ctx, a, dto.BeforeID); err != nil {
+			return nil, err
+		}
+	}
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+	u := &models.UploadSession{
+		ID: hex.EncodeToString(b), DefectID: d.ID, UploaderID: userID,
+		Filename: filename, ContentType: a.ContentType, Kind: a.Kind, BeforeID: a.BeforeID,
+		Length: dto.Length, ExpiresAt: time.Now().Add(s.ttl),
+	}
+	if dto.Replaces != 0 {
+		u.ReplacesID = &dto.Replaces
+	}
+	if u.PartPath, err = s.storage.CreatePart(u.ID); err != nil {
+		return nil, err
+	}
+	// the declared length is reserved until the upload ends
+	if err := s.repo.Create(ctx, u, s.quotas.Guard(d.ID, dto.Length)); err != nil {
+		s.storage.RemoveFile(u.PartPath)
+		return nil, err
+	}
+	return u, nil
+}
+
+// find returns a session of the user
+func (s *uploadService) find(ctx context.Context, userID uint, id string) (*models.UploadSession, error) {
+	u, err := s.repo.FindByID(ctx, id)
+	if err != nil || u == nil || u.UploaderID != userID {
+		return nil, ErrNotFound
+	}
+	return u, nil
+}
+
+func (s *uploadService) Get(ctx context.Context, userID uint, id string) (*models.UploadSession, error) {
+	return s.find(ctx, userID, id)
+}
+
+func (s *uploadService) Append(ctx context.Context, userID uint, id string, offset int64, r io.Reader) (*models.UploadSession, error) {
+	defer s.lock(id)()
+	u, err := s.find(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if offset != u.Offset {
+		return nil, ErrUploadOffset
+	}
+	if u.AttachmentID != nil {
+		// the last chunk was stored; a retry of it changes nothing
+		return u, nil
+	}
+	if time.Now().After(u.ExpiresAt) {
+		return nil, ErrUploadExpired
+	}
+	if !u.Complete() {
+		n, err := s.storage.AppendPart(u.PartPath, u.Offset, r, u.Length-u.Offset)
+		if n > 0 {
+			from := u.Offset
+			u.Offset += n
+			u.ExpiresAt = time.Now().Add(s.ttl)
+			// the client may be gone already; what arrived is kept for the resume
+			if uerr := s.repo.UpdateOffset(context.WithoutCancel(ctx), u, from); uerr != nil {
+				if errors.Is(uerr, repository.ErrVersionConflict) {
+					return nil, ErrUploadOffset
+				}
+				return nil, uerr
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+		if !u.Complete() {
+			return u, nil
+		}
+	}
+	return u, s.finish(context.WithoutCancel(ctx), u)
+}
+
+// finish turns the received file into an attachment of the defect
+func (s *uploadService) finish(ctx context.Context, u *models.UploadSession) error {
+	st, err := s.storage.StageFile(u.PartPath)
+	if err != nil {
+		return err
+	}
+	if st.Size != u.Length {
+		return ErrPartOffset
+	}
+	a := &models.Attachment{
+		DefectID: u.DefectID, UploaderID: u.UploaderID, Filename: u.Filename,
+		ContentType: u.ContentType, Kind: u.Kind, BeforeID: u.BeforeID,
+	}
+	if u.ReplacesID != nil {
+		// follow the latest version now, not the one current at creation
+		if err := s.attachments.PrepareVersion(ctx, a, *u.ReplacesID); err != nil {
+			return err
+		}
+	}
+	s.scans.Hold(a)
+	if err := s.repo.Complete(ctx, u, a, blobOf(st), func() error { return s.storage.PlaceBlob(st) }); err != nil {
+		// the partial file stays for another attempt
+		u.AttachmentID = nil
+		return versionError(err)
+	}
+	s.storage.RemoveFile(u.PartPath)
+	s.scans.Submit(a)
+	// reported on QuotaService.Warnings
+	_ = s.quotas.Observe(ctx, a.DefectID)
+	return nil
+}
+
+func (s *uploadService) Terminate(ctx context.Context, userID uint, id string) error {
+	defer s.lock(id)()
+	u, err := s.find(ctx, userID, id)
+	if err != nil {
+		return err
+	}
+	if err := s.storage.RemoveFile(u.PartPath); err != nil {
+		return err
+	}
+	return s.repo.Delete(ctx, u.ID)
+}
+
+func (s *uploadService) ExpireSessions(ctx context.Context) (int, error) {
+	now := time.Now()
+	list, err := s.repo.Expired(ctx, now)
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for _, u := range list {
+		expired, err := s.expire(ctx, u.ID, now)
+		if err != nil {
+			return n, err
+		}
+		if expired {
+			n++
+		}
+	}
+	return n, nil
+}
+
+// expire removes a session unless a chunk arrived since it was listed
+func (s *uploadService) expire(ctx context.Context, id string, now time.Time) (bool, error) {
+	defer s.lock(id)()
+	u, err := s.repo.FindByID(ctx, id)
+	if err != nil || u.ExpiresAt.After(now) {
+		return false, nil
+	}
+	if err := s.storage.RemoveFile(u.PartPath); err != nil {
+		return false, err
+	}
+	return true, s.repo.Delete(ctx, u.ID)
+}
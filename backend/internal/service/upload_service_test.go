@@ -0,0 +1,161 @@
+package service_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"example.com/defect-control-system/internal/models"
+	"example.com/defect-control-system/internal/repository"
+	"example.com/defect-control-system/internal/service"
+)
+
+// memUploadRepo keeps upload sessions in memory, creating attachments in attach
+type memUploadRepo struct {
+	sessions map[string]*models.UploadSession
+	attach   *memAttachRepo
+}
+
+func (m *memUploadRepo) Create(ctx context.Context, s *models.UploadSession, quota *repository.QuotaGuard) error {
+	c := *s
+	m.sessions[s.ID] = &c
+	return nil
+}
+func (m *memUploadRepo) FindByID(ctx context.Context, id string) (*models.UploadSession, error) {
+	s, ok := m.sessions[id]
+	if !ok {
+		return nil, errors.New("not found")
+	}
+	c := *s
+	return &c, nil
+}
+func (m *memUploadRepo) UpdateOffset(ctx context.Context, s *models.UploadSession, from int64) error {
+	m.sessions[s.ID].Offset, m.sessions[s.ID].ExpiresAt = s.Offset, s.ExpiresAt
+	return nil
+}
+func (m *memUploadRepo) Complete(ctx context.Context, s *models.UploadSession, a *models.Attachment, b *models.Blob, place func() error) error {
+	if err := place(); err != nil {
+		return err
+	}
+	a.Path, a.SHA256, a.Size = b.Path, b.SHA256, b.Size
+	if err := m.attach.Create(ctx, a); err != nil {
+		return err
+	}
+	s.AttachmentID = &a.ID
+	m.sessions[s.ID].AttachmentID = &a.ID
+	return nil
+}
+func (m *memUploadRepo) Expired(ctx context.Context, t time.Time) ([]*models.UploadSession, error) {
+	var list []*models.UploadSession
+	for _, s := range m.sessions {
+		if s.ExpiresAt.Before(t) {
+			list = append(list, s)
+		}
+	}
+	return list, nil
+}
+func (m *memUploadRepo) Delete(ctx context.Context, id string) error {
+	delete(m.sessions, id)
+	return nil
+}
+
+// brokenReader yields its data and then fails like a dropped connection
+type brokenReader struct{ data []byte }
+
+func (r *brokenReader) Read(p []byte) (int, error) {
+	if len(r.data) == 0 {
+		return 0, errors.New("connection reset by peer")
+	}
+	n := copy(p, r.data)
+	r.data = r.data[n:]
+	return n, nil
+}
+
+func TestUploads_ResumeAndFinish(t *testing.T) {
+	dir := t.TempDir()
+	viper.Set("uploads.path", dir)
+	viper.Set("uploads.allowed_types", []string{"image/jpeg"})
+	defer viper.Set("uploads.allowed_types", nil)
+
+	attach := &memAttachRepo{}
+	repo := &memUploadRepo{sessions: map[string]*models.UploadSession{}, attach: attach}
+	defects := &actDefectRepo{defects: map[uint]*models.Defect{1: {ID: 1, ProjectID: 7}}}
+	members := service.NewMembershipService(&mockMemberRepo{}, &mockProjectRepo{}, &mockUserRepo{})
+	s := service.NewUploadService(repo, service.NewLocalStorage(), service.NewAttachmentService(attach, defects, members), defects, members, service.NewScanService(nil, nil, nil), service.NewQuotaService(nil, nil, 0), 4096, time.Hour)
+	ctx := context.Background()
+
+	data := append([]byte("\xff\xd8\xff\xdb"), bytes.Repeat([]byte{7}, 996)...)
+	dto := service.CreateUploadDTO{DefectID: 1, Filename: `C:\site\roof.jpg`, Length: int64(len(data))}
+	_, err := s.Create(ctx, 2, "engineer", dto)
+	assert.ErrorIs(t, err, service.ErrForbidden)
+	_, err = s.Create(ctx, 1, "engineer", service.CreateUploadDTO{DefectID: 1, Filename: "big.jpg", Length: 5000})
+	assert.ErrorIs(t, err, service.ErrUploadTooLarge)
+
+	u, err := s.Create(ctx, 1, "engineer", dto)
+	require.NoError(t, err)
+	assert.Equal(t, "roof.jpg", u.Filename)
+	assert.Equal(t, "image/jpeg", u.ContentType)
+	assert.Equal(t, models.AttachmentEvidence, u.Kind)
+
+	u, err = s.Append(ctx, 1, u.ID, 0, bytes.NewReader(data[:300]))
+	require.NoError(t, err)
+	assert.Equal(t, int64(300), u.Offset)
+	_, err = s.Append(ctx, 1, u.ID, 0, bytes.NewReader(data[:300]))
+	assert.ErrorIs(t, err, service.ErrUploadOffset)
+	_, err = s.Get(ctx, 2, u.ID)
+	assert.ErrorIs(t, err, service.ErrNotFound)
+
+	// the link drops mid-chunk: what arrived is kept
+	u, err = s.Append(ctx, 1, u.ID, 300, &brokenReader{data: data[300:450]})
+	require.NoError(t, err)
+	assert.Equal(t, int64(450), u.Offset)
+	assert.Nil(t, u.AttachmentID)
+
+	// the rest plus trailing garbage: only Length bytes are taken
+	u, err = s.Append(ctx, 1, u.ID, 450, io.MultiReader(bytes.NewReader(data[450:]), bytes.NewReader([]byte("extra"))))
+	require.NoError(t, err)
+	require.NotNil(t, u.AttachmentID)
+	a, err := attach.FindByID(ctx, *u.AttachmentID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(len(data)), a.Size)
+	stored, err := os.ReadFile(filepath.Join(dir, a.Path))
+	require.NoError(t, err)
+	assert.Equal(t, data, stored)
+	_, err = os.Stat(filepath.Join(dir, u.PartPath))
+	assert.True(t, os.IsNotExist(err), "partial file removed")
+
+	// retrying the last chunk does not create a second attachment
+	again, err := s.Append(ctx, 1, u.ID, int64(len(data)), bytes.NewReader(nil))
+	require.NoError(t, err)
+	assert.Equal(t, *u.AttachmentID, *again.AttachmentID)
+	assert.Len(t, attach.list, 1)
+
+	// content type is checked on the first chunk
+	bad, err := s.Create(ctx, 1, "engineer", service.CreateUploadDTO{DefectID: 1, Filename: "notes.jpg", Length: 20})
+	require.NoError(t, err)
+	_, err = s.Append(ctx, 1, bad.ID, 0, bytes.NewReader([]byte("plain text, not jpeg")))
+	assert.Error(t, err)
+	bad, _ = s.Get(ctx, 1, bad.ID)
+	assert.Equal(t, int64(0), bad.Offset)
+
+	// abandoned sessions expire with their partial files
+	repo.sessions[bad.ID].ExpiresAt = time.Now().Add(-time.Minute)
+	_, err = s.Append(ctx, 1, bad.ID, 0, bytes.NewReader(data))
+	assert.ErrorIs(t, err, service.ErrUploadExpired)
+	n, err := s.ExpireSessions(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	_, err = os.Stat(filepath.Join(dir, bad.PartPath))
+	assert.True(t, os.IsNotExist(err))
+	_, err = s.Get(ctx, 1, bad.ID)
+	assert.ErrorIs(t, err, service.ErrNotFound)
+}
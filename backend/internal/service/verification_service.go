@@ -0,0 +1,176 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"example.com/defect-control-system/internal/models"
+	"example.com/defect-control-system/internal/repository"
+)
+
+// SubmitVerificationDTO hands a fixed defect over to verification
+type SubmitVerificationDTO struct {
+	// Comment describes the remediation; it is posted as a public comment
+	Comment string `json:"comment" validate:"required"`
+	// AttachmentIDs are photos of the fix already uploaded to the defect
+	AttachmentIDs []uint `json:"attachment_ids" validate:"required"`
+}
+
+// RejectVerificationDTO returns a fix to the contractor
+type RejectVerificationDTO struct {
+	Reason string `json:"reason" validate:"required"`
+}
+
+// VerificationService runs the verification stage: the contractor submits
+// evidence of the fix, the defect's verifier approves (closing the defect) or
+// rejects it (back to in_progress). Without a designated verifier, project
+// managers verify. Nobody reviews a fix they submitted themselves.
+type VerificationService interface {
+	Submit(ctx context.Context, userID uint, role string, projectID, defectID uint, dto SubmitVerificationDTO) (*models.Verification, error)
+	Approve(ctx context.Context, userID uint, role string, projectID, defectID uint) (*models.Verification, error)
+	Reject(ctx context.Context, userID uint, role string, projectID, defectID uint, dto RejectVerificationDTO) (*models.Verification, error)
+	List(ctx context.Context, userID uint, role string, projectID, defectID uint) ([]*models.Verification, error)
+}
+
+type verificationService struct {
+	repo       repository.VerificationRepository
+	defectRepo repository.DefectRepository
+	attachRepo repository.AttachmentRepository
+	members    MembershipService
+}
+
+func NewVerificationService(r repository.VerificationRepository, dr repository.DefectRepository, ar repository.AttachmentRepository, m MembershipService) VerificationService {
+	return &verificationService{repo: r, defectRepo: dr, attachRepo: ar, members: m}
+}
+
+// defect loads a defect of the project the user can access
+func (s *verificationService) defect(ctx context.Context, userID uint, role string, projectID, defectID uint) (*models.Defect, error) {
+	ok, err := s.members.CanAccessProject(ctx, userID, role, projectID)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrForbidden
+	}
+	d, err := s.defectRepo.FindByID(ctx, defectID)
+	if err != nil || d == nil || d.ProjectID != projectID {
+		return nil, ErrNotFound
+	}
+	return d, nil
+}
+
+func (s *verificationService) Submit(ctx context.Context, userID uint, role string, projectID, defectID uint, dto SubmitVerificationDTO) (*models.Verification, error) {
+	if !CanViewInternalComments(role) {
+		return nil, ErrForbidden
+	}
+	d, err := s.defect(ctx, userID, role, projectID, defectID)
+	if err != nil {
+		return nil, err
+	}
+	if d.Status != StatusInProgress {
+		return nil, &WorkflowError{From: d.Status, To: StatusVerification}
+	}
+	text := strings.TrimSpace(dto.Comment)
+	if text == "" {
+		return nil, invalid("comment is required")
+	}
+	ids := uniqueUints(dto.AttachmentIDs)
+	if len(ids) == 0 {
+		return nil, invalid("at least one photo of the fix is required")
+	}
+	photos := make([]models.Attachment, 0, len(ids))
+	for _, id := range ids {
+		a, err := s.attachRepo.FindByID(ctx, id)
+		if err != nil || a.DefectID != d.ID {
+			return nil, invalid("attachment %d not found on the defect", id)
+		}
+		photos = append(photos, *a)
+	}
+	author := userID
+	c := &models.Comment{DefectID: d.ID, AuthorID: &author, Body: text, Visibility: models.CommentVisibilityPublic}
+	v := &models.Verification{DefectID: d.ID, SubmittedByID: userID, Photos: photos, Decision: models.VerificationPending}
+	d.Status = StatusVerification
+	if err := s.repo.Submit(ctx, v, c, d); err != nil {
+		return nil, versionError(err)
+	}
+	v.Comment = c
+	return v, nil
+}
+
+// pending loads the defect and its pending verification and checks that the
+// user is its verifier and did not submit it
+func (s *verificationService) pending(ctx context.Context, userID uint, role string, projectID, defectID uint) (*models.Defect, *models.Verification, error) {
+	d, err := s.defect(ctx, userID, role, projectID, defectID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if d.VerifierID != nil {
+		if *d.VerifierID != userID {
+			return nil, nil, fmt.Errorf("%w: only the designated verifier can review the fix", ErrForbidden)
+		}
+	} else {
+		ok, err := s.members.CanManageProject(ctx, userID, role, projectID)
+		if err != nil {
+			return nil, nil, err
+		}
+		if !ok {
+			return nil, nil, fmt.Errorf("%w: only project managers can review the fix", ErrForbidden)
+		}
+	}
+	if d.Status != StatusVerification {
+		return nil, nil, invalid("the defect is not awaiting verification")
+	}
+	v, err := s.repo.Pending(ctx, d.ID)
+	if err != nil {
+		return nil, nil, invalid("the defect is not awaiting verification")
+	}
+	if v.SubmittedByID == userID {
+		return nil, nil, fmt.Errorf("%w: the fix is reviewed by someone other than its submitter", ErrForbidden)
+	}
+	return d, v, nil
+}
+
+func (s *verificationService) Approve(ctx context.Context, userID uint, role string, projectID, defectID uint) (*models.Verification, error) {
+	d, v, err := s.pending(ctx, userID, role, projectID, defectID)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	reviewer := userID
+	v.Decision, v.ReviewerID, v.ReviewedAt = models.VerificationApproved, &reviewer, &now
+	d.Status, d.VerifiedByID, d.VerifiedAt = StatusClosed, &reviewer, &now
+	if err := s.repo.Decide(ctx, v, d, []string{"status", "verified_by_id", "verified_at"}); err != nil {
+		return nil, versionError(err)
+	}
+	return v, nil
+}
+
+func (s *verificationService) Reject(ctx context.Context, userID uint, role string, projectID, defectID uint, dto RejectVerificationDTO) (*models.Verification, error) {
+	reason := strings.TrimSpace(dto.Reason)
+	if reason == "" {
+		return nil, invalid("reason is required")
+	}
+	d, v, err := s.pending(ctx, userID, role, projectID, defectID)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	reviewer := userID
+	v.Decision, v.Reason, v.ReviewerID, v.ReviewedAt = models.VerificationRejected, reason, &reviewer, &now
+	d.Status = StatusInProgress
+	d.RejectionCount++
+	if err := s.repo.Decide(ctx, v, d, []string{"status", "rejection_count"}); err != nil {
+		return nil, versionError(err)
+	}
+	return v, nil
+}
+
+func (s *verificationService) List(ctx context.Context, userID uint, role string, projectID, defectID uint) ([]*models.Verification, error) {
+	d, err := s.defect(ctx, userID, role, projectID, defectID)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.ListByDefect(ctx, d.ID)
+}
@@ -0,0 +1,175 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"example.com/defect-control-system/internal/models"
+	"example.com/defect-control-system/internal/service"
+)
+
+// storedDefectRepo serves a single defect
+type storedDefectRepo struct {
+	mockDefectRepo
+	defect *models.Defect
+}
+
+func (m *storedDefectRepo) FindByID(ctx context.Context, id uint) (*models.Defect, error) {
+	if id != m.defect.ID {
+		return nil, errors.New("not found")
+	}
+	c := *m.defect
+	return &c, nil
+}
+
+// photoAttachRepo knows attachments 10 and 11 of defect 1 and 20 of defect 2
+type photoAttachRepo struct{}
+
+func (m *photoAttachRepo) Create(ctx context.Context, a *models.Attachment) error { return nil }
+func (m *photoAttachRepo) FindByID(ctx context.Context, id uint) (*models.Attachment, error) {
+	switch id {
+	case 10, 11:
+		return &models.Attachment{ID: id, DefectID: 1}, nil
+	case 20:
+		return &models.Attachment{ID: id, DefectID: 2}, nil
+	}
+	return nil, errors.New("not found")
+}
+func (m *photoAttachRepo) ListByDefect(ctx context.Context, defectID uint) ([]*models.Attachment, error) {
+	return nil, nil
+}
+func (m *photoAttachRepo) ListVersions(ctx context.Context, rootID uint) ([]*models.Attachment, error) {
+	return nil, nil
+}
+func (m *photoAttachRepo) ListByProject(ctx context.Context, projectID uint) ([]*models.Attachment, error) {
+	return nil, nil
+}
+func (m *photoAttachRepo) Update(ctx context.Context, a *models.Attachment, columns []string) error {
+	return nil
+}
+
+// mockVerificationRepo applies writes to the stored defect
+type mockVerificationRepo struct {
+	defects *storedDefectRepo
+	list    []*models.Verification
+}
+
+func (m *mockVerificationRepo) Submit(ctx context.Context, v *models.Verification, c *models.Comment, d *models.Defect) error {
+	v.ID = uint(len(m.list) + 1)
+	m.list = append(m.list, v)
+	m.defects.defect = d
+	return nil
+}
+func (m *mockVerificationRepo) Decide(ctx context.Context, v *models.Verification, d *models.Defect, columns []string) error {
+	m.defects.defect = d
+	return nil
+}
+func (m *mockVerificationRepo) Pending(ctx context.Context, defectID uint) (*models.Verification, error) {
+	for _, v := range m.list {
+		if v.DefectID == defectID && v.Decision == models.VerificationPending {
+			return v, nil
+		}
+	}
+	return nil, errors.New("not found")
+}
+func (m *mockVerificationRepo) ListByDefect(ctx context.Context, defectID uint) ([]*models.Verification, error) {
+	return m.list, nil
+}
+
+func TestVerification_RejectThenApprove(t *testing.T) {
+	verifier := uint(5)
+	defects := &storedDefectRepo{defect: &models.Defect{ID: 1, ProjectID: 7, Status: "in_progress", VerifierID: &verifier}}
+	repo := &mockVerificationRepo{defects: defects}
+	members := service.NewMembershipService(&mockMemberRepo{}, &mockProjectRepo{}, &mockUserRepo{})
+	s := service.NewVerificationService(repo, defects, &photoAttachRepo{}, members)
+	ctx := context.Background()
+
+	// evidence must be a photo of this defect
+	_, err := s.Submit(ctx, 1, "engineer", 7, 1, service.SubmitVerificationDTO{Comment: "fixed", AttachmentIDs: []uint{20}})
+	assert.Error(t, err)
+	_, err = s.Submit(ctx, 1, "engineer", 7, 1, service.SubmitVerificationDTO{Comment: " ", AttachmentIDs: []uint{10}})
+	assert.Error(t, err)
+
+	v, err := s.Submit(ctx, 1, "engineer", 7, 1, service.SubmitVerificationDTO{Comment: "Sealed the joint", AttachmentIDs: []uint{10, 11, 10}})
+	assert.NoError(t, err)
+	assert.Len(t, v.Photos, 2)
+	assert.Equal(t, "Sealed the joint", v.Comment.Body)
+	assert.Equal(t, "verification", defects.defect.Status)
+
+	// only the designated verifier reviews, a reason is required to reject
+	_, err = s.Approve(ctx, 9, "manager", 7, 1)
+	assert.ErrorIs(t, err, service.ErrForbidden)
+	_, err = s.Reject(ctx, 5, "stakeholder", 7, 1, service.RejectVerificationDTO{})
+	assert.Error(t, err)
+
+	v, err = s.Reject(ctx, 5, "admin", 7, 1, service.RejectVerificationDTO{Reason: "Gap remains at the corner"})
+	assert.NoError(t, err)
+	assert.Equal(t, models.VerificationRejected, v.Decision)
+	assert.Equal(t, "in_progress", defects.defect.Status)
+	assert.Equal(t, 1, defects.defect.RejectionCount)
+
+	_, err = s.Approve(ctx, 5, "admin", 7, 1)
+	assert.Error(t, err, "nothing is pending after a rejection")
+
+	_, err = s.Submit(ctx, 1, "engineer", 7, 1, service.SubmitVerificationDTO{Comment: "Resealed", AttachmentIDs: []uint{11}})
+	assert.NoError(t, err)
+	v, err = s.Approve(ctx, 5, "admin", 7, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, models.VerificationApproved, v.Decision)
+	assert.Equal(t, "closed", defects.defect.Status)
+	assert.Equal(t, uint(5), *defects.defect.VerifiedByID)
+	assert.NotNil(t, defects.defect.VerifiedAt)
+	assert.Equal(t, 1, defects.defect.RejectionCount)
+
+	// a closed defect cannot be submitted again
+	_, err = s.Submit(ctx, 1, "engineer", 7, 1, service.SubmitVerificationDTO{Comment: "again", AttachmentIDs: []uint{11}})
+	var flowErr *service.WorkflowError
+	assert.ErrorAs(t, err, &flowErr)
+}
+
+func TestVerification_ManagersReviewWithoutVerifier(t *testing.T) {
+	defects := &storedDefectRepo{defect: &models.Defect{ID: 1, ProjectID: 7, Status: "in_progress"}}
+	repo := &mockVerificationRepo{defects: defects}
+	members := service.NewMembershipService(&mockMemberRepo{}, &mockProjectRepo{}, &mockUserRepo{})
+	s := service.NewVerificationService(repo, defects, &photoAttachRepo{}, members)
+	ctx := context.Background()
+
+	// stakeholders do not submit fixes, outsiders see nothing
+	_, err := s.Submit(ctx, 1, "stakeholder", 7, 1, service.SubmitVerificationDTO{Comment: "done", AttachmentIDs: []uint{10}})
+	assert.ErrorIs(t, err, service.ErrForbidden)
+	_, err = s.List(ctx, 2, "engineer", 7, 1)
+	assert.ErrorIs(t, err, service.ErrForbidden)
+
+	_, err = s.Submit(ctx, 1, "engineer", 7, 1, service.SubmitVerificationDTO{Comment: "done", AttachmentIDs: []uint{10}})
+	assert.NoError(t, err)
+	_, err = s.Approve(ctx, 1, "engineer", 7, 1)
+	assert.ErrorIs(t, err, service.ErrForbidden)
+	_, err = s.Approve(ctx, 9, "manager", 7, 1)
+	assert.NoError(t, err)
+}
+
+func TestVerification_SubmitterCannotReview(t *testing.T) {
+	verifier := uint(1)
+	defects := &storedDefectRepo{defect: &models.Defect{ID: 1, ProjectID: 7, Status: "in_progress", VerifierID: &verifier}}
+	repo := &mockVerificationRepo{defects: defects}
+	members := service.NewMembershipService(&mockMemberRepo{}, &mockProjectRepo{}, &mockUserRepo{})
+	s := service.NewVerificationService(repo, defects, &photoAttachRepo{}, members)
+	ctx := context.Background()
+
+	_, err := s.Submit(ctx, 1, "engineer", 7, 1, service.SubmitVerificationDTO{Comment: "done", AttachmentIDs: []uint{10}})
+	assert.NoError(t, err)
+	_, err = s.Approve(ctx, 1, "engineer", 7, 1)
+	assert.ErrorIs(t, err, service.ErrForbidden)
+	_, err = s.Reject(ctx, 1, "engineer", 7, 1, service.RejectVerificationDTO{Reason: "not really"})
+	assert.ErrorIs(t, err, service.ErrForbidden)
+	assert.Equal(t, "verification", defects.defect.Status)
+
+	// a manager reviewing without a designated verifier did not submit it either
+	defects.defect.VerifierID = nil
+	repo.list[0].SubmittedByID = 9
+	_, err = s.Approve(ctx, 9, "manager", 7, 1)
+	assert.ErrorIs(t, err, service.ErrForbidden)
+}
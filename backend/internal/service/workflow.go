@@ -0,0 +1,78 @@
+package service
+
+import "fmt"
+
+// Defect statuses
+const (
+	StatusOpen       = "open"
+	StatusInProgress = "in_progress"
+	// StatusVerification waits for the verifier to approve or reject the fix
+	StatusVerification = "verification"
+	StatusClosed       = "closed"
+	StatusCancelled    = "cancelled"
+)
+
+// transition is an allowed status change. manage marks changes reserved to
+// users who manage the project (see MembershipService.CanManageProject),
+// verify those made only by submitting or reviewing a fix (VerificationService)
+type transition struct {
+	to     string
+	manage bool
+	verify bool
+}
+
+// defectWorkflow lists the status changes allowed from each status. A defect
+// is closed only by the verifier approving the fix.
+var defectWorkflow = map[string][]transition{
+	StatusOpen:         {{to: StatusInProgress}, {to: StatusCancelled, manage: true}},
+	StatusInProgress:   {{to: StatusOpen}, {to: StatusVerification, verify: true}, {to: StatusCancelled, manage: true}},
+	StatusVerification: {{to: StatusClosed, verify: true}, {to: StatusInProgress, verify: true}},
+	StatusClosed:       {{to: StatusOpen, manage: true}},
+	StatusCancelled:    {{to: StatusOpen, manage: true}},
+}
+
+// WorkflowError reports a status change the workflow does not allow
+type WorkflowError struct {
+	From string
+	To   string
+	// Verify is set when the change is only made through verification
+	Verify bool
+}
+
+func (e *WorkflowError) Error() string {
+	if _, ok := defectWorkflow[e.To]; !ok {
+		return fmt.Sprintf("unknown status %q", e.To)
+	}
+	if e.Verify {
+		return fmt.Sprintf("status changes from %s to %s through verification only", e.From, e.To)
+	}
+	return fmt.Sprintf("status cannot change from %s to %s", e.From, e.To)
+}
+
+// Is makes the error ErrInvalid
+func (e *WorkflowError) Is(target error) bool { return target == ErrInvalid }
+
+// checkTransition validates a direct status change and reports whether it
+// requires project management rights. Statuses outside the workflow, left
+// over from free-form data, are treated as open.
+func checkTransition(from, to string) (manage bool, err error) {
+	if _, ok := defectWorkflow[to]; !ok {
+		return false, &WorkflowError{From: from, To: to}
+	}
+	if from == to {
+		return false, nil
+	}
+	allowed, ok := defectWorkflow[from]
+	if !ok {
+		allowed = defectWorkflow[StatusOpen]
+	}
+	for _, t := range allowed {
+		if t.to == to {
+			if t.verify {
+				return false, &WorkflowError{From: from, To: to, Verify: true}
+			}
+			return t.manage, nil
+		}
+	}
+	return false, &WorkflowError{From: from, To: to}
+}
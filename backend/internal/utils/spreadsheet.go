@@ -0,0 +1,21 @@
+package utils
+
+import "strings"
+
+// SpreadsheetCell keeps a user-supplied value of a CSV export from being run
+// as a formula when the file is opened in a spreadsheet: a leading =, +, -,
+// @, tab or carriage return gets a ' in front
+func SpreadsheetCell(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+// SpreadsheetRow applies SpreadsheetCell to every cell of row in place
+func SpreadsheetRow(row []string) []string {
+	for i := range row {
+		row[i] = SpreadsheetCell(row[i])
+	}
+	return row
+}
@@ -166,7 +166,7 @@ export default function DefectDetail() {
     <div className="min-h-screen bg-gray-100">
       <Header />
       <main className="max-w-3xl mx-auto p-6">
-        <h1 className="text-2xl font-bold mb-4">Дефект: {defect?.title || defectId}</h1>
+        <h1 className="text-2xl font-bold mb-4">Дефект {defect?.key || `#${defectId}`}: {defect?.title}</h1>
         <section className="mb-4 bg-white p-4 rounded">
           <div className="text-sm text-gray-700 mb-2">{defect?.description}</div>
           <div className="text-xs text-gray-500">Приоритет: {defect?.priority || "-"}</div>
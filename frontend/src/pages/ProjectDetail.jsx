@@ -13,6 +13,11 @@ export default function ProjectDetail() {
   const [loading, setLoading] = useState(false);
   const [error, setError] = useState(null);
   const [users, setUsers] = useState([]);
+  const [priorities, setPriorities] = useState([
+    { value: "high", label: "Высокий" },
+    { value: "medium", label: "Средний" },
+    { value: "low", label: "Низкий" },
+  ]);
   const [assigneeQuery, setAssigneeQuery] = useState("");
   const [assigneeResults, setAssigneeResults] = useState([]);
   const [assigneeOpen, setAssigneeOpen] = useState(false);
@@ -61,6 +66,18 @@ export default function ProjectDetail() {
       } catch (e) {
         // ignore if endpoint not present
       }
+      try {
+        // allowed priorities of the project, highest first
+        const rm = await api.get(`/projects/${id}/meta`);
+        const meta = rm.data.data || rm.data;
+        if (meta?.priorities?.length) {
+          setPriorities(meta.priorities);
+          const def = meta.priorities.find((p) => p.default) || meta.priorities[0];
+          setForm((f) => ({ ...f, priority: def.value }));
+        }
+      } catch (e) {
+        // keep the built-in list
+      }
     };
     load();
   }, [id]);
@@ -193,9 +210,11 @@ export default function ProjectDetail() {
                   onChange={(e) => setForm({ ...form, priority: e.target.value })}
                   className="w-full border rounded p-2"
                 >
-                  <option value="low">Низкий</option>
-                  <option value="medium">Средний</option>
-                  <option value="high">Высокий</option>
+                  {priorities.map((p) => (
+                    <option key={p.value} value={p.value}>
+                      {p.label}
+                    </option>
+                  ))}
                 </select>
               </div>
 
@@ -245,7 +264,7 @@ export default function ProjectDetail() {
             {defects.length === 0 && <div className="text-gray-600">Нет дефектов</div>}
             {defects.map((d) => (
               <Link key={d.id} to={`/projects/${id}/defects/${d.id}`} className="block bg-white p-3 rounded border hover:shadow">
-                <h3 className="font-bold">{d.title}</h3>
+                <h3 className="font-bold"><span className="text-gray-500 mr-2">{d.key}</span>{d.title}</h3>
                 <div className="text-sm text-gray-700">{d.description}</div>
               </Link>
             ))}